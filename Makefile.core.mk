@@ -266,6 +266,12 @@ endef
 $(foreach bin,$(STANDARD_BINARIES),$(eval $(call build-linux,$(bin),$(STANDARD_TAGS))))
 $(foreach bin,$(LINUX_AGENT_BINARIES),$(eval $(call build-linux,$(bin),$(AGENT_TAGS))))
 
+# pilot-discovery built with cgo, which is required to load the PKCS#11 modules of HSMs holding the key of a plugged-in CA.
+# The binary is dynamically linked against the C library, unlike the static binaries above.
+.PHONY: $(TARGET_OUT_LINUX)/pilot-discovery-pkcs11
+$(TARGET_OUT_LINUX)/pilot-discovery-pkcs11: $(TARGET_OUT_LINUX)
+	GOOS=linux GOARCH=$(GOARCH_LOCAL) CGO_ENABLED=1 STATIC=0 common/scripts/gobuild.sh $@ -tags=$(STANDARD_TAGS) ./pilot/cmd/pilot-discovery
+
 # Create helper targets for each binary, like "pilot-discovery"
 # As an optimization, these still build everything
 $(foreach bin,$(BINARIES),$(shell basename $(bin))): build
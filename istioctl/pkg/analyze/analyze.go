@@ -80,6 +80,8 @@ var (
 	recursive         bool
 	ignoreUnknown     bool
 	revisionSpecified string
+	fix               bool
+	dryRun            bool
 
 	fileExtensions = []string{".json", ".yaml", ".yml"}
 )
@@ -112,6 +114,12 @@ func Analyze(ctx cli.Context) *cobra.Command {
   # and suppress MisplacedAnnotation on deployment foobar in namespace default.
   istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+  # Analyze yaml files and apply the suggested fixes to them
+  istioctl analyze --use-kube=false --fix a.yaml b.yaml
+
+  # Print the suggested fixes of yaml files as a diff without changing the files
+  istioctl analyze --fix --dry-run a.yaml b.yaml
+
   # List available analyzers
   istioctl analyze -L`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -123,6 +131,18 @@ func Analyze(ctx cli.Context) *cobra.Command {
 				}
 			}
 
+			if dryRun && !fix {
+				return util.CommandParseError{
+					Err: fmt.Errorf("--dry-run can only be used with --fix"),
+				}
+			}
+			if dryRun && isStructuredOutputFormat() {
+				// The diff would be mixed into the structured output.
+				return util.CommandParseError{
+					Err: fmt.Errorf("--dry-run can only be used with the %s output format", formatting.LogFormat),
+				}
+			}
+
 			if listAnalyzers {
 				fmt.Print(AnalyzersAsString(analyzers.All()))
 				return nil
@@ -264,6 +284,12 @@ func Analyze(ctx cli.Context) *cobra.Command {
 			}
 			fmt.Fprintln(cmd.OutOrStdout(), output)
 
+			if fix {
+				if err := applyFixes(cmd.OutOrStdout(), cmd.ErrOrStderr(), outputMessages, dryRun); err != nil {
+					return err
+				}
+			}
+
 			// An extra message on success
 			if len(outputMessages) == 0 {
 				if parseErrors == 0 {
@@ -333,6 +359,10 @@ func Analyze(ctx cli.Context) *cobra.Command {
 		"Don't complain about un-parseable input documents, for cases where analyze should run only on k8s compliant inputs.")
 	analysisCmd.PersistentFlags().StringVarP(&revisionSpecified, "revision", "", "default",
 		"analyze a specific revision deployed.")
+	analysisCmd.PersistentFlags().BoolVar(&fix, "fix", false,
+		"Apply the fixes suggested by the analyzers to the local YAML files the affected resources were read from.")
+	analysisCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false,
+		"Used with --fix, print the suggested fixes as a unified diff instead of changing the files. Requires the log output format.")
 	return analysisCmd
 }
 
@@ -343,7 +373,7 @@ func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error
 
 		// Handle "-" as stdin as a special case.
 		if f == "-" {
-			if isatty.IsTerminal(os.Stdin.Fd()) && !isJSONorYAMLOutputFormat() {
+			if isatty.IsTerminal(os.Stdin.Fd()) && !isStructuredOutputFormat() {
 				fmt.Fprint(cmd.OutOrStdout(), "Reading from stdin:\n")
 			}
 			r = os.Stdin
@@ -481,8 +511,8 @@ func analyzeTargetAsString() string {
 }
 
 // TODO: Refactor output writer so that it is smart enough to know when to output what.
-func isJSONorYAMLOutputFormat() bool {
-	return msgOutputFormat == formatting.JSONFormat || msgOutputFormat == formatting.YAMLFormat
+func isStructuredOutputFormat() bool {
+	return msgOutputFormat != formatting.LogFormat
 }
 
 type Client struct {
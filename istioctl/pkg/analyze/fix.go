@@ -0,0 +1,370 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/legacy/source/kube"
+)
+
+// applyFixes applies the fixes of the messages to the files the resources of the messages were read from.
+// When dryRun is set, the changes are written to w as a unified diff instead.
+func applyFixes(w, errW io.Writer, ms diag.Messages, dryRun bool) error {
+	byFile := map[string][]diag.Message{}
+	skipped := 0
+	for _, m := range ms {
+		if m.Fix == nil {
+			continue
+		}
+		file := fileOf(m)
+		if file == "" {
+			skipped++
+			continue
+		}
+		byFile[file] = append(byFile[file], m)
+	}
+	files := make([]string, 0, len(byFile))
+	for f := range byFile {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	fixed, changedFiles := 0, 0
+	for _, file := range files {
+		before, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		docs := splitDocuments(string(before))
+		n := 0
+		for _, m := range byFile[file] {
+			if err := fixDocument(docs, m); err != nil {
+				fmt.Fprintf(errW, "Could not fix %s in %s: %v\n", m.Type.Code(), file, err)
+				skipped++
+				continue
+			}
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		after, err := renderDocuments(docs)
+		if err != nil {
+			return err
+		}
+		fixed += n
+		changedFiles++
+		if dryRun {
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(string(before)),
+				B:        difflib.SplitLines(after),
+				FromFile: "a/" + file,
+				ToFile:   "b/" + file,
+				Context:  3,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(w, diff)
+			continue
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, []byte(after), fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	verb := "Fixed"
+	if dryRun {
+		verb = "Would fix"
+	}
+	fmt.Fprintf(errW, "%s %d issue(s) in %d file(s).\n", verb, fixed, changedFiles)
+	if skipped > 0 {
+		fmt.Fprintf(errW, "%d issue(s) with a suggested fix could not be fixed; only resources read from local YAML files can be fixed.\n", skipped)
+	}
+	return nil
+}
+
+// fileOf returns the local YAML file the resource of the message was read from, if any.
+func fileOf(m diag.Message) string {
+	if m.Resource == nil {
+		return ""
+	}
+	origin, ok := m.Resource.Origin.(*kube.Origin)
+	if !ok {
+		return ""
+	}
+	pos, ok := origin.Ref.(*kube.Position)
+	if !ok || pos.Filename == "-" || !isYAMLFile(pos.Filename) {
+		return ""
+	}
+	return pos.Filename
+}
+
+func isYAMLFile(name string) bool {
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")
+}
+
+// document is a document of a multi-document YAML file. Only documents that were
+// changed are re-encoded, so that the rest of the file is preserved as written.
+type document struct {
+	separator string
+	text      string
+	node      *yaml.Node
+	changed   bool
+}
+
+func splitDocuments(content string) []*document {
+	docs := []*document{{}}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed == "---" || strings.HasPrefix(line, "--- ") {
+			docs = append(docs, &document{separator: line})
+			continue
+		}
+		docs[len(docs)-1].text += line
+	}
+	return docs
+}
+
+func renderDocuments(docs []*document) (string, error) {
+	var sb strings.Builder
+	for _, d := range docs {
+		sb.WriteString(d.separator)
+		if !d.changed {
+			sb.WriteString(d.text)
+			continue
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(d.node); err != nil {
+			return "", err
+		}
+		if err := enc.Close(); err != nil {
+			return "", err
+		}
+		sb.Write(buf.Bytes())
+	}
+	return sb.String(), nil
+}
+
+// fixDocument applies the fix of the message to the document of its resource. The document
+// is left unchanged if any operation of the fix fails.
+func fixDocument(docs []*document, m diag.Message) error {
+	origin := m.Resource.Origin.(*kube.Origin)
+	for _, d := range docs {
+		if d.node == nil {
+			var node yaml.Node
+			if err := yaml.Unmarshal([]byte(d.text), &node); err != nil || node.Kind != yaml.DocumentNode {
+				continue
+			}
+			d.node = &node
+		}
+		root := d.node.Content[0]
+		if !isResource(root, origin) {
+			continue
+		}
+		patched := copyNode(root)
+		for _, op := range m.Fix.Patch {
+			if err := applyOperation(patched, op); err != nil {
+				return fmt.Errorf("%s %s: %v", op.Op, op.Path, err)
+			}
+		}
+		d.node.Content[0] = patched
+		d.changed = true
+		return nil
+	}
+	return fmt.Errorf("%s %s not found", origin.Type.Kind, origin.FullName)
+}
+
+func isResource(root *yaml.Node, origin *kube.Origin) bool {
+	kind := mappingValue(root, "kind")
+	metadata := mappingValue(root, "metadata")
+	if kind == nil || kind.Value != origin.Type.Kind || metadata == nil {
+		return false
+	}
+	name := mappingValue(metadata, "name")
+	if name == nil || name.Value != origin.FullName.Name.String() {
+		return false
+	}
+	ns := mappingValue(metadata, "namespace")
+	return ns == nil || ns.Value == origin.FullName.Namespace.String()
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, 0, len(n.Content))
+	for _, child := range n.Content {
+		c.Content = append(c.Content, copyNode(child))
+	}
+	return &c
+}
+
+// applyOperation applies a JSON patch operation to a YAML node.
+func applyOperation(root *yaml.Node, op diag.PatchOperation) error {
+	if !strings.HasPrefix(op.Path, "/") {
+		return fmt.Errorf("invalid path")
+	}
+	segments := strings.Split(op.Path[1:], "/")
+	for i, s := range segments {
+		segments[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+	}
+	parent := root
+	for _, s := range segments[:len(segments)-1] {
+		i, err := childIndex(parent, s)
+		if err != nil {
+			return err
+		}
+		parent = parent.Content[i]
+	}
+	key := segments[len(segments)-1]
+
+	switch op.Op {
+	case diag.PatchAdd:
+		value, err := valueNode(op.Value)
+		if err != nil {
+			return err
+		}
+		return addChild(parent, key, value)
+	case diag.PatchRemove:
+		i, err := childIndex(parent, key)
+		if err != nil {
+			return err
+		}
+		if parent.Kind == yaml.MappingNode {
+			i--
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+		} else {
+			parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+		}
+		return nil
+	case diag.PatchReplace:
+		i, err := childIndex(parent, key)
+		if err != nil {
+			return err
+		}
+		value, err := valueNode(op.Value)
+		if err != nil {
+			return err
+		}
+		parent.Content[i] = value
+		return nil
+	case diag.PatchTest:
+		i, err := childIndex(parent, key)
+		if err != nil {
+			return err
+		}
+		var got any
+		if err := parent.Content[i].Decode(&got); err != nil {
+			return err
+		}
+		gotJSON, err := json.Marshal(got)
+		if err != nil {
+			return err
+		}
+		wantJSON, err := json.Marshal(op.Value)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(gotJSON, wantJSON) {
+			return fmt.Errorf("value is %s, not %s", gotJSON, wantJSON)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported operation")
+	}
+}
+
+// childIndex returns the index in the content of the parent of the child at key.
+func childIndex(parent *yaml.Node, key string) (int, error) {
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == key {
+				return i + 1, nil
+			}
+		}
+		return 0, fmt.Errorf("field %q not found", key)
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(parent.Content) {
+			return 0, fmt.Errorf("index %q out of range", key)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("%q is not in an object or array", key)
+	}
+}
+
+// addChild adds the value at key of the parent, replacing an existing field of an object.
+func addChild(parent *yaml.Node, key string, value *yaml.Node) error {
+	switch parent.Kind {
+	case yaml.MappingNode:
+		if i, err := childIndex(parent, key); err == nil {
+			parent.Content[i] = value
+			return nil
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+		return nil
+	case yaml.SequenceNode:
+		if key == "-" {
+			parent.Content = append(parent.Content, value)
+			return nil
+		}
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i > len(parent.Content) {
+			return fmt.Errorf("index %q out of range", key)
+		}
+		parent.Content = append(parent.Content[:i], append([]*yaml.Node{value}, parent.Content[i:]...)...)
+		return nil
+	default:
+		return fmt.Errorf("%q is not in an object or array", key)
+	}
+}
+
+func valueNode(v any) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
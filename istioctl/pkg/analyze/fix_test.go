@@ -0,0 +1,218 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analyze
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/config/analysis/diag"
+)
+
+func runAnalyzeFix(t *testing.T, name string, args ...string) (string, string) {
+	t.Helper()
+	input, err := os.ReadFile(filepath.Join("testdata/analyze-fix", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, input, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := Analyze(cli.NewFakeContext(nil))
+	cmd.SetArgs(append([]string{"--use-kube=false", "--failure-threshold", "ERROR", file}, args...))
+	var out strings.Builder
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SilenceUsage = true
+	_ = cmd.Execute()
+
+	fixed, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), string(fixed)
+}
+
+func TestFix(t *testing.T) {
+	g := NewWithT(t)
+
+	out, fixed := runAnalyzeFix(t, "conflicting-hosts.yaml", "--fix")
+	g.Expect(out).To(ContainSubstring("Fixed 1 issue(s) in 1 file(s)."))
+	input, _ := os.ReadFile("testdata/analyze-fix/conflicting-hosts.yaml")
+	// Only the fixed document is re-encoded.
+	g.Expect(fixed).To(HavePrefix(strings.SplitAfter(string(input), "---\n")[0]))
+	g.Expect(fixed).To(HaveSuffix(`---
+# Also routes the reviews host, which conflicts with the reviews virtual service.
+apiVersion: networking.istio.io/v1
+kind: VirtualService
+metadata:
+  name: reviews-canary
+  namespace: default
+spec:
+  hosts:
+    - ratings
+  http:
+    - route:
+        - destination:
+            host: ratings
+`))
+}
+
+func TestFixUnknownSubset(t *testing.T) {
+	g := NewWithT(t)
+
+	out, fixed := runAnalyzeFix(t, "unknown-subset.yaml", "--fix")
+	// Only the subset of the reviews host, which has a single subset, is replaced.
+	g.Expect(out).To(ContainSubstring("Fixed 1 issue(s) in 1 file(s)."))
+	g.Expect(out).To(ContainSubstring(`reviews+v3`))
+	g.Expect(fixed).To(HavePrefix(`apiVersion: networking.istio.io/v1
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+    - reviews
+  http:
+    - route:
+        - destination:
+            host: reviews
+            subset: v1
+---
+`))
+	g.Expect(fixed).To(ContainSubstring("        host: ratings\n        subset: v3\n"))
+}
+
+func TestFixDryRun(t *testing.T) {
+	g := NewWithT(t)
+
+	out, fixed := runAnalyzeFix(t, "conflicting-hosts.yaml", "--fix", "--dry-run")
+	g.Expect(out).To(ContainSubstring("Would fix 1 issue(s) in 1 file(s)."))
+	g.Expect(out).To(ContainSubstring("-  - reviews\n"))
+	input, _ := os.ReadFile("testdata/analyze-fix/conflicting-hosts.yaml")
+	g.Expect(fixed).To(Equal(string(input)))
+}
+
+func TestDryRunRequiresFix(t *testing.T) {
+	g := NewWithT(t)
+
+	cmd := Analyze(cli.NewFakeContext(nil))
+	cmd.SetArgs([]string{"--use-kube=false", "--dry-run", "testdata/analyze-fix/conflicting-hosts.yaml"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	g.Expect(cmd.Execute()).To(MatchError(ContainSubstring("--dry-run can only be used with --fix")))
+}
+
+func TestDryRunRequiresLogOutput(t *testing.T) {
+	g := NewWithT(t)
+
+	cmd := Analyze(cli.NewFakeContext(nil))
+	cmd.SetArgs([]string{"--use-kube=false", "--fix", "--dry-run", "-o", "json", "testdata/analyze-fix/conflicting-hosts.yaml"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	g.Expect(cmd.Execute()).To(MatchError(ContainSubstring("--dry-run can only be used with the log output format")))
+}
+
+func TestApplyOperation(t *testing.T) {
+	cases := []struct {
+		name    string
+		op      diag.PatchOperation
+		want    string
+		wantErr string
+	}{
+		{
+			name: "add field",
+			op:   diag.PatchOperation{Op: diag.PatchAdd, Path: "/metadata/labels/istio-injection", Value: "enabled"},
+			want: "metadata:\n  name: foo\n  labels:\n    app: foo\n    istio-injection: enabled\nspec:\n  hosts:\n    - a\n    - b\n",
+		},
+		{
+			name: "add object",
+			op:   diag.PatchOperation{Op: diag.PatchAdd, Path: "/spec/tls", Value: map[string]any{"mode": "SIMPLE"}},
+			want: "metadata:\n  name: foo\n  labels:\n    app: foo\nspec:\n  hosts:\n    - a\n    - b\n  tls:\n    mode: SIMPLE\n",
+		},
+		{
+			name: "add array element",
+			op:   diag.PatchOperation{Op: diag.PatchAdd, Path: "/spec/hosts/1", Value: "c"},
+			want: "metadata:\n  name: foo\n  labels:\n    app: foo\nspec:\n  hosts:\n    - a\n    - c\n    - b\n",
+		},
+		{
+			name: "append array element",
+			op:   diag.PatchOperation{Op: diag.PatchAdd, Path: "/spec/hosts/-", Value: "c"},
+			want: "metadata:\n  name: foo\n  labels:\n    app: foo\nspec:\n  hosts:\n    - a\n    - b\n    - c\n",
+		},
+		{
+			name: "remove field",
+			op:   diag.PatchOperation{Op: diag.PatchRemove, Path: "/metadata/labels"},
+			want: "metadata:\n  name: foo\nspec:\n  hosts:\n    - a\n    - b\n",
+		},
+		{
+			name: "remove array element",
+			op:   diag.PatchOperation{Op: diag.PatchRemove, Path: "/spec/hosts/0"},
+			want: "metadata:\n  name: foo\n  labels:\n    app: foo\nspec:\n  hosts:\n    - b\n",
+		},
+		{
+			name: "replace",
+			op:   diag.PatchOperation{Op: diag.PatchReplace, Path: "/metadata/name", Value: "bar"},
+			want: "metadata:\n  name: bar\n  labels:\n    app: foo\nspec:\n  hosts:\n    - a\n    - b\n",
+		},
+		{
+			name: "test",
+			op:   diag.PatchOperation{Op: diag.PatchTest, Path: "/spec/hosts", Value: []string{"a", "b"}},
+			want: "metadata:\n  name: foo\n  labels:\n    app: foo\nspec:\n  hosts:\n    - a\n    - b\n",
+		},
+		{
+			name:    "failed test",
+			op:      diag.PatchOperation{Op: diag.PatchTest, Path: "/spec/hosts/0", Value: "b"},
+			wantErr: `value is "a", not "b"`,
+		},
+		{
+			name:    "missing field",
+			op:      diag.PatchOperation{Op: diag.PatchRemove, Path: "/spec/gateways"},
+			wantErr: `field "gateways" not found`,
+		},
+		{
+			name:    "index out of range",
+			op:      diag.PatchOperation{Op: diag.PatchRemove, Path: "/spec/hosts/2"},
+			wantErr: `index "2" out of range`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			var doc yaml.Node
+			g.Expect(yaml.Unmarshal([]byte("metadata:\n  name: foo\n  labels:\n    app: foo\nspec:\n  hosts:\n  - a\n  - b\n"), &doc)).To(Succeed())
+			err := applyOperation(doc.Content[0], tc.op)
+			if tc.wantErr != "" {
+				g.Expect(err).To(MatchError(tc.wantErr))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			docs := []*document{{node: &doc, changed: true}}
+			got, err := renderDocuments(docs)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(got).To(Equal(tc.want))
+		})
+	}
+}
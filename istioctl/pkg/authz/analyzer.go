@@ -45,6 +45,23 @@ func NewAnalyzer(envoyConfig *configdump.Wrapper) (*Analyzer, error) {
 
 // Print print the analysis results.
 func (a *Analyzer) Print(writer io.Writer) {
+	listeners, err := a.listeners()
+	if err != nil {
+		return
+	}
+	Print(writer, listeners)
+}
+
+// Evaluate evaluates the request against the authorization policy of the proxy.
+func (a *Analyzer) Evaluate(req *Request) (*Decision, error) {
+	listeners, err := a.listeners()
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(listeners, req)
+}
+
+func (a *Analyzer) listeners() ([]*listener.Listener, error) {
 	var listeners []*listener.Listener
 	for _, l := range a.listenerDump.DynamicListeners {
 		listenerTyped := &listener.Listener{}
@@ -52,9 +69,9 @@ func (a *Analyzer) Print(writer io.Writer) {
 		l.ActiveState.Listener.TypeUrl = v3.ListenerType
 		err := l.ActiveState.Listener.UnmarshalTo(listenerTyped)
 		if err != nil {
-			return
+			return nil, fmt.Errorf("failed to unmarshal listener %s: %v", l.Name, err)
 		}
 		listeners = append(listeners, listenerTyped)
 	}
-	Print(writer, listeners)
+	return listeners, nil
 }
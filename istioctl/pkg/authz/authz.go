@@ -16,9 +16,11 @@ package authz
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -33,7 +35,16 @@ import (
 
 var configDumpFile string
 
+// requestFlags are the flags describing the request to evaluate.
+var requestFlags = []string{
+	"source-principal", "source-ip", "remote-ip", "destination-ip", "port", "sni",
+	"method", "path", "host", "header", "jwt-claims",
+}
+
 func checkCmd(ctx cli.Context) *cobra.Command {
+	req := &Request{}
+	var headers []string
+	var claims string
 	cmd := &cobra.Command{
 		Use:   "check [<type>/]<name>[.<namespace>]",
 		Short: "Check AuthorizationPolicy applied in the pod.",
@@ -42,7 +53,11 @@ the Envoy configuration of the pod. The command is especially useful for inspect
 the policy propagation from Istiod to Envoy and the final AuthorizationPolicy list merged
 from multiple sources (mesh-level, namespace-level and workload-level).
 
-The command also supports reading from a standalone config dump file with flag -f.`,
+The command also supports reading from a standalone config dump file with flag -f.
+
+If any of the request flags is set, the command instead evaluates the described request
+against the AuthorizationPolicy of the pod, and prints whether it is allowed or denied,
+and which policy and rule decided it.`,
 		Example: `  # Check AuthorizationPolicy applied to pod httpbin-88ddbcfdd-nt5jb:
   istioctl x authz check httpbin-88ddbcfdd-nt5jb
 
@@ -50,7 +65,15 @@ The command also supports reading from a standalone config dump file with flag -
   istioctl x authz check deployment/productpage-v1
 
   # Check AuthorizationPolicy from Envoy config dump file:
-  istioctl x authz check -f httpbin_config_dump.json`,
+  istioctl x authz check -f httpbin_config_dump.json
+
+  # Check whether a GET request from the sleep service account to port 8000 of pod httpbin-88ddbcfdd-nt5jb is allowed:
+  istioctl x authz check httpbin-88ddbcfdd-nt5jb --source-principal cluster.local/ns/default/sa/sleep \
+    --port 8000 --method GET --path /headers
+
+  # Check whether a request with a JWT is allowed, from Envoy config dump file:
+  istioctl x authz check -f httpbin_config_dump.json --method GET --path /ip \
+    --header x-token=admin --jwt-claims '{"iss": "https://example.com", "sub": "alice", "groups": ["admin"]}'`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 1 {
 				cmd.Println(cmd.UsageString())
@@ -86,16 +109,71 @@ The command also supports reading from a standalone config dump file with flag -
 			if err != nil {
 				return err
 			}
-			analyzer.Print(cmd.OutOrStdout())
+			if !hasRequestFlags(cmd) {
+				analyzer.Print(cmd.OutOrStdout())
+				return nil
+			}
+			if err := completeRequest(req, headers, claims); err != nil {
+				return err
+			}
+			decision, err := analyzer.Evaluate(req)
+			if err != nil {
+				return fmt.Errorf("failed to evaluate request: %v", err)
+			}
+			PrintDecision(cmd.OutOrStdout(), decision)
 			return nil
 		},
 		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
 	}
 	cmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"The json file with Envoy config dump to be checked")
+	cmd.PersistentFlags().StringVar(&req.SourcePrincipal, "source-principal", "",
+		"The mTLS identity of the client of the request to evaluate, e.g. cluster.local/ns/default/sa/sleep. "+
+			"Leave empty for plaintext requests")
+	cmd.PersistentFlags().StringVar(&req.SourceIP, "source-ip", "", "The source IP address of the request to evaluate")
+	cmd.PersistentFlags().StringVar(&req.RemoteIP, "remote-ip", "",
+		"The original client IP address of the request to evaluate, defaults to --source-ip")
+	cmd.PersistentFlags().StringVar(&req.DestinationIP, "destination-ip", "", "The destination IP address of the request to evaluate")
+	cmd.PersistentFlags().Uint32Var(&req.Port, "port", 0, "The destination port of the request to evaluate")
+	cmd.PersistentFlags().StringVar(&req.SNI, "sni", "", "The server name requested by the client of the request to evaluate")
+	cmd.PersistentFlags().StringVar(&req.Method, "method", "", "The HTTP method of the request to evaluate")
+	cmd.PersistentFlags().StringVar(&req.Path, "path", "", "The HTTP path of the request to evaluate")
+	cmd.PersistentFlags().StringVar(&req.Host, "host", "", "The HTTP host of the request to evaluate")
+	cmd.PersistentFlags().StringArrayVar(&headers, "header", nil,
+		"A header of the request to evaluate in the form name=value, can be repeated")
+	cmd.PersistentFlags().StringVar(&claims, "jwt-claims", "",
+		"The claims of the validated JWT of the request to evaluate, as a JSON object")
 	return cmd
 }
 
+func hasRequestFlags(cmd *cobra.Command) bool {
+	for _, name := range requestFlags {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// completeRequest sets the headers and JWT claims of the request from their flags.
+func completeRequest(req *Request, headers []string, claims string) error {
+	req.SourcePrincipal = NormalizePrincipal(req.SourcePrincipal)
+	req.Headers = map[string]string{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid header %q, expecting name=value", header)
+		}
+		req.Headers[name] = value
+	}
+	if claims != "" {
+		if err := json.Unmarshal([]byte(claims), &req.Claims); err != nil {
+			return fmt.Errorf("invalid JWT claims %q: %v", claims, err)
+		}
+	}
+	return nil
+}
+
 func getConfigDumpFromFile(filename string) (*configdump.Wrapper, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -37,11 +37,34 @@ ALLOW    _anonymous_match_nothing_   1
 ALLOW    httpbin.default             1
 `,
 		},
+		{
+			Args: []string{
+				"-f", "testdata/configdump.yaml", "--port", "80", "--method", "GET", "--path", "/info/1",
+				"--source-principal", "cluster.local/ns/default/sa/sleep", "--jwt-claims", `{"iss": "https://accounts.google.com"}`,
+			},
+			ExpectedOutput: `ACTION   AuthorizationPolicy   RULE   REASON
+ALLOW    httpbin.default       0      request matches an ALLOW policy
+`,
+		},
+		{
+			Args: []string{
+				"-f", "testdata/configdump.yaml", "--port", "80", "--method", "DELETE", "--path", "/info/1",
+				"--source-principal", "cluster.local/ns/default/sa/sleep", "--jwt-claims", `{"iss": "https://accounts.google.com"}`,
+			},
+			ExpectedOutput: `ACTION   AuthorizationPolicy   RULE   REASON
+DENY     -                     -      request matches no ALLOW policy
+`,
+		},
+		{
+			Args:           []string{"-f", "testdata/configdump.yaml", "--header", "x-token"},
+			ExpectedOutput: "Error: invalid header \"x-token\", expecting name=value\n",
+			WantException:  true,
+		},
 	}
 
-	authzCmd := checkCmd(cli.NewFakeContext(&cli.NewFakeContextOption{}))
 	for i, c := range cases {
 		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.Args, " ")), func(t *testing.T) {
+			authzCmd := checkCmd(cli.NewFakeContext(&cli.NewFakeContextOption{}))
 			testutil.VerifyOutput(t, authzCmd, c)
 		})
 	}
@@ -0,0 +1,344 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"istio.io/istio/pilot/pkg/model"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	"istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	// ActionCustom is the action of requests delegated to an external authorizer.
+	ActionCustom = "CUSTOM"
+
+	authnClaimsKey    = "request.auth.claims"
+	authnPrincipalKey = "request.auth.principal"
+	authnAudiencesKey = "request.auth.audiences"
+	authnPresenterKey = "request.auth.presenter"
+	jwtPayloadKey     = "payload"
+)
+
+// Request is a synthetic request evaluated against the authorization policy of a proxy.
+type Request struct {
+	// SourcePrincipal is the mTLS identity of the client, e.g. "cluster.local/ns/default/sa/sleep". It is empty for
+	// plaintext requests.
+	SourcePrincipal string
+	// SourceIP is the IP address of the downstream connection.
+	SourceIP string
+	// RemoteIP is the original client IP address, defaulting to SourceIP.
+	RemoteIP string
+	// DestinationIP is the IP address the connection was sent to.
+	DestinationIP string
+	// Port is the destination port of the request.
+	Port uint32
+	// SNI is the server name requested by the client.
+	SNI string
+
+	Method string
+	Path   string
+	Host   string
+	// Headers are the other request headers, keyed by name.
+	Headers map[string]string
+	// Claims is the payload of the validated JWT of the request, if any.
+	Claims map[string]any
+}
+
+// Decision is the result of evaluating a request.
+type Decision struct {
+	// Action is ALLOW, DENY or CUSTOM.
+	Action string
+	// Policy is the AuthorizationPolicy deciding the request, in the form name.namespace, if any.
+	Policy string
+	// Rule is the index of the rule of the policy matching the request.
+	Rule string
+	// Reason explains the decision.
+	Reason string
+}
+
+// rbacConfig is implemented by the configs of both the HTTP and network RBAC filters.
+type rbacConfig interface {
+	GetRules() *rbacpb.RBAC
+	GetShadowRules() *rbacpb.RBAC
+	GetShadowRulesStatPrefix() string
+}
+
+// Evaluate evaluates the request against the RBAC filters of the listeners, the way the proxy would.
+func Evaluate(listeners []*listener.Listener, req *Request) (*Decision, error) {
+	fc, err := selectFilterChain(parse(listeners), req)
+	if err != nil {
+		return nil, err
+	}
+	// Network filters run before the HTTP filters. The builder orders the filters as CUSTOM, DENY, ALLOW and AUDIT.
+	var configs []rbacConfig
+	for _, c := range fc.rbacTCP {
+		configs = append(configs, c)
+	}
+	for _, c := range fc.rbacHTTP {
+		configs = append(configs, c)
+	}
+
+	var allowed *Decision
+	for _, c := range configs {
+		if c.GetShadowRulesStatPrefix() == authzmodel.RBACExtAuthzShadowRulesStatPrefix {
+			name, err := firstMatch(c.GetShadowRules(), req)
+			if err != nil {
+				return nil, err
+			}
+			if name != "" {
+				return newDecision(ActionCustom, name, "request is sent to the external authorizer of the CUSTOM policy"), nil
+			}
+			continue
+		}
+		// Shadow rules of dry-run policies never affect the decision.
+		rules := c.GetRules()
+		if rules == nil {
+			continue
+		}
+		switch rules.GetAction() {
+		case rbacpb.RBAC_DENY:
+			name, err := firstMatch(rules, req)
+			if err != nil {
+				return nil, err
+			}
+			if name != "" {
+				return newDecision(rbacpb.RBAC_DENY.String(), name, "request matches a DENY policy"), nil
+			}
+		case rbacpb.RBAC_ALLOW:
+			name, err := firstMatch(rules, req)
+			if err != nil {
+				return nil, err
+			}
+			if name == "" {
+				return &Decision{Action: rbacpb.RBAC_DENY.String(), Reason: "request matches no ALLOW policy"}, nil
+			}
+			if allowed == nil {
+				allowed = newDecision(rbacpb.RBAC_ALLOW.String(), name, "request matches an ALLOW policy")
+			}
+		}
+	}
+	if allowed != nil {
+		return allowed, nil
+	}
+	return &Decision{Action: rbacpb.RBAC_ALLOW.String(), Reason: "no ALLOW or DENY policy applies"}, nil
+}
+
+func newDecision(action, name, reason string) *Decision {
+	d := &Decision{Action: action, Policy: name, Reason: reason}
+	if re.MatchString(name) {
+		d.Policy, d.Rule = extractName(name)
+	}
+	return d
+}
+
+// firstMatch returns the name of the first policy of the rules matching the request, or empty if none matches.
+func firstMatch(rules *rbacpb.RBAC, req *Request) (string, error) {
+	names := make([]string, 0, len(rules.GetPolicies()))
+	for name := range rules.GetPolicies() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		matched, err := matchPolicy(rules.GetPolicies()[name], req)
+		if err != nil {
+			return "", fmt.Errorf("failed to evaluate policy %s: %v", name, err)
+		}
+		if matched {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// selectFilterChain returns the filter chain handling the request. For sidecars, this is the inbound filter chain of
+// the destination port, falling back to the passthrough filter chains. Otherwise, it is a filter chain of the listener
+// on the destination port. HTTP filter chains are preferred, as are TLS filter chains for mTLS requests.
+func selectFilterChain(listeners []*parsedListener, req *Request) (*filterChain, error) {
+	var candidates []*filterChain
+	for _, l := range listeners {
+		if l.name != model.VirtualInboundListenerName {
+			continue
+		}
+		var passthrough []*filterChain
+		for _, fc := range l.filterChains {
+			switch port := fc.match.GetDestinationPort(); {
+			case port == nil:
+				passthrough = append(passthrough, fc)
+			case port.GetValue() == req.Port:
+				candidates = append(candidates, fc)
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = passthrough
+		}
+	}
+	if len(candidates) == 0 {
+		for _, l := range listeners {
+			if l.name == model.VirtualInboundListenerName || (req.Port != 0 && l.port != req.Port) {
+				continue
+			}
+			candidates = append(candidates, l.filterChains...)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no filter chain found for port %d", req.Port)
+	}
+
+	wantTLS := req.SourcePrincipal != ""
+	var selected *filterChain
+	best := -1
+	for _, fc := range candidates {
+		score := 0
+		if fc.http {
+			score += 2
+		}
+		if (fc.match.GetTransportProtocol() == "tls") == wantTLS {
+			score++
+		}
+		if score > best {
+			selected, best = fc, score
+		}
+	}
+	return selected, nil
+}
+
+// pathWithoutQuery returns the path matched by url_path matchers.
+func (r *Request) pathWithoutQuery() string {
+	path, _, _ := strings.Cut(r.Path, "?")
+	return path
+}
+
+func (r *Request) remoteIP() string {
+	if r.RemoteIP != "" {
+		return r.RemoteIP
+	}
+	return r.SourceIP
+}
+
+// header returns the value of a request header, including the pseudo-headers.
+func (r *Request) header(name string) (string, bool) {
+	var value string
+	switch name = strings.ToLower(name); name {
+	case ":method":
+		value = r.Method
+	case ":path":
+		value = r.Path
+	case ":authority", "host":
+		value = r.Host
+	default:
+		for k, v := range r.Headers {
+			if strings.EqualFold(k, name) {
+				return v, true
+			}
+		}
+		return "", false
+	}
+	return value, value != ""
+}
+
+// metadata returns the dynamic metadata written by the JWT and Istio authentication filters for the request.
+func (r *Request) metadata() map[string]*structpb.Struct {
+	if len(r.Claims) == 0 {
+		return nil
+	}
+	authn := map[string]any{authnClaimsKey: claimsAsLists(r.Claims)}
+	iss, _ := r.Claims["iss"].(string)
+	sub, _ := r.Claims["sub"].(string)
+	if iss != "" && sub != "" {
+		authn[authnPrincipalKey] = iss + "/" + sub
+	}
+	switch aud := r.Claims["aud"].(type) {
+	case string:
+		authn[authnAudiencesKey] = aud
+	case []any:
+		var audiences []string
+		for _, a := range aud {
+			audiences = append(audiences, fmt.Sprint(a))
+		}
+		authn[authnAudiencesKey] = strings.Join(audiences, ",")
+	}
+	if azp, ok := r.Claims["azp"].(string); ok {
+		authn[authnPresenterKey] = azp
+	}
+
+	metadata := map[string]*structpb.Struct{}
+	for filter, fields := range map[string]map[string]any{
+		filters.AuthnFilterName:    authn,
+		filters.EnvoyJwtFilterName: {jwtPayloadKey: r.Claims},
+	} {
+		s, err := structpb.NewStruct(fields)
+		if err != nil {
+			log.Errorf("failed to convert JWT claims to metadata: %v", err)
+			continue
+		}
+		metadata[filter] = s
+	}
+	return metadata
+}
+
+// claimsAsLists converts the string claims to lists, the way the Istio authentication filter stores them.
+func claimsAsLists(claims map[string]any) map[string]any {
+	out := make(map[string]any, len(claims))
+	for k, v := range claims {
+		switch v := v.(type) {
+		case string:
+			out[k] = []any{v}
+		case map[string]any:
+			out[k] = claimsAsLists(v)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// NormalizePrincipal adds the SPIFFE prefix to the principal if it is missing.
+func NormalizePrincipal(principal string) string {
+	if principal == "" || strings.HasPrefix(principal, spiffe.URIPrefix) {
+		return principal
+	}
+	return spiffe.URIPrefix + principal
+}
+
+// PrintDecision prints the decision of a request.
+func PrintDecision(writer io.Writer, d *Decision) {
+	orNone := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	buf := strings.Builder{}
+	buf.WriteString("ACTION\tAuthorizationPolicy\tRULE\tREASON\n")
+	buf.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\n", d.Action, orNone(d.Policy), orNone(d.Rule), d.Reason))
+
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 3, ' ', 0)
+	if _, err := fmt.Fprint(w, buf.String()); err != nil {
+		log.Errorf("failed to print output: %s", err)
+	}
+	_ = w.Flush()
+}
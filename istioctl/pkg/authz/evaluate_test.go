@@ -0,0 +1,238 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"testing"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+
+	"istio.io/api/annotation"
+	authpb "istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/security/authz/builder"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/wellknown"
+)
+
+func inboundListener(httpFilters []*hcm.HttpFilter) *listener.Listener {
+	cm := &hcm.HttpConnectionManager{HttpFilters: httpFilters}
+	return &listener.Listener{
+		Name: model.VirtualInboundListenerName,
+		FilterChains: []*listener.FilterChain{{
+			Filters: []*listener.Filter{{
+				Name:       wellknown.HTTPConnectionManager,
+				ConfigType: &listener.Filter_TypedConfig{TypedConfig: protoconv.MessageToAny(cm)},
+			}},
+		}},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	policies := model.AuthorizationPoliciesResult{
+		Deny: []model.AuthorizationPolicy{
+			{
+				Name:      "deny-admin",
+				Namespace: "default",
+				Spec: &authpb.AuthorizationPolicy{
+					Action: authpb.AuthorizationPolicy_DENY,
+					Rules: []*authpb.Rule{{
+						To: []*authpb.Rule_To{{Operation: &authpb.Operation{Paths: []string{"/admin/*"}}}},
+					}},
+				},
+			},
+			{
+				Name:        "deny-all-dry-run",
+				Namespace:   "default",
+				Annotations: map[string]string{annotation.IoIstioDryRun.Name: "true"},
+				Spec: &authpb.AuthorizationPolicy{
+					Action: authpb.AuthorizationPolicy_DENY,
+					Rules:  []*authpb.Rule{{}},
+				},
+			},
+		},
+		Allow: []model.AuthorizationPolicy{
+			{
+				Name:      "allow-sleep",
+				Namespace: "default",
+				Spec: &authpb.AuthorizationPolicy{
+					Rules: []*authpb.Rule{{
+						From: []*authpb.Rule_From{{Source: &authpb.Source{Principals: []string{"cluster.local/ns/default/sa/sleep"}}}},
+						To: []*authpb.Rule_To{{Operation: &authpb.Operation{
+							Methods: []string{"GET"},
+							Paths:   []string{"/info*", "/admin/*"},
+							Ports:   []string{"8000"},
+						}}},
+					}},
+				},
+			},
+			{
+				Name:      "allow-jwt",
+				Namespace: "default",
+				Spec: &authpb.AuthorizationPolicy{
+					Rules: []*authpb.Rule{
+						{
+							From: []*authpb.Rule_From{{Source: &authpb.Source{RequestPrincipals: []string{"https://example.com/alice"}}}},
+							To:   []*authpb.Rule_To{{Operation: &authpb.Operation{Hosts: []string{"httpbin.example.com"}}}},
+						},
+						{
+							To:   []*authpb.Rule_To{{Operation: &authpb.Operation{Paths: []string{"/groups"}}}},
+							When: []*authpb.Condition{{Key: "request.auth.claims[groups]", Values: []string{"admin"}}},
+						},
+					},
+				},
+			},
+			{
+				Name:      "allow-ip",
+				Namespace: "default",
+				Spec: &authpb.AuthorizationPolicy{
+					Rules: []*authpb.Rule{{
+						From: []*authpb.Rule_From{{Source: &authpb.Source{IpBlocks: []string{"10.0.0.0/8"}, NotNamespaces: []string{"test"}}}},
+						When: []*authpb.Condition{{Key: "request.headers[x-token]", Values: []string{"secret"}}},
+					}},
+				},
+			},
+		},
+		Audit: []model.AuthorizationPolicy{
+			{
+				Name:      "audit-all",
+				Namespace: "default",
+				Spec: &authpb.AuthorizationPolicy{
+					Action: authpb.AuthorizationPolicy_AUDIT,
+					Rules:  []*authpb.Rule{{}},
+				},
+			},
+		},
+	}
+
+	sleep := "spiffe://cluster.local/ns/default/sa/sleep"
+	cases := []struct {
+		name string
+		req  *Request
+		want *Decision
+	}{
+		{
+			name: "allowed by principal, method, path and port",
+			req:  &Request{SourcePrincipal: sleep, Port: 8000, Method: "GET", Path: "/info/1?q=1"},
+			want: &Decision{Action: "ALLOW", Policy: "allow-sleep.default", Rule: "0", Reason: "request matches an ALLOW policy"},
+		},
+		{
+			name: "denied before allowed",
+			req:  &Request{SourcePrincipal: sleep, Port: 8000, Method: "GET", Path: "/admin/users"},
+			want: &Decision{Action: "DENY", Policy: "deny-admin.default", Rule: "0", Reason: "request matches a DENY policy"},
+		},
+		{
+			name: "wrong port",
+			req:  &Request{SourcePrincipal: sleep, Port: 9000, Method: "GET", Path: "/info"},
+			want: &Decision{Action: "DENY", Reason: "request matches no ALLOW policy"},
+		},
+		{
+			name: "wrong method",
+			req:  &Request{SourcePrincipal: sleep, Port: 8000, Method: "POST", Path: "/info"},
+			want: &Decision{Action: "DENY", Reason: "request matches no ALLOW policy"},
+		},
+		{
+			name: "allowed by request principal and host",
+			req: &Request{
+				Method: "GET", Path: "/", Host: "HTTPBIN.example.com",
+				Claims: map[string]any{"iss": "https://example.com", "sub": "alice"},
+			},
+			want: &Decision{Action: "ALLOW", Policy: "allow-jwt.default", Rule: "0", Reason: "request matches an ALLOW policy"},
+		},
+		{
+			name: "wrong request principal",
+			req: &Request{
+				Method: "GET", Path: "/", Host: "httpbin.example.com",
+				Claims: map[string]any{"iss": "https://example.com", "sub": "bob"},
+			},
+			want: &Decision{Action: "DENY", Reason: "request matches no ALLOW policy"},
+		},
+		{
+			name: "allowed by claim",
+			req: &Request{
+				Method: "GET", Path: "/groups",
+				Claims: map[string]any{"iss": "https://example.com", "sub": "bob", "groups": []any{"user", "admin"}},
+			},
+			want: &Decision{Action: "ALLOW", Policy: "allow-jwt.default", Rule: "1", Reason: "request matches an ALLOW policy"},
+		},
+		{
+			name: "allowed by IP and header",
+			req:  &Request{SourceIP: "10.1.2.3", Method: "GET", Path: "/", Headers: map[string]string{"X-Token": "secret"}},
+			want: &Decision{Action: "ALLOW", Policy: "allow-ip.default", Rule: "0", Reason: "request matches an ALLOW policy"},
+		},
+		{
+			name: "wrong IP",
+			req:  &Request{SourceIP: "192.168.1.1", Method: "GET", Path: "/", Headers: map[string]string{"x-token": "secret"}},
+			want: &Decision{Action: "DENY", Reason: "request matches no ALLOW policy"},
+		},
+		{
+			name: "excluded namespace",
+			req: &Request{
+				SourcePrincipal: "spiffe://cluster.local/ns/test/sa/default", SourceIP: "10.1.2.3",
+				Method: "GET", Path: "/", Headers: map[string]string{"x-token": "secret"},
+			},
+			want: &Decision{Action: "DENY", Reason: "request matches no ALLOW policy"},
+		},
+	}
+	for _, option := range []builder.Option{{}, {UseFilterState: true}, {UseExtendedJwt: true}} {
+		b := builder.New(trustdomain.NewBundle("cluster.local", nil), nil, policies, option)
+		listeners := []*listener.Listener{inboundListener(b.BuildHTTP())}
+		for _, tt := range cases {
+			t.Run(fmt.Sprintf("%+v/%s", option, tt.name), func(t *testing.T) {
+				got, err := Evaluate(listeners, tt.req)
+				assert.NoError(t, err)
+				assert.Equal(t, got, tt.want)
+			})
+		}
+	}
+}
+
+func TestEvaluateCustomAndNoPolicy(t *testing.T) {
+	custom := &rbachttp.RBAC{
+		ShadowRules: &rbacpb.RBAC{
+			Action: rbacpb.RBAC_DENY,
+			Policies: map[string]*rbacpb.Policy{
+				"istio-ext-authz-ns[foo]-policy[ext-authz]-rule[0]": {
+					Permissions: []*rbacpb.Permission{{Rule: &rbacpb.Permission_Any{Any: true}}},
+					Principals:  []*rbacpb.Principal{{Identifier: &rbacpb.Principal_Any{Any: true}}},
+				},
+			},
+		},
+		ShadowRulesStatPrefix: authzmodel.RBACExtAuthzShadowRulesStatPrefix,
+	}
+	listeners := []*listener.Listener{inboundListener([]*hcm.HttpFilter{{
+		Name:       wellknown.HTTPRoleBasedAccessControl,
+		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: protoconv.MessageToAny(custom)},
+	}})}
+	got, err := Evaluate(listeners, &Request{Method: "GET", Path: "/"})
+	assert.NoError(t, err)
+	assert.Equal(t, got, &Decision{
+		Action: ActionCustom,
+		Policy: "ext-authz.foo",
+		Rule:   "0",
+		Reason: "request is sent to the external authorizer of the CUSTOM policy",
+	})
+
+	got, err = Evaluate([]*listener.Listener{inboundListener(nil)}, &Request{Method: "GET", Path: "/"})
+	assert.NoError(t, err)
+	assert.Equal(t, got, &Decision{Action: "ALLOW", Reason: "no ALLOW or DENY policy applies"})
+}
@@ -41,11 +41,15 @@ const (
 var re = regexp.MustCompile(`ns\[(.+)\]-policy\[(.+)\]-rule\[(.+)\]`)
 
 type filterChain struct {
+	match    *listener.FilterChainMatch
+	http     bool
 	rbacHTTP []*rbachttp.RBAC
 	rbacTCP  []*rbactcp.RBAC
 }
 
 type parsedListener struct {
+	name         string
+	port         uint32
 	filterChains []*filterChain
 }
 
@@ -81,13 +85,14 @@ func getHTTPFilterConfig(filter *hcm.HttpFilter, out proto.Message) error {
 func parse(listeners []*listener.Listener) []*parsedListener {
 	var parsedListeners []*parsedListener
 	for _, l := range listeners {
-		parsed := &parsedListener{}
+		parsed := &parsedListener{name: l.GetName(), port: l.GetAddress().GetSocketAddress().GetPortValue()}
 		for _, fc := range l.FilterChains {
-			parsedFC := &filterChain{}
+			parsedFC := &filterChain{match: fc.GetFilterChainMatch()}
 			for _, filter := range fc.Filters {
 				switch filter.Name {
 				case wellknown.HTTPConnectionManager, "envoy.http_connection_manager":
 					if cm := getHTTPConnectionManager(filter); cm != nil {
+						parsedFC.http = true
 						for _, httpFilter := range cm.GetHttpFilters() {
 							switch httpFilter.GetName() {
 							case wellknown.HTTPRoleBasedAccessControl:
@@ -0,0 +1,356 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	uritemplate "github.com/envoyproxy/go-control-plane/envoy/extensions/path/match/uri_template/v3"
+	matcherpb "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// peerPrincipalFilterState is the filter state holding the peer principal, used instead of the authenticated
+// principal when the proxy does not terminate mTLS itself.
+const peerPrincipalFilterState = "io.istio.peer_principal"
+
+// matchPolicy returns true if the request matches any permission and any principal of the policy, following the
+// semantics of the Envoy RBAC filter.
+func matchPolicy(policy *rbacpb.Policy, req *Request) (bool, error) {
+	if policy.GetCondition() != nil || policy.GetCheckedCondition() != nil {
+		return false, fmt.Errorf("CEL conditions are not supported")
+	}
+	permitted := false
+	for _, permission := range policy.GetPermissions() {
+		ok, err := matchPermission(permission, req)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			permitted = true
+			break
+		}
+	}
+	if !permitted {
+		return false, nil
+	}
+	for _, principal := range policy.GetPrincipals() {
+		ok, err := matchPrincipal(principal, req)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchPermission(permission *rbacpb.Permission, req *Request) (bool, error) {
+	switch rule := permission.GetRule().(type) {
+	case *rbacpb.Permission_Any:
+		return rule.Any, nil
+	case *rbacpb.Permission_AndRules:
+		for _, p := range rule.AndRules.GetRules() {
+			if ok, err := matchPermission(p, req); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case *rbacpb.Permission_OrRules:
+		for _, p := range rule.OrRules.GetRules() {
+			if ok, err := matchPermission(p, req); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case *rbacpb.Permission_NotRule:
+		ok, err := matchPermission(rule.NotRule, req)
+		return !ok, err
+	case *rbacpb.Permission_Header:
+		return matchHeader(rule.Header, req)
+	case *rbacpb.Permission_UrlPath:
+		return matchString(rule.UrlPath.GetPath(), req.pathWithoutQuery())
+	case *rbacpb.Permission_UriTemplate:
+		return matchURITemplate(rule.UriTemplate, req.pathWithoutQuery())
+	case *rbacpb.Permission_DestinationIp:
+		return matchCIDR(rule.DestinationIp, req.DestinationIP)
+	case *rbacpb.Permission_DestinationPort:
+		return req.Port == rule.DestinationPort, nil
+	case *rbacpb.Permission_DestinationPortRange:
+		r := rule.DestinationPortRange
+		return int64(req.Port) >= int64(r.GetStart()) && int64(req.Port) < int64(r.GetEnd()), nil
+	case *rbacpb.Permission_RequestedServerName:
+		return matchString(rule.RequestedServerName, req.SNI)
+	case *rbacpb.Permission_Metadata:
+		return matchMetadata(rule.Metadata, req.metadata())
+	default:
+		return false, fmt.Errorf("unsupported permission %T", rule)
+	}
+}
+
+func matchPrincipal(principal *rbacpb.Principal, req *Request) (bool, error) {
+	switch id := principal.GetIdentifier().(type) {
+	case *rbacpb.Principal_Any:
+		return id.Any, nil
+	case *rbacpb.Principal_AndIds:
+		for _, p := range id.AndIds.GetIds() {
+			if ok, err := matchPrincipal(p, req); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case *rbacpb.Principal_OrIds:
+		for _, p := range id.OrIds.GetIds() {
+			if ok, err := matchPrincipal(p, req); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case *rbacpb.Principal_NotId:
+		ok, err := matchPrincipal(id.NotId, req)
+		return !ok, err
+	case *rbacpb.Principal_Authenticated_:
+		// Only mTLS connections are authenticated.
+		if req.SourcePrincipal == "" {
+			return false, nil
+		}
+		if id.Authenticated.GetPrincipalName() == nil {
+			return true, nil
+		}
+		return matchString(id.Authenticated.GetPrincipalName(), req.SourcePrincipal)
+	case *rbacpb.Principal_FilterState:
+		if id.FilterState.GetKey() != peerPrincipalFilterState {
+			return false, fmt.Errorf("unsupported filter state %q", id.FilterState.GetKey())
+		}
+		if req.SourcePrincipal == "" {
+			return false, nil
+		}
+		return matchString(id.FilterState.GetStringMatch(), req.SourcePrincipal)
+	case *rbacpb.Principal_SourceIp:
+		return matchCIDR(id.SourceIp, req.SourceIP)
+	case *rbacpb.Principal_DirectRemoteIp:
+		return matchCIDR(id.DirectRemoteIp, req.SourceIP)
+	case *rbacpb.Principal_RemoteIp:
+		return matchCIDR(id.RemoteIp, req.remoteIP())
+	case *rbacpb.Principal_Header:
+		return matchHeader(id.Header, req)
+	case *rbacpb.Principal_UrlPath:
+		return matchString(id.UrlPath.GetPath(), req.pathWithoutQuery())
+	case *rbacpb.Principal_Metadata:
+		return matchMetadata(id.Metadata, req.metadata())
+	default:
+		return false, fmt.Errorf("unsupported principal %T", id)
+	}
+}
+
+func matchString(m *matcherpb.StringMatcher, value string) (bool, error) {
+	if m.GetIgnoreCase() {
+		value = strings.ToLower(value)
+	}
+	lower := func(s string) string {
+		if m.GetIgnoreCase() {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch p := m.GetMatchPattern().(type) {
+	case *matcherpb.StringMatcher_Exact:
+		return value == lower(p.Exact), nil
+	case *matcherpb.StringMatcher_Prefix:
+		return strings.HasPrefix(value, lower(p.Prefix)), nil
+	case *matcherpb.StringMatcher_Suffix:
+		return strings.HasSuffix(value, lower(p.Suffix)), nil
+	case *matcherpb.StringMatcher_Contains:
+		return strings.Contains(value, lower(p.Contains)), nil
+	case *matcherpb.StringMatcher_SafeRegex:
+		return matchRegex(p.SafeRegex, value)
+	default:
+		return false, fmt.Errorf("unsupported string matcher %T", p)
+	}
+}
+
+func matchRegex(m *matcherpb.RegexMatcher, value string) (bool, error) {
+	// Envoy regex matchers must match the whole value.
+	re, err := regexp.Compile("^(?:" + m.GetRegex() + ")$")
+	if err != nil {
+		return false, fmt.Errorf("invalid regex %q: %v", m.GetRegex(), err)
+	}
+	return re.MatchString(value), nil
+}
+
+func matchHeader(m *routepb.HeaderMatcher, req *Request) (bool, error) {
+	value, found := req.header(m.GetName())
+	if !found && m.GetTreatMissingHeaderAsEmpty() {
+		value, found = "", true
+	}
+	var matched bool
+	if !found {
+		// A missing header only matches a present_match of false.
+		if p, ok := m.GetHeaderMatchSpecifier().(*routepb.HeaderMatcher_PresentMatch); ok {
+			matched = !p.PresentMatch
+		}
+		return matched != m.GetInvertMatch(), nil
+	}
+	var err error
+	switch p := m.GetHeaderMatchSpecifier().(type) {
+	case nil:
+		matched = true
+	case *routepb.HeaderMatcher_ExactMatch:
+		matched = value == p.ExactMatch
+	case *routepb.HeaderMatcher_SafeRegexMatch:
+		matched, err = matchRegex(p.SafeRegexMatch, value)
+	case *routepb.HeaderMatcher_RangeMatch:
+		n, perr := strconv.ParseInt(value, 10, 64)
+		matched = perr == nil && n >= p.RangeMatch.GetStart() && n < p.RangeMatch.GetEnd()
+	case *routepb.HeaderMatcher_PresentMatch:
+		matched = p.PresentMatch
+	case *routepb.HeaderMatcher_PrefixMatch:
+		matched = strings.HasPrefix(value, p.PrefixMatch)
+	case *routepb.HeaderMatcher_SuffixMatch:
+		matched = strings.HasSuffix(value, p.SuffixMatch)
+	case *routepb.HeaderMatcher_ContainsMatch:
+		matched = strings.Contains(value, p.ContainsMatch)
+	case *routepb.HeaderMatcher_StringMatch:
+		matched, err = matchString(p.StringMatch, value)
+	default:
+		err = fmt.Errorf("unsupported header matcher %T", p)
+	}
+	if err != nil {
+		return false, err
+	}
+	return matched != m.GetInvertMatch(), nil
+}
+
+func matchCIDR(cidr *core.CidrRange, ip string) (bool, error) {
+	if ip == "" {
+		return false, nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, fmt.Errorf("invalid IP address %q: %v", ip, err)
+	}
+	prefixAddr, err := netip.ParseAddr(cidr.GetAddressPrefix())
+	if err != nil {
+		return false, fmt.Errorf("invalid CIDR range address %q: %v", cidr.GetAddressPrefix(), err)
+	}
+	bits := prefixAddr.BitLen()
+	if cidr.GetPrefixLen() != nil {
+		bits = int(cidr.GetPrefixLen().GetValue())
+	}
+	prefix, err := prefixAddr.Prefix(bits)
+	if err != nil {
+		return false, fmt.Errorf("invalid CIDR range %s/%d: %v", cidr.GetAddressPrefix(), bits, err)
+	}
+	return prefix.Contains(addr.Unmap()), nil
+}
+
+// matchURITemplate matches the path against an Envoy URI template, where "*" matches a single path segment and "**"
+// matches any number of segments.
+func matchURITemplate(config *core.TypedExtensionConfig, path string) (bool, error) {
+	template := &uritemplate.UriTemplateMatchConfig{}
+	if err := config.GetTypedConfig().UnmarshalTo(template); err != nil {
+		return false, fmt.Errorf("unsupported URI template config: %v", err)
+	}
+	segments := strings.Split(template.GetPathTemplate(), "/")
+	for i, segment := range segments {
+		switch segment {
+		case "*", "{*}":
+			segments[i] = "[^/]+"
+		case "**", "{**}":
+			segments[i] = ".*"
+		default:
+			segments[i] = regexp.QuoteMeta(segment)
+		}
+	}
+	return regexp.MustCompile("^" + strings.Join(segments, "/") + "$").MatchString(path), nil
+}
+
+// matchMetadata matches the dynamic metadata of the request.
+func matchMetadata(m *matcherpb.MetadataMatcher, metadata map[string]*structpb.Struct) (bool, error) {
+	var value *structpb.Value
+	if s, ok := metadata[m.GetFilter()]; ok {
+		value = structpb.NewStructValue(s)
+	}
+	for _, segment := range m.GetPath() {
+		value = value.GetStructValue().GetFields()[segment.GetKey()]
+		if value == nil {
+			break
+		}
+	}
+	matched, err := matchValue(m.GetValue(), value)
+	if err != nil {
+		return false, err
+	}
+	return matched != m.GetInvert(), nil
+}
+
+func matchValue(m *matcherpb.ValueMatcher, value *structpb.Value) (bool, error) {
+	switch p := m.GetMatchPattern().(type) {
+	case *matcherpb.ValueMatcher_NullMatch_:
+		_, ok := value.GetKind().(*structpb.Value_NullValue)
+		return ok, nil
+	case *matcherpb.ValueMatcher_PresentMatch:
+		return (value != nil) == p.PresentMatch, nil
+	case *matcherpb.ValueMatcher_BoolMatch:
+		v, ok := value.GetKind().(*structpb.Value_BoolValue)
+		return ok && v.BoolValue == p.BoolMatch, nil
+	case *matcherpb.ValueMatcher_DoubleMatch:
+		v, ok := value.GetKind().(*structpb.Value_NumberValue)
+		if !ok {
+			return false, nil
+		}
+		switch d := p.DoubleMatch.GetMatchPattern().(type) {
+		case *matcherpb.DoubleMatcher_Exact:
+			return v.NumberValue == d.Exact, nil
+		case *matcherpb.DoubleMatcher_Range:
+			return v.NumberValue >= d.Range.GetStart() && v.NumberValue < d.Range.GetEnd(), nil
+		}
+		return false, nil
+	case *matcherpb.ValueMatcher_StringMatch:
+		v, ok := value.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return false, nil
+		}
+		return matchString(p.StringMatch, v.StringValue)
+	case *matcherpb.ValueMatcher_ListMatch:
+		v, ok := value.GetKind().(*structpb.Value_ListValue)
+		if !ok {
+			return false, nil
+		}
+		for _, item := range v.ListValue.GetValues() {
+			if ok, err := matchValue(p.ListMatch.GetOneOf(), item); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case *matcherpb.ValueMatcher_OrMatch:
+		for _, vm := range p.OrMatch.GetValueMatchers() {
+			if ok, err := matchValue(vm, value); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported value matcher %T", p)
+	}
+}
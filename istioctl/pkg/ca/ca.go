@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/slices"
+	pkica "istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+// reasonCodes are the revocation reasons defined in RFC 5280 section 5.3.1.
+var reasonCodes = map[string]int{
+	"unspecified":          0,
+	"keyCompromise":        1,
+	"cACompromise":         2,
+	"affiliationChanged":   3,
+	"superseded":           4,
+	"cessationOfOperation": 5,
+	"certificateHold":      6,
+	"privilegeWithdrawn":   9,
+	"aACompromise":         10,
+}
+
+// Cmd represents the ca command
+func Cmd(ctx cli.Context) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ca",
+		Short: "Manage the certificates issued by the Istiod CA",
+	}
+	cmd.AddCommand(revokeCmd(ctx))
+	return cmd
+}
+
+func revokeCmd(ctx cli.Context) *cobra.Command {
+	var certFiles []string
+	var reason string
+	cmd := &cobra.Command{
+		Use:   "revoke [<serial-number>...]",
+		Short: "Revoke certificates issued by the Istiod CA",
+		Long: `Adds certificates to the certificates revoked by the Istiod CA, stored in the istio-ca-revocations
+ConfigMap of the Istio namespace. When PILOT_ENABLE_CA_REVOCATION is enabled, Istiod re-issues its certificate
+revocation list once the ConfigMap is updated, and proxies reject mTLS peers presenting a revoked certificate.
+Certificates are given by their hex encoded serial number, or by their PEM encoded certificate.`,
+		Example: `  # Revoke a certificate by its serial number
+  istioctl experimental ca revoke 5f:3a:91:0c --reason keyCompromise
+
+  # Revoke the certificate of a file
+  istioctl experimental ca revoke -f cert-chain.pem`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && len(certFiles) == 0 {
+				return fmt.Errorf("expected a serial number or a certificate file")
+			}
+			if _, ok := reasonCodes[reason]; !ok {
+				return fmt.Errorf("unknown revocation reason %q, expected one of %s",
+					reason, strings.Join(slices.Sort(maps.Keys(reasonCodes)), ", "))
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			now := time.Now().UTC().Truncate(time.Second)
+			var revoked []pkica.RevokedCertificate
+			for _, arg := range args {
+				serial, ok := new(big.Int).SetString(strings.ReplaceAll(arg, ":", ""), 16)
+				if !ok {
+					return fmt.Errorf("invalid serial number %q", arg)
+				}
+				revoked = append(revoked, pkica.RevokedCertificate{
+					SerialNumber:   serial.Text(16),
+					RevocationTime: now,
+					ReasonCode:     reasonCodes[reason],
+				})
+			}
+			for _, file := range certFiles {
+				r, err := revokedCertificate(file)
+				if err != nil {
+					return err
+				}
+				r.RevocationTime, r.ReasonCode = now, reasonCodes[reason]
+				revoked = append(revoked, r)
+			}
+
+			kubeClient, err := ctx.CLIClient()
+			if err != nil {
+				return err
+			}
+			store := pkica.NewConfigMapRevocationStore(kubeClient.Kube().CoreV1(), ctx.IstioNamespace())
+			if err := store.Revoke(revoked...); err != nil {
+				return fmt.Errorf("failed to revoke certificates: %v", err)
+			}
+			for _, r := range revoked {
+				fmt.Fprintf(cmd.OutOrStdout(), "Revoked certificate %s\n", r.SerialNumber)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&certFiles, "cert-file", "f", nil,
+		"PEM encoded certificate to revoke; only the first certificate of a chain is revoked")
+	cmd.Flags().StringVar(&reason, "reason", "unspecified", "The reason of the revocation, as defined in RFC 5280")
+	return cmd
+}
+
+// revokedCertificate returns the revocation of the first certificate of a PEM encoded file. Its expiration time is
+// recorded, so that it is omitted from the revocation list once it has expired.
+func revokedCertificate(file string) (pkica.RevokedCertificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return pkica.RevokedCertificate{}, err
+	}
+	cert, err := util.ParsePemEncodedCertificate(data)
+	if err != nil {
+		return pkica.RevokedCertificate{}, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	return pkica.RevokedCertificate{
+		SerialNumber: cert.SerialNumber.Text(16),
+		NotAfter:     &cert.NotAfter,
+	}, nil
+}
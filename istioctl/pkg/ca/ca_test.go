@@ -0,0 +1,76 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pkg/test/util/assert"
+	pkica "istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func runRevoke(ctx cli.Context, args ...string) (string, error) {
+	cmd := Cmd(ctx)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"revoke"}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestRevoke(t *testing.T) {
+	certPEM, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host: "spiffe://cluster.local/ns/default/sa/default", TTL: time.Hour, IsSelfSigned: true, ECSigAlg: util.EcdsaSigAlg,
+	})
+	assert.NoError(t, err)
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	assert.NoError(t, err)
+	certFile := filepath.Join(t.TempDir(), "cert-chain.pem")
+	assert.NoError(t, os.WriteFile(certFile, certPEM, 0o644))
+
+	ctx := cli.NewFakeContext(&cli.NewFakeContextOption{IstioNamespace: "istio-system"})
+	out, err := runRevoke(ctx, "5F:3A:91:0C", "-f", certFile, "--reason", "keyCompromise")
+	assert.NoError(t, err)
+	serial := cert.SerialNumber.Text(16)
+	assert.Equal(t, out, "Revoked certificate 5f3a910c\nRevoked certificate "+serial+"\n")
+
+	client, err := ctx.CLIClient()
+	assert.NoError(t, err)
+	revoked, err := pkica.NewConfigMapRevocationStore(client.Kube().CoreV1(), "istio-system").List()
+	assert.NoError(t, err)
+	assert.Equal(t, len(revoked), 2)
+	assert.Equal(t, revoked[0].SerialNumber, "5f3a910c")
+	assert.Equal(t, revoked[0].ReasonCode, 1)
+	assert.Equal(t, revoked[0].NotAfter, nil)
+	assert.Equal(t, revoked[1].SerialNumber, serial)
+	assert.Equal(t, revoked[1].NotAfter.Equal(cert.NotAfter), true)
+
+	for _, args := range [][]string{
+		{},
+		{"not-hex"},
+		{"1a", "--reason", "unknown"},
+		{"-f", filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		_, err := runRevoke(ctx, args...)
+		assert.Error(t, err)
+	}
+}
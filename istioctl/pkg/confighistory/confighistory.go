@@ -0,0 +1,296 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confighistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/resource"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ledger"
+)
+
+const distributionTrackingDisabledErrorString = "config history requires distribution tracking " +
+	"(To enable this feature, please set PILOT_ENABLE_CONFIG_DISTRIBUTION_TRACKING=true)"
+
+// Cmd represents the config-history command
+func Cmd(ctx cli.Context) *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var version string
+	cmd := &cobra.Command{
+		Use:   "config-history [<type> <name>[.<namespace>]]",
+		Short: "Show the config versions known to Istiod and the config at a previous version",
+		Long: `Lists the config versions retained by each Istiod instance, or the configs which were current at a
+previous version. The version prefixes the nonces sent to proxies, so a nonce can be used to find out which
+config a proxy was computed from. Requires PILOT_ENABLE_CONFIG_DISTRIBUTION_TRACKING to be enabled in Istiod.`,
+		Example: `  # List the config versions retained by Istiod
+  istioctl experimental config-history
+
+  # List the configs which were current at a previous version
+  istioctl experimental config-history --version ZgnzpC1AW9Y=
+
+  # Show the bookinfo virtual service at the version a proxy acked, given the nonce of the proxy
+  istioctl experimental config-history --version ZgnzpC1AW9Y=6c5eba7a-3cdb-4ab4-a0c2-7c1a3a1cb4a2 virtualservice bookinfo.default
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 && len(args) != 2 {
+				return fmt.Errorf("expected a type and a name, got %d arguments", len(args))
+			}
+			if len(args) == 2 && version == "" {
+				return fmt.Errorf("--version is required to show a resource")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := ctx.CLIClientWithRevision(opts.Revision)
+			if err != nil {
+				return err
+			}
+			resourceKey := ""
+			if len(args) == 2 {
+				schema, err := lookupSchema(args[0])
+				if err != nil {
+					return err
+				}
+				name, ns := handlers.InferPodInfo(args[1], ctx.NamespaceOrDefault(ctx.Namespace()))
+				resourceKey = config.Key(schema.Group(), schema.Version(), schema.Kind(), name, ns)
+			}
+
+			path := "debug/config_history"
+			if version != "" {
+				query := url.Values{"version": []string{version}}
+				if resourceKey != "" {
+					query.Set("resource", resourceKey)
+				}
+				path += "?" + query.Encode()
+			}
+			responses, err := queryIstiods(kubeClient, ctx.IstioNamespace(), path)
+			if err != nil {
+				return fmt.Errorf("unable to query Istiod for config history: %v", err)
+			}
+			retained, err := retainingIstiods(cmd.ErrOrStderr(), responses, version)
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			switch {
+			case version == "":
+				return printVersions(w, retained)
+			case resourceKey == "":
+				return printConfigs(w, retained, version)
+			default:
+				return printResource(w, retained, version, resourceKey)
+			}
+		},
+	}
+	cmd.PersistentFlags().StringVar(&version, "version", "",
+		"The config version, or a nonce sent to a proxy, to show the configs of")
+	opts.AttachControlPlaneFlags(cmd)
+	return cmd
+}
+
+func lookupSchema(kind string) (resource.Schema, error) {
+	normalized := strings.ReplaceAll(kind, "-", "")
+	for _, s := range collections.Pilot.All() {
+		if strings.EqualFold(normalized, s.Kind()) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("type %s is not recognized", kind)
+}
+
+// istiodResponse is the response of a single Istiod instance to a config history request.
+type istiodResponse struct {
+	status int
+	body   []byte
+}
+
+// queryIstiods sends the request to each running Istiod instance. Unlike AllDiscoveryDo, the status of
+// each response is returned, as a version may only be retained by some of the instances.
+var queryIstiods = func(kubeClient kube.CLIClient, istiodNamespace, path string) (map[string]istiodResponse, error) {
+	istiods, err := kubeClient.GetIstioPods(context.TODO(), istiodNamespace, metav1.ListOptions{
+		LabelSelector: "app=istiod",
+		FieldSelector: kube.RunningStatus,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(istiods) == 0 {
+		return nil, fmt.Errorf("unable to find any Istiod instances")
+	}
+	responses := map[string]istiodResponse{}
+	for _, istiod := range istiods {
+		res, err := istiodDo(kubeClient, &istiod, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", istiod.Name, err)
+		}
+		responses[istiod.Name] = res
+	}
+	return responses, nil
+}
+
+func istiodDo(kubeClient kube.CLIClient, istiod *corev1.Pod, path string) (istiodResponse, error) {
+	fw, err := kubeClient.NewPortForwarder(istiod.Name, istiod.Namespace, "", 0, kube.FindIstiodMonitoringPort(istiod))
+	if err != nil {
+		return istiodResponse{}, err
+	}
+	if err := fw.Start(); err != nil {
+		return istiodResponse{}, fmt.Errorf("failure running port forward process: %v", err)
+	}
+	defer fw.Close()
+	resp, err := http.Get(fmt.Sprintf("http://%s/%s", fw.Address(), path))
+	if err != nil {
+		return istiodResponse{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return istiodResponse{}, err
+	}
+	return istiodResponse{status: resp.StatusCode, body: body}, nil
+}
+
+// retainingIstiods returns the bodies of the responses of the Istiod instances which have distribution tracking
+// enabled and, if a version is given, still retain it. The instances which do not are reported to w.
+func retainingIstiods(w io.Writer, responses map[string]istiodResponse, version string) (map[string][]byte, error) {
+	retained := map[string][]byte{}
+	var disabled, missing []string
+	for istiod, res := range responses {
+		switch res.status {
+		case http.StatusOK:
+			retained[istiod] = res.body
+		case http.StatusConflict:
+			disabled = append(disabled, istiod)
+		case http.StatusNotFound:
+			missing = append(missing, istiod)
+		default:
+			return nil, fmt.Errorf("%s: unexpected status code %d: %s", istiod, res.status, strings.TrimSpace(string(res.body)))
+		}
+	}
+	sort.Strings(disabled)
+	sort.Strings(missing)
+	if len(retained) == 0 {
+		switch {
+		case len(missing) > 0:
+			return nil, fmt.Errorf("config version %s is not retained by any Istiod instance", version)
+		case len(disabled) > 0:
+			return nil, fmt.Errorf(distributionTrackingDisabledErrorString)
+		}
+		return nil, fmt.Errorf("no Istiod instance returned the config history")
+	}
+	if len(disabled) > 0 {
+		_, _ = fmt.Fprintf(w, "Distribution tracking is disabled on %s\n", strings.Join(disabled, ", "))
+	}
+	if version != "" {
+		_, _ = fmt.Fprintf(w, "Config version %s is retained by %s", version, strings.Join(sortedIstiods(retained), ", "))
+		if len(missing) > 0 {
+			_, _ = fmt.Fprintf(w, ", not by %s", strings.Join(missing, ", "))
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	return retained, nil
+}
+
+func sortedIstiods(responses map[string][]byte) []string {
+	istiods := make([]string, 0, len(responses))
+	for istiod := range responses {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	return istiods
+}
+
+func printVersions(w io.Writer, responses map[string][]byte) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ISTIOD\tVERSION\tTIME")
+	for _, istiod := range sortedIstiods(responses) {
+		var versions []ledger.RootHash
+		if err := json.Unmarshal(responses[istiod], &versions); err != nil {
+			return fmt.Errorf("%s: failed to parse config versions: %v", istiod, err)
+		}
+		// Newest first, as recent versions are usually the most relevant.
+		for i := len(versions) - 1; i >= 0; i-- {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", istiod, versions[i].Hash, versions[i].Time.Format(time.RFC3339))
+		}
+	}
+	return tw.Flush()
+}
+
+// configsAt returns the configs at version. Instances with the same config versions report the same
+// configs, so the first response is used.
+func configsAt(responses map[string][]byte, version string) ([]json.RawMessage, error) {
+	for _, istiod := range sortedIstiods(responses) {
+		var configs []json.RawMessage
+		if err := json.Unmarshal(responses[istiod], &configs); err != nil {
+			return nil, fmt.Errorf("%s: failed to parse configs: %v", istiod, err)
+		}
+		return configs, nil
+	}
+	return nil, fmt.Errorf("no Istiod instance returned the configs at version %s", version)
+}
+
+func printConfigs(w io.Writer, responses map[string][]byte, version string) error {
+	configs, err := configsAt(responses, version)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "KIND\tNAME\tNAMESPACE\tGENERATION")
+	for _, raw := range configs {
+		c := crd.IstioKind{}
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return fmt.Errorf("failed to parse config: %v", err)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", c.Kind, c.Name, c.Namespace, c.Generation)
+	}
+	return tw.Flush()
+}
+
+func printResource(w io.Writer, responses map[string][]byte, version, resourceKey string) error {
+	configs, err := configsAt(responses, version)
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return fmt.Errorf("resource %s did not exist at config version %s", resourceKey, version)
+	}
+	out, err := yaml.JSONToYAML(configs[0])
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
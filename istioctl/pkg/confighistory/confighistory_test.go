@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confighistory
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/ledger"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestConfigHistory(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	versions, _ := json.Marshal([]ledger.RootHash{
+		{Hash: "AAAAAAAAAAA=", Time: now},
+		{Hash: "BBBBBBBBBBB=", Time: now.Add(time.Minute)},
+	})
+	configs := []byte(`[{"apiVersion":"networking.istio.io/v1alpha3","kind":"VirtualService",` +
+		`"metadata":{"name":"bookinfo","namespace":"default","generation":2},"spec":{"hosts":["bookinfo"]}}]`)
+
+	ok := func(body []byte) istiodResponse {
+		return istiodResponse{status: http.StatusOK, body: body}
+	}
+	notFound := istiodResponse{status: http.StatusNotFound, body: []byte("version not found")}
+	disabled := istiodResponse{status: http.StatusConflict, body: []byte(xds.DistributionTrackingDisabledMessage)}
+
+	cases := []struct {
+		name           string
+		results        map[string]istiodResponse
+		args           []string
+		expectedOutput string
+		expectedStderr string
+		wantErr        string
+	}{
+		{
+			name:    "versions",
+			results: map[string]istiodResponse{"istiod-1": ok(versions)},
+			expectedOutput: `ISTIOD   VERSION      TIME
+istiod-1 BBBBBBBBBBB= 2024-01-01T00:01:00Z
+istiod-1 AAAAAAAAAAA= 2024-01-01T00:00:00Z
+`,
+		},
+		{
+			name:    "configs at version",
+			results: map[string]istiodResponse{"istiod-1": ok(configs)},
+			args:    []string{"--version", "AAAAAAAAAAA="},
+			expectedOutput: `KIND           NAME     NAMESPACE GENERATION
+VirtualService bookinfo default   2
+`,
+			expectedStderr: "Config version AAAAAAAAAAA= is retained by istiod-1\n",
+		},
+		{
+			name:    "resource at version",
+			results: map[string]istiodResponse{"istiod-1": ok(configs)},
+			args:    []string{"--version", "AAAAAAAAAAA=", "virtual-service", "bookinfo.default"},
+			expectedOutput: `apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  generation: 2
+  name: bookinfo
+  namespace: default
+spec:
+  hosts:
+  - bookinfo
+`,
+			expectedStderr: "Config version AAAAAAAAAAA= is retained by istiod-1\n",
+		},
+		{
+			name:    "resource did not exist",
+			results: map[string]istiodResponse{"istiod-1": ok([]byte("[]"))},
+			args:    []string{"--version", "AAAAAAAAAAA=", "virtualservice", "bookinfo.default"},
+			wantErr: "did not exist at config version",
+		},
+		{
+			name:    "resource without version",
+			results: map[string]istiodResponse{"istiod-1": ok(configs)},
+			args:    []string{"virtualservice", "bookinfo.default"},
+			wantErr: "--version is required",
+		},
+		{
+			name:    "unknown type",
+			results: map[string]istiodResponse{"istiod-1": ok(configs)},
+			args:    []string{"--version", "AAAAAAAAAAA=", "not-a-type", "bookinfo.default"},
+			wantErr: "type not-a-type is not recognized",
+		},
+		{
+			name:    "tracking disabled",
+			results: map[string]istiodResponse{"istiod-1": disabled},
+			wantErr: distributionTrackingDisabledErrorString,
+		},
+		{
+			name:    "tracking disabled on some instances",
+			results: map[string]istiodResponse{"istiod-1": ok(versions), "istiod-2": disabled},
+			expectedOutput: `ISTIOD   VERSION      TIME
+istiod-1 BBBBBBBBBBB= 2024-01-01T00:01:00Z
+istiod-1 AAAAAAAAAAA= 2024-01-01T00:00:00Z
+`,
+			expectedStderr: "Distribution tracking is disabled on istiod-2\n",
+		},
+		{
+			name:    "version retained by some instances",
+			results: map[string]istiodResponse{"istiod-1": notFound, "istiod-2": ok(configs), "istiod-3": notFound},
+			args:    []string{"--version", "AAAAAAAAAAA="},
+			expectedOutput: `KIND           NAME     NAMESPACE GENERATION
+VirtualService bookinfo default   2
+`,
+			expectedStderr: "Config version AAAAAAAAAAA= is retained by istiod-2, not by istiod-1, istiod-3\n",
+		},
+		{
+			name:    "version not retained",
+			results: map[string]istiodResponse{"istiod-1": notFound, "istiod-2": disabled},
+			args:    []string{"--version", "AAAAAAAAAAA="},
+			wantErr: "config version AAAAAAAAAAA= is not retained by any Istiod instance",
+		},
+		{
+			name:    "unexpected status",
+			results: map[string]istiodResponse{"istiod-1": {status: http.StatusInternalServerError, body: []byte("boom")}},
+			wantErr: "istiod-1: unexpected status code 500: boom",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := cli.NewFakeContext(&cli.NewFakeContextOption{
+				Namespace: "default",
+			})
+			query := queryIstiods
+			queryIstiods = func(kube.CLIClient, string, string) (map[string]istiodResponse, error) {
+				return c.results, nil
+			}
+			t.Cleanup(func() { queryIstiods = query })
+			cmd := Cmd(ctx)
+			var out, stderr bytes.Buffer
+			cmd.SetArgs(c.args)
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			cmd.SetOut(&out)
+			cmd.SetErr(&stderr)
+			err := cmd.Execute()
+			if c.wantErr != "" {
+				assert.Error(t, err)
+				assert.Equal(t, strings.Contains(err.Error(), c.wantErr), true)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, out.String(), c.expectedOutput)
+			assert.Equal(t, stderr.String(), c.expectedStderr)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyconfig
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/writer/compare"
+)
+
+func diffConfigCmd(ctx cli.Context) *cobra.Command {
+	var files []string
+
+	diffConfigCmd := &cobra.Command{
+		Use:   "diff [<type>/]<name-1>[.<namespace-1>] [<type>/]<name-2>[.<namespace-2>]",
+		Short: "Diffs the configuration of the Envoys in two pods",
+		Long: `Diff the listeners, clusters, routes and secrets of the Envoy instances in two pods, or in Envoy config dumps.
+Resources are aligned by name, and fields that change without changing the behavior of the proxy, such as versions
+and update times, are ignored. Certificates are compared by their identities rather than their contents.`,
+		Example: `  # Diff the configuration of two pods.
+  istioctl x proxy-config diff <pod-name-1[.namespace]> <pod-name-2[.namespace]>
+
+  # Diff the configuration of a pod against an Envoy config dump.
+  istioctl x proxy-config diff <pod-name[.namespace]> --file envoy-config.json
+
+  # Diff two Envoy config dumps without using Kubernetes API
+  istioctl x proxy-config diff --file envoy-config-1.json --file envoy-config-2.json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args)+len(files) != 2 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("diff requires 2 pod names or --file parameters")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			if outputFormat != summaryOutput && outputFormat != jsonOutput && outputFormat != yamlOutput {
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+			var names []string
+			var dumps [][]byte
+			for _, arg := range args {
+				kubeClient, err := ctx.CLIClient()
+				if err != nil {
+					return err
+				}
+				podName, podNamespace, err := getPodName(ctx, arg)
+				if err != nil {
+					return err
+				}
+				dump, err := extractConfigDump(kubeClient, podName, podNamespace, false)
+				if err != nil {
+					return err
+				}
+				names = append(names, podName+"."+podNamespace)
+				dumps = append(dumps, dump)
+			}
+			for _, file := range files {
+				dump, err := readFile(file)
+				if err != nil {
+					return err
+				}
+				names = append(names, file)
+				dumps = append(dumps, dump)
+			}
+			comparator, err := compare.NewProxyComparator(c.OutOrStdout(), names[0], dumps[0], names[1], dumps[1])
+			if err != nil {
+				return err
+			}
+			return comparator.Diff(outputFormat)
+		},
+		ValidArgsFunction: completion.ValidPodsNameArgs(ctx),
+	}
+
+	diffConfigCmd.PersistentFlags().StringArrayVarP(&files, "file", "f", nil,
+		"Envoy config dump JSON file to diff instead of a pod. Can be repeated.")
+	return diffConfigCmd
+}
+
+// ExperimentalProxyConfig returns the experimental proxy-config commands
+func ExperimentalProxyConfig(ctx cli.Context) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:     "proxy-config",
+		Short:   "Experimental commands to inspect the configuration of Envoy [kube only]",
+		Long:    `A group of experimental commands used to inspect the proxy configuration from the Envoy config dump`,
+		Aliases: []string{"pc"},
+	}
+
+	configCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	configCmd.PersistentFlags().IntVar(&proxyAdminPort, "proxy-admin-port", defaultProxyAdminPort, "Envoy proxy admin port")
+
+	configCmd.AddCommand(diffConfigCmd(ctx))
+
+	return configCmd
+}
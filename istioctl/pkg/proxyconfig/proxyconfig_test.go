@@ -175,6 +175,56 @@ func TestProxyConfig(t *testing.T) {
 	}
 }
 
+func TestProxyConfigDiff(t *testing.T) {
+	configDumps := map[string][]byte{
+		"productpage-1": util.ReadFile(t, "../writer/compare/testdata/configdump.json"),
+		"productpage-2": util.ReadFile(t, "../writer/compare/testdata/configdump.json"),
+		"productpage-3": util.ReadFile(t, "../writer/compare/testdata/configdump_diff.json"),
+	}
+	cases := []execTestCase{
+		{ // a single pod
+			args:           strings.Split("diff productpage-1", " "),
+			expectedString: "diff requires 2 pod names or --file parameters",
+			wantException:  true,
+		},
+		{
+			execClientConfig: configDumps,
+			args:             strings.Split("diff productpage-1 productpage-2", " "),
+			expectedOutput:   "The configurations of productpage-1.default and productpage-2.default match\n",
+		},
+		{
+			execClientConfig: configDumps,
+			args:             strings.Split("diff productpage-1 productpage-3", " "),
+			expectedString:   "6 resource(s) differ between productpage-1.default and productpage-3.default",
+		},
+		{ // pod against a config dump file
+			execClientConfig: configDumps,
+			args:             strings.Split("diff productpage-1 -f ../writer/compare/testdata/configdump_diff.json", " "),
+			expectedString:   "Cluster inbound-vip|9999|http|ratings.default.svc.cluster.local is only in ../writer/compare/testdata/configdump_diff.json",
+		},
+		{ // config dump files
+			args: strings.Split("diff -o json -f ../writer/compare/testdata/configdump.json -f ../writer/compare/testdata/configdump_diff.json", " "),
+			expectedString: `"type": "Route",
+        "name": "inbound-vip|9080|http|reviews-v3.default.svc.cluster.local",
+        "status": "first-only"`,
+		},
+		{
+			args:           strings.Split("diff -o prom -f ../writer/compare/testdata/configdump.json -f ../writer/compare/testdata/configdump.json", " "),
+			expectedString: `output format "prom" not supported`,
+			wantException:  true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecTestOutput(t, ExperimentalProxyConfig(cli.NewFakeContext(&cli.NewFakeContextOption{
+				Results:   c.execClientConfig,
+				Namespace: "default",
+			})), c)
+		})
+	}
+}
+
 func verifyExecTestOutput(t *testing.T, cmd *cobra.Command, c execTestCase) {
 	t.Helper()
 
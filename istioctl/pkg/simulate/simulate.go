@@ -0,0 +1,335 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/completion"
+	"istio.io/istio/istioctl/pkg/multixds"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/simulation/traffic"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/kube"
+)
+
+// Cmd represents the simulate command
+func Cmd(ctx cli.Context) *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var centralOpts clioptions.CentralControlPlaneOptions
+	var from, to, protocol, path, configDumpFile string
+	var headers []string
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate the handling of a request by a proxy",
+		Long: `Simulates how a proxy would handle a request, using the configuration Istiod computed for the proxy.
+Prints the listener, filter chain, route and cluster the request would match, and whether the request would be
+sent with mutual TLS. No traffic is sent.
+
+The command also supports reading from a standalone config dump file with flag -f.`,
+		Example: `  # Simulate an HTTP request from a pod to the reviews service
+  istioctl x simulate --from productpage-v1-7f44c4d57c-h6wls.default --to reviews:9080 --path /reviews/0
+
+  # Simulate a request with a header from a pod of a deployment
+  istioctl x simulate --from deployment/productpage-v1 --to reviews.default.svc.cluster.local:9080 --header end-user=jason
+
+  # Simulate a TCP connection using an Envoy config dump file
+  istioctl x simulate -f productpage_config_dump.json --to 10.96.12.7:27017 --protocol tcp`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("simulate does not take arguments, found: %d", len(args))
+			}
+			if (from == "") == (configDumpFile == "") {
+				return fmt.Errorf("exactly one of --from or --file is required")
+			}
+			if to == "" {
+				return fmt.Errorf("--to is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host, port, err := parseDestination(to)
+			if err != nil {
+				return err
+			}
+			call := traffic.Call{
+				Port:       port,
+				Path:       path,
+				Protocol:   traffic.Protocol(protocol),
+				HostHeader: host,
+				Headers:    http.Header{},
+			}
+			switch call.Protocol {
+			case traffic.HTTP, traffic.HTTP2, traffic.TCP:
+			default:
+				return fmt.Errorf("unsupported protocol %q, expected one of http, http2 or tcp", protocol)
+			}
+			for _, h := range headers {
+				k, v, ok := strings.Cut(h, "=")
+				if !ok {
+					return fmt.Errorf("invalid header %q, expected <name>=<value>", h)
+				}
+				call.Headers.Add(k, v)
+			}
+
+			var dump *configdump.Wrapper
+			if configDumpFile != "" {
+				// Offline, the cluster is not consulted and only IP destinations are resolved.
+				dump, err = configDumpFromFile(configDumpFile)
+				if err != nil {
+					return err
+				}
+				call.Address = resolveAddress(nil, host, "")
+			} else {
+				kubeClient, err := ctx.CLIClientWithRevision(opts.Revision)
+				if err != nil {
+					return err
+				}
+				podName, namespace, err := ctx.InferPodInfoFromTypedResource(from, ctx.Namespace())
+				if err != nil {
+					return err
+				}
+				dump, err = configDumpFromIstiod(kubeClient, centralOpts, ctx.IstioNamespace(), podName, namespace)
+				if err != nil {
+					return err
+				}
+				call.Address = resolveAddress(kubeClient, host, namespace)
+			}
+
+			return simulate(cmd.OutOrStdout(), dump, call)
+		},
+	}
+	cmd.PersistentFlags().StringVar(&from, "from", "", "The pod, as [<type>/]<name>[.<namespace>], sending the request")
+	cmd.PersistentFlags().StringVar(&to, "to", "", "The destination of the request, as <host>:<port>")
+	cmd.PersistentFlags().StringVar(&protocol, "protocol", string(traffic.HTTP), "The protocol of the request, one of http, http2 or tcp")
+	cmd.PersistentFlags().StringVar(&path, "path", "/", "The path of an HTTP request")
+	cmd.PersistentFlags().StringArrayVar(&headers, "header", nil, "A header of an HTTP request, as <name>=<value>. May be repeated")
+	cmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "", "Envoy config dump JSON file of the proxy sending the request")
+	opts.AttachControlPlaneFlags(cmd)
+	centralOpts.AttachControlPlaneFlags(cmd)
+	_ = cmd.RegisterFlagCompletionFunc("from", completion.ValidPodsNameArgs(ctx))
+	return cmd
+}
+
+func parseDestination(to string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(to)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid destination %q, expected <host>:<port>: %v", to, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q", portStr)
+	}
+	return host, port, nil
+}
+
+// resolveAddress returns the address the request is sent to. Service hosts are resolved to the
+// cluster IP of the service, as listeners for TCP services are bound to it. Returns an empty address,
+// which the simulation fills in, if the host cannot be resolved or kubeClient is nil.
+func resolveAddress(kubeClient kube.CLIClient, host, namespace string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return host
+	}
+	if kubeClient == nil {
+		return ""
+	}
+	parts := strings.Split(host, ".")
+	if len(parts) > 1 {
+		namespace = parts[1]
+	}
+	svc, err := kubeClient.Kube().CoreV1().Services(namespace).Get(context.TODO(), parts[0], metav1.GetOptions{})
+	if err != nil || svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == "None" {
+		return ""
+	}
+	return svc.Spec.ClusterIP
+}
+
+func configDumpFromFile(filename string) (*configdump.Wrapper, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dump := &configdump.Wrapper{}
+	if err := dump.UnmarshalJSON(data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config dump from %s: %v", filename, err)
+	}
+	return dump, nil
+}
+
+// configDumpFromIstiod fetches the config Istiod computed for the proxy. Only the Istiod instance the proxy
+// is connected to has the config, so the first response which is a config dump is used.
+func configDumpFromIstiod(kubeClient kube.CLIClient, centralOpts clioptions.CentralControlPlaneOptions,
+	istioNamespace, podName, podNamespace string,
+) (*configdump.Wrapper, error) {
+	xdsRequest := discovery.DiscoveryRequest{
+		ResourceNames: []string{fmt.Sprintf("config_dump?proxyID=%s.%s", podName, podNamespace)},
+		Node: &core.Node{
+			Id: "debug~0.0.0.0~istioctl~cluster.local",
+		},
+		TypeUrl: v3.DebugType,
+	}
+	xdsResponses, err := multixds.AllRequestAndProcessXds(&xdsRequest, centralOpts, istioNamespace,
+		"", "", kubeClient, multixds.DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	for _, response := range xdsResponses {
+		for _, resource := range response.Resources {
+			dump := &configdump.Wrapper{}
+			if err := dump.UnmarshalJSON(resource.Value); err == nil {
+				return dump, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("proxy %s.%s is not connected to Istiod", podName, podNamespace)
+}
+
+// newSimulation builds a Simulation from the dynamic resources in the config dump.
+func newSimulation(dump *configdump.Wrapper) (*traffic.Simulation, error) {
+	sim := &traffic.Simulation{}
+	listeners, err := dump.GetDynamicListenerDump(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listeners from config dump: %v", err)
+	}
+	for _, l := range listeners.GetDynamicListeners() {
+		if l.GetActiveState() == nil {
+			continue
+		}
+		lis := &listener.Listener{}
+		if err := l.GetActiveState().GetListener().UnmarshalTo(lis); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal listener %s: %v", l.Name, err)
+		}
+		sim.Listeners = append(sim.Listeners, lis)
+	}
+	routes, err := dump.GetDynamicRouteDump(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get routes from config dump: %v", err)
+	}
+	for _, r := range routes.GetDynamicRouteConfigs() {
+		rc := &route.RouteConfiguration{}
+		if err := r.GetRouteConfig().UnmarshalTo(rc); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal route: %v", err)
+		}
+		sim.Routes = append(sim.Routes, rc)
+	}
+	clusters, err := dump.GetDynamicClusterDump(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clusters from config dump: %v", err)
+	}
+	for _, c := range clusters.GetDynamicActiveClusters() {
+		cl := &cluster.Cluster{}
+		if err := c.GetCluster().UnmarshalTo(cl); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cluster: %v", err)
+		}
+		sim.Clusters = append(sim.Clusters, cl)
+	}
+	return sim, nil
+}
+
+func simulate(w io.Writer, dump *configdump.Wrapper, call traffic.Call) error {
+	sim, err := newSimulation(dump)
+	if err != nil {
+		return err
+	}
+	result := sim.Run(call)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	printMatch(tw, "Listener", result.ListenerMatched)
+	printMatch(tw, "Filter chain", result.FilterChainMatched)
+	printMatch(tw, "Route config", result.RouteConfigMatched)
+	printMatch(tw, "Virtual host", result.VirtualHostMatched)
+	printMatch(tw, "Route", result.RouteMatched)
+	printMatch(tw, "Cluster", result.ClusterMatched)
+	if result.Error == nil {
+		_, _ = fmt.Fprintf(tw, "mTLS:\t%s\n", clusterTLS(sim.Clusters, result.ClusterMatched))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if result.Error != nil {
+		return fmt.Errorf("request would fail: %v", result.Error)
+	}
+	return nil
+}
+
+func printMatch(w io.Writer, name, matched string) {
+	if matched == "" {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "%s:\t%s\n", name, matched)
+}
+
+// clusterTLS describes the TLS settings a request sent to the named cluster uses.
+func clusterTLS(clusters []*cluster.Cluster, name string) string {
+	if name == util.PassthroughCluster || name == util.BlackHoleCluster {
+		return "n/a"
+	}
+	var c *cluster.Cluster
+	for _, cl := range clusters {
+		if cl.Name == name {
+			c = cl
+			break
+		}
+	}
+	if c == nil {
+		return "unknown, cluster not found"
+	}
+	if c.TransportSocket != nil {
+		return transportSocketTLS(c.TransportSocket)
+	}
+	for _, m := range c.TransportSocketMatches {
+		if m.Name != "tlsMode-"+model.IstioMutualTLSModeLabel {
+			continue
+		}
+		if m.Match != nil {
+			return "auto (" + transportSocketTLS(m.TransportSocket) + " to endpoints with a sidecar, plaintext otherwise)"
+		}
+		return transportSocketTLS(m.TransportSocket)
+	}
+	return "disabled"
+}
+
+func transportSocketTLS(ts *core.TransportSocket) string {
+	tlsContext := &tls.UpstreamTlsContext{}
+	if err := ts.GetTypedConfig().UnmarshalTo(tlsContext); err != nil {
+		return "unknown transport socket " + ts.GetName()
+	}
+	sds := tlsContext.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs()
+	if len(sds) > 0 && sds[0].Name == "default" {
+		return "ISTIO_MUTUAL"
+	}
+	return "TLS origination"
+}
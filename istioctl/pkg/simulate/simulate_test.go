@@ -0,0 +1,290 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulate
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/istioctl/pkg/cli"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/networking/core"
+	"istio.io/istio/pilot/pkg/simulation/traffic"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+const config = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.default.svc.cluster.local
+  addresses:
+  - 10.0.0.1
+  ports:
+  - number: 9080
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: mongo
+  namespace: default
+spec:
+  hosts:
+  - mongo.default.svc.cluster.local
+  addresses:
+  - 10.0.0.2
+  ports:
+  - number: 27017
+    name: tcp
+    protocol: TCP
+  resolution: STATIC
+  endpoints:
+  - address: 2.2.2.2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.default.svc.cluster.local
+  http:
+  - name: v2
+    match:
+    - headers:
+        end-user:
+          exact: jason
+    route:
+    - destination:
+        host: reviews.default.svc.cluster.local
+        subset: v2
+  - name: default
+    match:
+    - uri:
+        prefix: /reviews
+    route:
+    - destination:
+        host: reviews.default.svc.cluster.local
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  host: reviews.default.svc.cluster.local
+  trafficPolicy:
+    tls:
+      mode: ISTIO_MUTUAL
+  subsets:
+  - name: v2
+    labels:
+      version: v2
+`
+
+// configDumpJSON returns the config dump of a sidecar, in the form Istiod returns it.
+func configDumpJSON(t *testing.T) []byte {
+	cg := core.NewConfigGenTest(t, core.TestOptions{ConfigString: config})
+	proxy := cg.SetupProxy(nil)
+	listeners := cg.Listeners(proxy)
+
+	ld := &admin.ListenersConfigDump{}
+	for _, l := range listeners {
+		ld.DynamicListeners = append(ld.DynamicListeners, &admin.ListenersConfigDump_DynamicListener{
+			Name:        l.Name,
+			ActiveState: &admin.ListenersConfigDump_DynamicListenerState{Listener: protoconv.MessageToAny(l)},
+		})
+	}
+	rd := &admin.RoutesConfigDump{}
+	for _, r := range cg.RoutesFromListeners(proxy, listeners) {
+		rd.DynamicRouteConfigs = append(rd.DynamicRouteConfigs, &admin.RoutesConfigDump_DynamicRouteConfig{
+			RouteConfig: protoconv.MessageToAny(r),
+		})
+	}
+	cd := &admin.ClustersConfigDump{}
+	for _, c := range cg.Clusters(proxy) {
+		cd.DynamicActiveClusters = append(cd.DynamicActiveClusters, &admin.ClustersConfigDump_DynamicCluster{
+			Cluster: protoconv.MessageToAny(c),
+		})
+	}
+	b, err := protomarshal.Marshal(&admin.ConfigDump{Configs: []*anypb.Any{
+		protoconv.MessageToAny(cd),
+		protoconv.MessageToAny(ld),
+		protoconv.MessageToAny(rd),
+	}})
+	assert.NoError(t, err)
+	return b
+}
+
+func configDump(t *testing.T) *configdump.Wrapper {
+	dump := &configdump.Wrapper{}
+	assert.NoError(t, dump.UnmarshalJSON(configDumpJSON(t)))
+	return dump
+}
+
+func TestSimulate(t *testing.T) {
+	dump := configDump(t)
+	cases := []struct {
+		name     string
+		call     traffic.Call
+		expected string
+		err      bool
+	}{
+		{
+			name: "http",
+			call: traffic.Call{Port: 9080, Path: "/reviews/0", Protocol: traffic.HTTP, HostHeader: "reviews.default.svc.cluster.local"},
+			expected: `Listener:     0.0.0.0_9080
+Route config: 9080
+Virtual host: reviews.default.svc.cluster.local:9080
+Route:        default
+Cluster:      outbound|9080||reviews.default.svc.cluster.local
+mTLS:         ISTIO_MUTUAL
+`,
+		},
+		{
+			name: "http with header",
+			call: traffic.Call{
+				Port:       9080,
+				Protocol:   traffic.HTTP,
+				HostHeader: "reviews",
+				Headers:    http.Header{"End-User": []string{"jason"}},
+			},
+			expected: `Listener:     0.0.0.0_9080
+Route config: 9080
+Virtual host: reviews.default.svc.cluster.local:9080
+Route:        v2
+Cluster:      outbound|9080|v2|reviews.default.svc.cluster.local
+mTLS:         ISTIO_MUTUAL
+`,
+		},
+		{
+			name: "tcp",
+			call: traffic.Call{Address: "10.0.0.2", Port: 27017, Protocol: traffic.TCP},
+			expected: `Listener: 10.0.0.2_27017
+Cluster:  outbound|27017||mongo.default.svc.cluster.local
+mTLS:     disabled
+`,
+		},
+		{
+			name: "unknown host",
+			call: traffic.Call{Port: 9080, Protocol: traffic.HTTP, HostHeader: "unknown.example.com"},
+			expected: `Listener:     0.0.0.0_9080
+Route config: 9080
+Virtual host: allow_any
+Route:        allow_any
+Cluster:      PassthroughCluster
+mTLS:         n/a
+`,
+		},
+		{
+			name: "no route",
+			call: traffic.Call{Port: 9080, Path: "/ratings", Protocol: traffic.HTTP, HostHeader: "reviews"},
+			err:  true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := simulate(out, dump, tt.call)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, out.String(), tt.expected)
+		})
+	}
+}
+
+// offlineContext fails to connect to a cluster.
+type offlineContext struct {
+	cli.Context
+}
+
+func (offlineContext) CLIClientWithRevision(string) (kube.CLIClient, error) {
+	return nil, errors.New("no cluster")
+}
+
+func TestSimulateFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config_dump.json")
+	assert.NoError(t, os.WriteFile(file, configDumpJSON(t), 0o644))
+	cases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name: "service host",
+			args: []string{"-f", file, "--to", "reviews.default.svc.cluster.local:9080", "--path", "/reviews/0"},
+			expected: `Listener:     0.0.0.0_9080
+Route config: 9080
+Virtual host: reviews.default.svc.cluster.local:9080
+Route:        default
+Cluster:      outbound|9080||reviews.default.svc.cluster.local
+mTLS:         ISTIO_MUTUAL
+`,
+		},
+		{
+			name: "ip",
+			args: []string{"-f", file, "--to", "10.0.0.2:27017", "--protocol", "tcp"},
+			expected: `Listener: 10.0.0.2_27017
+Cluster:  outbound|27017||mongo.default.svc.cluster.local
+mTLS:     disabled
+`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			cmd := Cmd(offlineContext{cli.NewFakeContext(nil)})
+			cmd.SetArgs(tt.args)
+			cmd.SetOut(out)
+			assert.NoError(t, cmd.Execute())
+			assert.Equal(t, out.String(), tt.expected)
+		})
+	}
+}
+
+func TestParseDestination(t *testing.T) {
+	host, port, err := parseDestination("reviews.default:9080")
+	assert.NoError(t, err)
+	assert.Equal(t, host, "reviews.default")
+	assert.Equal(t, port, 9080)
+
+	_, _, err = parseDestination("reviews")
+	assert.Error(t, err)
+	_, _, err = parseDestination("reviews:http")
+	assert.Error(t, err)
+}
@@ -19,7 +19,10 @@ import (
 
 	. "github.com/onsi/gomega"
 
+	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/legacy/source/kube"
+	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/url"
 )
 
@@ -138,6 +141,138 @@ func TestFormatter_PrintYAML(t *testing.T) {
 	g.Expect(output).To(Equal(expectedOutput))
 }
 
+// fileMessages returns messages for a resource read from a file, and a resource without file.
+func fileMessages() diag.Messages {
+	fileResource := &resource.Instance{
+		Origin: &kube.Origin{
+			Type:     config.GroupVersionKind{Kind: "VirtualService"},
+			FullName: resource.NewFullName("default", "reviews"),
+			Ref:      &kube.Position{Filename: "samples/reviews.yaml", Line: 12},
+		},
+	}
+	firstMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		fileResource,
+		"the bubble is too big",
+	)
+	firstMsg.Line = 20
+	secondMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Info, "C1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+	return diag.Messages{firstMsg, secondMsg}
+}
+
+func TestFormatter_PrintSARIF(t *testing.T) {
+	g := NewWithT(t)
+
+	output, err := Print(fileMessages(), SARIFFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	expectedOutput := `{
+	"version": "2.1.0",
+	"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+	"runs": [
+		{
+			"tool": {
+				"driver": {
+					"name": "istioctl analyze",
+					"informationUri": "` + url.ConfigAnalysis + `/",
+					"rules": [
+						{
+							"id": "B1",
+							"helpUri": "` + url.ConfigAnalysis + `/b1/",
+							"defaultConfiguration": {
+								"level": "error"
+							}
+						},
+						{
+							"id": "C1",
+							"helpUri": "` + url.ConfigAnalysis + `/c1/",
+							"defaultConfiguration": {
+								"level": "note"
+							}
+						}
+					]
+				}
+			},
+			"results": [
+				{
+					"ruleId": "B1",
+					"ruleIndex": 0,
+					"level": "error",
+					"message": {
+						"text": "Explosion accident: the bubble is too big"
+					},
+					"locations": [
+						{
+							"physicalLocation": {
+								"artifactLocation": {
+									"uri": "samples/reviews.yaml"
+								},
+								"region": {
+									"startLine": 20
+								}
+							},
+							"logicalLocations": [
+								{
+									"fullyQualifiedName": "VirtualService default/reviews",
+									"kind": "resource"
+								}
+							]
+						}
+					]
+				},
+				{
+					"ruleId": "C1",
+					"ruleIndex": 1,
+					"level": "note",
+					"message": {
+						"text": "Collapse danger: the castle is too old"
+					},
+					"locations": [
+						{
+							"logicalLocations": [
+								{
+									"fullyQualifiedName": "GrandCastle",
+									"kind": "resource"
+								}
+							]
+						}
+					]
+				}
+			]
+		}
+	]
+}`
+
+	g.Expect(output).To(Equal(expectedOutput))
+}
+
+func TestFormatter_PrintJUnit(t *testing.T) {
+	g := NewWithT(t)
+
+	output, err := Print(fileMessages(), JUnitFormat, false)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	expectedOutput := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="istioctl analyze" tests="2" failures="1">
+  <testsuite name="istioctl analyze" tests="2" failures="1" errors="0" skipped="0">
+    <testcase name="B1 VirtualService default/reviews" classname="VirtualService default/reviews" file="samples/reviews.yaml" line="20">
+      <failure message="Explosion accident: the bubble is too big" type="Error"><![CDATA[Error [B1] (VirtualService default/reviews samples/reviews.yaml:20) Explosion accident: the bubble is too big
+` + url.ConfigAnalysis + `/b1/]]></failure>
+    </testcase>
+    <testcase name="C1 GrandCastle" classname="GrandCastle">
+      <system-out><![CDATA[Info [C1] (GrandCastle) Collapse danger: the castle is too old
+` + url.ConfigAnalysis + `/c1/]]></system-out>
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+	g.Expect(output).To(Equal(expectedOutput))
+}
+
 func TestFormatter_PrintEmpty(t *testing.T) {
 	g := NewWithT(t)
 
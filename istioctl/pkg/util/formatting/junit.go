@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+)
+
+// The subset of the JUnit XML format understood by CI systems. Each message is reported as a test case, failing for
+// errors and warnings.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+func printJUnit(ms diag.Messages) (string, error) {
+	suite := junitTestSuite{Name: toolName, TestCases: []junitTestCase{}}
+	for _, m := range ms {
+		text := fmt.Sprintf(m.Type.Template(), m.Parameters...)
+		resource := "analysis"
+		if m.Resource != nil && m.Resource.Origin != nil {
+			resource = m.Resource.Origin.FriendlyName()
+		}
+		tc := junitTestCase{
+			Name:      fmt.Sprintf("%s %s", m.Type.Code(), resource),
+			ClassName: resource,
+		}
+		tc.File, tc.Line = location(m)
+		details := fmt.Sprintf("%s\n%s", m.String(), m.Unstructured(false)["documentationUrl"])
+		if m.Type.Level() == diag.Error || m.Type.Level() == diag.Warning {
+			tc.Failure = &junitFailure{Message: text, Type: m.Type.Level().String(), Text: details}
+			suite.Failures++
+		} else {
+			tc.SystemOut = &junitOutput{Text: details}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	out, err := xml.MarshalIndent(junitTestSuites{
+		Name:     toolName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(xml.Header, "\n") + "\n" + string(out), nil
+}
@@ -0,0 +1,445 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// Statuses of a ResourceDiff
+const (
+	Changed    = "changed"
+	FirstOnly  = "first-only"
+	SecondOnly = "second-only"
+)
+
+// volatileFields are fields that change between config updates without changing the behavior of the proxy.
+var volatileFields = map[string]bool{
+	"versionInfo": true,
+	"lastUpdated": true,
+}
+
+// ResourceDiff is the difference of a listener, cluster, route or secret between the configurations of two proxies
+type ResourceDiff struct {
+	Type    string        `json:"type"`
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange is a field of a resource with a different value in the configurations of two proxies.
+// A value that is not set in one of the configurations is omitted.
+type FieldChange struct {
+	Path   string `json:"path"`
+	First  any    `json:"first,omitempty"`
+	Second any    `json:"second,omitempty"`
+}
+
+// ProxyComparator diffs between the config dumps of two proxies
+type ProxyComparator struct {
+	first, second         *configdump.Wrapper
+	firstName, secondName string
+	w                     io.Writer
+}
+
+// NewProxyComparator is a comparator constructor
+func NewProxyComparator(w io.Writer, firstName string, first []byte, secondName string, second []byte) (*ProxyComparator, error) {
+	c := &ProxyComparator{firstName: firstName, secondName: secondName, w: w}
+	c.first = &configdump.Wrapper{}
+	if err := json.Unmarshal(first, c.first); err != nil {
+		return nil, fmt.Errorf("error unmarshalling config dump of %s: %v", firstName, err)
+	}
+	c.second = &configdump.Wrapper{}
+	if err := json.Unmarshal(second, c.second); err != nil {
+		return nil, fmt.Errorf("error unmarshalling config dump of %s: %v", secondName, err)
+	}
+	if len(c.first.GetConfigs()) == 0 {
+		return nil, fmt.Errorf("config dump of %s has no configuration", firstName)
+	}
+	if len(c.second.GetConfigs()) == 0 {
+		return nil, fmt.Errorf("config dump of %s has no configuration", secondName)
+	}
+	return c, nil
+}
+
+// Diffs returns the differences between the listeners, clusters, routes and secrets of the two proxies,
+// aligned by name.
+func (c *ProxyComparator) Diffs() ([]ResourceDiff, error) {
+	var diffs []ResourceDiff
+	for _, t := range []struct {
+		name      string
+		section   string
+		resources func(w *configdump.Wrapper) ([]*anypb.Any, error)
+	}{
+		{"Listener", "type.googleapis.com/envoy.admin.v3.ListenersConfigDump", listenerResources},
+		{"Cluster", "type.googleapis.com/envoy.admin.v3.ClustersConfigDump", clusterResources},
+		{"Route", "type.googleapis.com/envoy.admin.v3.RoutesConfigDump", routeResources},
+		{"Secret", "type.googleapis.com/envoy.admin.v3.SecretsConfigDump", secretResources},
+	} {
+		first, err := namedResources(c.first, t.section, t.resources)
+		if err != nil {
+			return nil, fmt.Errorf("%ss of %s: %v", strings.ToLower(t.name), c.firstName, err)
+		}
+		second, err := namedResources(c.second, t.section, t.resources)
+		if err != nil {
+			return nil, fmt.Errorf("%ss of %s: %v", strings.ToLower(t.name), c.secondName, err)
+		}
+		for _, name := range sortedKeys(first, second) {
+			a, inFirst := first[name]
+			b, inSecond := second[name]
+			switch {
+			case !inSecond:
+				diffs = append(diffs, ResourceDiff{Type: t.name, Name: name, Status: FirstOnly})
+			case !inFirst:
+				diffs = append(diffs, ResourceDiff{Type: t.name, Name: name, Status: SecondOnly})
+			default:
+				var changes []FieldChange
+				diffValues("", a, b, &changes)
+				if len(changes) > 0 {
+					diffs = append(diffs, ResourceDiff{Type: t.name, Name: name, Status: Changed, Changes: changes})
+				}
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// Diff prints the differences between the two proxies to the passed writer in the output format,
+// which is one of json, yaml or short.
+func (c *ProxyComparator) Diff(outputFormat string) error {
+	diffs, err := c.Diffs()
+	if err != nil {
+		return err
+	}
+	switch outputFormat {
+	case "json", "yaml":
+		if diffs == nil {
+			diffs = []ResourceDiff{}
+		}
+		out, err := json.MarshalIndent(diffs, "", "    ")
+		if err != nil {
+			return err
+		}
+		if outputFormat == "yaml" {
+			if out, err = yaml.JSONToYAML(out); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(c.w, string(out))
+	default:
+		c.printDiffs(diffs)
+	}
+	return nil
+}
+
+func (c *ProxyComparator) printDiffs(diffs []ResourceDiff) {
+	if len(diffs) == 0 {
+		fmt.Fprintf(c.w, "The configurations of %s and %s match\n", c.firstName, c.secondName)
+		return
+	}
+	for _, d := range diffs {
+		switch d.Status {
+		case FirstOnly:
+			fmt.Fprintf(c.w, "%s %s is only in %s\n", d.Type, d.Name, c.firstName)
+		case SecondOnly:
+			fmt.Fprintf(c.w, "%s %s is only in %s\n", d.Type, d.Name, c.secondName)
+		default:
+			fmt.Fprintf(c.w, "%s %s differs:\n", d.Type, d.Name)
+			for _, change := range d.Changes {
+				fmt.Fprintf(c.w, "    %s: %s -> %s\n", change.Path, shortValue(change.First), shortValue(change.Second))
+			}
+		}
+	}
+	fmt.Fprintf(c.w, "%d resource(s) differ between %s and %s\n", len(diffs), c.firstName, c.secondName)
+}
+
+// shortValue formats a value of a field change on a single line.
+func shortValue(v any) string {
+	if v == nil {
+		return "<unset>"
+	}
+	const maxLen = 100
+	b, _ := json.Marshal(v)
+	if len(b) > maxLen {
+		return string(b[:maxLen]) + "..."
+	}
+	return string(b)
+}
+
+func listenerResources(w *configdump.Wrapper) ([]*anypb.Any, error) {
+	dump, err := w.GetListenerConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	var resources []*anypb.Any
+	for _, l := range dump.GetStaticListeners() {
+		resources = append(resources, l.GetListener())
+	}
+	for _, l := range dump.GetDynamicListeners() {
+		if l.GetActiveState() != nil {
+			resources = append(resources, l.GetActiveState().GetListener())
+		}
+	}
+	return resources, nil
+}
+
+func clusterResources(w *configdump.Wrapper) ([]*anypb.Any, error) {
+	dump, err := w.GetClusterConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	var resources []*anypb.Any
+	for _, c := range dump.GetStaticClusters() {
+		resources = append(resources, c.GetCluster())
+	}
+	for _, c := range dump.GetDynamicActiveClusters() {
+		resources = append(resources, c.GetCluster())
+	}
+	return resources, nil
+}
+
+func routeResources(w *configdump.Wrapper) ([]*anypb.Any, error) {
+	dump, err := w.GetRouteConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	var resources []*anypb.Any
+	for _, r := range dump.GetStaticRouteConfigs() {
+		resources = append(resources, r.GetRouteConfig())
+	}
+	for _, r := range dump.GetDynamicRouteConfigs() {
+		resources = append(resources, r.GetRouteConfig())
+	}
+	return resources, nil
+}
+
+func secretResources(w *configdump.Wrapper) ([]*anypb.Any, error) {
+	dump, err := w.GetSecretConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	var resources []*anypb.Any
+	for _, s := range dump.GetStaticSecrets() {
+		resources = append(resources, s.GetSecret())
+	}
+	for _, s := range dump.GetDynamicActiveSecrets() {
+		resources = append(resources, s.GetSecret())
+	}
+	return resources, nil
+}
+
+// namedResources returns the resources of a config dump as JSON values keyed by name. A config dump
+// without the section of the resources has no resources of the type.
+func namedResources(w *configdump.Wrapper, section string,
+	resources func(w *configdump.Wrapper) ([]*anypb.Any, error),
+) (map[string]any, error) {
+	out := map[string]any{}
+	if !hasSection(w, section) {
+		return out, nil
+	}
+	rs, err := resources(w)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rs {
+		if r == nil {
+			continue
+		}
+		js, err := protomarshal.ToJSONWithAnyResolver(r, "", &envoyResolver)
+		if err != nil {
+			return nil, err
+		}
+		var v map[string]any
+		if err := json.Unmarshal([]byte(js), &v); err != nil {
+			return nil, err
+		}
+		delete(v, "@type")
+		name, _ := v["name"].(string)
+		out[name] = normalize(v)
+	}
+	return out, nil
+}
+
+func hasSection(w *configdump.Wrapper, section string) bool {
+	for _, c := range w.GetConfigs() {
+		if c.GetTypeUrl() == section {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize removes volatile fields from a resource and replaces inline certificates with their identities,
+// as the certificates of two proxies differ even if they have the same identity.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if volatileFields[k] {
+				delete(v, k)
+				continue
+			}
+			switch k {
+			case "certificateChain":
+				v[k] = summarizeCertificates(child, false)
+			case "trustedCa":
+				v[k] = summarizeCertificates(child, true)
+			default:
+				v[k] = normalize(child)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+	}
+	return v
+}
+
+// summarizeCertificates replaces the inline PEM certificates of a data source with their subjects and
+// identities. Trust anchors are summarized with their fingerprints, as the exact certificates matter.
+func summarizeCertificates(source any, fingerprints bool) any {
+	m, ok := source.(map[string]any)
+	if !ok {
+		return source
+	}
+	encoded, ok := m["inlineBytes"].(string)
+	if !ok {
+		return source
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return source
+	}
+	var certs []any
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return source
+		}
+		summary := map[string]any{"subject": cert.Subject.String()}
+		if fingerprints {
+			sum := sha256.Sum256(cert.Raw)
+			summary["sha256"] = hex.EncodeToString(sum[:])
+		} else {
+			summary["issuer"] = cert.Issuer.String()
+			var ids []any
+			for _, u := range cert.URIs {
+				ids = append(ids, u.String())
+			}
+			for _, d := range cert.DNSNames {
+				ids = append(ids, d)
+			}
+			if len(ids) > 0 {
+				summary["identities"] = ids
+			}
+		}
+		certs = append(certs, summary)
+	}
+	if len(certs) == 0 {
+		return source
+	}
+	return map[string]any{"certificates": certs}
+}
+
+// diffValues appends the changes between two JSON values to changes. Arrays of objects with unique names
+// are aligned by name rather than by index.
+func diffValues(path string, a, b any, changes *[]FieldChange) {
+	am, aIsMap := a.(map[string]any)
+	bm, bIsMap := b.(map[string]any)
+	if aIsMap && bIsMap {
+		for _, k := range sortedKeys(am, bm) {
+			diffValues(path+"."+k, am[k], bm[k], changes)
+		}
+		return
+	}
+	as, aIsSlice := a.([]any)
+	bs, bIsSlice := b.([]any)
+	if aIsSlice && bIsSlice {
+		an, aNamed := byName(as)
+		bn, bNamed := byName(bs)
+		if aNamed && bNamed {
+			for _, k := range sortedKeys(an, bn) {
+				diffValues(path+"["+k+"]", an[k], bn[k], changes)
+			}
+			return
+		}
+		for i := 0; i < len(as) || i < len(bs); i++ {
+			var av, bv any
+			if i < len(as) {
+				av = as[i]
+			}
+			if i < len(bs) {
+				bv = bs[i]
+			}
+			diffValues(path+"["+strconv.Itoa(i)+"]", av, bv, changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, FieldChange{Path: strings.TrimPrefix(path, "."), First: a, Second: b})
+	}
+}
+
+// byName returns the elements of an array keyed by their names, if all elements are objects with unique names.
+func byName(values []any) (map[string]any, bool) {
+	out := make(map[string]any, len(values))
+	for _, v := range values {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		name, ok := m["name"].(string)
+		if !ok || name == "" {
+			return nil, false
+		}
+		if _, dup := out[name]; dup {
+			return nil, false
+		}
+		out[name] = v
+	}
+	return out, true
+}
+
+func sortedKeys(a, b map[string]any) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
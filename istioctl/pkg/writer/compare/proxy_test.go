@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestProxyComparatorSameConfigs(t *testing.T) {
+	cfg, err := os.ReadFile("testdata/configdump.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+
+	var out bytes.Buffer
+	comparator, err := NewProxyComparator(&out, "a", cfg, "b", cfg)
+	if err != nil {
+		t.Fatalf("Failed to create ProxyComparator: %v", err)
+	}
+	if err := comparator.Diff("short"); err != nil {
+		t.Fatalf("Unexpected error during diff: %v", err)
+	}
+	assert.Equal(t, out.String(), "The configurations of a and b match\n")
+}
+
+func TestProxyComparatorMismatchedConfigs(t *testing.T) {
+	cfg, err := os.ReadFile("testdata/configdump.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	diffCfg, err := os.ReadFile("testdata/configdump_diff.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+
+	comparator, err := NewProxyComparator(nil, "a", cfg, "b", diffCfg)
+	if err != nil {
+		t.Fatalf("Failed to create ProxyComparator: %v", err)
+	}
+	diffs, err := comparator.Diffs()
+	if err != nil {
+		t.Fatalf("Unexpected error during diff: %v", err)
+	}
+	assert.Equal(t, diffs, []ResourceDiff{
+		{
+			Type:   "Listener",
+			Name:   "connect_terminate",
+			Status: Changed,
+			Changes: []FieldChange{{
+				Path:  "filterChains[default].filters[envoy.filters.network.http_connection_manager].typedConfig.useRemoteAddress",
+				First: false,
+			}},
+		},
+		{
+			Type:   "Listener",
+			Name:   "main_internal",
+			Status: Changed,
+			Changes: []FieldChange{{
+				Path: "filterChains[inbound-vip|9080||details.default.svc.cluster.local-http]." +
+					"filters[envoy.filters.network.http_connection_manager].typedConfig.statPrefix",
+				First:  "inbound_0.0.0.0_9080",
+				Second: "inbound_0.0.0.0_9999",
+			}},
+		},
+		{Type: "Cluster", Name: "inbound-vip|9080|http|ratings.default.svc.cluster.local", Status: FirstOnly},
+		{Type: "Cluster", Name: "inbound-vip|9999|http|ratings.default.svc.cluster.local", Status: SecondOnly},
+		{Type: "Route", Name: "inbound-vip|9080|http|reviews-v3.default.svc.cluster.local", Status: FirstOnly},
+		{Type: "Route", Name: "inbound-vip|9999|http|reviews-v3.default.svc.cluster.local", Status: SecondOnly},
+	})
+
+	var out bytes.Buffer
+	comparator.w = &out
+	if err := comparator.Diff("short"); err != nil {
+		t.Fatalf("Unexpected error during diff: %v", err)
+	}
+	for _, want := range []string{
+		"Listener main_internal differs:\n",
+		`typedConfig.statPrefix: "inbound_0.0.0.0_9080" -> "inbound_0.0.0.0_9999"`,
+		"typedConfig.useRemoteAddress: false -> <unset>",
+		"Cluster inbound-vip|9080|http|ratings.default.svc.cluster.local is only in a\n",
+		"Route inbound-vip|9999|http|reviews-v3.default.svc.cluster.local is only in b\n",
+		"6 resource(s) differ between a and b\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
+
+func secretConfigDump(t *testing.T, certChain, rootCert, lastUpdated string) []byte {
+	t.Helper()
+	read := func(name string) string {
+		b, err := os.ReadFile(env.IstioSrc + "/tests/testdata/certs/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	dump := fmt.Sprintf(`{"configs": [{
+  "@type": "type.googleapis.com/envoy.admin.v3.SecretsConfigDump",
+  "dynamic_active_secrets": [
+    {"name": "default", "version_info": %[1]q, "last_updated": %[1]q, "secret": {
+      "@type": "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret",
+      "name": "default",
+      "tls_certificate": {"certificate_chain": {"inline_bytes": %[2]q}, "private_key": {"inline_bytes": "W3JlZGFjdGVkXQ=="}}}},
+    {"name": "ROOTCA", "version_info": %[1]q, "last_updated": %[1]q, "secret": {
+      "@type": "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret",
+      "name": "ROOTCA",
+      "validation_context": {"trusted_ca": {"inline_bytes": %[3]q}}}}
+  ]}]}`, lastUpdated, read(certChain), read(rootCert))
+	if !json.Valid([]byte(dump)) {
+		t.Fatalf("invalid config dump: %s", dump)
+	}
+	return []byte(dump)
+}
+
+func TestProxyComparatorSecrets(t *testing.T) {
+	first := secretConfigDump(t, "default/cert-chain.pem", "default/root-cert.pem", "2023-05-15T01:32:52.262Z")
+
+	// Versions and update times are ignored.
+	comparator, err := NewProxyComparator(nil, "a", first, "b", secretConfigDump(t, "default/cert-chain.pem", "default/root-cert.pem", "2023-05-16T01:32:52.262Z"))
+	if err != nil {
+		t.Fatalf("Failed to create ProxyComparator: %v", err)
+	}
+	diffs, err := comparator.Diffs()
+	if err != nil {
+		t.Fatalf("Unexpected error during diff: %v", err)
+	}
+	assert.Equal(t, len(diffs), 0)
+
+	// Certificates are compared by identity and trust anchors by fingerprint.
+	comparator, err = NewProxyComparator(nil, "a", first, "b", secretConfigDump(t, "dns/cert-chain.pem", "dns/fake-root-cert.pem", "2023-05-15T01:32:52.262Z"))
+	if err != nil {
+		t.Fatalf("Failed to create ProxyComparator: %v", err)
+	}
+	diffs, err = comparator.Diffs()
+	if err != nil {
+		t.Fatalf("Unexpected error during diff: %v", err)
+	}
+	paths := map[string][]string{}
+	for _, d := range diffs {
+		for _, c := range d.Changes {
+			paths[d.Name] = append(paths[d.Name], c.Path)
+		}
+	}
+	assert.Equal(t, paths["ROOTCA"], []string{"validationContext.trustedCa.certificates[0].sha256"})
+	if len(paths["default"]) == 0 || !strings.HasPrefix(paths["default"][0], "tlsCertificate.certificateChain.certificates[0].") {
+		t.Fatalf("unexpected changes of the default secret: %v", paths["default"])
+	}
+	for _, p := range paths["default"] {
+		if strings.Contains(p, "privateKey") {
+			t.Fatalf("unexpected change of the private key: %v", p)
+		}
+	}
+}
+
+func TestProxyComparatorErrors(t *testing.T) {
+	cfg, err := os.ReadFile("testdata/configdump.json")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+
+	// A config dump without any configuration is not compared.
+	if _, err := NewProxyComparator(nil, "a", cfg, "b", []byte(`{}`)); err == nil {
+		t.Fatalf("expected an error for an empty config dump")
+	}
+
+	// Sections that cannot be read are reported instead of being skipped.
+	comparator, err := NewProxyComparator(nil, "a", cfg, "b", cfg)
+	if err != nil {
+		t.Fatalf("Failed to create ProxyComparator: %v", err)
+	}
+	comparator.second = &configdump.Wrapper{ConfigDump: &admin.ConfigDump{Configs: []*anypb.Any{{
+		TypeUrl: "type.googleapis.com/envoy.admin.v3.ListenersConfigDump",
+		Value:   []byte{0xff},
+	}}}}
+	if _, err := comparator.Diffs(); err == nil {
+		t.Fatalf("expected an error for an unreadable listener section")
+	}
+}
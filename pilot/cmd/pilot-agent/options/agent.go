@@ -22,6 +22,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/bootstrap/platform"
+	dnsClient "istio.io/istio/pkg/dns/client"
 	istioagent "istio.io/istio/pkg/istio-agent"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/istio/pkg/wasm"
@@ -49,6 +50,8 @@ func NewAgentOptions(proxy *ProxyArgs, cfg *meshconfig.ProxyConfig, sds istioage
 			PurgeInterval:         wasmPurgeInterval,
 			HTTPRequestTimeout:    wasmHTTPRequestTimeout,
 			HTTPRequestMaxRetries: wasmHTTPRequestMaxRetries,
+			PublicKeysFile:        wasmPublicKeysFile,
+			MaxCacheSize:          int64(wasmCacheMaxSize),
 		},
 		ProxyIPAddresses:            proxy.IPAddresses,
 		ServiceNode:                 proxy.ServiceNode(),
@@ -71,6 +74,11 @@ func NewAgentOptions(proxy *ProxyArgs, cfg *meshconfig.ProxyConfig, sds istioage
 		UseExternalWorkloadSDS:      useExternalWorkloadSDSEnv,
 		MetadataDiscovery:           enableWDSEnv,
 		SDSFactory:                  sds,
+		DNSCache: dnsClient.CacheOptions{
+			MaxEntries:     DNSCacheSize.Get(),
+			MaxTTL:         DNSCacheMaxTTL.Get(),
+			MaxNegativeTTL: DNSCacheMaxNegativeTTL.Get(),
+		},
 	}
 	extractXDSHeadersFromEnv(o)
 	return o
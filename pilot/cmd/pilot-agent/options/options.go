@@ -80,8 +80,13 @@ var (
 		"Specify the RSA key size to use for workload certificates.").Get()
 	pkcs8KeysEnv = env.Register("PKCS8_KEY", false,
 		"Whether to generate PKCS#8 private keys").Get()
-	eccSigAlgEnv        = env.Register("ECC_SIGNATURE_ALGORITHM", "", "The type of ECC signature algorithm to use when generating private keys").Get()
-	eccCurvEnv          = env.Register("ECC_CURVE", "P256", "The elliptic curve to use when ECC_SIGNATURE_ALGORITHM is set to ECDSA").Get()
+	eccSigAlgEnv = env.Register("ECC_SIGNATURE_ALGORITHM", "",
+		"The type of ECC signature algorithm to use when generating private keys, ECDSA or ED25519. "+
+			"ED25519 requires peers and proxies which support Ed25519 certificates.").Get()
+	eccCurvEnv   = env.Register("ECC_CURVE", "P256", "The elliptic curve to use when ECC_SIGNATURE_ALGORITHM is set to ECDSA").Get()
+	rsaSigAlgEnv = env.Register("RSA_SIGNATURE_ALGORITHM", "",
+		"The signature scheme to use with RSA private keys, when ECC_SIGNATURE_ALGORITHM is not set. If set to PSS, "+
+			"RSASSA-PSS is used instead of RSASSA-PKCS1-v1_5.").Get()
 	fileMountedCertsEnv = env.Register("FILE_MOUNTED_CERTS", false, "").Get()
 	credFetcherTypeEnv  = env.Register("CREDENTIAL_FETCHER_TYPE", security.JWT,
 		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine").Get()
@@ -102,6 +107,16 @@ var (
 	DNSForwardParallel = env.Register("DNS_FORWARD_PARALLEL", false,
 		"If set to true, agent will send parallel DNS queries to all upstream nameservers")
 
+	// The DNS cache settings are set in the proxy metadata of ProxyConfig, like ISTIO_META_DNS_CAPTURE.
+	DNSCacheSize = env.Register("ISTIO_META_DNS_CACHE_SIZE", 0,
+		"Maximum number of upstream DNS responses cached by the agent. Caching is disabled if set to 0")
+
+	DNSCacheMaxTTL = env.Register("ISTIO_META_DNS_CACHE_MAX_TTL", 5*time.Minute,
+		"Maximum time an upstream DNS response is cached by the agent, regardless of the TTL of its records")
+
+	DNSCacheMaxNegativeTTL = env.Register("ISTIO_META_DNS_CACHE_MAX_NEGATIVE_TTL", 30*time.Second,
+		"Maximum time an upstream NXDOMAIN or empty DNS response is cached by the agent")
+
 	// Ability of istio-agent to retrieve proxyConfig via XDS for dynamic configuration updates
 	enableProxyConfigXdsEnv = env.Register("PROXY_CONFIG_XDS_AGENT", false,
 		"If set to true, agent retrieves dynamic proxy-config updates via xds channel").Get()
@@ -121,6 +136,14 @@ var (
 	wasmHTTPRequestMaxRetries = env.Register("WASM_HTTP_REQUEST_MAX_RETRIES", wasm.DefaultHTTPRequestMaxRetries,
 		"maximum number of HTTP/HTTPS request retries for pulling a Wasm module via http/https").Get()
 
+	wasmPublicKeysFile = env.Register("WASM_SIGNATURE_PUBLIC_KEYS", "",
+		"path to a file with PEM encoded public keys, for example mounted from a Secret. If set, only Wasm modules "+
+			"pulled from OCI images with a cosign signature made by one of the keys are loaded").Get()
+
+	wasmCacheMaxSize = env.Register("WASM_CACHE_MAX_SIZE", 0,
+		"maximum total size in bytes of the Wasm modules cached on disk. When exceeded, the least recently used "+
+			"modules are evicted. No limit if set to 0").Get()
+
 	enableWDSEnv = env.Register("PEER_METADATA_DISCOVERY", false,
 		"If set to true, enable the peer metadata discovery extension in Envoy").Get()
 
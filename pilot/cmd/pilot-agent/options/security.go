@@ -27,6 +27,7 @@ import (
 	"istio.io/istio/security/pkg/credentialfetcher"
 	"istio.io/istio/security/pkg/nodeagent/cafile"
 	"istio.io/istio/security/pkg/nodeagent/plugin/providers/google/stsclient"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
 	"istio.io/istio/security/pkg/stsservice/tokenmanager"
 )
 
@@ -47,6 +48,7 @@ func NewSecurityOptions(proxyConfig *meshconfig.ProxyConfig, stsPort int, tokenM
 		Pkcs8Keys:                      pkcs8KeysEnv,
 		ECCSigAlg:                      eccSigAlgEnv,
 		ECCCurve:                       eccCurvEnv,
+		RSASigAlg:                      rsaSigAlgEnv,
 		SecretTTL:                      secretTTLEnv,
 		FileDebounceDuration:           fileDebounceDuration,
 		SecretRotationGracePeriodRatio: secretRotationGracePeriodRatioEnv,
@@ -92,6 +94,14 @@ func SetupSecurityOptions(proxyConfig *meshconfig.ProxyConfig, secOpt *security.
 
 	o := secOpt
 
+	if err := pkiutil.ValidateKeyAlgorithms(pkiutil.SupportedECSignatureAlgorithms(o.ECCSigAlg),
+		pkiutil.SupportedRSASignatureAlgorithms(o.RSASigAlg)); err != nil {
+		return nil, fmt.Errorf("invalid key algorithm: %v", err)
+	}
+	if !pkiutil.IsSupportedEllipticCurve(pkiutil.SupportedEllipticCurves(o.ECCCurve)) {
+		log.Warnf("unsupported ECC_CURVE %q, using %v", o.ECCCurve, pkiutil.P256Curve)
+	}
+
 	// If not set explicitly, default to the discovery address.
 	if o.CAEndpoint == "" {
 		o.CAEndpoint = proxyConfig.DiscoveryAddress
@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"istio.io/istio/pilot/pkg/credentials/acme"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
+	"istio.io/istio/pkg/log"
+)
+
+// initACMEController obtains the certificates of annotated gateways from an ACME server, if configured.
+func (s *Server) initACMEController(args *PilotArgs) error {
+	if features.ACMEDirectoryURL == "" {
+		return nil
+	}
+	if s.kubeClient == nil {
+		log.Warnf("ACME certificates require a Kubernetes cluster, ignoring PILOT_ACME_DIRECTORY_URL")
+		return nil
+	}
+	httpClient := http.DefaultClient
+	if features.ACMECACertFile != "" {
+		caCert, err := os.ReadFile(features.ACMECACertFile)
+		if err != nil {
+			return fmt.Errorf("failed to read PILOT_ACME_CA_CERT_FILE: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("invalid certificate in PILOT_ACME_CA_CERT_FILE %s", features.ACMECACertFile)
+		}
+		httpClient = &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		}}
+	}
+	opts := acme.Options{
+		DirectoryURL: features.ACMEDirectoryURL,
+		Email:        features.ACMEEmail,
+		Namespace:    args.Namespace,
+		RenewBefore:  features.ACMERenewBefore,
+		HTTPClient:   httpClient,
+	}
+	if features.ACMEDNSNameserver != "" {
+		provider := &acme.RFC2136{
+			Nameserver:    features.ACMEDNSNameserver,
+			Zone:          features.ACMEDNSZone,
+			TSIGKey:       features.ACMEDNSTSIGKey,
+			TSIGAlgorithm: features.ACMEDNSTSIGAlgorithm,
+		}
+		if provider.TSIGKey != "" {
+			secret, err := os.ReadFile(features.ACMEDNSTSIGSecretFile)
+			if err != nil {
+				return fmt.Errorf("failed to read PILOT_ACME_DNS_TSIG_SECRET_FILE: %v", err)
+			}
+			provider.TSIGSecret = strings.TrimSpace(string(secret))
+		}
+		opts.DNSProvider = provider
+	}
+	s.addStartFunc("acme controller", func(stop <-chan struct{}) error {
+		go leaderelection.
+			NewLeaderElection(args.Namespace, args.PodName, leaderelection.ACMEController, args.Revision, s.kubeClient).
+			AddRunFunction(func(leaderStop <-chan struct{}) {
+				log.Infof("starting ACME controller with directory %s", opts.DirectoryURL)
+				controller := acme.NewController(s.kubeClient, opts)
+				// Start the informers created after acquiring the leader lock. They are stopped with istiod, not
+				// when the lock is lost, as they would not be recreated.
+				s.kubeClient.RunAndWait(stop)
+				controller.Run(leaderStop)
+			}).Run(stop)
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/kube/watcher/configmapwatcher"
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/security/pkg/pki/ca"
+)
+
+// caRevocationCheckInterval is the interval at which the trust anchors are checked for changes, and the
+// certificate revocation list for expiry.
+const caRevocationCheckInterval = time.Minute
+
+// caRevocation maintains the certificate revocation list of the Istiod CA, which is served to proxies over SDS.
+//
+// Envoy rejects peer certificates whose issuer has no revocation list once it is given one, so the revocation list is
+// only served while the Istiod CA roots are the only trust anchors of the mesh. Peers with an intermediate CA of
+// their own under the same roots cannot be told apart; PILOT_ENABLE_CA_REVOCATION must not be enabled for them.
+type caRevocation struct {
+	ca       *ca.IstioCA
+	validity time.Duration
+	// roots returns the PEM encoded trust anchors of the mesh.
+	roots func() []byte
+	// caRoots returns the PEM encoded roots of the Istiod CA.
+	caRoots func() []byte
+	// push notifies proxies of an updated revocation list.
+	push func()
+
+	mu      sync.RWMutex
+	revoked []ca.RevokedCertificate
+	rootPEM []byte
+	crlPEM  []byte
+	issued  time.Time
+	// localRoots is set if the trust anchors are all roots of the Istiod CA.
+	localRoots bool
+}
+
+var _ xds.CARevocationSource = &caRevocation{}
+
+func (r *caRevocation) CARevocation() ([]byte, []byte) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.localRoots {
+		return r.rootPEM, nil
+	}
+	return r.rootPEM, r.crlPEM
+}
+
+// update replaces the revoked certificates and re-issues the revocation list.
+func (r *caRevocation) update(revoked []ca.RevokedCertificate) {
+	r.mu.Lock()
+	r.revoked = revoked
+	r.mu.Unlock()
+	r.refresh(true)
+}
+
+// refresh re-issues the revocation list if force is set, the trust anchors changed, or half of its validity has passed.
+func (r *caRevocation) refresh(force bool) {
+	roots := r.roots()
+	r.mu.Lock()
+	if !force && bytes.Equal(roots, r.rootPEM) && time.Since(r.issued) < r.validity/2 {
+		r.mu.Unlock()
+		return
+	}
+	if err := r.issueLocked(roots); err != nil {
+		// Keep serving the previous revocation list.
+		r.mu.Unlock()
+		log.Errorf("failed to generate certificate revocation list: %v", err)
+		return
+	}
+	r.mu.Unlock()
+	r.push()
+}
+
+// issueLocked issues a revocation list of the revoked certificates. The caller must hold the lock.
+func (r *caRevocation) issueLocked(roots []byte) error {
+	crl, err := r.ca.GenerateCRL(r.revoked, r.validity)
+	if err != nil {
+		return err
+	}
+	localRoots := onlyLocalRoots(roots, r.caRoots())
+	if !localRoots && (r.localRoots || r.rootPEM == nil) {
+		log.Warnf("the mesh trusts roots other than the Istiod CA roots, not serving the certificate revocation list")
+	}
+	r.rootPEM, r.crlPEM, r.issued, r.localRoots = roots, crl, time.Now(), localRoots
+	log.Infof("issued certificate revocation list with %d revoked certificates", len(r.revoked))
+	return nil
+}
+
+// onlyLocalRoots returns whether all certificates of the PEM encoded roots are in the PEM encoded caRoots.
+func onlyLocalRoots(roots, caRoots []byte) bool {
+	local := sets.New(pemCertificates(caRoots)...)
+	for _, cert := range pemCertificates(roots) {
+		if !local.Contains(cert) {
+			return false
+		}
+	}
+	return true
+}
+
+// pemCertificates returns the DER encoded certificates of PEM encoded data.
+func pemCertificates(data []byte) []string {
+	var certs []string
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs
+		}
+		if block.Type == "CERTIFICATE" {
+			certs = append(certs, string(block.Bytes))
+		}
+	}
+}
+
+// initCARevocation serves the certificate revocation list of the Istiod CA to proxies, if enabled. The revoked
+// certificates are read from the revocation ConfigMap in the istiod namespace. The initial revocation list is issued
+// before proxies are configured to use it, and startup fails if the CA cannot sign revocation lists.
+func (s *Server) initCARevocation(args *PilotArgs) error {
+	if !features.EnableCARevocation {
+		return nil
+	}
+	if s.CA == nil || s.kubeClient == nil {
+		log.Warnf("certificate revocation requires the Istiod CA and a Kubernetes cluster, ignoring PILOT_ENABLE_CA_REVOCATION")
+		return nil
+	}
+	secretGen, ok := s.XDSServer.Generators[v3.SecretType].(*xds.SecretGen)
+	if !ok {
+		log.Warnf("SDS is not served by istiod, ignoring PILOT_ENABLE_CA_REVOCATION")
+		return nil
+	}
+	r := &caRevocation{
+		ca:       s.CA,
+		validity: features.CARevocationListValidity,
+		roots: func() []byte {
+			if roots := s.workloadTrustBundle.GetTrustBundle(); len(roots) > 0 {
+				return []byte(strings.Join(roots, "\n"))
+			}
+			return s.CA.GetCAKeyCertBundle().GetRootCertPem()
+		},
+		caRoots: func() []byte {
+			return s.CA.GetCAKeyCertBundle().GetRootCertPem()
+		},
+		push: func() {
+			s.XDSServer.ConfigUpdate(&model.PushRequest{
+				Full:           false,
+				ConfigsUpdated: sets.New(xds.CARevocationConfigKey),
+				Reason:         model.NewReasonStats(model.SecretTrigger),
+			})
+		},
+	}
+	revoked, err := s.CA.RevokedCertificates()
+	if err != nil {
+		return fmt.Errorf("failed to read certificate revocations: %v", err)
+	}
+	r.revoked = revoked
+	if err := r.issueLocked(r.roots()); err != nil {
+		return fmt.Errorf("PILOT_ENABLE_CA_REVOCATION is set, but the Istiod CA cannot issue a certificate revocation list: %v", err)
+	}
+	secretGen.SetCARevocationSource(r)
+	s.environment.CARevocationServed = true
+
+	watcher := configmapwatcher.NewController(s.kubeClient, args.Namespace, ca.RevocationConfigMap, func(cm *v1.ConfigMap) {
+		revoked, err := ca.ParseRevocations(cm)
+		if err != nil {
+			// Keep serving the previous revocation list.
+			log.Errorf("invalid certificate revocations: %v", err)
+			return
+		}
+		r.update(revoked)
+	})
+	s.addStartFunc("ca revocation", func(stop <-chan struct{}) error {
+		go watcher.Run(stop)
+		go func() {
+			ticker := time.NewTicker(caRevocationCheckInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					r.refresh(false)
+				}
+			}
+		}()
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/security/pkg/pki/ca"
+)
+
+func TestCARevocationLocalRoots(t *testing.T) {
+	opts, err := ca.NewSelfSignedDebugIstioCAOptions("", time.Hour, time.Hour, time.Hour, "local", 2048)
+	assert.NoError(t, err)
+	istioCA, err := ca.NewIstioCA(opts)
+	assert.NoError(t, err)
+	caRoots := istioCA.GetCAKeyCertBundle().GetRootCertPem()
+	remote := newTestCA(t, "remote")
+
+	roots := caRoots
+	pushes := 0
+	r := &caRevocation{
+		ca:       istioCA,
+		validity: time.Hour,
+		roots:    func() []byte { return roots },
+		caRoots:  func() []byte { return caRoots },
+		push:     func() { pushes++ },
+	}
+	assert.NoError(t, r.issueLocked(r.roots()))
+	gotRoots, crl := r.CARevocation()
+	assert.Equal(t, gotRoots, caRoots)
+	assert.Equal(t, crl != nil, true)
+
+	// Once the mesh trusts another root, its peers may present certificates of issuers without a revocation list.
+	roots = bytes.Join([][]byte{caRoots, remote.root}, []byte("\n"))
+	r.refresh(false)
+	assert.Equal(t, pushes, 1)
+	gotRoots, crl = r.CARevocation()
+	assert.Equal(t, gotRoots, roots)
+	assert.Equal(t, crl, nil)
+
+	roots = caRoots
+	r.refresh(false)
+	assert.Equal(t, pushes, 2)
+	_, crl = r.CARevocation()
+	assert.Equal(t, crl != nil, true)
+}
+
+func TestOnlyLocalRoots(t *testing.T) {
+	local, remote := newTestCA(t, "local"), newTestCA(t, "remote")
+	both := bytes.Join([][]byte{local.root, remote.root}, []byte("\n"))
+	assert.Equal(t, onlyLocalRoots(local.root, local.root), true)
+	assert.Equal(t, onlyLocalRoots(local.root, both), true)
+	assert.Equal(t, onlyLocalRoots(both, local.root), false)
+	assert.Equal(t, onlyLocalRoots(remote.root, local.root), false)
+}
@@ -0,0 +1,702 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/log"
+	"istio.io/istio/security/pkg/pki/ca"
+	caerror "istio.io/istio/security/pkg/pki/error"
+	"istio.io/istio/security/pkg/pki/util"
+	caserver "istio.io/istio/security/pkg/server/ca"
+)
+
+// caRotationCheckInterval is the interval at which the rotation checks whether it can proceed to the next phase.
+const caRotationCheckInterval = time.Minute
+
+const (
+	// caRotationConfigMap is the name of the ConfigMap, in the istiod namespace, persisting the rotation state
+	// shared by the istiod replicas.
+	caRotationConfigMap = "istio-ca-rotation"
+	// caRotationConfigMapKey is the key of the ConfigMap data holding the JSON encoded caRotationState.
+	caRotationConfigMapKey = "rotation.json"
+	// caRotationOldCASecret is the name of the Secret, in the istiod namespace, persisting the old CA until the
+	// rotation completes.
+	caRotationOldCASecret = "istio-ca-rotation-old-ca"
+)
+
+type caRotationPhase string
+
+const (
+	// caRotationIdle is the phase until a rotation is started.
+	caRotationIdle caRotationPhase = "Idle"
+	// caRotationDistributingRoots trusts both the old and new roots, while the old CA still signs certificates.
+	caRotationDistributingRoots caRotationPhase = "DistributingRoots"
+	// caRotationSigningWithNewCA signs certificates with the new CA, while the old roots are still trusted until
+	// the certificates signed by the old CA have been renewed.
+	caRotationSigningWithNewCA caRotationPhase = "SigningWithNewCA"
+	// caRotationComplete only trusts the new roots.
+	caRotationComplete caRotationPhase = "Complete"
+)
+
+func (p caRotationPhase) inProgress() bool {
+	return p == caRotationDistributingRoots || p == caRotationSigningWithNewCA
+}
+
+// caRotationStatus is the progress of the plugged-in CA rotation, as served by the debug endpoint.
+type caRotationStatus struct {
+	Phase          caRotationPhase `json:"phase"`
+	PhaseStartTime *time.Time      `json:"phaseStartTime,omitempty"`
+	// NextPhaseTime is the earliest time at which the rotation proceeds to the next phase.
+	NextPhaseTime *time.Time `json:"nextPhaseTime,omitempty"`
+	OldRoots      []string   `json:"oldRoots,omitempty"`
+	NewRoots      []string   `json:"newRoots,omitempty"`
+	// PendingNamespaces are the namespaces whose root cert ConfigMap does not contain the new roots yet.
+	PendingNamespaces []string `json:"pendingNamespaces,omitempty"`
+	// SigningSuspended is set while istiod refuses to sign certificates, as neither the CA of the phase is available.
+	SigningSuspended bool   `json:"signingSuspended,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// caRotationState is the rotation state shared by the istiod replicas. The private key of the new CA is
+// read from cacerts, and never persisted.
+type caRotationState struct {
+	Phase          caRotationPhase `json:"phase"`
+	PhaseStartTime time.Time       `json:"phaseStartTime"`
+	// OldRoots and NewRoots are the PEM encoded roots trusted before and after the rotation.
+	OldRoots string `json:"oldRoots"`
+	NewRoots string `json:"newRoots"`
+}
+
+func (s *caRotationState) equal(o *caRotationState) bool {
+	if s == nil || o == nil {
+		return s == o
+	}
+	return s.Phase == o.Phase && s.PhaseStartTime.Equal(o.PhaseStartTime) && s.OldRoots == o.OldRoots && s.NewRoots == o.NewRoots
+}
+
+// caRotationOldCA is the PEM encoded CA signing until the new roots are distributed.
+type caRotationOldCA struct {
+	Cert  []byte
+	Key   []byte
+	Chain []byte
+}
+
+// errCARotationConflict is returned by caRotationStore.Save when the state was changed since it was loaded.
+var errCARotationConflict = errors.New("the CA rotation state was changed concurrently")
+
+// caRotationStore persists the rotation state.
+type caRotationStore interface {
+	// Load returns the persisted state, or nil if there is none.
+	Load() (*caRotationState, error)
+	// Save persists the state if the persisted state is still prev, and returns errCARotationConflict otherwise.
+	Save(prev *caRotationState, state caRotationState) error
+	// LoadOldCA returns the persisted old CA, or nil if there is none.
+	LoadOldCA() (*caRotationOldCA, error)
+	// SaveOldCA persists the old CA, so that a restarted istiod keeps signing with it until the roots are distributed.
+	SaveOldCA(oldCA caRotationOldCA) error
+	// DeleteOldCA deletes the persisted old CA, if any.
+	DeleteOldCA() error
+}
+
+// memoryCARotationStore keeps the state in memory, when istiod does not run in Kubernetes.
+type memoryCARotationStore struct {
+	state *caRotationState
+	oldCA *caRotationOldCA
+}
+
+func (m *memoryCARotationStore) Load() (*caRotationState, error) {
+	return m.state, nil
+}
+
+func (m *memoryCARotationStore) Save(prev *caRotationState, state caRotationState) error {
+	if !m.state.equal(prev) {
+		return errCARotationConflict
+	}
+	m.state = &state
+	return nil
+}
+
+func (m *memoryCARotationStore) LoadOldCA() (*caRotationOldCA, error) {
+	return m.oldCA, nil
+}
+
+func (m *memoryCARotationStore) SaveOldCA(oldCA caRotationOldCA) error {
+	m.oldCA = &oldCA
+	return nil
+}
+
+func (m *memoryCARotationStore) DeleteOldCA() error {
+	m.oldCA = nil
+	return nil
+}
+
+// configMapCARotationStore keeps the state in the caRotationConfigMap, and the old CA in the caRotationOldCASecret.
+type configMapCARotationStore struct {
+	client    corev1.CoreV1Interface
+	namespace string
+}
+
+func (c configMapCARotationStore) get() (*v1.ConfigMap, *caRotationState, error) {
+	cm, err := c.client.ConfigMaps(c.namespace).Get(context.TODO(), caRotationConfigMap, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	if cm.Data[caRotationConfigMapKey] == "" {
+		return cm, nil, nil
+	}
+	state := &caRotationState{}
+	if err := json.Unmarshal([]byte(cm.Data[caRotationConfigMapKey]), state); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s/%s: %v", c.namespace, caRotationConfigMap, err)
+	}
+	return cm, state, nil
+}
+
+func (c configMapCARotationStore) Load() (*caRotationState, error) {
+	_, state, err := c.get()
+	return state, err
+}
+
+func (c configMapCARotationStore) Save(prev *caRotationState, state caRotationState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	cm, current, err := c.get()
+	if err != nil {
+		return err
+	}
+	if !current.equal(prev) {
+		return errCARotationConflict
+	}
+	if cm == nil {
+		_, err = c.client.ConfigMaps(c.namespace).Create(context.TODO(), &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: caRotationConfigMap, Namespace: c.namespace},
+			Data:       map[string]string{caRotationConfigMapKey: string(b)},
+		}, metav1.CreateOptions{})
+	} else {
+		// The resource version of cm guards against concurrent changes since it was read.
+		cm = cm.DeepCopy()
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[caRotationConfigMapKey] = string(b)
+		_, err = c.client.ConfigMaps(c.namespace).Update(context.TODO(), cm, metav1.UpdateOptions{})
+	}
+	if apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) {
+		return errCARotationConflict
+	}
+	return err
+}
+
+func (c configMapCARotationStore) LoadOldCA() (*caRotationOldCA, error) {
+	secret, err := c.client.Secrets(c.namespace).Get(context.TODO(), caRotationOldCASecret, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &caRotationOldCA{
+		Cert:  secret.Data[ca.CACertFile],
+		Key:   secret.Data[ca.CAPrivateKeyFile],
+		Chain: secret.Data[ca.CertChainFile],
+	}, nil
+}
+
+func (c configMapCARotationStore) SaveOldCA(oldCA caRotationOldCA) error {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: caRotationOldCASecret, Namespace: c.namespace},
+		Data: map[string][]byte{
+			ca.CACertFile:       oldCA.Cert,
+			ca.CAPrivateKeyFile: oldCA.Key,
+			ca.CertChainFile:    oldCA.Chain,
+		},
+	}
+	_, err := c.client.Secrets(c.namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = c.client.Secrets(c.namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+func (c configMapCARotationStore) DeleteOldCA() error {
+	err := c.client.Secrets(c.namespace).Delete(context.TODO(), caRotationOldCASecret, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// pluggedCARotation rotates the plugged-in CA without downtime. Proxies only trust peers whose certificates were
+// signed by a root they received, so the new roots are distributed before the new CA signs certificates, and the
+// old roots are only dropped once all certificates signed by the old CA have been renewed.
+//
+// The phase and the roots are persisted in the store, shared by the istiod replicas. Each replica follows the
+// persisted phase, which only proceeds once it has lasted the period since it was persisted, and an istiod
+// restarted during a rotation resumes it. The old CA is persisted in the store as well until the rotation
+// completes, so that an istiod restarted while the new roots are distributed keeps signing with the old CA, while
+// cacerts already holds the new one. If the old CA is not available, istiod refuses to sign certificates until the
+// roots are distributed.
+type pluggedCARotation struct {
+	bundle *util.KeyCertBundle
+	store  caRotationStore
+	// period is the minimum duration of the phases, which should allow all workload certificates to be renewed.
+	period time.Duration
+	// pendingNamespaces returns the namespaces which did not receive the roots yet. It may be nil.
+	pendingNamespaces func(roots []byte) ([]string, error)
+	// publish distributes the roots of the bundle and re-issues the istiod certificate.
+	publish func() error
+	now     func() time.Time
+
+	mu       sync.Mutex
+	status   caRotationStatus
+	oldRoots []byte
+	newRoots []byte
+	// newCert, newKey and newChain are the new CA, until the bundle signs with it.
+	newCert  []byte
+	newKey   []byte
+	newChain []byte
+	// signingSuspended is set if the bundle holds the new CA while the new roots are distributed.
+	signingSuspended bool
+}
+
+func newPluggedCARotation(bundle *util.KeyCertBundle, period time.Duration, publish func() error) *pluggedCARotation {
+	return &pluggedCARotation{
+		bundle:  bundle,
+		store:   &memoryCARotationStore{},
+		period:  period,
+		publish: publish,
+		now:     time.Now,
+		status:  caRotationStatus{Phase: caRotationIdle},
+	}
+}
+
+// Status returns the progress of the rotation.
+func (r *pluggedCARotation) Status() any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// inProgress returns whether a rotation is started and not completed yet.
+func (r *pluggedCARotation) inProgress() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status.Phase.inProgress()
+}
+
+// signingError returns an error while istiod must not sign certificates.
+func (r *pluggedCARotation) signingError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.signingSuspended {
+		return errors.New("the plugged-in CA is rotated and the old CA is not available, " +
+			"certificates are signed once the new roots are distributed")
+	}
+	return nil
+}
+
+// caRotationSigner refuses to sign certificates while the rotation does not allow it.
+type caRotationSigner struct {
+	caserver.CertificateAuthority
+	rotation *pluggedCARotation
+}
+
+func (s caRotationSigner) Sign(csrPEM []byte, opts ca.CertOpts) ([]byte, error) {
+	if err := s.rotation.signingError(); err != nil {
+		return nil, caerror.NewError(caerror.CANotReady, err)
+	}
+	return s.CertificateAuthority.Sign(csrPEM, opts)
+}
+
+func (s caRotationSigner) SignWithCertChain(csrPEM []byte, opts ca.CertOpts) ([]string, error) {
+	if err := s.rotation.signingError(); err != nil {
+		return nil, caerror.NewError(caerror.CANotReady, err)
+	}
+	return s.CertificateAuthority.SignWithCertChain(csrPEM, opts)
+}
+
+// resume resumes the persisted rotation, if the bundle loaded from cacerts is the new CA of a rotation in progress.
+// While the new roots are distributed, the persisted old CA keeps signing until the next phase.
+func (r *pluggedCARotation) resume() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, err := r.store.Load()
+	if err != nil || state == nil || !state.Phase.inProgress() {
+		return err
+	}
+	roots := r.bundle.GetRootCertPem()
+	if !containsRootCerts(roots, []byte(state.NewRoots)) {
+		log.Warnf("Ignoring the plugged-in CA rotation in phase %s, cacerts does not contain its new roots", state.Phase)
+		return nil
+	}
+	r.oldRoots, r.newRoots = []byte(state.OldRoots), []byte(state.NewRoots)
+	merged := mergeRootCerts(r.oldRoots, roots)
+	if err := r.bundle.VerifyAndSetRootCert(merged); err != nil {
+		return err
+	}
+	if state.Phase == caRotationDistributingRoots {
+		if err := r.resumeOldCA(merged); err != nil {
+			log.Warnf("Resuming the plugged-in CA rotation while the new roots are distributed, but the old CA is not "+
+				"available: refusing to sign certificates until the roots are distributed: %v", err)
+			r.signingSuspended = true
+		}
+	}
+	r.setStatus(state)
+	log.Infof("Resumed plugged-in CA rotation in phase %s", state.Phase)
+	return nil
+}
+
+// resumeOldCA signs with the persisted old CA, and keeps the new CA loaded from cacerts for the next phase.
+func (r *pluggedCARotation) resumeOldCA(roots []byte) error {
+	oldCA, err := r.store.LoadOldCA()
+	if err != nil {
+		return err
+	}
+	if oldCA == nil {
+		return errors.New("the old CA was not persisted")
+	}
+	newCert, newKey, newChain, _ := r.bundle.GetAllPem()
+	if err := r.bundle.VerifyAndSetAll(oldCA.Cert, oldCA.Key, oldCA.Chain, roots); err != nil {
+		return fmt.Errorf("invalid old CA: %v", err)
+	}
+	r.newCert, r.newKey, r.newChain = newCert, newKey, newChain
+	return nil
+}
+
+// start starts a rotation to the given CA, whose roots are not all trusted yet. The new roots are trusted
+// immediately, in addition to the current roots. If another replica already started the rotation to the
+// same CA, the persisted rotation is joined instead.
+func (r *pluggedCARotation) start(certBytes, keyBytes, certChainBytes, rootCertBytes []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status.Phase.inProgress() {
+		// The cacerts files may be written again, e.g. when the Secret is re-synced.
+		if bytes.Equal(r.newRoots, rootCertBytes) && (r.newCert == nil || bytes.Equal(r.newCert, certBytes)) {
+			return nil
+		}
+		return fmt.Errorf("a rotation is in progress (phase %s), the new CA certificates can only be changed once it completes",
+			r.status.Phase)
+	}
+	if err := util.Verify(certBytes, keyBytes, certChainBytes, rootCertBytes); err != nil {
+		return fmt.Errorf("invalid new CA certificates: %v", err)
+	}
+
+	state, err := r.store.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load the CA rotation state: %v", err)
+	}
+	if state == nil || !state.Phase.inProgress() {
+		// The current CA keeps signing until the roots are distributed, also after a restart.
+		oldCert, oldKey, oldChain, _ := r.bundle.GetAllPem()
+		if err := r.store.SaveOldCA(caRotationOldCA{Cert: oldCert, Key: oldKey, Chain: oldChain}); err != nil {
+			return fmt.Errorf("failed to persist the old CA: %v", err)
+		}
+		prev := state
+		state = &caRotationState{
+			Phase:          caRotationDistributingRoots,
+			PhaseStartTime: r.now(),
+			OldRoots:       string(r.bundle.GetRootCertPem()),
+			NewRoots:       string(rootCertBytes),
+		}
+		if err := r.store.Save(prev, *state); errors.Is(err, errCARotationConflict) {
+			// Another replica started the rotation concurrently.
+			if state, err = r.store.Load(); err != nil {
+				return fmt.Errorf("failed to load the CA rotation state: %v", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to persist the CA rotation state: %v", err)
+		}
+	}
+	if state == nil || !state.Phase.inProgress() || !bytes.Equal([]byte(state.NewRoots), rootCertBytes) {
+		return errors.New("another rotation of the CA certificates is in progress")
+	}
+
+	r.oldRoots, r.newRoots = []byte(state.OldRoots), rootCertBytes
+	r.newCert, r.newKey, r.newChain = certBytes, keyBytes, certChainBytes
+	if err := r.apply(state.Phase); err != nil {
+		return err
+	}
+	r.setStatus(state)
+	log.Infof("Started plugged-in CA rotation in phase %s, distributing the new roots %v", state.Phase, r.status.NewRoots)
+	return r.publish()
+}
+
+// apply updates the bundle for the phase.
+func (r *pluggedCARotation) apply(phase caRotationPhase) error {
+	roots := mergeRootCerts(r.oldRoots, r.newRoots)
+	if phase == caRotationComplete {
+		roots = r.newRoots
+	}
+	if phase == caRotationDistributingRoots || r.newKey == nil {
+		return r.bundle.VerifyAndSetRootCert(roots)
+	}
+	if err := r.bundle.VerifyAndSetAll(r.newCert, r.newKey, r.newChain, roots); err != nil {
+		return err
+	}
+	// The new private key is no longer needed.
+	r.newCert, r.newKey, r.newChain = nil, nil, nil
+	return nil
+}
+
+// check follows the persisted phase of the rotation, and proceeds to the next phase once the current phase has
+// lasted the period and, for the distribution of the roots, all namespaces received them.
+func (r *pluggedCARotation) check() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.status.Phase.inProgress() {
+		return
+	}
+	state, err := r.store.Load()
+	if err != nil {
+		r.status.Error = fmt.Sprintf("failed to load the CA rotation state: %v", err)
+		return
+	}
+	if state == nil || !bytes.Equal([]byte(state.NewRoots), r.newRoots) {
+		r.status.Error = "the persisted CA rotation state does not match the rotation in progress"
+		return
+	}
+	r.status.Error = ""
+
+	next := *state
+	if state.Phase == r.status.Phase {
+		if r.now().Before(state.PhaseStartTime.Add(r.period)) {
+			return
+		}
+		switch state.Phase {
+		case caRotationDistributingRoots:
+			if r.pendingNamespaces != nil {
+				pending, err := r.pendingNamespaces(r.newRoots)
+				if err != nil {
+					r.status.Error = fmt.Sprintf("failed to check the distribution of the new roots: %v", err)
+					return
+				}
+				r.status.PendingNamespaces = pending
+				if len(pending) > 0 {
+					return
+				}
+			}
+			next.Phase = caRotationSigningWithNewCA
+		case caRotationSigningWithNewCA:
+			next.Phase = caRotationComplete
+		}
+		next.PhaseStartTime = r.now()
+		if err := r.store.Save(state, next); err != nil {
+			if !errors.Is(err, errCARotationConflict) {
+				r.status.Error = fmt.Sprintf("failed to persist the CA rotation state: %v", err)
+			}
+			// Otherwise, another replica proceeded first: follow it on the next check.
+			return
+		}
+	}
+
+	// Follow the persisted phase, which may have been changed by another replica.
+	if err := r.apply(next.Phase); err != nil {
+		r.status.Error = fmt.Sprintf("failed to proceed to phase %s: %v", next.Phase, err)
+		return
+	}
+	if next.Phase != caRotationDistributingRoots {
+		r.signingSuspended = false
+	}
+	r.setStatus(&next)
+	switch next.Phase {
+	case caRotationSigningWithNewCA:
+		log.Info("Plugged-in CA rotation: the new roots are distributed, signing with the new CA")
+	case caRotationComplete:
+		log.Infof("Plugged-in CA rotation completed, dropped the old roots %v", r.status.OldRoots)
+		if err := r.store.DeleteOldCA(); err != nil {
+			r.status.Error = fmt.Sprintf("failed to delete the old CA: %v", err)
+		}
+	}
+	if err := r.publish(); err != nil {
+		r.status.Error = fmt.Sprintf("failed to publish the roots: %v", err)
+	}
+}
+
+func (r *pluggedCARotation) setStatus(state *caRotationState) {
+	start := state.PhaseStartTime
+	r.status = caRotationStatus{
+		Phase:            state.Phase,
+		PhaseStartTime:   &start,
+		OldRoots:         rootCertSubjects([]byte(state.OldRoots)),
+		NewRoots:         rootCertSubjects([]byte(state.NewRoots)),
+		SigningSuspended: r.signingSuspended,
+	}
+	if state.Phase.inProgress() {
+		next := start.Add(r.period)
+		r.status.NextPhaseTime = &next
+	}
+}
+
+// run checks the rotation periodically until stop is closed.
+func (r *pluggedCARotation) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(caRotationCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.check()
+		}
+	}
+}
+
+// initPluggedCARotation sets up the staged rotation of the plugged-in CA with the given bundle, if enabled.
+// The rotation state is persisted in the namespace, and a rotation in progress is resumed.
+func (s *Server) initPluggedCARotation(bundle *util.KeyCertBundle, namespace string) error {
+	if !features.EnablePluggedCARotation {
+		return nil
+	}
+	period := features.PluggedCARotationPeriod
+	if period <= 0 {
+		period = workloadCertTTL.Get()
+	}
+	s.caRotation = newPluggedCARotation(bundle, period, s.updateRootCertAndGenKeyCert)
+	if s.kubeClient != nil {
+		s.caRotation.store = configMapCARotationStore{client: s.kubeClient.Kube().CoreV1(), namespace: namespace}
+		s.caRotation.pendingNamespaces = s.pendingRootCertNamespaces
+	}
+	if err := s.caRotation.resume(); err != nil {
+		return fmt.Errorf("failed to resume the plugged-in CA rotation: %v", err)
+	}
+	s.XDSServer.CARotationStatus = s.caRotation.Status
+	s.addStartFunc("plugged ca rotation", func(stop <-chan struct{}) error {
+		go s.caRotation.run(stop)
+		return nil
+	})
+	return nil
+}
+
+// pendingRootCertNamespaces returns the namespaces whose root cert ConfigMap does not contain all roots.
+func (s *Server) pendingRootCertNamespaces(roots []byte) ([]string, error) {
+	cms, err := s.kubeClient.Kube().CoreV1().ConfigMaps(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "metadata.name=" + controller.CACertNamespaceConfigMap,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, cm := range cms.Items {
+		if !containsRootCerts([]byte(cm.Data[constants.CACertNamespaceConfigMapDataName]), roots) {
+			pending = append(pending, cm.Namespace)
+		}
+	}
+	sort.Strings(pending)
+	return pending, nil
+}
+
+func parseRootCerts(roots []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, roots = pem.Decode(roots)
+		if block == nil {
+			return certs
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// containsRootCerts returns whether all certificates of roots are in bundle.
+func containsRootCerts(bundle, roots []byte) bool {
+	have := parseRootCerts(bundle)
+	for _, root := range parseRootCerts(roots) {
+		found := false
+		for _, c := range have {
+			if c.Equal(root) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeRootCerts appends the certificates of newRoots which are not in roots.
+func mergeRootCerts(roots, newRoots []byte) []byte {
+	merged := append([]byte{}, roots...)
+	if len(merged) > 0 && merged[len(merged)-1] != '\n' {
+		merged = append(merged, '\n')
+	}
+	for _, root := range parseRootCerts(newRoots) {
+		rootPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw})
+		if !containsRootCerts(roots, rootPEM) {
+			merged = append(merged, rootPEM...)
+		}
+	}
+	return merged
+}
+
+func rootCertSubjects(roots []byte) []string {
+	var subjects []string
+	for _, root := range parseRootCerts(roots) {
+		subjects = append(subjects, root.Subject.String())
+	}
+	return subjects
+}
+
+// startPluggedCARotation starts the rotation to the CA in the cacerts directory.
+func (s *Server) startPluggedCARotation(fileBundle ca.SigningCAFileBundle) error {
+	certBytes, err := os.ReadFile(fileBundle.SigningCertFile)
+	if err != nil {
+		return err
+	}
+	keyBytes, err := os.ReadFile(fileBundle.SigningKeyFile)
+	if err != nil {
+		return err
+	}
+	var certChainBytes []byte
+	for _, f := range fileBundle.CertChainFiles {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		certChainBytes = append(certChainBytes, b...)
+	}
+	rootCertBytes, err := os.ReadFile(fileBundle.RootCertFile)
+	if err != nil {
+		return err
+	}
+	return s.caRotation.start(certBytes, keyBytes, certChainBytes, rootCertBytes)
+}
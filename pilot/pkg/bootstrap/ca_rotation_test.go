@@ -0,0 +1,298 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+type testCA struct {
+	root, cert, key []byte
+}
+
+func newTestCA(t *testing.T, org string) testCA {
+	rootPEM, rootKeyPEM, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org: org, TTL: time.Hour, IsCA: true, IsSelfSigned: true, ECSigAlg: util.EcdsaSigAlg,
+	})
+	assert.NoError(t, err)
+	rootCert, err := util.ParsePemEncodedCertificate(rootPEM)
+	assert.NoError(t, err)
+	rootKey, err := util.ParsePemEncodedKey(rootKeyPEM)
+	assert.NoError(t, err)
+	certPEM, keyPEM, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org: org, TTL: time.Hour, IsCA: true, SignerCert: rootCert, SignerPriv: rootKey, ECSigAlg: util.EcdsaSigAlg,
+	})
+	assert.NoError(t, err)
+	return testCA{root: rootPEM, cert: certPEM, key: keyPEM}
+}
+
+func TestPluggedCARotation(t *testing.T) {
+	oldCA, newCA := newTestCA(t, "old"), newTestCA(t, "new")
+	bundle, err := util.NewVerifiedKeyCertBundleFromPem(oldCA.cert, oldCA.key, oldCA.cert, oldCA.root)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	published := 0
+	var pending []string
+	r := newPluggedCARotation(bundle, time.Hour, func() error {
+		published++
+		return nil
+	})
+	r.now = func() time.Time { return now }
+	r.pendingNamespaces = func(roots []byte) ([]string, error) {
+		assert.Equal(t, roots, newCA.root)
+		return pending, nil
+	}
+	signingCert := func() []byte {
+		cert, _, _, _ := bundle.GetAllPem()
+		return cert
+	}
+	assert.Equal(t, r.Status().(caRotationStatus).Phase, caRotationIdle)
+
+	// Certificates which do not match are rejected.
+	assert.Error(t, r.start(newCA.cert, oldCA.key, newCA.cert, newCA.root))
+	assert.Equal(t, r.inProgress(), false)
+
+	// Both roots are trusted, the old CA still signs.
+	assert.NoError(t, r.start(newCA.cert, newCA.key, newCA.cert, newCA.root))
+	assert.Equal(t, r.inProgress(), true)
+	assert.Equal(t, published, 1)
+	assert.Equal(t, signingCert(), oldCA.cert)
+	assert.Equal(t, containsRootCerts(bundle.GetRootCertPem(), oldCA.root), true)
+	assert.Equal(t, containsRootCerts(bundle.GetRootCertPem(), newCA.root), true)
+	// Re-writing the same files does not restart the rotation, other files are rejected.
+	assert.NoError(t, r.start(newCA.cert, newCA.key, newCA.cert, newCA.root))
+	assert.Error(t, r.start(oldCA.cert, oldCA.key, oldCA.cert, oldCA.root))
+
+	// The phase lasts at least the period, and until the roots are distributed to all namespaces.
+	r.check()
+	assert.Equal(t, r.Status().(caRotationStatus).Phase, caRotationDistributingRoots)
+	now = now.Add(time.Hour)
+	pending = []string{"default"}
+	r.check()
+	status := r.Status().(caRotationStatus)
+	assert.Equal(t, status.Phase, caRotationDistributingRoots)
+	assert.Equal(t, status.PendingNamespaces, []string{"default"})
+
+	// The new CA signs, the old root is still trusted.
+	pending = nil
+	r.check()
+	assert.Equal(t, r.Status().(caRotationStatus).Phase, caRotationSigningWithNewCA)
+	assert.Equal(t, published, 2)
+	assert.Equal(t, signingCert(), newCA.cert)
+	assert.Equal(t, containsRootCerts(bundle.GetRootCertPem(), oldCA.root), true)
+
+	// The old root is dropped after the period.
+	r.check()
+	assert.Equal(t, r.Status().(caRotationStatus).Phase, caRotationSigningWithNewCA)
+	now = now.Add(time.Hour)
+	r.check()
+	status = r.Status().(caRotationStatus)
+	assert.Equal(t, status.Phase, caRotationComplete)
+	assert.Equal(t, status.OldRoots, []string{"O=old"})
+	assert.Equal(t, status.NewRoots, []string{"O=new"})
+	assert.Equal(t, published, 3)
+	assert.Equal(t, bundle.GetRootCertPem(), newCA.root)
+	assert.Equal(t, r.inProgress(), false)
+}
+
+func TestPluggedCARotationSharedState(t *testing.T) {
+	oldCA, newCA := newTestCA(t, "old"), newTestCA(t, "new")
+	client := fake.NewSimpleClientset()
+	store := configMapCARotationStore{client: client.CoreV1(), namespace: "istio-system"}
+	now := time.Now()
+	newReplica := func(c testCA) (*pluggedCARotation, *util.KeyCertBundle) {
+		bundle, err := util.NewVerifiedKeyCertBundleFromPem(c.cert, c.key, c.cert, c.root)
+		assert.NoError(t, err)
+		r := newPluggedCARotation(bundle, time.Hour, func() error { return nil })
+		r.store = store
+		r.now = func() time.Time { return now }
+		return r, bundle
+	}
+	signingCert := func(bundle *util.KeyCertBundle) []byte {
+		cert, _, _, _ := bundle.GetAllPem()
+		return cert
+	}
+
+	a, bundleA := newReplica(oldCA)
+	b, bundleB := newReplica(oldCA)
+	assert.NoError(t, a.start(newCA.cert, newCA.key, newCA.cert, newCA.root))
+	// The second replica joins the rotation started by the first one, and keeps its phase start time.
+	now = now.Add(30 * time.Minute)
+	assert.NoError(t, b.start(newCA.cert, newCA.key, newCA.cert, newCA.root))
+	assert.Equal(t, b.Status().(caRotationStatus).PhaseStartTime, a.Status().(caRotationStatus).PhaseStartTime)
+	assert.Equal(t, containsRootCerts(bundleB.GetRootCertPem(), oldCA.root), true)
+	// A different rotation cannot be started while it is in progress.
+	c, _ := newReplica(oldCA)
+	otherCA := newTestCA(t, "other")
+	assert.Error(t, c.start(otherCA.cert, otherCA.key, otherCA.cert, otherCA.root))
+
+	// Only one replica proceeds to the next phase, the other follows the persisted phase.
+	now = now.Add(30 * time.Minute)
+	a.check()
+	assert.Equal(t, a.Status().(caRotationStatus).Phase, caRotationSigningWithNewCA)
+	assert.Equal(t, b.Status().(caRotationStatus).Phase, caRotationDistributingRoots)
+	b.check()
+	assert.Equal(t, b.Status().(caRotationStatus).Phase, caRotationSigningWithNewCA)
+	assert.Equal(t, signingCert(bundleB), newCA.cert)
+	state, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, state.Phase, caRotationSigningWithNewCA)
+	assert.Equal(t, state.OldRoots, string(oldCA.root))
+
+	// A restarted replica loads the new CA from cacerts, and resumes the rotation with the old roots.
+	restarted, bundle := newReplica(newCA)
+	assert.NoError(t, restarted.resume())
+	assert.Equal(t, restarted.inProgress(), true)
+	assert.Equal(t, containsRootCerts(bundle.GetRootCertPem(), oldCA.root), true)
+	assert.Equal(t, signingCert(bundle), newCA.cert)
+	now = now.Add(time.Hour)
+	restarted.check()
+	assert.Equal(t, restarted.Status().(caRotationStatus).Phase, caRotationComplete)
+	assert.Equal(t, bundle.GetRootCertPem(), newCA.root)
+	a.check()
+	assert.Equal(t, a.Status().(caRotationStatus).Phase, caRotationComplete)
+	assert.Equal(t, bundleA.GetRootCertPem(), newCA.root)
+
+	// Once completed, there is nothing to resume.
+	restarted, bundle = newReplica(newCA)
+	assert.NoError(t, restarted.resume())
+	assert.Equal(t, restarted.inProgress(), false)
+	assert.Equal(t, bundle.GetRootCertPem(), newCA.root)
+}
+
+func TestPluggedCARotationResumeDistributingRoots(t *testing.T) {
+	oldCA, newCA := newTestCA(t, "old"), newTestCA(t, "new")
+	client := fake.NewSimpleClientset()
+	store := configMapCARotationStore{client: client.CoreV1(), namespace: "istio-system"}
+	now := time.Now()
+	newReplica := func(c testCA) (*pluggedCARotation, *util.KeyCertBundle) {
+		bundle, err := util.NewVerifiedKeyCertBundleFromPem(c.cert, c.key, c.cert, c.root)
+		assert.NoError(t, err)
+		r := newPluggedCARotation(bundle, time.Hour, func() error { return nil })
+		r.store = store
+		r.now = func() time.Time { return now }
+		return r, bundle
+	}
+	signingCert := func(bundle *util.KeyCertBundle) []byte {
+		cert, _, _, _ := bundle.GetAllPem()
+		return cert
+	}
+
+	a, _ := newReplica(oldCA)
+	assert.NoError(t, a.start(newCA.cert, newCA.key, newCA.cert, newCA.root))
+
+	// A replica restarted while the new roots are distributed loads the new CA from cacerts, but keeps signing with
+	// the persisted old CA until the next phase.
+	restarted, bundle := newReplica(newCA)
+	assert.NoError(t, restarted.resume())
+	assert.Equal(t, signingCert(bundle), oldCA.cert)
+	assert.Equal(t, containsRootCerts(bundle.GetRootCertPem(), newCA.root), true)
+	assert.NoError(t, restarted.signingError())
+	now = now.Add(time.Hour)
+	restarted.check()
+	assert.Equal(t, restarted.Status().(caRotationStatus).Phase, caRotationSigningWithNewCA)
+	assert.Equal(t, signingCert(bundle), newCA.cert)
+
+	// Without the persisted old CA, the restarted replica refuses to sign until the next phase.
+	assert.NoError(t, store.DeleteOldCA())
+	assert.NoError(t, store.Save(&caRotationState{
+		Phase:          caRotationSigningWithNewCA,
+		PhaseStartTime: now,
+		OldRoots:       string(oldCA.root),
+		NewRoots:       string(newCA.root),
+	}, caRotationState{
+		Phase:          caRotationDistributingRoots,
+		PhaseStartTime: now,
+		OldRoots:       string(oldCA.root),
+		NewRoots:       string(newCA.root),
+	}))
+	restarted, bundle = newReplica(newCA)
+	assert.NoError(t, restarted.resume())
+	assert.Equal(t, restarted.Status().(caRotationStatus).SigningSuspended, true)
+	signer := caRotationSigner{rotation: restarted}
+	_, err := signer.Sign(nil, ca.CertOpts{})
+	assert.Error(t, err)
+	_, err = signer.SignWithCertChain(nil, ca.CertOpts{})
+	assert.Error(t, err)
+	now = now.Add(time.Hour)
+	restarted.check()
+	assert.Equal(t, restarted.Status().(caRotationStatus).Phase, caRotationSigningWithNewCA)
+	assert.Equal(t, restarted.Status().(caRotationStatus).SigningSuspended, false)
+	assert.NoError(t, restarted.signingError())
+	assert.Equal(t, signingCert(bundle), newCA.cert)
+}
+
+func TestPluggedCARotationOldCA(t *testing.T) {
+	oldCA, newCA := newTestCA(t, "old"), newTestCA(t, "new")
+	client := fake.NewSimpleClientset()
+	store := configMapCARotationStore{client: client.CoreV1(), namespace: "istio-system"}
+	bundle, err := util.NewVerifiedKeyCertBundleFromPem(oldCA.cert, oldCA.key, oldCA.cert, oldCA.root)
+	assert.NoError(t, err)
+	now := time.Now()
+	r := newPluggedCARotation(bundle, time.Hour, func() error { return nil })
+	r.store = store
+	r.now = func() time.Time { return now }
+
+	// The old CA is persisted when the rotation starts, and deleted once it completes.
+	assert.NoError(t, r.start(newCA.cert, newCA.key, newCA.cert, newCA.root))
+	oldCAState, err := store.LoadOldCA()
+	assert.NoError(t, err)
+	assert.Equal(t, *oldCAState, caRotationOldCA{Cert: oldCA.cert, Key: oldCA.key, Chain: oldCA.cert})
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Hour)
+		r.check()
+	}
+	assert.Equal(t, r.Status().(caRotationStatus).Phase, caRotationComplete)
+	oldCAState, err = store.LoadOldCA()
+	assert.NoError(t, err)
+	assert.Equal(t, oldCAState, nil)
+}
+
+func TestPendingRootCertNamespaces(t *testing.T) {
+	oldCA, newCA := newTestCA(t, "old"), newTestCA(t, "new")
+	rootCertConfigMap := func(ns string, roots []byte) *v1.ConfigMap {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: controller.CACertNamespaceConfigMap, Namespace: ns},
+			Data:       map[string]string{constants.CACertNamespaceConfigMapDataName: string(roots)},
+		}
+	}
+	s := &Server{kubeClient: kube.NewFakeClient(
+		rootCertConfigMap("b", oldCA.root),
+		rootCertConfigMap("a", mergeRootCerts(oldCA.root, newCA.root)),
+		rootCertConfigMap("c", bytes.TrimSpace(oldCA.root)),
+	)}
+	s.kubeClient.RunAndWait(test.NewStop(t))
+	pending, err := s.pendingRootCertNamespaces(newCA.root)
+	assert.NoError(t, err)
+	assert.Equal(t, pending, []string{"b", "c"})
+	pending, err = s.pendingRootCertNamespaces(oldCA.root)
+	assert.NoError(t, err)
+	assert.Equal(t, len(pending), 0)
+}
@@ -312,8 +312,9 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 	}
 	if features.EnableDistributionTracking {
 		s.statusReporter = &distribution.Reporter{
-			UpdateInterval: features.StatusUpdateInterval,
-			PodName:        args.PodName,
+			UpdateInterval:   features.StatusUpdateInterval,
+			PodName:          args.PodName,
+			HistoryRetention: args.RegistryOptions.DistributionCacheRetention,
 		}
 		s.addStartFunc("status reporter init", func(stop <-chan struct{}) error {
 			s.statusReporter.Init(s.environment.GetLedger(), stop)
@@ -326,6 +327,7 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 			return nil
 		})
 		s.XDSServer.StatusReporter = s.statusReporter
+		s.XDSServer.ConfigHistory = s.statusReporter
 	}
 	if writeStatus {
 		s.addTerminatingStartFunc("status distribution", func(stop <-chan struct{}) error {
@@ -38,6 +38,7 @@ import (
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/security/pkg/cmd"
 	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/pkcs11"
 	"istio.io/istio/security/pkg/pki/ra"
 	caserver "istio.io/istio/security/pkg/server/ca"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
@@ -129,11 +130,56 @@ var (
 
 	// TODO: Likely to be removed and added to mesh config
 	externalCaType = env.Register("EXTERNAL_CA", "",
-		"External CA Integration Type. Permitted value is ISTIOD_RA_KUBERNETES_API.").Get()
+		"External CA Integration Type. Permitted values are ISTIOD_RA_KUBERNETES_API and ISTIOD_RA_VAULT_PKI.").Get()
 
 	// TODO: Likely to be removed and added to mesh config
 	k8sSigner = env.Register("K8S_SIGNER", "",
 		"Kubernetes CA Signer type. Valid from Kubernetes 1.18").Get()
+
+	pkcs11Module = env.Register("PILOT_CA_PKCS11_MODULE", "",
+		"Path of the PKCS#11 module of the HSM holding the private key of the plugged-in CA certificate. "+
+			"If set, the CA signs through the HSM, and the key is not read from the cacerts directory. "+
+			"Requires the pilot-pkcs11 image; the pilot image is built without cgo and cannot load PKCS#11 modules.")
+
+	pkcs11TokenLabel = env.Register("PILOT_CA_PKCS11_TOKEN_LABEL", "",
+		"Label of the PKCS#11 token holding the private key of the plugged-in CA certificate.")
+
+	pkcs11KeyLabel = env.Register("PILOT_CA_PKCS11_KEY_LABEL", "",
+		"Label of the private key of the plugged-in CA certificate in the PKCS#11 token.")
+
+	pkcs11PinFile = env.Register("PILOT_CA_PKCS11_PIN_FILE", "",
+		"Path of the file containing the user PIN of the PKCS#11 token.")
+
+	vaultAddr = env.Register("PILOT_RA_VAULT_ADDR", "",
+		"Address of the Vault server signing workload certificates when EXTERNAL_CA is ISTIOD_RA_VAULT_PKI.")
+
+	vaultNamespace = env.Register("PILOT_RA_VAULT_NAMESPACE", "",
+		"Vault Enterprise namespace of the PKI secrets engine and auth method.")
+
+	vaultCACertFile = env.Register("PILOT_RA_VAULT_CA_CERT_FILE", "",
+		"Path of the PEM encoded CA certificate used to verify the Vault server. If unset, the system roots are used.")
+
+	vaultPKIMount = env.Register("PILOT_RA_VAULT_PKI_MOUNT", "pki",
+		"Path the Vault PKI secrets engine is mounted at.")
+
+	vaultPKIRole = env.Register("PILOT_RA_VAULT_PKI_ROLE", "",
+		"Vault PKI role used to sign workload certificates. The role should allow the SPIFFE URI SANs of the trust "+
+			"domain and set require_cn to false.")
+
+	vaultAuthMethod = env.Register("PILOT_RA_VAULT_AUTH_METHOD", "kubernetes",
+		"Vault auth method used by istiod, either kubernetes or approle.")
+
+	vaultAuthMount = env.Register("PILOT_RA_VAULT_AUTH_MOUNT", "",
+		"Path the Vault auth method is mounted at. If unset, the name of the auth method is used.")
+
+	vaultAuthRole = env.Register("PILOT_RA_VAULT_AUTH_ROLE", "",
+		"Role of the Vault kubernetes auth method, bound to the service account of istiod.")
+
+	vaultAppRoleID = env.Register("PILOT_RA_VAULT_APPROLE_ROLE_ID", "",
+		"Role ID of the Vault approle auth method.")
+
+	vaultAppRoleSecretIDFile = env.Register("PILOT_RA_VAULT_APPROLE_SECRET_ID_FILE", "",
+		"Path of the file containing the secret ID of the Vault approle auth method.")
 )
 
 // initCAServer create a CA Server. The CA API uses cert with the max workload cert TTL.
@@ -145,6 +191,13 @@ func (s *Server) initCAServer(ca caserver.CertificateAuthority, opts *caOptions)
 		log.Fatalf("failed to create istio ca server: %v", startErr)
 	}
 	s.caServer = caServer
+	if len(caServer.AuditSinks) > 0 {
+		s.addTerminatingStartFunc("ca audit", func(stop <-chan struct{}) error {
+			<-stop
+			caServer.Close()
+			return nil
+		})
+	}
 }
 
 // RunCA will start the cert signing GRPC service on an existing server.
@@ -314,6 +367,14 @@ func handleEvent(s *Server) {
 		return
 	}
 
+	// New roots are distributed before the new CA signs, if the staged rotation is enabled.
+	if s.caRotation != nil && (s.caRotation.inProgress() || !containsRootCerts(currentCABundle, newCABundle)) {
+		if err := s.startPluggedCARotation(fileBundle); err != nil {
+			log.Errorf("Failed to rotate plugged-in CA certs: %v", err)
+		}
+		return
+	}
+
 	// Only updating intermediate CA is supported now
 	if !bytes.Equal(currentCABundle, newCABundle) {
 		if !features.MultiRootMesh {
@@ -431,13 +492,21 @@ func (s *Server) createIstioCA(opts *caOptions) (*ca.IstioCA, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to determine signing file format %v", err)
 	}
-	if _, err := os.Stat(fileBundle.SigningKeyFile); err == nil {
+	signingKeyFile := fileBundle.SigningKeyFile
+	if pkcs11Module.Get() != "" {
+		// The private key is held by the HSM, only the certificates are mounted.
+		signingKeyFile = fileBundle.SigningCertFile
+	}
+	if _, err := os.Stat(signingKeyFile); err == nil {
 		detectedSigningCABundle = true
 		if _, err := os.Stat(path.Join(LocalCertDir.Get(), ca.IstioGenerated)); err == nil {
 			istioGenerated = true
 		}
 	}
 
+	if pkcs11Module.Get() != "" && (!detectedSigningCABundle || istioGenerated) {
+		return nil, fmt.Errorf("PILOT_CA_PKCS11_MODULE requires a plugged-in CA certificate in %s", LocalCertDir.Get())
+	}
 	if !detectedSigningCABundle || (features.UseCacertsForSelfSignedCA && istioGenerated) {
 		if features.UseCacertsForSelfSignedCA && istioGenerated {
 			log.Infof("IstioGenerated %s secret found, use it as the CA certificate", ca.CACertsSecret)
@@ -460,12 +529,32 @@ func (s *Server) createIstioCA(opts *caOptions) (*ca.IstioCA, error) {
 		// The secret is mounted and the "istio-generated" key is not used.
 		log.Info("Use local CA certificate")
 
-		caOpts, err = ca.NewPluggedCertIstioCAOptions(fileBundle, workloadCertTTL.Get(), maxWorkloadCertTTL.Get(), caRSAKeySize.Get())
-		if err != nil {
-			return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
-		}
+		if pkcs11Module.Get() != "" {
+			signer, err := s.createPKCS11Signer()
+			if err != nil {
+				return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
+			}
+			caOpts, err = ca.NewPluggedCertIstioCAOptionsWithSigner(fileBundle, signer,
+				workloadCertTTL.Get(), maxWorkloadCertTTL.Get(), caRSAKeySize.Get())
+			if err != nil {
+				return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
+			}
+			// The cacerts watcher reloads the key from ca-key.pem, which does not exist.
+			log.Info("CA private key is held by a PKCS#11 token, changes to cacerts require a restart")
+		} else {
+			caOpts, err = ca.NewPluggedCertIstioCAOptions(fileBundle, workloadCertTTL.Get(), maxWorkloadCertTTL.Get(), caRSAKeySize.Get())
+			if err != nil {
+				return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
+			}
 
-		s.initCACertsWatcher()
+			if err := s.initPluggedCARotation(caOpts.KeyCertBundle, opts.Namespace); err != nil {
+				return nil, err
+			}
+			s.initCACertsWatcher()
+		}
+	}
+	if features.EnableCARevocation && s.kubeClient != nil {
+		caOpts.RevocationStore = ca.NewConfigMapRevocationStore(s.kubeClient.Kube().CoreV1(), opts.Namespace)
 	}
 	istioCA, err := ca.NewIstioCA(caOpts)
 	if err != nil {
@@ -529,7 +618,10 @@ func (s *Server) createIstioRA(opts *caOptions) (ra.RegistrationAuthority, error
 		}
 
 		// File does not exist.
-		if certSignerDomain == "" {
+		if opts.ExternalCAType == ra.ExtCAVault {
+			// The Vault RA reads the root certificate from Vault.
+			caCertFile = ""
+		} else if certSignerDomain == "" {
 			log.Infof("CA cert file %q not found, using %q.", caCertFile, defaultCACertPath)
 			caCertFile = defaultCACertPath
 		} else {
@@ -552,6 +644,20 @@ func (s *Server) createIstioRA(opts *caOptions) (ra.RegistrationAuthority, error
 		TrustDomain:      opts.TrustDomain,
 		CertSignerDomain: opts.CertSignerDomain,
 	}
+	if opts.ExternalCAType == ra.ExtCAVault {
+		raOpts.Vault = ra.VaultOptions{
+			Addr:                vaultAddr.Get(),
+			Namespace:           vaultNamespace.Get(),
+			CACertFile:          vaultCACertFile.Get(),
+			PKIMount:            vaultPKIMount.Get(),
+			Role:                vaultPKIRole.Get(),
+			AuthMethod:          vaultAuthMethod.Get(),
+			AuthMount:           vaultAuthMount.Get(),
+			AuthRole:            vaultAuthRole.Get(),
+			AppRoleID:           vaultAppRoleID.Get(),
+			AppRoleSecretIDFile: vaultAppRoleSecretIDFile.Get(),
+		}
+	}
 	raServer, err := ra.NewIstioRA(raOpts)
 	if err != nil {
 		return nil, err
@@ -564,3 +670,31 @@ func (s *Server) createIstioRA(opts *caOptions) (ra.RegistrationAuthority, error
 	})
 	return raServer, err
 }
+
+// createPKCS11Signer opens the PKCS#11 token holding the private key of the plugged-in CA certificate.
+// The token is closed when istiod stops.
+func (s *Server) createPKCS11Signer() (*pkcs11.Signer, error) {
+	var pin string
+	if pinFile := pkcs11PinFile.Get(); pinFile != "" {
+		b, err := os.ReadFile(pinFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PKCS#11 PIN: %v", err)
+		}
+		pin = strings.TrimSpace(string(b))
+	}
+	signer, err := pkcs11.NewSigner(pkcs11.Config{
+		ModulePath: pkcs11Module.Get(),
+		TokenLabel: pkcs11TokenLabel.Get(),
+		Pin:        pin,
+		KeyLabel:   pkcs11KeyLabel.Get(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Using PKCS#11 key %q of token %q for the Istio CA", pkcs11KeyLabel.Get(), pkcs11TokenLabel.Get())
+	s.addTerminatingStartFunc("pkcs11 signer", func(stop <-chan struct{}) error {
+		<-stop
+		return signer.Close()
+	})
+	return signer, nil
+}
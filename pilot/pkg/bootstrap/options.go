@@ -36,6 +36,9 @@ type RegistryOptions struct {
 
 	Registries []string
 
+	// ServiceDir is the directory watched by the File service registry.
+	ServiceDir string
+
 	// Kubernetes controller options
 	KubeOptions kubecontroller.Options
 	// ClusterRegistriesNamespace specifies where the multi-cluster secret resides
@@ -41,6 +41,7 @@ import (
 	"istio.io/api/security/v1beta1"
 	"istio.io/istio/pilot/pkg/controllers/untaint"
 	kubecredentials "istio.io/istio/pilot/pkg/credentials/kube"
+	"istio.io/istio/pilot/pkg/credentials/ocsp"
 	"istio.io/istio/pilot/pkg/features"
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
 	"istio.io/istio/pilot/pkg/keycertbundle"
@@ -56,6 +57,7 @@ import (
 	"istio.io/istio/pilot/pkg/status/distribution"
 	tb "istio.io/istio/pilot/pkg/trustbundle"
 	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
@@ -87,6 +89,9 @@ import (
 const (
 	// debounce file watcher events to minimize noise in logs
 	watchDebounceDelay = 100 * time.Millisecond
+
+	// spiffeBundlePath is the path of the SPIFFE bundle endpoint on the https port.
+	spiffeBundlePath = "/spiffe/bundle"
 )
 
 func init() {
@@ -143,6 +148,8 @@ type Server struct {
 	// certWatcher watches the certificates for changes and triggers a notification to Istiod.
 	cacertsWatcher *fsnotify.Watcher
 	dnsNames       []string
+	// caRotation rotates the plugged-in CA in stages, nil if disabled.
+	caRotation *pluggedCARotation
 
 	CA       *ca.IstioCA
 	RA       ra.RegistrationAuthority
@@ -277,6 +284,9 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		namespaces := kclient.New[*corev1.Namespace](s.kubeClient)
 		filter := namespace.NewDiscoveryNamespacesFilter(namespaces, s.environment.Watcher, s.internalStop)
 		s.kubeClient = kubelib.SetObjectFilter(s.kubeClient, filter)
+		s.XDSServer.PushPriority = xds.NamespacePushPriority(func(ns string) map[string]string {
+			return namespaces.Get(ns, "").GetLabels()
+		})
 	}
 
 	s.initMeshNetworks(args, s.fileWatcher)
@@ -314,6 +324,15 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		return nil, err
 	}
 
+	if err := s.initCARevocation(args); err != nil {
+		return nil, err
+	}
+	s.initOCSPStapling()
+
+	if err := s.initACMEController(args); err != nil {
+		return nil, err
+	}
+
 	// Parse and validate Istiod Address.
 	istiodHost, _, err := e.GetDiscoveryAddress()
 	if err != nil {
@@ -346,6 +365,8 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		}
 	}
 
+	s.initSpiffeBundleEndpoint()
+
 	// This should be called only after controllers are initialized.
 	s.initRegistryEventHandlers()
 
@@ -1210,7 +1231,11 @@ func (s *Server) startCA(caOpts *caOptions) {
 		s.initCAServer(s.RA, caOpts)
 	} else if s.CA != nil {
 		log.Infof("initializing CA server with IstioD CA")
-		s.initCAServer(s.CA, caOpts)
+		var signer caserver.CertificateAuthority = s.CA
+		if s.caRotation != nil {
+			signer = caRotationSigner{CertificateAuthority: s.CA, rotation: s.caRotation}
+		}
+		s.initCAServer(signer, caOpts)
 	}
 	s.addStartFunc("ca", func(stop <-chan struct{}) error {
 		grpcServer := s.secureGrpcServer
@@ -1308,6 +1333,48 @@ func (s *Server) initWorkloadTrustBundle(args *PilotArgs) error {
 	return nil
 }
 
+// initOCSPStapling staples the OCSP responses of gateway certificates, if enabled.
+func (s *Server) initOCSPStapling() {
+	if !features.EnableGatewayOCSPStapling {
+		return
+	}
+	secretGen, ok := s.XDSServer.Generators[v3.SecretType].(*xds.SecretGen)
+	if !ok {
+		log.Warnf("SDS is not served by istiod, ignoring PILOT_ENABLE_GATEWAY_OCSP_STAPLING")
+		return
+	}
+	stapler := ocsp.NewStapler(func(name, namespace string) {
+		s.XDSServer.ConfigUpdate(&model.PushRequest{
+			Full:           false,
+			ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.Secret, Name: name, Namespace: namespace}),
+			Reason:         model.NewReasonStats(model.SecretTrigger),
+		})
+	})
+	secretGen.SetOCSPStapler(stapler)
+	s.addStartFunc("ocsp stapling", func(stop <-chan struct{}) error {
+		go stapler.Run(stop)
+		return nil
+	})
+}
+
+// initSpiffeBundleEndpoint serves the trust anchors of the local trust domain as a SPIFFE bundle on the https port,
+// so that other meshes can federate with this one.
+func (s *Server) initSpiffeBundleEndpoint() {
+	if !features.EnableSpiffeBundleEndpoint {
+		return
+	}
+	if !features.MultiRootMesh {
+		log.Warnf("the SPIFFE bundle endpoint requires ISTIO_MULTIROOT_MESH, ignoring PILOT_ENABLE_SPIFFE_BUNDLE_ENDPOINT")
+		return
+	}
+	if s.httpsMux == nil {
+		log.Warnf("the SPIFFE bundle endpoint requires the https server, ignoring PILOT_ENABLE_SPIFFE_BUNDLE_ENDPOINT")
+		return
+	}
+	s.httpsMux.Handle(spiffeBundlePath, tb.NewSpiffeBundleEndpoint(s.workloadTrustBundle, features.SpiffeBundleRefreshHint))
+	log.Infof("serving the SPIFFE bundle at %s", spiffeBundlePath)
+}
+
 // isCADisabled returns whether CA functionality is disabled in istiod.
 // It returns true only if istiod certs is signed by Kubernetes or
 // workload certs are signed by external CA
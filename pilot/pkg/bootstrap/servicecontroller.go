@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
+	"istio.io/istio/pilot/pkg/serviceregistry/file"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
@@ -53,6 +54,10 @@ func (s *Server) initServiceControllers(args *PilotArgs) error {
 			if err := s.initKubeRegistry(args); err != nil {
 				return err
 			}
+		case provider.File:
+			if err := s.initFileRegistry(args); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("service registry %s is not supported", r)
 		}
@@ -93,3 +98,16 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 
 	return
 }
+
+// initFileRegistry creates the service registry backed by the service definitions in RegistryOptions.ServiceDir
+func (s *Server) initFileRegistry(args *PilotArgs) error {
+	if args.RegistryOptions.ServiceDir == "" {
+		return fmt.Errorf("service registry %s requires --serviceDir", provider.File)
+	}
+	s.ServiceController().AddRegistry(file.NewController(file.Options{
+		Root:       args.RegistryOptions.ServiceDir,
+		ClusterID:  s.clusterID,
+		XDSUpdater: s.XDSServer,
+	}))
+	return nil
+}
@@ -0,0 +1,428 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package acme obtains and renews the certificates of Gateway servers from an ACME certificate authority, such as
+// Let's Encrypt. The certificates are stored in the Secrets named by the credentialName of the servers, from where
+// they are served to the gateways over SDS like any other gateway credential.
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	networkingclient "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	credkube "istio.io/istio/pilot/pkg/credentials/kube"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/kube/kclient"
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	// GatewayAnnotation opts a Gateway in to ACME certificates when set to "true". Every server of the Gateway with
+	// SIMPLE TLS and a credentialName gets a certificate for its hosts. With the HTTP-01 challenge, the Gateway must
+	// also have a plain HTTP server on port 80 for the hosts.
+	GatewayAnnotation = "networking.istio.io/acme"
+
+	// AccountSecret is the name of the Secret, in the istiod namespace, holding the key of the ACME account.
+	AccountSecret = "istio-acme-account"
+
+	// managedLabel marks the Secrets and VirtualServices written by the controller. Secrets without it are never
+	// overwritten.
+	managedLabel = "networking.istio.io/acme-managed"
+	// domainsAnnotation records the domains of the certificate in a Secret.
+	domainsAnnotation = "networking.istio.io/acme-domains"
+	accountKey        = "key.pem"
+
+	issueTimeout   = 5 * time.Minute
+	resyncInterval = time.Hour
+	maxRetries     = 5
+
+	// http01CheckTimeout is how long the gateway is polled for the HTTP-01 challenge response before the challenge
+	// is accepted, and http01CheckInterval the interval between polls.
+	http01CheckTimeout  = 2 * time.Minute
+	http01CheckInterval = 2 * time.Second
+)
+
+var log = istiolog.RegisterScope("acme", "ACME gateway certificates")
+
+// Options configures the ACME controller.
+type Options struct {
+	// DirectoryURL is the directory URL of the ACME server.
+	DirectoryURL string
+	// Email is the contact address of the ACME account, if any.
+	Email string
+	// Namespace is the namespace of the account Secret.
+	Namespace string
+	// RenewBefore is how long before expiry certificates are renewed.
+	RenewBefore time.Duration
+	// HTTPClient is used to talk to the ACME server. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+	// DNSProvider solves DNS-01 challenges. If nil, HTTP-01 challenges are used, and wildcard hosts are skipped.
+	DNSProvider DNSProvider
+}
+
+// Controller obtains the certificates of the servers of annotated Gateways.
+type Controller struct {
+	opts       Options
+	queue      controllers.Queue
+	kubeClient kube.Client
+
+	gateways        kclient.Client[*networkingclient.Gateway]
+	secrets         kclient.Client[*v1.Secret]
+	virtualServices kclient.Writer[*networkingclient.VirtualService]
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	client *acme.Client
+	now    func() time.Time
+
+	// http01Client fetches the HTTP-01 challenge responses from the gateways, every http01CheckInterval until
+	// http01CheckTimeout.
+	http01Client        *http.Client
+	http01CheckInterval time.Duration
+	http01CheckTimeout  time.Duration
+}
+
+// NewController returns a controller obtaining certificates from the ACME server of opts.
+func NewController(kubeClient kube.Client, opts Options) *Controller {
+	c := &Controller{
+		opts:       opts,
+		kubeClient: kubeClient,
+		now:        time.Now,
+
+		http01Client:        &http.Client{Timeout: 10 * time.Second},
+		http01CheckInterval: http01CheckInterval,
+		http01CheckTimeout:  http01CheckTimeout,
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.queue = controllers.NewQueue("acme controller",
+		controllers.WithReconciler(c.reconcile),
+		controllers.WithMaxAttempts(maxRetries))
+	c.gateways = kclient.NewFiltered[*networkingclient.Gateway](kubeClient, kclient.Filter{
+		ObjectFilter: kubeClient.ObjectFilter(),
+	})
+	c.secrets = kclient.NewFiltered[*v1.Secret](kubeClient, kclient.Filter{
+		LabelSelector: managedLabel + "=true",
+		ObjectFilter:  kubeClient.ObjectFilter(),
+	})
+	c.virtualServices = kclient.NewWriteClient[*networkingclient.VirtualService](kubeClient)
+
+	c.gateways.AddEventHandler(controllers.FilteredObjectSpecHandler(c.queue.AddObject, func(o controllers.Object) bool {
+		return o.GetAnnotations()[GatewayAnnotation] == "true"
+	}))
+	// Reissue the certificates of deleted or modified Secrets.
+	c.secrets.AddEventHandler(controllers.ObjectHandler(func(o controllers.Object) {
+		c.enqueueGateways(o.GetNamespace())
+	}))
+	return c
+}
+
+// Run starts the controller until stop is closed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	defer c.cancel()
+	if !kube.WaitForCacheSync("acme controller", stop, c.gateways.HasSynced, c.secrets.HasSynced) {
+		return
+	}
+	go func() {
+		// Check periodically whether certificates are due for renewal.
+		ticker := time.NewTicker(resyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.enqueueGateways(metav1.NamespaceAll)
+			case <-stop:
+				c.cancel()
+				return
+			}
+		}
+	}()
+	c.queue.Run(stop)
+	controllers.ShutdownAll(c.gateways, c.secrets)
+}
+
+func (c *Controller) enqueueGateways(namespace string) {
+	for _, gw := range c.gateways.List(namespace, klabels.Everything()) {
+		if gw.Annotations[GatewayAnnotation] == "true" {
+			c.queue.AddObject(gw)
+		}
+	}
+}
+
+// certificateRequest is a certificate to obtain for the servers of a Gateway.
+type certificateRequest struct {
+	Secret  string
+	Domains []string
+}
+
+// certificateRequests returns the certificates needed by the servers of a Gateway, one per credentialName.
+func certificateRequests(gw *networking.Gateway, wildcards bool) []certificateRequest {
+	domains := map[string][]string{}
+	for _, server := range gw.GetServers() {
+		tls := server.GetTls()
+		if tls.GetMode() != networking.ServerTLSSettings_SIMPLE || tls.GetCredentialName() == "" {
+			continue
+		}
+		for _, host := range server.GetHosts() {
+			// Drop the namespace of the host.
+			if i := strings.Index(host, "/"); i >= 0 {
+				host = host[i+1:]
+			}
+			if host == "*" || (strings.HasPrefix(host, "*.") && !wildcards) {
+				continue
+			}
+			if !slices.Contains(domains[tls.GetCredentialName()], host) {
+				domains[tls.GetCredentialName()] = append(domains[tls.GetCredentialName()], host)
+			}
+		}
+	}
+	var requests []certificateRequest
+	for secret, names := range domains {
+		sort.Strings(names)
+		requests = append(requests, certificateRequest{Secret: secret, Domains: names})
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Secret < requests[j].Secret })
+	return requests
+}
+
+func (c *Controller) reconcile(key types.NamespacedName) error {
+	gw := c.gateways.Get(key.Name, key.Namespace)
+	if gw == nil || gw.Annotations[GatewayAnnotation] != "true" {
+		// Certificates already issued are kept, they are owned by the Secrets.
+		return nil
+	}
+	var errs []error
+	for _, req := range certificateRequests(&gw.Spec, c.opts.DNSProvider != nil) {
+		if err := c.reconcileCertificate(gw, req); err != nil {
+			errs = append(errs, fmt.Errorf("certificate %s/%s: %v", gw.Namespace, req.Secret, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Controller) reconcileCertificate(gw *networkingclient.Gateway, req certificateRequest) error {
+	existing := c.secrets.Get(req.Secret, gw.Namespace)
+	if existing != nil && !c.needsRenewal(existing, req.Domains) {
+		return nil
+	}
+	if existing == nil {
+		// Only managed Secrets are cached, check that the Secret is not provided by someone else before ordering.
+		_, err := c.kubeClient.Kube().CoreV1().Secrets(gw.Namespace).Get(c.ctx, req.Secret, metav1.GetOptions{})
+		if err == nil {
+			log.Debugf("secret %s/%s is not managed by istiod, skipping", gw.Namespace, req.Secret)
+			return nil
+		}
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	log.Infof("obtaining certificate for %v of gateway %s/%s", req.Domains, gw.Namespace, gw.Name)
+	ctx, cancel := context.WithTimeout(c.ctx, issueTimeout)
+	defer cancel()
+	certPEM, keyPEM, err := c.issue(ctx, gw, req.Domains)
+	if err != nil {
+		return err
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        req.Secret,
+			Namespace:   gw.Namespace,
+			Labels:      map[string]string{managedLabel: "true"},
+			Annotations: map[string]string{domainsAnnotation: strings.Join(req.Domains, ",")},
+		},
+		Type: v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			credkube.TLSSecretCert: certPEM,
+			credkube.TLSSecretKey:  keyPEM,
+		},
+	}
+	if existing == nil {
+		if _, err := c.secrets.Create(secret); err != nil {
+			return err
+		}
+	} else {
+		secret.ResourceVersion = existing.ResourceVersion
+		if _, err := c.secrets.Update(secret); err != nil {
+			return err
+		}
+	}
+	log.Infof("stored certificate for %v in secret %s/%s", req.Domains, gw.Namespace, req.Secret)
+	return nil
+}
+
+// needsRenewal returns whether the certificate in the secret is for other domains or is close to expiry.
+func (c *Controller) needsRenewal(secret *v1.Secret, domains []string) bool {
+	if secret.Annotations[domainsAnnotation] != strings.Join(domains, ",") {
+		return true
+	}
+	cert, err := util.ParsePemEncodedCertificate(secret.Data[credkube.TLSSecretCert])
+	if err != nil {
+		return true
+	}
+	return c.now().After(cert.NotAfter.Add(-c.opts.RenewBefore))
+}
+
+// account returns the ACME client of the account, registering the account on first use.
+func (c *Controller) account(ctx context.Context) (*acme.Client, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+	key, err := c.accountKey()
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{Key: key, DirectoryURL: c.opts.DirectoryURL, HTTPClient: c.opts.HTTPClient, UserAgent: "istiod"}
+	account := &acme.Account{}
+	if c.opts.Email != "" {
+		account.Contact = []string{"mailto:" + c.opts.Email}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && err != acme.ErrAccountAlreadyExists {
+		return nil, fmt.Errorf("failed to register ACME account: %v", err)
+	}
+	c.client = client
+	return client, nil
+}
+
+// accountKey reads the key of the ACME account from its Secret, creating it if needed.
+func (c *Controller) accountKey() (crypto.Signer, error) {
+	if secret := c.secrets.Get(AccountSecret, c.opts.Namespace); secret != nil {
+		key, err := util.ParsePemEncodedKey(secret.Data[accountKey])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ACME account key: %v", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("invalid ACME account key type %T", key)
+		}
+		return signer, nil
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	_, err = c.secrets.Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      AccountSecret,
+			Namespace: c.opts.Namespace,
+			Labels:    map[string]string{managedLabel: "true"},
+		},
+		Data: map[string][]byte{accountKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store ACME account key: %v", err)
+	}
+	return key, nil
+}
+
+// issue obtains a certificate for the domains, returning the PEM encoded certificate chain and private key.
+func (c *Controller) issue(ctx context.Context, gw *networkingclient.Gateway, domains []string) ([]byte, []byte, error) {
+	client, err := c.account(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create order: %v", err)
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err := c.authorize(ctx, client, gw, authzURL); err != nil {
+			return nil, nil, err
+		}
+	}
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, nil, fmt.Errorf("order failed: %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: domains}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to finalize order: %v", err)
+	}
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// authorize solves a challenge of the authorization, unless it is already valid.
+func (c *Controller) authorize(ctx context.Context, client *acme.Client, gw *networkingclient.Gateway, authzURL string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("failed to get authorization: %v", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	domain := authz.Identifier.Value
+	solver := c.solver(gw, authz)
+	if solver == nil {
+		return fmt.Errorf("no supported challenge for %s", domain)
+	}
+	chal := solver.challenge
+	if err := solver.present(ctx, client, domain, chal.Token); err != nil {
+		return fmt.Errorf("failed to present %s challenge for %s: %v", chal.Type, domain, err)
+	}
+	defer func() {
+		if err := solver.cleanUp(context.Background(), client, domain, chal.Token); err != nil {
+			log.Warnf("failed to clean up %s challenge for %s: %v", chal.Type, domain, err)
+		}
+	}()
+	if solver.wait != nil {
+		solver.wait(ctx, client, domain, chal.Token)
+	}
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("failed to accept %s challenge for %s: %v", chal.Type, domain, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("authorization of %s failed: %v", domain, err)
+	}
+	return nil
+}
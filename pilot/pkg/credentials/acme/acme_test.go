@@ -0,0 +1,223 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	networkingclient "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	credkube "istio.io/istio/pilot/pkg/credentials/kube"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/kclient"
+	"istio.io/istio/pkg/kube/kclient/clienttest"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func simpleServer(credentialName string, hosts ...string) *networking.Server {
+	return &networking.Server{
+		Port:  &networking.Port{Number: 443, Name: "https", Protocol: "HTTPS"},
+		Hosts: hosts,
+		Tls:   &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_SIMPLE, CredentialName: credentialName},
+	}
+}
+
+func TestCertificateRequests(t *testing.T) {
+	gw := &networking.Gateway{
+		Servers: []*networking.Server{
+			simpleServer("foo-cert", "ns/foo.example.com", "*.example.com"),
+			simpleServer("foo-cert", "./bar.example.com", "foo.example.com"),
+			simpleServer("baz-cert", "baz.example.com", "*"),
+			simpleServer("", "nocert.example.com"),
+			{
+				Port:  &networking.Port{Number: 443, Name: "tls", Protocol: "TLS"},
+				Hosts: []string{"passthrough.example.com"},
+				Tls:   &networking.ServerTLSSettings{Mode: networking.ServerTLSSettings_PASSTHROUGH, CredentialName: "passthrough"},
+			},
+			{
+				Port:  &networking.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+				Hosts: []string{"http.example.com"},
+			},
+		},
+	}
+	assert.Equal(t, certificateRequests(gw, false), []certificateRequest{
+		{Secret: "baz-cert", Domains: []string{"baz.example.com"}},
+		{Secret: "foo-cert", Domains: []string{"bar.example.com", "foo.example.com"}},
+	})
+	// Wildcard domains can only be validated with DNS-01.
+	assert.Equal(t, certificateRequests(gw, true), []certificateRequest{
+		{Secret: "baz-cert", Domains: []string{"baz.example.com"}},
+		{Secret: "foo-cert", Domains: []string{"*.example.com", "bar.example.com", "foo.example.com"}},
+	})
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	certPEM, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:         "foo.example.com",
+		NotBefore:    time.Now(),
+		TTL:          90 * 24 * time.Hour,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	assert.NoError(t, err)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{domainsAnnotation: "foo.example.com"}},
+		Data:       map[string][]byte{credkube.TLSSecretCert: certPEM},
+	}
+	c := &Controller{opts: Options{RenewBefore: 30 * 24 * time.Hour}, now: time.Now}
+	assert.Equal(t, c.needsRenewal(secret, []string{"foo.example.com"}), false)
+	assert.Equal(t, c.needsRenewal(secret, []string{"bar.example.com", "foo.example.com"}), true)
+
+	c.now = func() time.Time { return time.Now().Add(61 * 24 * time.Hour) }
+	assert.Equal(t, c.needsRenewal(secret, []string{"foo.example.com"}), true)
+
+	secret.Data = nil
+	c.now = time.Now
+	assert.Equal(t, c.needsRenewal(secret, []string{"foo.example.com"}), true)
+}
+
+func TestHTTP01VirtualService(t *testing.T) {
+	gw := &networkingclient.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "istio-ingress"}}
+	vs := http01VirtualService(gw, "foo.example.com", "/.well-known/acme-challenge/token", "token.thumbprint")
+	assert.Equal(t, vs.Name, http01VirtualServiceName("/.well-known/acme-challenge/token"))
+	assert.Equal(t, vs.Namespace, "istio-ingress")
+	assert.Equal(t, vs.Labels[managedLabel], "true")
+	assert.Equal(t, vs.Spec.Hosts, []string{"foo.example.com"})
+	assert.Equal(t, vs.Spec.Gateways, []string{"gateway"})
+	assert.Equal(t, vs.Spec.Http[0].Match[0].GetUri().GetExact(), "/.well-known/acme-challenge/token")
+	assert.Equal(t, vs.Spec.Http[0].DirectResponse.Status, uint32(200))
+	assert.Equal(t, vs.Spec.Http[0].DirectResponse.Body.GetString_(), "token.thumbprint")
+}
+
+func TestWaitForHTTP01(t *testing.T) {
+	requests := atomic.NewInt32(0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The route is only distributed to the gateway after a few requests.
+		if requests.Inc() < 3 || r.URL.Path != "/.well-known/acme-challenge/token" || r.Host != "foo.example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("token.thumbprint"))
+	}))
+	defer server.Close()
+	c := &Controller{
+		// Send the requests for the domain to the test server.
+		http01Client: &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		}},
+		http01CheckInterval: time.Millisecond,
+		http01CheckTimeout:  time.Minute,
+	}
+
+	c.waitForHTTP01(context.Background(), "foo.example.com", "/.well-known/acme-challenge/token", "token.thumbprint")
+	assert.Equal(t, requests.Load(), int32(3))
+
+	// The challenge is accepted anyway once the timeout has passed.
+	c.http01CheckTimeout = 50 * time.Millisecond
+	c.waitForHTTP01(context.Background(), "foo.example.com", "/.well-known/acme-challenge/other", "token.thumbprint")
+	assert.Equal(t, requests.Load() > 3, true)
+}
+
+func annotatedGateway(hosts ...string) *networkingclient.Gateway {
+	return &networkingclient.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "gateway",
+			Namespace:   "istio-ingress",
+			Annotations: map[string]string{GatewayAnnotation: "true"},
+		},
+		Spec: networking.Gateway{
+			Servers: []*networking.Server{simpleServer("gateway-cert", hosts...)},
+		},
+	}
+}
+
+func TestUnmanagedSecret(t *testing.T) {
+	client := kube.NewFakeClient(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway-cert", Namespace: "istio-ingress"},
+	})
+	// The ACME server is never contacted, as the secret is provided by the user.
+	c := NewController(client, Options{DirectoryURL: "https://127.0.0.1:1/dir", Namespace: "istio-system"})
+	stop := test.NewStop(t)
+	client.RunAndWait(stop)
+	go c.Run(stop)
+	gateways := clienttest.Wrap(t, c.gateways)
+	gateways.Create(annotatedGateway("foo.example.com"))
+	retry.UntilOrFail(t, func() bool { return c.gateways.Get("gateway", "istio-ingress") != nil })
+	assert.NoError(t, c.reconcile(types.NamespacedName{Name: "gateway", Namespace: "istio-ingress"}))
+	assert.Equal(t, c.client == nil, true)
+}
+
+// TestPebble obtains a certificate from a Pebble ACME server started with PEBBLE_VA_ALWAYS_VALID=1, as the challenges
+// cannot be served by a gateway in the test. It is skipped unless PEBBLE_DIRECTORY_URL and PEBBLE_CA_CERT_FILE, the
+// certificate of the Pebble listener, are set.
+func TestPebble(t *testing.T) {
+	directory, caCertFile := os.Getenv("PEBBLE_DIRECTORY_URL"), os.Getenv("PEBBLE_CA_CERT_FILE")
+	if directory == "" || caCertFile == "" {
+		t.Skip("PEBBLE_DIRECTORY_URL and PEBBLE_CA_CERT_FILE are not set")
+	}
+	caCert, err := os.ReadFile(caCertFile)
+	assert.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(caCert)
+
+	client := kube.NewFakeClient()
+	c := NewController(client, Options{
+		DirectoryURL: directory,
+		Namespace:    "istio-system",
+		RenewBefore:  time.Hour,
+		HTTPClient:   &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}},
+	})
+	// The challenge responses are not served, do not wait for them.
+	c.http01CheckTimeout = time.Millisecond
+	stop := test.NewStop(t)
+	client.RunAndWait(stop)
+	go c.Run(stop)
+
+	kclient.NewWriteClient[*networkingclient.Gateway](client).Create(annotatedGateway("foo.example.com"))
+	secrets := client.Kube().CoreV1().Secrets("istio-ingress")
+	var secret *v1.Secret
+	retry.UntilSuccessOrFail(t, func() error {
+		secret, err = secrets.Get(context.Background(), "gateway-cert", metav1.GetOptions{})
+		return err
+	}, retry.Timeout(time.Minute))
+	cert, err := util.ParsePemEncodedCertificate(secret.Data[credkube.TLSSecretCert])
+	assert.NoError(t, err)
+	assert.Equal(t, cert.DNSNames, []string{"foo.example.com"})
+	_, err = tls.X509KeyPair(secret.Data[credkube.TLSSecretCert], secret.Data[credkube.TLSSecretKey])
+	assert.NoError(t, err)
+
+	// The challenge VirtualServices are removed.
+	vs, err := client.Istio().NetworkingV1alpha3().VirtualServices("istio-ingress").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, len(vs.Items), 0)
+}
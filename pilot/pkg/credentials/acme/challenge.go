@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	networkingclient "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/pkg/kube/controllers"
+)
+
+// DNSProvider publishes the TXT records of DNS-01 challenges, for example through the API of a DNS hosting service.
+type DNSProvider interface {
+	// Present creates a TXT record named fqdn with the value.
+	Present(ctx context.Context, fqdn, value string) error
+	// CleanUp removes the TXT record created by Present.
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// solver presents and cleans up the response to an ACME challenge.
+type solver struct {
+	challenge *acme.Challenge
+	present   func(ctx context.Context, client *acme.Client, domain, token string) error
+	// wait, if set, waits until the presented response is served, before the challenge is accepted.
+	wait    func(ctx context.Context, client *acme.Client, domain, token string)
+	cleanUp func(ctx context.Context, client *acme.Client, domain, token string) error
+}
+
+// solver picks the challenge of the authorization to solve: DNS-01 if a DNS provider is configured, as it is the
+// only one valid for wildcard domains, and HTTP-01 otherwise.
+func (c *Controller) solver(gw *networkingclient.Gateway, authz *acme.Authorization) *solver {
+	for _, chal := range authz.Challenges {
+		switch {
+		case chal.Type == "dns-01" && c.opts.DNSProvider != nil:
+			return &solver{
+				challenge: chal,
+				present: func(ctx context.Context, client *acme.Client, domain, token string) error {
+					value, err := client.DNS01ChallengeRecord(token)
+					if err != nil {
+						return err
+					}
+					return c.opts.DNSProvider.Present(ctx, dns01Record(domain), value)
+				},
+				cleanUp: func(ctx context.Context, client *acme.Client, domain, token string) error {
+					value, err := client.DNS01ChallengeRecord(token)
+					if err != nil {
+						return err
+					}
+					return c.opts.DNSProvider.CleanUp(ctx, dns01Record(domain), value)
+				},
+			}
+		case chal.Type == "http-01" && c.opts.DNSProvider == nil && !authz.Wildcard:
+			return &solver{
+				challenge: chal,
+				present: func(_ context.Context, client *acme.Client, domain, token string) error {
+					response, err := client.HTTP01ChallengeResponse(token)
+					if err != nil {
+						return err
+					}
+					_, err = c.virtualServices.Create(http01VirtualService(gw, domain, client.HTTP01ChallengePath(token), response))
+					return err
+				},
+				wait: func(ctx context.Context, client *acme.Client, domain, token string) {
+					response, err := client.HTTP01ChallengeResponse(token)
+					if err != nil {
+						return
+					}
+					c.waitForHTTP01(ctx, domain, client.HTTP01ChallengePath(token), response)
+				},
+				cleanUp: func(_ context.Context, client *acme.Client, _, token string) error {
+					name := http01VirtualServiceName(client.HTTP01ChallengePath(token))
+					return controllers.IgnoreNotFound(c.virtualServices.Delete(name, gw.Namespace))
+				},
+			}
+		}
+	}
+	return nil
+}
+
+// waitForHTTP01 polls the gateway until it answers the HTTP-01 challenge at path of domain with the response, so
+// that the ACME server does not validate the challenge before the VirtualService is distributed to the gateway.
+// As istiod may not be able to reach the gateway at the address of domain, the challenge is accepted anyway once
+// http01CheckTimeout has passed.
+func (c *Controller) waitForHTTP01(ctx context.Context, domain, path, response string) {
+	ctx, cancel := context.WithTimeout(ctx, c.http01CheckTimeout)
+	defer cancel()
+	url := "http://" + domain + path
+	for {
+		err := c.checkHTTP01(ctx, url, response)
+		if err == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			log.Warnf("the HTTP-01 challenge response for %s is not served yet, accepting the challenge anyway: %v", domain, err)
+			return
+		case <-time.After(c.http01CheckInterval):
+		}
+	}
+}
+
+// checkHTTP01 returns an error unless url serves the response.
+func (c *Controller) checkHTTP01(ctx context.Context, url, response string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http01Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(len(response))+1024))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != response {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+func dns01Record(domain string) string {
+	return "_acme-challenge." + domain + "."
+}
+
+// http01VirtualServiceName returns the name of the VirtualService answering the HTTP-01 challenge at path.
+func http01VirtualServiceName(path string) string {
+	return fmt.Sprintf("acme-challenge-%x", sha256.Sum256([]byte(path)))[:len("acme-challenge-")+16]
+}
+
+// http01VirtualService returns a VirtualService making the gateway itself answer the HTTP-01 challenge for domain.
+func http01VirtualService(gw *networkingclient.Gateway, domain, path, response string) *networkingclient.VirtualService {
+	return &networkingclient.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      http01VirtualServiceName(path),
+			Namespace: gw.Namespace,
+			Labels:    map[string]string{managedLabel: "true"},
+		},
+		Spec: networking.VirtualService{
+			Hosts:    []string{domain},
+			Gateways: []string{gw.Name},
+			Http: []*networking.HTTPRoute{{
+				Name: "acme-challenge",
+				Match: []*networking.HTTPMatchRequest{{
+					Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Exact{Exact: path}},
+				}},
+				DirectResponse: &networking.HTTPDirectResponse{
+					Status: 200,
+					Body: &networking.HTTPBody{
+						Specifier: &networking.HTTPBody_String_{String_: response},
+					},
+				},
+			}},
+		},
+	}
+}
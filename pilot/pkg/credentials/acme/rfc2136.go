@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// rfc2136TTL is the TTL of the TXT records of DNS-01 challenges. It is short, as the records are removed once the
+// challenge is validated.
+const rfc2136TTL = 60
+
+// RFC2136 is a DNSProvider publishing the TXT records with dynamic DNS updates (RFC 2136), which most authoritative
+// DNS servers support, such as BIND, Knot DNS and PowerDNS.
+type RFC2136 struct {
+	// Nameserver is the address, host:port, of the authoritative DNS server accepting the updates.
+	Nameserver string
+	// Zone is the zone of the records. If empty, it is the zone of the SOA record that Nameserver returns for them.
+	Zone string
+	// TSIGKey is the name of the TSIG key authenticating the updates, if any.
+	TSIGKey string
+	// TSIGSecret is the base64 encoded secret of the TSIG key.
+	TSIGSecret string
+	// TSIGAlgorithm is the algorithm of the TSIG key. If empty, hmac-sha256 is used.
+	TSIGAlgorithm string
+}
+
+var _ DNSProvider = &RFC2136{}
+
+// Present implements DNSProvider.
+func (p *RFC2136) Present(ctx context.Context, fqdn, value string) error {
+	return p.update(ctx, fqdn, value, true)
+}
+
+// CleanUp implements DNSProvider.
+func (p *RFC2136) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.update(ctx, fqdn, value, false)
+}
+
+func (p *RFC2136) update(ctx context.Context, fqdn, value string, insert bool) error {
+	zone := p.Zone
+	if zone == "" {
+		var err error
+		if zone, err = p.findZone(ctx, fqdn); err != nil {
+			return err
+		}
+	}
+	rr := &dns.TXT{
+		Hdr: dns.RR_Header{Name: dns.Fqdn(fqdn), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: rfc2136TTL},
+		Txt: []string{value},
+	}
+	m := new(dns.Msg)
+	m.SetUpdate(dns.Fqdn(zone))
+	if insert {
+		m.Insert([]dns.RR{rr})
+	} else {
+		m.Remove([]dns.RR{rr})
+	}
+	reply, err := p.exchange(ctx, m)
+	if err != nil {
+		return fmt.Errorf("failed to update %s in zone %s: %v", fqdn, zone, err)
+	}
+	if reply.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("failed to update %s in zone %s: %s", fqdn, zone, dns.RcodeToString[reply.Rcode])
+	}
+	return nil
+}
+
+// findZone returns the zone of fqdn, from the SOA record of the answer or authority section of a SOA query.
+func (p *RFC2136) findZone(ctx context.Context, fqdn string) (string, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(fqdn), dns.TypeSOA)
+	reply, err := p.exchange(ctx, m)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the zone of %s: %v", fqdn, err)
+	}
+	for _, rr := range append(reply.Answer, reply.Ns...) {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa.Hdr.Name, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find the zone of %s: %s returned no SOA record", fqdn, p.Nameserver)
+}
+
+func (p *RFC2136) exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	c := &dns.Client{Net: "tcp"}
+	if p.TSIGKey != "" {
+		algorithm := p.TSIGAlgorithm
+		if algorithm == "" {
+			algorithm = dns.HmacSHA256
+		}
+		key := dns.Fqdn(p.TSIGKey)
+		c.TsigSecret = map[string]string{key: p.TSIGSecret}
+		m.SetTsig(key, dns.Fqdn(algorithm), 300, time.Now().Unix())
+	}
+	reply, _, err := c.ExchangeContext(ctx, m, p.Nameserver)
+	return reply, err
+}
@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const (
+	testTSIGKey    = "acme."
+	testTSIGSecret = "c2VjcmV0c2VjcmV0c2VjcmV0c2VjcmV0" // base64 of "secretsecretsecretsecret"
+)
+
+// fakeNameserver is an authoritative DNS server of the example.com zone accepting dynamic updates signed with
+// the test TSIG key.
+type fakeNameserver struct {
+	mu      sync.Mutex
+	records map[string]string
+}
+
+func (f *fakeNameserver) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	switch {
+	case r.Opcode == dns.OpcodeUpdate:
+		if r.IsTsig() == nil || w.TsigStatus() != nil || r.Question[0].Name != "example.com." {
+			m.Rcode = dns.RcodeRefused
+			break
+		}
+		f.mu.Lock()
+		for _, rr := range r.Ns {
+			txt := rr.(*dns.TXT)
+			if rr.Header().Class == dns.ClassNONE {
+				delete(f.records, txt.Hdr.Name)
+			} else {
+				f.records[txt.Hdr.Name] = txt.Txt[0]
+			}
+		}
+		f.mu.Unlock()
+	case r.Question[0].Qtype == dns.TypeSOA:
+		m.Ns = []dns.RR{&dns.SOA{
+			Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 60},
+			Ns:  "ns.example.com.", Mbox: "admin.example.com.", Serial: 1, Refresh: 60, Retry: 60, Expire: 60, Minttl: 60,
+		}}
+	}
+	if r.IsTsig() != nil {
+		m.SetTsig(testTSIGKey, dns.HmacSHA256, 300, int64(r.IsTsig().TimeSigned))
+	}
+	_ = w.WriteMsg(m)
+}
+
+func (f *fakeNameserver) record(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.records[name]
+}
+
+func startFakeNameserver(t *testing.T) (*fakeNameserver, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := &fakeNameserver{records: map[string]string{}}
+	server := &dns.Server{
+		Listener:   l,
+		Handler:    ns,
+		TsigSecret: map[string]string{testTSIGKey: testTSIGSecret},
+		// The default accept function rejects updates.
+		MsgAcceptFunc: func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept },
+	}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() { close(started) }
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = server.Shutdown() })
+	return ns, l.Addr().String()
+}
+
+func TestRFC2136(t *testing.T) {
+	ns, addr := startFakeNameserver(t)
+	ctx := context.Background()
+	record := dns01Record("www.example.com")
+
+	p := &RFC2136{Nameserver: addr, TSIGKey: "acme", TSIGSecret: testTSIGSecret}
+	assert.NoError(t, p.Present(ctx, record, "token"))
+	assert.Equal(t, ns.record(record), "token")
+	assert.NoError(t, p.CleanUp(ctx, record, "token"))
+	assert.Equal(t, ns.record(record), "")
+
+	// The zone may be configured instead of looked up.
+	p.Zone = "example.com"
+	assert.NoError(t, p.Present(ctx, record, "token"))
+	assert.Equal(t, ns.record(record), "token")
+
+	// Updates without the TSIG key are refused.
+	unsigned := &RFC2136{Nameserver: addr, Zone: "example.com"}
+	assert.Error(t, unsigned.CleanUp(ctx, record, "token"))
+	assert.Equal(t, ns.record(record), "token")
+}
@@ -0,0 +1,300 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocsp fetches and refreshes the OCSP responses of gateway certificates from the OCSP responders named in
+// their Authority Information Access extension, so that they can be stapled by the gateways.
+package ocsp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/monitoring"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+var log = istiolog.RegisterScope("ocsp", "OCSP stapling of gateway certificates")
+
+const (
+	// checkInterval is how often responses are checked for refresh.
+	checkInterval = time.Minute
+	// retryInterval is how long to wait before fetching a response again after a failure.
+	retryInterval = 5 * time.Minute
+	// unusedTimeout is how long a response is refreshed after it was last requested. The secrets of the response are
+	// then pushed, and it is dropped unless they request it again within unusedGracePeriod.
+	unusedTimeout     = 24 * time.Hour
+	unusedGracePeriod = 10 * time.Minute
+	// fetchTimeout is the timeout of requests to OCSP responders.
+	fetchTimeout = 10 * time.Second
+	// maxResponseSize is the maximum size of an OCSP response.
+	maxResponseSize = 1 << 20
+)
+
+var (
+	resultTag = monitoring.CreateLabel("result")
+
+	fetches = monitoring.NewSum(
+		"pilot_ocsp_staple_fetches_total",
+		"Total number of OCSP responses fetched for gateway certificates, by result.",
+	)
+
+	expired = monitoring.NewSum(
+		"pilot_ocsp_staple_expired_total",
+		"Total number of times an expired OCSP response could not be refreshed and was no longer stapled.",
+	)
+)
+
+// secretKey identifies a Secret holding a certificate.
+type secretKey struct {
+	name, namespace string
+}
+
+// entry is the OCSP response of a certificate.
+type entry struct {
+	leaf, issuer *x509.Certificate
+	secrets      map[secretKey]struct{}
+
+	response    []byte
+	nextUpdate  time.Time
+	refreshAt   time.Time
+	lastUsed    time.Time
+	fetching    bool
+	expiredSeen bool
+	// unusedPushed is when the secrets were pushed after the response was not requested for unusedTimeout.
+	unusedPushed time.Time
+}
+
+// Stapler fetches the OCSP responses of certificates in the background and refreshes them half way through their
+// validity. Responses past their next update time are no longer stapled.
+type Stapler struct {
+	client   *http.Client
+	onUpdate func(name, namespace string)
+	now      func() time.Time
+
+	mutex   sync.Mutex
+	entries map[[sha256.Size]byte]*entry
+	// bySecret is the entry of the certificate last stapled for each Secret.
+	bySecret map[secretKey][sha256.Size]byte
+}
+
+// NewStapler returns a Stapler calling onUpdate with the Secrets whose stapled OCSP response changed.
+func NewStapler(onUpdate func(name, namespace string)) *Stapler {
+	return &Stapler{
+		client:   &http.Client{Timeout: fetchTimeout},
+		onUpdate: onUpdate,
+		now:      time.Now,
+		entries:  map[[sha256.Size]byte]*entry{},
+		bySecret: map[secretKey][sha256.Size]byte{},
+	}
+}
+
+// Staple returns the OCSP response to staple to the PEM encoded certificate chain of a Secret, or nil if there is no
+// valid response yet. The response is fetched in the background if needed, and onUpdate is called once it is
+// available.
+func (s *Stapler) Staple(name, namespace string, certChain []byte) []byte {
+	certs, _, err := util.ParsePemEncodedCertificateChain(certChain)
+	if err != nil || len(certs) < 2 || len(certs[0].OCSPServer) == 0 {
+		// The issuer is needed to build the request, and the leaf must name a responder.
+		return nil
+	}
+	key := sha256.Sum256(certs[0].Raw)
+	now := s.now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry{leaf: certs[0], issuer: certs[1], secrets: map[secretKey]struct{}{}}
+		s.entries[key] = e
+	}
+	secret := secretKey{name, namespace}
+	if previous, ok := s.bySecret[secret]; ok && previous != key {
+		// The certificate of the Secret changed.
+		if pe, ok := s.entries[previous]; ok {
+			delete(pe.secrets, secret)
+		}
+	}
+	s.bySecret[secret] = key
+	e.secrets[secret] = struct{}{}
+	e.lastUsed = now
+	e.unusedPushed = time.Time{}
+	if !e.fetching && !now.Before(e.refreshAt) {
+		e.fetching = true
+		go s.refresh(key, e)
+	}
+	if e.response == nil || !now.Before(e.nextUpdate) {
+		return nil
+	}
+	return e.response
+}
+
+// Used records that the response stapled to the certificate of a Secret is still served, without regenerating it,
+// for example from a cache.
+func (s *Stapler) Used(name, namespace string) {
+	now := s.now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if key, ok := s.bySecret[secretKey{name, namespace}]; ok {
+		if e, ok := s.entries[key]; ok {
+			e.lastUsed = now
+			e.unusedPushed = time.Time{}
+		}
+	}
+}
+
+// Run refreshes the OCSP responses until stop is closed.
+func (s *Stapler) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// check refreshes the responses due for refresh, and drops the responses which are no longer requested.
+func (s *Stapler) check() {
+	now := s.now()
+	var updated []secretKey
+	s.mutex.Lock()
+	for key, e := range s.entries {
+		if now.Sub(e.lastUsed) > unusedTimeout {
+			if len(e.secrets) == 0 || (!e.unusedPushed.IsZero() && now.Sub(e.unusedPushed) > unusedGracePeriod) {
+				s.drop(key, e)
+				continue
+			}
+			if e.unusedPushed.IsZero() {
+				// Gateways may serve the response for longer without requesting it again, from a cache or because
+				// their secrets were not regenerated. Push the secrets once, so that they request the response if
+				// they still use it, before dropping it.
+				e.unusedPushed = now
+				for secret := range e.secrets {
+					updated = append(updated, secret)
+				}
+			}
+		}
+		if e.response != nil && !now.Before(e.nextUpdate) && !e.expiredSeen {
+			// The response could not be refreshed in time; push the secrets to stop stapling it.
+			e.expiredSeen = true
+			expired.Increment()
+			log.Warnf("OCSP response of certificate %s expired", e.leaf.SerialNumber.Text(16))
+			for secret := range e.secrets {
+				updated = append(updated, secret)
+			}
+		}
+		if !e.fetching && !now.Before(e.refreshAt) {
+			e.fetching = true
+			go s.refresh(key, e)
+		}
+	}
+	s.mutex.Unlock()
+	for _, secret := range updated {
+		s.onUpdate(secret.name, secret.namespace)
+	}
+}
+
+// drop removes an entry.
+func (s *Stapler) drop(key [sha256.Size]byte, e *entry) {
+	delete(s.entries, key)
+	for secret := range e.secrets {
+		if s.bySecret[secret] == key {
+			delete(s.bySecret, secret)
+		}
+	}
+}
+
+// refresh fetches the OCSP response of an entry, and notifies the secrets of the entry if it changed.
+func (s *Stapler) refresh(key [sha256.Size]byte, e *entry) {
+	resp, raw, err := s.fetch(e.leaf, e.issuer)
+	now := s.now()
+
+	s.mutex.Lock()
+	e.fetching = false
+	if err != nil {
+		fetches.With(resultTag.Value("error")).Increment()
+		log.Warnf("failed to fetch OCSP response of certificate %s from %s: %v", e.leaf.SerialNumber.Text(16), e.leaf.OCSPServer[0], err)
+		e.refreshAt = now.Add(retryInterval)
+		s.mutex.Unlock()
+		return
+	}
+	fetches.With(resultTag.Value("success")).Increment()
+	if resp.Status == ocsp.Revoked {
+		log.Warnf("certificate %s is revoked", e.leaf.SerialNumber.Text(16))
+	}
+	changed := !bytes.Equal(e.response, raw)
+	e.response = raw
+	e.expiredSeen = false
+	e.nextUpdate = resp.NextUpdate
+	if resp.NextUpdate.IsZero() {
+		// The responder always has newer information; refresh regularly but keep stapling the response.
+		e.nextUpdate = now.Add(unusedTimeout)
+		e.refreshAt = now.Add(time.Hour)
+	} else {
+		e.refreshAt = resp.ThisUpdate.Add(resp.NextUpdate.Sub(resp.ThisUpdate) / 2)
+		if e.refreshAt.Before(now.Add(checkInterval)) {
+			e.refreshAt = now.Add(checkInterval)
+		}
+	}
+	var secrets []secretKey
+	if _, ok := s.entries[key]; ok && changed {
+		for secret := range e.secrets {
+			secrets = append(secrets, secret)
+		}
+	}
+	s.mutex.Unlock()
+	for _, secret := range secrets {
+		s.onUpdate(secret.name, secret.namespace)
+	}
+}
+
+// fetch gets the OCSP response of a certificate from its responder.
+func (s *Stapler) fetch(leaf, issuer *x509.Certificate) (*ocsp.Response, []byte, error) {
+	req, err := ocsp.CreateRequest(leaf, issuer, &ocsp.RequestOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	httpResp, err := s.client.Post(leaf.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("responder returned status %d", httpResp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid OCSP response: %v", err)
+	}
+	if resp.Status == ocsp.Unknown {
+		return nil, nil, fmt.Errorf("responder does not know the certificate")
+	}
+	return resp, raw, nil
+}
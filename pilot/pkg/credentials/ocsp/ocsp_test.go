@@ -0,0 +1,301 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocsp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/crypto/ocsp"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+type responder struct {
+	t      *testing.T
+	issuer *x509.Certificate
+	key    crypto.Signer
+
+	mu         sync.Mutex
+	fail       bool
+	thisUpdate time.Time
+	requests   int
+}
+
+func (r *responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+	if r.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	ocspReq, err := ocsp.ParseRequest(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	resp, err := ocsp.CreateResponse(r.issuer, r.issuer, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: ocspReq.SerialNumber,
+		ThisUpdate:   r.thisUpdate,
+		NextUpdate:   r.thisUpdate.Add(time.Hour),
+	}, r.key)
+	if err != nil {
+		r.t.Error(err)
+		return
+	}
+	_, _ = w.Write(resp)
+}
+
+func (r *responder) setFail(fail bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fail = fail
+}
+
+func (r *responder) requestCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests
+}
+
+// newChain returns a PEM encoded leaf and issuer naming the OCSP server at url, and sets the issuer of r.
+func newChain(t *testing.T, url string, r *responder) []byte {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	assert.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	assert.NoError(t, err)
+	r.issuer, r.key = ca, caKey
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	if url != "" {
+		leafTemplate.OCSPServer = []string{url}
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, leafKey.Public(), caKey)
+	assert.NoError(t, err)
+	return append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+}
+
+func TestStapler(t *testing.T) {
+	now := time.Now()
+	clock := atomic.NewTime(now)
+	r := &responder{t: t, thisUpdate: now}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	chain := newChain(t, srv.URL, r)
+
+	updates := atomic.NewInt32(0)
+	s := NewStapler(func(name, namespace string) {
+		assert.Equal(t, name, "cert")
+		assert.Equal(t, namespace, "istio-system")
+		updates.Inc()
+	})
+	s.now = clock.Load
+
+	// The response is fetched in the background, and the secret is pushed once it is available.
+	assert.Equal(t, s.Staple("cert", "istio-system", chain) == nil, true)
+	retry.UntilOrFail(t, func() bool { return updates.Load() == 1 }, retry.Timeout(5*time.Second))
+	staple := s.Staple("cert", "istio-system", chain)
+	resp, err := ocsp.ParseResponse(staple, r.issuer)
+	assert.NoError(t, err)
+	assert.Equal(t, resp.Status, ocsp.Good)
+	assert.Equal(t, r.requestCount(), 1)
+
+	// Responses are not refreshed before half of their validity.
+	clock.Store(now.Add(20 * time.Minute))
+	s.check()
+	assert.Equal(t, r.requestCount(), 1)
+
+	// Failed refreshes keep stapling the previous response, and are retried later.
+	r.setFail(true)
+	clock.Store(now.Add(40 * time.Minute))
+	s.check()
+	retry.UntilOrFail(t, func() bool { return r.requestCount() == 2 }, retry.Timeout(5*time.Second))
+	retry.UntilOrFail(t, func() bool {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, e := range s.entries {
+			return !e.fetching
+		}
+		return false
+	}, retry.Timeout(5*time.Second))
+	assert.Equal(t, s.Staple("cert", "istio-system", chain), staple)
+	clock.Store(now.Add(42 * time.Minute))
+	s.check()
+	assert.Equal(t, r.requestCount(), 2)
+
+	// Expired responses are no longer stapled, and the secret is pushed to remove them.
+	clock.Store(now.Add(61 * time.Minute))
+	r.mu.Lock()
+	r.requests = 0
+	r.mu.Unlock()
+	s.check()
+	assert.Equal(t, updates.Load(), int32(2))
+	assert.Equal(t, s.Staple("cert", "istio-system", chain) == nil, true)
+
+	// A new response is stapled again once the responder recovers.
+	retry.UntilOrFail(t, func() bool { return r.requestCount() == 1 }, retry.Timeout(5*time.Second))
+	r.mu.Lock()
+	r.fail = false
+	r.thisUpdate = now.Add(time.Hour)
+	r.mu.Unlock()
+	clock.Store(now.Add(70 * time.Minute))
+	retry.UntilOrFail(t, func() bool {
+		s.check()
+		return updates.Load() == 3
+	}, retry.Timeout(5*time.Second))
+	assert.Equal(t, s.Staple("cert", "istio-system", chain) != nil, true)
+
+	// Responses which are no longer requested are dropped, once the secret was pushed without requesting them.
+	pushed := updates.Load()
+	clock.Store(now.Add(unusedTimeout + 2*time.Hour))
+	s.check()
+	assert.Equal(t, updates.Load() > pushed, true)
+	clock.Store(now.Add(unusedTimeout + 2*time.Hour + unusedGracePeriod + time.Minute))
+	s.check()
+	s.mutex.Lock()
+	assert.Equal(t, len(s.entries), 0)
+	s.mutex.Unlock()
+}
+
+func TestStaplerKeepsUsedResponses(t *testing.T) {
+	now := time.Now()
+	clock := atomic.NewTime(now)
+	r := &responder{t: t, thisUpdate: now}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	chain := newChain(t, srv.URL, r)
+
+	// watched simulates a gateway watching the secret, which regenerates it when it is pushed.
+	watched := atomic.NewBool(false)
+	updates := atomic.NewInt32(0)
+	var s *Stapler
+	s = NewStapler(func(name, namespace string) {
+		updates.Inc()
+		if watched.Load() {
+			s.Staple(name, namespace, chain)
+		}
+	})
+	s.now = clock.Load
+	entries := func() int {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		return len(s.entries)
+	}
+	// advance moves the clock and waits for the response to be refreshed.
+	advance := func(d time.Duration) {
+		t.Helper()
+		r.mu.Lock()
+		r.thisUpdate = now.Add(d)
+		r.mu.Unlock()
+		clock.Store(now.Add(d))
+		requests := r.requestCount()
+		s.check()
+		retry.UntilOrFail(t, func() bool { return r.requestCount() > requests }, retry.Timeout(5*time.Second))
+		retry.UntilOrFail(t, func() bool {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			for _, e := range s.entries {
+				if e.fetching {
+					return false
+				}
+			}
+			return true
+		}, retry.Timeout(5*time.Second))
+	}
+
+	assert.Equal(t, s.Staple("cert", "istio-system", chain) == nil, true)
+	retry.UntilOrFail(t, func() bool { return updates.Load() == 1 }, retry.Timeout(5*time.Second))
+
+	// A secret served from the cache for longer than unusedTimeout keeps its response refreshed, and is pushed
+	// when it changes.
+	advance(12 * time.Hour)
+	s.Used("cert", "istio-system")
+	pushed := updates.Load()
+	advance(30 * time.Hour)
+	assert.Equal(t, updates.Load() > pushed, true)
+	clock.Store(now.Add(30*time.Hour + unusedGracePeriod + time.Minute))
+	s.check()
+	assert.Equal(t, entries(), 1)
+	resp, err := ocsp.ParseResponse(s.Staple("cert", "istio-system", chain), r.issuer)
+	assert.NoError(t, err)
+	assert.Equal(t, resp.ThisUpdate.Equal(now.Add(30*time.Hour).Truncate(time.Second)), true)
+
+	// Responses which are not requested for unusedTimeout are kept if the secret requests them again once pushed.
+	watched.Store(true)
+	advance(55 * time.Hour)
+	clock.Store(now.Add(55*time.Hour + unusedGracePeriod + time.Minute))
+	s.check()
+	assert.Equal(t, entries(), 1)
+
+	// Otherwise they are dropped.
+	watched.Store(false)
+	advance(80 * time.Hour)
+	clock.Store(now.Add(80*time.Hour + unusedGracePeriod + time.Minute))
+	s.check()
+	assert.Equal(t, entries(), 0)
+	s.mutex.Lock()
+	assert.Equal(t, len(s.bySecret), 0)
+	s.mutex.Unlock()
+}
+
+func TestStaplerIgnoresCertificatesWithoutResponder(t *testing.T) {
+	r := &responder{t: t}
+	chain := newChain(t, "", r)
+	s := NewStapler(func(string, string) {
+		t.Fatal("unexpected update")
+	})
+	assert.Equal(t, s.Staple("cert", "istio-system", chain) == nil, true)
+	// A certificate without its issuer cannot be checked.
+	block, _ := pem.Decode(chain)
+	assert.Equal(t, s.Staple("cert", "istio-system", pem.EncodeToMemory(block)) == nil, true)
+	assert.Equal(t, len(s.entries), 0)
+}
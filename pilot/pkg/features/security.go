@@ -16,6 +16,7 @@ package features
 
 import (
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/types"
 
@@ -90,6 +91,101 @@ var (
 
 	CertSignerDomain = env.Register("CERT_SIGNER_DOMAIN", "", "The cert signer domain info").Get()
 
+	CAAuditLogPath = env.Register("CA_AUDIT_LOG_PATH", "",
+		"If set, the CA appends a JSON audit record of every certificate signing request to this file.").Get()
+
+	CAAuditLogMaxSize = env.Register("CA_AUDIT_LOG_MAX_SIZE", 100,
+		"The size, in megabytes, at which the CA audit log file is rotated.").Get()
+
+	CAAuditLogMaxBackups = env.Register("CA_AUDIT_LOG_MAX_BACKUPS", 10,
+		"The maximum number of rotated CA audit log files to retain. If 0, all rotated files are retained.").Get()
+
+	CAAuditWebhookURL = env.Register("CA_AUDIT_WEBHOOK_URL", "",
+		"If set, the CA posts a JSON audit record of every certificate signing request to this URL.").Get()
+
+	CAAuditWebhookTimeout = env.Register("CA_AUDIT_WEBHOOK_TIMEOUT", 5*time.Second,
+		"The timeout of requests posting audit records to CA_AUDIT_WEBHOOK_URL.").Get()
+
+	CACSRRateLimitPerIdentity = env.Register("CA_CSR_RATE_LIMIT_PER_IDENTITY", 0.0,
+		"The maximum rate, in CSRs per second, at which the CA signs certificates for a single identity. "+
+			"Requests over the limit are rejected with RESOURCE_EXHAUSTED. If 0, the rate is not limited.").Get()
+
+	CACSRBurstPerIdentity = env.Register("CA_CSR_BURST_PER_IDENTITY", 10,
+		"The number of CSRs of a single identity the CA signs in a burst over CA_CSR_RATE_LIMIT_PER_IDENTITY.").Get()
+
+	CACSRRateLimitPerNamespace = env.Register("CA_CSR_RATE_LIMIT_PER_NAMESPACE", 0.0,
+		"The maximum rate, in CSRs per second, at which the CA signs certificates for the identities of a single "+
+			"namespace. Requests over the limit are rejected with RESOURCE_EXHAUSTED. If 0, the rate is not limited.").Get()
+
+	CACSRBurstPerNamespace = env.Register("CA_CSR_BURST_PER_NAMESPACE", 100,
+		"The number of CSRs of a single namespace the CA signs in a burst over CA_CSR_RATE_LIMIT_PER_NAMESPACE.").Get()
+
+	EnableCARevocation = env.Register("PILOT_ENABLE_CA_REVOCATION", false,
+		"If enabled, the Istiod CA publishes a certificate revocation list of the certificates listed in the "+
+			"istio-ca-revocations ConfigMap, and proxies validate mesh mTLS peers against it. The trust anchors and "+
+			"revocation list are served by Istiod over SDS instead of by the agent. The revocation list is not served "+
+			"while the mesh trusts roots other than the Istiod CA roots. This must not be enabled if workloads are "+
+			"issued certificates by other CAs under the same roots, such as the istiod of another cluster with its own "+
+			"intermediate CA, as proxies would reject them.").Get()
+
+	CARevocationListValidity = env.Register("PILOT_CA_REVOCATION_LIST_VALIDITY", 24*time.Hour,
+		"The validity of the certificate revocation list published by the Istiod CA. The list is re-issued "+
+			"when half of its validity has passed.").Get()
+
+	EnablePluggedCARotation = env.Register("PILOT_ENABLE_PLUGGED_CA_ROTATION", false,
+		"If enabled, a change of the root certificates of the plugged-in CA in the cacerts directory starts a staged "+
+			"rotation: the old and new roots are trusted first, the new CA signs once the roots are distributed, and the "+
+			"old roots are dropped once the certificates signed by the old CA have been renewed.").Get()
+
+	PluggedCARotationPeriod = env.Register("PILOT_PLUGGED_CA_ROTATION_PERIOD", time.Duration(0),
+		"The minimum duration of each stage of the plugged-in CA rotation. It should not be shorter than the TTL of "+
+			"workload certificates, which pick up the new roots when they are renewed. If unset, "+
+			"DEFAULT_WORKLOAD_CERT_TTL is used.").Get()
+
+	EnableSpiffeBundleEndpoint = env.Register("PILOT_ENABLE_SPIFFE_BUNDLE_ENDPOINT", false,
+		"If enabled, istiod serves the trust anchors of its trust domain as a SPIFFE bundle at /spiffe/bundle on the "+
+			"https port, so that other meshes or SPIRE deployments can federate with this mesh. Requires ISTIO_MULTIROOT_MESH.").Get()
+
+	SpiffeBundleRefreshHint = env.Register("PILOT_SPIFFE_BUNDLE_REFRESH_HINT", 5*time.Minute,
+		"The refresh hint advertised in the SPIFFE bundle served by istiod, telling federated consumers how often "+
+			"to poll for new trust anchors.").Get()
+
+	ACMEDirectoryURL = env.Register("PILOT_ACME_DIRECTORY_URL", "",
+		"If set, istiod obtains and renews the certificates of the servers of Gateways annotated with "+
+			"networking.istio.io/acme from the ACME server with this directory URL, and stores them in the "+
+			"Secrets named by the credentialName of the servers.").Get()
+
+	ACMEEmail = env.Register("PILOT_ACME_EMAIL", "",
+		"The contact email address of the ACME account of istiod.").Get()
+
+	ACMECACertFile = env.Register("PILOT_ACME_CA_CERT_FILE", "",
+		"Path of the PEM encoded CA certificate used to verify the ACME server. If unset, the system roots are used.").Get()
+
+	ACMERenewBefore = env.Register("PILOT_ACME_RENEW_BEFORE", 30*24*time.Hour,
+		"How long before expiry the certificates obtained from the ACME server are renewed.").Get()
+
+	ACMEDNSNameserver = env.Register("PILOT_ACME_DNS_NAMESERVER", "",
+		"If set, istiod solves the DNS-01 challenges of the ACME server, which also allows wildcard hosts, by "+
+			"publishing TXT records with dynamic DNS updates (RFC 2136) to the authoritative DNS server at this "+
+			"host:port. If unset, HTTP-01 challenges are answered by the gateways.").Get()
+
+	ACMEDNSZone = env.Register("PILOT_ACME_DNS_ZONE", "",
+		"The zone updated with the DNS-01 challenge records. If unset, it is looked up from PILOT_ACME_DNS_NAMESERVER.").Get()
+
+	ACMEDNSTSIGKey = env.Register("PILOT_ACME_DNS_TSIG_KEY", "",
+		"The name of the TSIG key authenticating the dynamic DNS updates, if any.").Get()
+
+	ACMEDNSTSIGAlgorithm = env.Register("PILOT_ACME_DNS_TSIG_ALGORITHM", "hmac-sha256",
+		"The algorithm of the TSIG key authenticating the dynamic DNS updates.").Get()
+
+	ACMEDNSTSIGSecretFile = env.Register("PILOT_ACME_DNS_TSIG_SECRET_FILE", "",
+		"Path of the file holding the base64 encoded secret of the TSIG key.").Get()
+
+	EnableGatewayOCSPStapling = env.Register("PILOT_ENABLE_GATEWAY_OCSP_STAPLING", false,
+		"If enabled, istiod fetches the OCSP responses of gateway certificates from the OCSP responder named in the "+
+			"certificates, and staples them to the certificates served to gateways over SDS. An OCSP staple "+
+			"provided in the secret takes precedence.").Get()
+
 	UseCacertsForSelfSignedCA = env.Register("USE_CACERTS_FOR_SELF_SIGNED_CA", false,
 		"If enabled, istiod will use a secret named cacerts to store its self-signed istio-"+
 			"generated root certificate.").Get()
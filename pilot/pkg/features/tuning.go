@@ -89,6 +89,20 @@ var (
 			"for this time, we'll trigger a push.",
 	).Get()
 
+	EnableAdaptiveDebounce = env.Register(
+		"PILOT_ENABLE_ADAPTIVE_DEBOUNCE",
+		false,
+		"If enabled, the debounce quiet window is tuned from the observed push load. It starts at PILOT_DEBOUNCE_AFTER, "+
+			"grows up to PILOT_DEBOUNCE_AFTER_MAX when pushes are slow or proxies are still queued from a previous push, "+
+			"and shrinks back once the mesh is idle.",
+	).Get()
+
+	DebounceAfterMax = env.Register(
+		"PILOT_DEBOUNCE_AFTER_MAX",
+		time.Second,
+		"The upper bound of the debounce quiet window when PILOT_ENABLE_ADAPTIVE_DEBOUNCE is enabled.",
+	).Get()
+
 	EnableEDSDebounce = env.Register(
 		"PILOT_ENABLE_EDS_DEBOUNCE",
 		true,
@@ -138,4 +152,11 @@ var (
 
 	XDSCacheIndexClearInterval = env.Register("PILOT_XDS_CACHE_INDEX_CLEAR_INTERVAL", 5*time.Second,
 		"The interval for xds cache index clearing.").Get()
+
+	XDSCacheSnapshotPath = env.Register("PILOT_XDS_CACHE_SNAPSHOT_PATH", "",
+		"If set, the XDS cache is periodically written to this file and reloaded on startup, "+
+			"discarding entries whose dependent configs changed in the meantime.").Get()
+
+	XDSCacheSnapshotInterval = env.Register("PILOT_XDS_CACHE_SNAPSHOT_INTERVAL", time.Minute,
+		"The interval at which the XDS cache is written to PILOT_XDS_CACHE_SNAPSHOT_PATH.").Get()
 )
@@ -55,6 +55,8 @@ const (
 	// * Other types use "prioritized leader election", which isn't implemented for Lease
 	GatewayDeploymentController = "istio-gateway-deployment"
 	NodeUntaintController       = "istio-node-untaint"
+	// ACMEController obtains the certificates of gateways from an ACME server.
+	ACMEController = "istio-acme-leader"
 )
 
 // Leader election key prefix for remote istiod managed clusters
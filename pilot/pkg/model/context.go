@@ -132,6 +132,10 @@ type Environment struct {
 	// TrustBundle: List of Mesh TrustAnchors
 	TrustBundle *trustbundle.TrustBundle
 
+	// CARevocationServed is set if Istiod serves the trust anchors and certificate revocation list of its CA to
+	// proxies over SDS.
+	CARevocationServed bool
+
 	clusterLocalServices ClusterLocalProvider
 
 	CredentialsController credentials.MulticlusterController
@@ -35,6 +35,9 @@ const (
 	BuiltinGatewaySecretTypeURI = BuiltinGatewaySecretType + "://"
 	// SdsCaSuffix is the suffix of the sds resource name for root CA.
 	SdsCaSuffix = "-cacert"
+	// IstioCARevocationResourceName is the name of the SDS secret served by Istiod with the trust anchors of the mesh
+	// and the certificate revocation list of the Istiod CA.
+	IstioCARevocationResourceName = "istiod://ROOTCA"
 )
 
 // SecretResource defines a reference to a secret
@@ -45,3 +45,7 @@ func (d *DisabledLedger) RootHash() string {
 func (d *DisabledLedger) GetPreviousValue(previousHash, key string) (result string, err error) {
 	return "", errors.New("distribution tracking is disabled")
 }
+
+func (d *DisabledLedger) History() []ledger.RootHash {
+	return nil
+}
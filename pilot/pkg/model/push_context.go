@@ -276,6 +276,10 @@ type PushContext struct {
 
 	Networks *meshconfig.MeshNetworks
 
+	// CARevocationServed is set if Istiod serves the trust anchors and certificate revocation list of its CA to
+	// proxies over SDS, which proxies then use to validate mesh mTLS peers.
+	CARevocationServed bool
+
 	InitDone        atomic.Bool
 	initializeMutex sync.Mutex
 	ambientIndex    AmbientIndexes
@@ -1252,6 +1256,7 @@ func (ps *PushContext) InitContext(env *Environment, oldPushContext *PushContext
 	ps.Mesh = env.Mesh()
 	ps.Networks = env.MeshNetworks()
 	ps.LedgerVersion = env.Version()
+	ps.CARevocationServed = env.CARevocationServed
 
 	// Must be initialized first as initServiceRegistry/VirtualServices/Destrules
 	// use the default export map.
@@ -103,6 +103,18 @@ type typedXdsCache[K comparable] interface {
 	Keys() []K
 	// Snapshot returns a snapshot of all keys and values. This is for testing/debug only
 	Snapshot() []*discovery.Resource
+	// Export returns all entries along with the configs they depend on, so they can be persisted.
+	Export() []exportedEntry[K]
+	// Restore inserts previously exported entries and returns the number of entries inserted. Restored
+	// entries are older than any push, so they are replaced by the first regular Add for the same key.
+	Restore(entries []exportedEntry[K]) int
+}
+
+// exportedEntry is a single cache entry as returned by Export.
+type exportedEntry[K comparable] struct {
+	key              K
+	value            *discovery.Resource
+	dependentConfigs []ConfigHash
 }
 
 // newTypedXdsCache returns an instance of a cache.
@@ -253,7 +265,8 @@ func (l *lruCache[K]) Add(k K, entry dependents, pushReq *PushRequest, value *di
 		if token <= cur.token {
 			return
 		}
-		if l.enableAssertions {
+		// Restored entries have a zero token and were generated by another instance, skip the assertion
+		if l.enableAssertions && cur.token != 0 {
 			l.assertUnchanged(k, cur.value, value)
 		}
 	}
@@ -357,6 +370,40 @@ func (l *lruCache[K]) Snapshot() []*discovery.Resource {
 	return res
 }
 
+func (l *lruCache[K]) Export() []exportedEntry[K] {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	keys := l.store.Keys()
+	res := make([]exportedEntry[K], 0, len(keys))
+	for _, k := range keys {
+		// Peek does not update the recentness of the entry
+		v, ok := l.store.Peek(k)
+		if !ok || v.value == nil {
+			continue
+		}
+		res = append(res, exportedEntry[K]{key: k, value: v.value, dependentConfigs: v.dependentConfigs})
+	}
+	return res
+}
+
+func (l *lruCache[K]) Restore(entries []exportedEntry[K]) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	restored := 0
+	for _, e := range entries {
+		if _, f := l.store.Peek(e.key); f {
+			// Never overwrite an entry generated by this instance
+			continue
+		}
+		// A zero token marks the entry as older than any push, so regular writes always win.
+		l.store.Add(e.key, cacheValue{value: e.value, dependentConfigs: e.dependentConfigs})
+		l.updateConfigIndex(e.key, e.dependentConfigs)
+		restored++
+	}
+	size(l.store.Len())
+	return restored
+}
+
 func (l *lruCache[K]) indexLength() int {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
@@ -395,3 +442,7 @@ func (d disabledCache[K]) ClearAll() {}
 func (d disabledCache[K]) Keys() []K { return nil }
 
 func (d disabledCache[K]) Snapshot() []*discovery.Resource { return nil }
+
+func (d disabledCache[K]) Export() []exportedEntry[K] { return nil }
+
+func (d disabledCache[K]) Restore([]exportedEntry[K]) int { return 0 }
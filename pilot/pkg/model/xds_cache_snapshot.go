@@ -0,0 +1,347 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/env"
+	"istio.io/istio/pkg/maps"
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/util/hash"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/pkg/version"
+)
+
+// xdsCacheSnapshotFormat is bumped whenever the on-disk layout or the fingerprints change in an incompatible way.
+const xdsCacheSnapshotFormat = 2
+
+// PersistentXdsCache is implemented by XdsCache implementations whose content can be saved to disk
+// and reloaded by a later instance of istiod.
+type PersistentXdsCache interface {
+	XdsCache
+	// Export returns all CDS, EDS and RDS entries. SDS entries hold private keys and are never exported.
+	Export() []XdsCacheSnapshotEntry
+	// Restore adds the given entries to the cache and returns the number of entries restored.
+	Restore(entries []XdsCacheSnapshotEntry) int
+}
+
+// XdsCacheSnapshotEntry is a single persisted XdsCache entry.
+type XdsCacheSnapshotEntry struct {
+	Type             string       `json:"type"`
+	Key              uint64       `json:"key"`
+	DependentConfigs []ConfigHash `json:"dependentConfigs,omitempty"`
+	// Resource is the serialized discovery.Resource.
+	Resource []byte `json:"resource"`
+}
+
+// XdsCacheSnapshot is the on-disk representation of the XdsCache.
+type XdsCacheSnapshot struct {
+	Format int `json:"format"`
+	// Version is the istiod build the snapshot was generated by. Generated config is only reused by the same build.
+	Version string `json:"version"`
+	// Environment is the fingerprint of the mesh-wide settings every entry depends on; see EnvironmentFingerprint.
+	Environment string `json:"environment"`
+	// Fingerprints records the state of every config the entries depend on at the time the snapshot was taken.
+	Fingerprints map[ConfigHash]string   `json:"fingerprints"`
+	Entries      []XdsCacheSnapshotEntry `json:"entries"`
+}
+
+var _ PersistentXdsCache = XdsCacheImpl{}
+
+func (x XdsCacheImpl) Export() []XdsCacheSnapshotEntry {
+	var out []XdsCacheSnapshotEntry
+	out = appendExported(out, CDSType, x.cds.Export())
+	out = appendExported(out, EDSType, x.eds.Export())
+	out = appendExported(out, RDSType, x.rds.Export())
+	return out
+}
+
+func appendExported(out []XdsCacheSnapshotEntry, typ string, entries []exportedEntry[uint64]) []XdsCacheSnapshotEntry {
+	for _, e := range entries {
+		b, err := proto.Marshal(e.value)
+		if err != nil {
+			log.Warnf("failed to marshal %s cache entry %d: %v", typ, e.key, err)
+			continue
+		}
+		out = append(out, XdsCacheSnapshotEntry{
+			Type:             typ,
+			Key:              e.key,
+			DependentConfigs: e.dependentConfigs,
+			Resource:         b,
+		})
+	}
+	return out
+}
+
+func (x XdsCacheImpl) Restore(entries []XdsCacheSnapshotEntry) int {
+	byType := map[string][]exportedEntry[uint64]{}
+	for _, e := range entries {
+		res := &discovery.Resource{}
+		if err := proto.Unmarshal(e.Resource, res); err != nil {
+			log.Warnf("failed to unmarshal %s cache entry %d: %v", e.Type, e.Key, err)
+			continue
+		}
+		byType[e.Type] = append(byType[e.Type], exportedEntry[uint64]{key: e.Key, value: res, dependentConfigs: e.DependentConfigs})
+	}
+	restored := 0
+	for typ, cache := range map[string]typedXdsCache[uint64]{CDSType: x.cds, EDSType: x.eds, RDSType: x.rds} {
+		if _, disabled := cache.(disabledCache[uint64]); disabled {
+			continue
+		}
+		restored += cache.Restore(byType[typ])
+	}
+	return restored
+}
+
+// NewXdsCacheSnapshot builds a snapshot of the given cache. The fingerprints must describe the
+// environment and configuration the cached entries were generated from; see EnvironmentFingerprint
+// and ConfigFingerprints.
+func NewXdsCacheSnapshot(cache PersistentXdsCache, environment string, fingerprints map[ConfigHash]string) *XdsCacheSnapshot {
+	entries := cache.Export()
+	// Only keep the fingerprints that are referenced, to keep the snapshot small.
+	used := make(map[ConfigHash]string)
+	for _, e := range entries {
+		for _, dep := range e.DependentConfigs {
+			if fp, f := fingerprints[dep]; f {
+				used[dep] = fp
+			}
+		}
+	}
+	return &XdsCacheSnapshot{
+		Format:       xdsCacheSnapshotFormat,
+		Version:      version.Info.String(),
+		Environment:  environment,
+		Fingerprints: used,
+		Entries:      entries,
+	}
+}
+
+// ValidEntries returns the entries of the snapshot that are still valid for the current environment and
+// configuration, described by the fingerprints. No entry is valid if the environment changed, otherwise
+// an entry is valid only if every config it depends on is unchanged.
+func (s *XdsCacheSnapshot) ValidEntries(environment string, fingerprints map[ConfigHash]string) []XdsCacheSnapshotEntry {
+	if s.Format != xdsCacheSnapshotFormat || s.Version != version.Info.String() || s.Environment != environment {
+		return nil
+	}
+	var out []XdsCacheSnapshotEntry
+outer:
+	for _, e := range s.Entries {
+		for _, dep := range e.DependentConfigs {
+			old, f := s.Fingerprints[dep]
+			if !f || old != fingerprints[dep] {
+				continue outer
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// WriteXdsCacheSnapshot atomically writes the snapshot to path.
+func WriteXdsCacheSnapshot(path string, snapshot *XdsCacheSnapshot) error {
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadXdsCacheSnapshot reads a snapshot previously written by WriteXdsCacheSnapshot.
+func ReadXdsCacheSnapshot(path string) (*XdsCacheSnapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &XdsCacheSnapshot{}
+	if err := json.Unmarshal(b, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse xds cache snapshot %s: %v", path, err)
+	}
+	return snapshot, nil
+}
+
+// Environment variables that affect generated config: the feature flags of istiod, which are named
+// with one of the prefixes, and a few settings named otherwise. Settings of the istiod instance,
+// such as POD_NAME or INSTANCE_IP, must not be included so that a snapshot written by one instance
+// can be restored by another.
+var (
+	configEnvPrefixes = []string{"PILOT_", "ENABLE_", "ISTIO_"}
+	configEnvVars     = sets.New(
+		"CLUSTER_ID",
+		"TRUST_DOMAIN",
+		"RESOLVE_HOSTNAME_GATEWAYS",
+		"PEER_METADATA_DISCOVERY",
+		"VERIFY_CERTIFICATE_AT_CLIENT",
+		"HTTP_STRIP_FRAGMENT_FROM_PATH_UNSAFE_IF_DISABLED",
+		"LABEL_CANONICAL_SERVICES_FOR_MESH_EXTERNAL_SERVICE_ENTRIES",
+		"PERSIST_OLDEST_FIRST_HEURISTIC_FOR_VIRTUAL_SERVICE_HOST_MATCHING",
+		"REWRITE_PROBE_LEGACY_LOCALHOST_DESTINATION",
+	)
+)
+
+func isConfigEnvVar(name string) bool {
+	if configEnvVars.Contains(name) {
+		return true
+	}
+	for _, p := range configEnvPrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvironmentFingerprint returns a fingerprint of the settings that are not tracked as config
+// dependencies, but affect all generated config: the mesh config, the mesh networks, and the
+// environment variables that configure istiod features.
+func EnvironmentFingerprint(e *Environment) string {
+	h := hash.New()
+	marshal := proto.MarshalOptions{Deterministic: true}
+	if mesh := e.Mesh(); mesh != nil {
+		b, _ := marshal.Marshal(mesh)
+		h.Write(b)
+	}
+	h.WriteString("/")
+	if networks := e.MeshNetworks(); networks != nil {
+		b, _ := marshal.Marshal(networks)
+		h.Write(b)
+	}
+	for _, v := range env.VarDescriptions() {
+		if !isConfigEnvVar(v.Name) {
+			continue
+		}
+		h.WriteString("/" + v.Name)
+		if val, f := os.LookupEnv(v.Name); f {
+			h.WriteString("=" + val)
+		}
+	}
+	return h.Sum()
+}
+
+// ConfigFingerprints returns a fingerprint for each config and service known to the environment,
+// keyed by the same ConfigHash used for cache dependencies. A fingerprint changes whenever the
+// config, or for services the service or its endpoints, change.
+func ConfigFingerprints(env *Environment) map[ConfigHash]string {
+	out := map[ConfigHash]string{}
+	if env.ConfigStore != nil {
+		for _, s := range env.ConfigStore.Schemas().All() {
+			k := kind.MustFromGVK(s.GroupVersionKind())
+			for _, cfg := range env.ConfigStore.List(s.GroupVersionKind(), NamespaceAll) {
+				key := ConfigKey{Kind: k, Name: cfg.Name, Namespace: cfg.Namespace}
+				out[key.HashCode()] = cfg.ResourceVersion + "/" + strconv.FormatInt(cfg.Generation, 10)
+			}
+		}
+	}
+	if env.ServiceDiscovery != nil {
+		for _, svc := range env.ServiceDiscovery.Services() {
+			key := ConfigKey{Kind: kind.ServiceEntry, Name: string(svc.Hostname), Namespace: svc.Attributes.Namespace}
+			out[key.HashCode()] = serviceFingerprint(env, svc)
+		}
+	}
+	return out
+}
+
+func serviceFingerprint(env *Environment, svc *Service) string {
+	h := hash.New()
+	if svc.ResourceVersion != "" {
+		// The resource version changes with the service spec. Addresses are also assigned outside
+		// of the spec, by other clusters and by auto allocation.
+		h.WriteString(svc.ResourceVersion)
+		h.WriteString("/" + svc.DefaultAddress + "/" + svc.AutoAllocatedIPv4Address + "/" + svc.AutoAllocatedIPv6Address)
+		vips := svc.ClusterVIPs.GetAddresses()
+		for _, c := range slices.Sort(maps.Keys(vips)) {
+			h.WriteString("/" + string(c))
+			for _, addr := range vips[c] {
+				h.WriteString("," + addr)
+			}
+		}
+	} else {
+		// Services without a resource version, such as those of some registries, are hashed in full.
+		b, _ := json.Marshal(svc)
+		h.Write(b)
+	}
+	if env.EndpointIndex == nil {
+		return h.Sum()
+	}
+	shards, f := env.EndpointIndex.ShardsForService(string(svc.Hostname), svc.Attributes.Namespace)
+	if !f {
+		return h.Sum()
+	}
+	shards.RLock()
+	// Endpoint order within a shard is not stable, so the endpoint hashes are combined with an order
+	// independent sum.
+	var sum, count uint64
+	for k, eps := range shards.Shards {
+		shard := k.String()
+		for _, ep := range eps {
+			sum += endpointHash(shard, ep)
+			count++
+		}
+	}
+	shards.RUnlock()
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], sum)
+	binary.LittleEndian.PutUint64(b[8:], count)
+	h.Write(b[:])
+	return h.Sum()
+}
+
+// endpointHash hashes the fields of the endpoint that generated config depends on.
+func endpointHash(shard string, ep *IstioEndpoint) uint64 {
+	h := hash.New()
+	h.WriteString(shard)
+	for _, f := range []string{
+		ep.Address, ep.ServicePortName, ep.ServiceAccount, string(ep.Network), ep.Locality.Label,
+		string(ep.Locality.ClusterID), ep.TLSMode, ep.Namespace, ep.WorkloadName, ep.HostName,
+		ep.SubDomain, ep.NodeName,
+	} {
+		h.WriteString("/" + f)
+	}
+	var b [16]byte
+	binary.LittleEndian.PutUint32(b[0:], uint32(ep.LegacyClusterPortKey))
+	binary.LittleEndian.PutUint32(b[4:], ep.EndpointPort)
+	binary.LittleEndian.PutUint32(b[8:], ep.LbWeight)
+	binary.LittleEndian.PutUint32(b[12:], uint32(ep.HealthStatus))
+	h.Write(b[:])
+	if ep.DiscoverabilityPolicy != nil {
+		h.WriteString(ep.DiscoverabilityPolicy.String())
+	}
+	for _, k := range slices.Sort(maps.Keys(ep.Labels)) {
+		h.WriteString("/" + k + "=" + ep.Labels[k])
+	}
+	return h.Sum64()
+}
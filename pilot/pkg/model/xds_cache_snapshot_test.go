@@ -0,0 +1,153 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/env"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+type snapshotEntry struct {
+	entry
+	typ string
+}
+
+func (e snapshotEntry) Type() string {
+	return e.typ
+}
+
+func (e snapshotEntry) Key() any {
+	return e.entry.Key()
+}
+
+func (e snapshotEntry) Cacheable() bool {
+	return true
+}
+
+func TestXdsCacheSnapshot(t *testing.T) {
+	drHash := ConfigKey{Kind: kind.DestinationRule, Name: "dr", Namespace: "default"}.HashCode()
+	seHash := ConfigKey{Kind: kind.ServiceEntry, Name: "foo.com", Namespace: "default"}.HashCode()
+	cds := snapshotEntry{typ: CDSType, entry: entry{key: "cds", dependentConfigs: []ConfigHash{drHash, seHash}}}
+	eds := snapshotEntry{typ: EDSType, entry: entry{key: "eds", dependentConfigs: []ConfigHash{seHash}}}
+
+	cache := NewXdsCache().(XdsCacheImpl)
+	req := &PushRequest{Start: time.Now()}
+	cache.Add(cds, req, &discovery.Resource{Name: "cds"})
+	cache.Add(eds, req, &discovery.Resource{Name: "eds"})
+
+	fingerprints := map[ConfigHash]string{drHash: "1", seHash: "1"}
+	path := filepath.Join(t.TempDir(), "snapshot")
+	assert.NoError(t, WriteXdsCacheSnapshot(path, NewXdsCacheSnapshot(cache, "env", fingerprints)))
+	snapshot, err := ReadXdsCacheSnapshot(path)
+	assert.NoError(t, err)
+	assert.Equal(t, len(snapshot.Entries), 2)
+
+	t.Run("unchanged", func(t *testing.T) {
+		restored := NewXdsCache().(XdsCacheImpl)
+		assert.Equal(t, restored.Restore(snapshot.ValidEntries("env", fingerprints)), 2)
+		assert.Equal(t, restored.Get(cds).GetName(), "cds")
+		assert.Equal(t, restored.Get(eds).GetName(), "eds")
+	})
+	t.Run("destination rule changed", func(t *testing.T) {
+		restored := NewXdsCache().(XdsCacheImpl)
+		assert.Equal(t, restored.Restore(snapshot.ValidEntries("env", map[ConfigHash]string{drHash: "2", seHash: "1"})), 1)
+		assert.Equal(t, restored.Get(cds), nil)
+		assert.Equal(t, restored.Get(eds).GetName(), "eds")
+	})
+	t.Run("environment changed", func(t *testing.T) {
+		restored := NewXdsCache().(XdsCacheImpl)
+		assert.Equal(t, restored.Restore(snapshot.ValidEntries("env2", fingerprints)), 0)
+	})
+	t.Run("service removed", func(t *testing.T) {
+		restored := NewXdsCache().(XdsCacheImpl)
+		assert.Equal(t, restored.Restore(snapshot.ValidEntries("env", map[ConfigHash]string{drHash: "1"})), 0)
+	})
+	t.Run("existing entries are kept", func(t *testing.T) {
+		restored := NewXdsCache().(XdsCacheImpl)
+		restored.Add(eds, &PushRequest{Start: time.Now()}, &discovery.Resource{Name: "eds-new"})
+		assert.Equal(t, restored.Restore(snapshot.ValidEntries("env", fingerprints)), 1)
+		assert.Equal(t, restored.Get(eds).GetName(), "eds-new")
+	})
+	t.Run("restored entries are invalidated and overwritten", func(t *testing.T) {
+		restored := NewXdsCache().(XdsCacheImpl)
+		restored.Restore(snapshot.ValidEntries("env", fingerprints))
+		restored.Add(eds, &PushRequest{Start: time.Now()}, &discovery.Resource{Name: "eds-new"})
+		assert.Equal(t, restored.Get(eds).GetName(), "eds-new")
+		restored.Clear(map[ConfigKey]struct{}{{Kind: kind.DestinationRule, Name: "dr", Namespace: "default"}: {}})
+		assert.Equal(t, restored.Get(cds), nil)
+	})
+}
+
+func TestEnvironmentFingerprint(t *testing.T) {
+	e := &Environment{Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{TrustDomain: "cluster.local"})}
+	fp := EnvironmentFingerprint(e)
+	assert.Equal(t, EnvironmentFingerprint(e), fp)
+
+	e.Watcher = mesh.NewFixedWatcher(&meshconfig.MeshConfig{TrustDomain: "example.com"})
+	assert.Equal(t, EnvironmentFingerprint(e) != fp, true)
+	fp = EnvironmentFingerprint(e)
+
+	// PILOT_XDS_CACHE_SNAPSHOT_INTERVAL is registered by the features package.
+	t.Setenv("PILOT_XDS_CACHE_SNAPSHOT_INTERVAL", "2m")
+	assert.Equal(t, EnvironmentFingerprint(e) != fp, true)
+	fp = EnvironmentFingerprint(e)
+
+	// Settings of the istiod instance do not affect the fingerprint. POD_NAME is registered by
+	// bootstrap, which is not imported by this package.
+	env.Register("POD_NAME", "", "")
+	t.Setenv("POD_NAME", "istiod-7d4f8b9c6-x2x9z")
+	assert.Equal(t, EnvironmentFingerprint(e), fp)
+}
+
+func TestServiceFingerprint(t *testing.T) {
+	svc := &Service{
+		Hostname:        "foo.default.svc.cluster.local",
+		Attributes:      ServiceAttributes{Namespace: "default"},
+		ResourceVersion: "1",
+	}
+	ep := func(addr string) *IstioEndpoint {
+		return &IstioEndpoint{Address: addr, EndpointPort: 80, Labels: map[string]string{"app": "foo"}}
+	}
+	shard := ShardKey{Cluster: "c1", Provider: "Kubernetes"}
+	index := NewEndpointIndex(DisabledCache{})
+	shards, _ := index.GetOrCreateEndpointShard(string(svc.Hostname), "default")
+	shards.Shards[shard] = []*IstioEndpoint{ep("1.1.1.1"), ep("2.2.2.2")}
+	env := &Environment{EndpointIndex: index}
+	fp := serviceFingerprint(env, svc)
+
+	// Endpoint order does not matter.
+	shards.Shards[shard] = []*IstioEndpoint{ep("2.2.2.2"), ep("1.1.1.1")}
+	assert.Equal(t, serviceFingerprint(env, svc), fp)
+
+	shards.Shards[shard] = []*IstioEndpoint{ep("2.2.2.2"), ep("3.3.3.3")}
+	assert.Equal(t, serviceFingerprint(env, svc) != fp, true)
+	fp = serviceFingerprint(env, svc)
+
+	svc.ResourceVersion = "2"
+	assert.Equal(t, serviceFingerprint(env, svc) != fp, true)
+	fp = serviceFingerprint(env, svc)
+
+	svc.ClusterVIPs.SetAddressesFor("c1", []string{"10.0.0.1"})
+	assert.Equal(t, serviceFingerprint(env, svc) != fp, true)
+}
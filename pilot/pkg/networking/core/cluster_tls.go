@@ -157,10 +157,14 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 		tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = append(tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs,
 			sec_model.ConstructSdsSecretConfig(sec_model.SDSDefaultResourceName))
 
+		var push *model.PushContext
+		if cb.req != nil {
+			push = cb.req.Push
+		}
 		tlsContext.CommonTlsContext.ValidationContextType = &tlsv3.CommonTlsContext_CombinedValidationContext{
 			CombinedValidationContext: &tlsv3.CommonTlsContext_CombinedCertificateValidationContext{
 				DefaultValidationContext:         &tlsv3.CertificateValidationContext{MatchSubjectAltNames: util.StringToExactMatch(tls.SubjectAltNames)},
-				ValidationContextSdsSecretConfig: sec_model.ConstructSdsSecretConfig(sec_model.SDSRootResourceName),
+				ValidationContextSdsSecretConfig: sec_model.ConstructRootSdsSecretConfig(push),
 			},
 		}
 		// Set default SNI of cluster name for istio_mutual if sni is not set.
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxy "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+
+	"istio.io/istio/pkg/wellknown"
+)
+
+// The helpers below read back generated xDS resources. They are used by the traffic simulation
+// and tests, and should not be used in XDS generation code.
+
+// ExtractListener returns the listener with the given name, or nil if there is none.
+func ExtractListener(name string, ll []*listener.Listener) *listener.Listener {
+	for _, l := range ll {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// ExtractRouteConfigurations indexes the route configurations by name.
+func ExtractRouteConfigurations(rc []*route.RouteConfiguration) map[string]*route.RouteConfiguration {
+	res := map[string]*route.RouteConfiguration{}
+	for _, l := range rc {
+		res[l.Name] = l
+	}
+	return res
+}
+
+// ExtractListenerFilters indexes the listener filters of l by name.
+func ExtractListenerFilters(l *listener.Listener) map[string]*listener.ListenerFilter {
+	res := map[string]*listener.ListenerFilter{}
+	for _, lf := range l.ListenerFilters {
+		res[lf.Name] = lf
+	}
+	return res
+}
+
+// ExtractTCPProxy returns the TCP proxy filter of the filter chain, or nil if there is none.
+func ExtractTCPProxy(fcs *listener.FilterChain) (*tcpproxy.TcpProxy, error) {
+	for _, fc := range fcs.Filters {
+		if fc.Name == wellknown.TCPProxy {
+			tcpProxy := &tcpproxy.TcpProxy{}
+			if fc.GetTypedConfig() != nil {
+				if err := fc.GetTypedConfig().UnmarshalTo(tcpProxy); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal tcp proxy: %v", err)
+				}
+			}
+			return tcpProxy, nil
+		}
+	}
+	return nil, nil
+}
+
+// ExtractHTTPConnectionManager returns the HTTP connection manager of the filter chain, or nil if there is none.
+func ExtractHTTPConnectionManager(fcs *listener.FilterChain) (*hcm.HttpConnectionManager, error) {
+	for _, fc := range fcs.Filters {
+		if fc.Name == wellknown.HTTPConnectionManager {
+			h := &hcm.HttpConnectionManager{}
+			if fc.GetTypedConfig() != nil {
+				if err := fc.GetTypedConfig().UnmarshalTo(h); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal hcm: %v", err)
+				}
+			}
+			return h, nil
+		}
+	}
+	return nil, nil
+}
+
+// EvaluateListenerFilterPredicates runs through the ListenerFilterChainMatchPredicate logic
+func EvaluateListenerFilterPredicates(predicate *listener.ListenerFilterChainMatchPredicate, port int) bool {
+	if predicate == nil {
+		return true
+	}
+	switch r := predicate.Rule.(type) {
+	case *listener.ListenerFilterChainMatchPredicate_NotMatch:
+		return !EvaluateListenerFilterPredicates(r.NotMatch, port)
+	case *listener.ListenerFilterChainMatchPredicate_OrMatch:
+		matches := false
+		for _, r := range r.OrMatch.Rules {
+			matches = matches || EvaluateListenerFilterPredicates(r, port)
+		}
+		return matches
+	case *listener.ListenerFilterChainMatchPredicate_DestinationPortRange:
+		return int32(port) >= r.DestinationPortRange.GetStart() && int32(port) < r.DestinationPortRange.GetEnd()
+	default:
+		panic("unsupported predicate")
+	}
+}
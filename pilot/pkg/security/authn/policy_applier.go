@@ -205,9 +205,9 @@ func (a policyApplier) InboundMTLSSettings(
 		Port: endpointPort,
 		Mode: effectiveMTLSMode,
 		TCP: authn_utils.BuildInboundTLS(effectiveMTLSMode, node, networking.ListenerProtocolTCP,
-			trustDomainAliases, minTLSVersion, mc),
+			trustDomainAliases, minTLSVersion, a.push),
 		HTTP: authn_utils.BuildInboundTLS(effectiveMTLSMode, node, networking.ListenerProtocolHTTP,
-			trustDomainAliases, minTLSVersion, mc),
+			trustDomainAliases, minTLSVersion, a.push),
 	}
 }
 
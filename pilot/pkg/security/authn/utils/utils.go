@@ -39,7 +39,7 @@ var SupportedCiphers = []string{
 // BuildInboundTLS returns the TLS context corresponding to the mTLS mode.
 func BuildInboundTLS(mTLSMode model.MutualTLSMode, node *model.Proxy,
 	protocol networking.ListenerProtocol, trustDomainAliases []string, minTLSVersion tls.TlsParameters_TlsProtocol,
-	mc *meshconfig.MeshConfig,
+	push *model.PushContext,
 ) *tls.DownstreamTlsContext {
 	if mTLSMode == model.MTLSDisable || mTLSMode == model.MTLSUnknown {
 		return nil
@@ -69,6 +69,10 @@ func BuildInboundTLS(mTLSMode model.MutualTLSMode, node *model.Proxy,
 		// protocol, e.g. HTTP/2.
 		ctx.CommonTlsContext.AlpnProtocols = util.ALPNHttp
 	}
+	var mc *meshconfig.MeshConfig
+	if push != nil {
+		mc = push.Mesh
+	}
 	ciphers := SupportedCiphers
 	if mc != nil && mc.MeshMTLS != nil && mc.MeshMTLS.CipherSuites != nil {
 		ciphers = mc.MeshMTLS.CipherSuites
@@ -79,7 +83,7 @@ func BuildInboundTLS(mTLSMode model.MutualTLSMode, node *model.Proxy,
 		TlsMinimumProtocolVersion: minTLSVersion,
 		TlsMaximumProtocolVersion: tls.TlsParameters_TLSv1_3,
 	}
-	authn_model.ApplyToCommonTLSContext(ctx.CommonTlsContext, node, []string{}, /*subjectAltNames*/
+	authn_model.ApplyToCommonTLSContext(ctx.CommonTlsContext, node, push, []string{}, /*subjectAltNames*/
 		"", /*crl*/
 		trustDomainAliases, ctx.RequireClientCertificate.Value)
 
@@ -93,7 +93,7 @@ func TestGetMTLSCipherSuites(t *testing.T) {
 				Metadata: &model.NodeMetadata{},
 			}
 
-			got := BuildInboundTLS(model.MTLSStrict, testNode, networking.ListenerProtocolTCP, []string{}, tls.TlsParameters_TLSv1_2, &tt.mesh)
+			got := BuildInboundTLS(model.MTLSStrict, testNode, networking.ListenerProtocolTCP, []string{}, tls.TlsParameters_TLSv1_2, &model.PushContext{Mesh: &tt.mesh})
 			if diff := cmp.Diff(tt.expectedMTLSCipherSuites, got.CommonTlsContext.TlsParams.CipherSuites, protocmp.Transform()); diff != "" {
 				t.Errorf("unexpected cipher suites: %v", diff)
 			}
@@ -117,6 +117,18 @@ func ConstructSdsSecretConfig(name string) *tls.SdsSecretConfig {
 	return pm.ConstructSdsSecretConfig(name)
 }
 
+// ConstructRootSdsSecretConfig constructs SDS Secret Configuration for validating mesh mTLS peers. If Istiod
+// serves the certificate revocation list of its CA, the trust anchors and revocation list are served by Istiod.
+func ConstructRootSdsSecretConfig(push *model.PushContext) *tls.SdsSecretConfig {
+	if push != nil && push.CARevocationServed {
+		return &tls.SdsSecretConfig{
+			Name:      credentials.IstioCARevocationResourceName,
+			SdsConfig: SDSAdsConfig,
+		}
+	}
+	return ConstructSdsSecretConfig(SDSRootResourceName)
+}
+
 func AppendURIPrefixToTrustDomain(trustDomainAliases []string) []string {
 	res := make([]string, 0, len(trustDomainAliases))
 	for _, td := range trustDomainAliases {
@@ -126,7 +138,7 @@ func AppendURIPrefixToTrustDomain(trustDomainAliases []string) []string {
 }
 
 // ApplyToCommonTLSContext completes the commonTlsContext
-func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Proxy,
+func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Proxy, push *model.PushContext,
 	subjectAltNames []string, crl string, trustDomainAliases []string, validateClient bool,
 ) {
 	// These are certs being mounted from within the pod. Rather than reading directly in Envoy,
@@ -150,6 +162,10 @@ func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Prox
 		defaultValidationContext := &tls.CertificateValidationContext{
 			MatchSubjectAltNames: matchSAN,
 		}
+		rootSdsConfig := ConstructRootSdsSecretConfig(push)
+		if res.GetRootResourceName() != "" {
+			rootSdsConfig = ConstructSdsSecretConfig(res.GetRootResourceName())
+		}
 		if crl != "" {
 			defaultValidationContext.Crl = &core.DataSource{
 				Specifier: &core.DataSource_Filename{
@@ -160,7 +176,7 @@ func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Prox
 		tlsContext.ValidationContextType = &tls.CommonTlsContext_CombinedValidationContext{
 			CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
 				DefaultValidationContext:         defaultValidationContext,
-				ValidationContextSdsSecretConfig: ConstructSdsSecretConfig(model.GetOrDefault(res.GetRootResourceName(), SDSRootResourceName)),
+				ValidationContextSdsSecretConfig: rootSdsConfig,
 			},
 		}
 
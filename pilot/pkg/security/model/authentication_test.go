@@ -78,6 +78,22 @@ func TestConstructSdsSecretConfig(t *testing.T) {
 	}
 }
 
+func TestConstructRootSdsSecretConfig(t *testing.T) {
+	got := ConstructRootSdsSecretConfig(&model.PushContext{})
+	if diff := cmp.Diff(got, ConstructSdsSecretConfig(SDSRootResourceName), protocmp.Transform()); diff != "" {
+		t.Fatal(diff)
+	}
+
+	got = ConstructRootSdsSecretConfig(&model.PushContext{CARevocationServed: true})
+	expected := &auth.SdsSecretConfig{
+		Name:      credentials.IstioCARevocationResourceName,
+		SdsConfig: SDSAdsConfig,
+	}
+	if diff := cmp.Diff(got, expected, protocmp.Transform()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestApplyToCommonTLSContext(t *testing.T) {
 	testCases := []struct {
 		name               string
@@ -490,7 +506,7 @@ func TestApplyToCommonTLSContext(t *testing.T) {
 	for _, test := range testCases {
 		t.Run(test.name, func(t *testing.T) {
 			tlsContext := &auth.CommonTlsContext{}
-			ApplyToCommonTLSContext(tlsContext, test.node, []string{}, test.crl, test.trustDomainAliases, test.validateClient)
+			ApplyToCommonTLSContext(tlsContext, test.node, nil, []string{}, test.crl, test.trustDomainAliases, test.validateClient)
 
 			if !cmp.Equal(tlsContext, test.expected, protocmp.Transform()) {
 				t.Errorf("got(%#v), want(%#v)\n", spew.Sdump(tlsContext), spew.Sdump(test.expected))
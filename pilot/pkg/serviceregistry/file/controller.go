@@ -0,0 +1,198 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"reflect"
+	"sync"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pilot/pkg/config/monitor"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	istiolog "istio.io/istio/pkg/log"
+	"istio.io/istio/pkg/maps"
+)
+
+var log = istiolog.RegisterScope("fileregistry", "File service registry")
+
+// Options for the file service registry.
+type Options struct {
+	// Root is the directory containing the service and endpoints definitions.
+	Root       string
+	ClusterID  cluster.ID
+	XDSUpdater model.XDSUpdater
+}
+
+// Controller is a service registry backed by YAML or JSON service definitions in a directory. The
+// directory is watched, and services and endpoints are updated whenever a file changes.
+type Controller struct {
+	opts Options
+
+	mutex sync.RWMutex
+	state *registryState
+
+	handlers model.ControllerHandlers
+	synced   atomic.Bool
+
+	model.NoopAmbientIndexes
+	model.NetworkGatewaysHandler
+}
+
+var _ serviceregistry.Instance = &Controller{}
+
+// NewController creates a new file service registry. Files are not read until Run is called.
+func NewController(opts Options) *Controller {
+	return &Controller{
+		opts:  opts,
+		state: convert(nil, opts.ClusterID),
+	}
+}
+
+func (c *Controller) Provider() provider.ID {
+	return provider.File
+}
+
+func (c *Controller) Cluster() cluster.ID {
+	return c.opts.ClusterID
+}
+
+// Run reads the directory and watches it for changes until stop is closed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	c.reload()
+	c.synced.Store(true)
+
+	ch := make(chan struct{}, 1)
+	if err := monitor.FileTrigger(c.opts.Root, ch, stop); err != nil {
+		log.Errorf("unable to watch %s: %v", c.opts.Root, err)
+	}
+	for {
+		select {
+		case <-ch:
+			log.Infof("reloading services from %s", c.opts.Root)
+			c.reload()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// HasSynced returns true once the directory has been read.
+func (c *Controller) HasSynced() bool {
+	return c.synced.Load()
+}
+
+// reload reads the directory and notifies about all changed services and endpoints.
+// If the directory cannot be read, the previous state is kept.
+func (c *Controller) reload() {
+	docs, err := readDir(c.opts.Root)
+	if err != nil {
+		log.Errorf("failed to read services from %s: %v", c.opts.Root, err)
+		return
+	}
+	next := convert(docs, c.opts.ClusterID)
+
+	c.mutex.Lock()
+	prev := c.state
+	c.state = next
+	c.mutex.Unlock()
+
+	shard := model.ShardKeyFromRegistry(c)
+	for hostname, svc := range next.services {
+		old, exists := prev.services[hostname]
+		endpointsChanged := !reflect.DeepEqual(prev.endpoints[hostname], next.endpoints[hostname])
+		switch {
+		case !exists:
+			c.opts.XDSUpdater.EDSCacheUpdate(shard, string(hostname), svc.Attributes.Namespace, next.endpoints[hostname])
+			c.notifyService(shard, nil, svc, model.EventAdd)
+		case !old.Equals(svc):
+			c.opts.XDSUpdater.EDSCacheUpdate(shard, string(hostname), svc.Attributes.Namespace, next.endpoints[hostname])
+			c.notifyService(shard, old, svc, model.EventUpdate)
+		case endpointsChanged:
+			c.opts.XDSUpdater.EDSUpdate(shard, string(hostname), svc.Attributes.Namespace, next.endpoints[hostname])
+		}
+	}
+	for hostname, svc := range prev.services {
+		if _, f := next.services[hostname]; !f {
+			c.notifyService(shard, nil, svc, model.EventDelete)
+		}
+	}
+}
+
+func (c *Controller) notifyService(shard model.ShardKey, prev, curr *model.Service, event model.Event) {
+	c.opts.XDSUpdater.SvcUpdate(shard, string(curr.Hostname), curr.Attributes.Namespace, event)
+	c.handlers.NotifyServiceHandlers(prev, curr, event)
+}
+
+// Services returns all services defined in the directory.
+func (c *Controller) Services() []*model.Service {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return maps.Values(c.state.services)
+}
+
+// GetService returns the service with the given hostname, or nil if there is none.
+func (c *Controller) GetService(hostname host.Name) *model.Service {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.state.services[hostname]
+}
+
+// GetProxyServiceTargets returns the service targets of endpoints matching the proxy IPs.
+func (c *Controller) GetProxyServiceTargets(proxy *model.Proxy) []model.ServiceTarget {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	out := make([]model.ServiceTarget, 0)
+	for _, ip := range proxy.IPAddresses {
+		for _, t := range c.state.targets[ip] {
+			// Match Kubernetes logic, where services do not cross namespace boundaries
+			if proxy.Metadata.Namespace == "" || t.Service.Attributes.Namespace == proxy.Metadata.Namespace {
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
+
+func (c *Controller) GetProxyWorkloadLabels(proxy *model.Proxy) labels.Instance {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, ip := range proxy.IPAddresses {
+		if l, f := c.state.labels[ip]; f {
+			return l
+		}
+	}
+	return nil
+}
+
+func (c *Controller) AppendServiceHandler(f model.ServiceHandler) {
+	c.handlers.AppendServiceHandler(f)
+}
+
+// AppendWorkloadHandler is a no-op, the file registry only defines endpoints of its own services.
+func (c *Controller) AppendWorkloadHandler(func(*model.WorkloadInstance, model.Event)) {}
+
+func (c *Controller) NetworkGateways() []model.NetworkGateway {
+	return nil
+}
+
+func (c *Controller) MCSServices() []model.MCSServiceInfo {
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/util/xdsfake"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const serviceYAML = `
+kind: Service
+hostname: db.example.com
+namespace: vm
+addresses: [240.0.0.1]
+ports:
+- name: tcp
+  port: 5432
+  protocol: TCP
+  targetPort: 15432
+endpoints:
+- address: 10.0.0.1
+  labels:
+    app: db
+---
+kind: Endpoints
+hostname: db.example.com
+namespace: vm
+endpoints:
+- address: 10.0.0.2
+  ports:
+    tcp: 25432
+`
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func TestController(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "db.yaml", serviceYAML)
+	// Unsupported files are ignored
+	writeFile(t, dir, "README.md", "not a service")
+
+	fx := xdsfake.NewFakeXDS()
+	c := NewController(Options{Root: dir, ClusterID: "vm-site", XDSUpdater: fx})
+	c.reload()
+
+	ev := fx.WaitOrFail(t, "eds cache")
+	assert.Equal(t, len(ev.Endpoints), 2)
+	fx.WaitOrFail(t, "service")
+
+	svc := c.GetService("db.example.com")
+	assert.Equal(t, svc != nil, true)
+	assert.Equal(t, svc.DefaultAddress, "240.0.0.1")
+	assert.Equal(t, svc.Attributes.Namespace, "vm")
+	assert.Equal(t, len(c.Services()), 1)
+
+	ports := map[string]uint32{}
+	for _, ep := range ev.Endpoints {
+		ports[ep.Address] = ep.EndpointPort
+	}
+	assert.Equal(t, ports, map[string]uint32{"10.0.0.1": 15432, "10.0.0.2": 25432})
+
+	proxy := &model.Proxy{IPAddresses: []string{"10.0.0.1"}, Metadata: &model.NodeMetadata{Namespace: "vm"}}
+	targets := c.GetProxyServiceTargets(proxy)
+	assert.Equal(t, len(targets), 1)
+	assert.Equal(t, targets[0].Port.TargetPort, uint32(15432))
+	assert.Equal(t, c.GetProxyWorkloadLabels(proxy).String(), "app=db")
+	proxy.Metadata.Namespace = "other"
+	assert.Equal(t, len(c.GetProxyServiceTargets(proxy)), 0)
+
+	t.Run("unchanged", func(t *testing.T) {
+		c.reload()
+		fx.AssertEmpty(t, 10*time.Millisecond)
+	})
+	t.Run("endpoints changed", func(t *testing.T) {
+		writeFile(t, dir, "extra.json", `{"kind": "Endpoints", "hostname": "db.example.com", "namespace": "vm", "endpoints": [{"address": "10.0.0.3"}]}`)
+		c.reload()
+		ev := fx.WaitOrFail(t, "eds")
+		assert.Equal(t, len(ev.Endpoints), 3)
+		fx.AssertEmpty(t, 10*time.Millisecond)
+	})
+	t.Run("invalid file keeps previous state", func(t *testing.T) {
+		writeFile(t, dir, "broken.yaml", "kind: Unknown\nhostname: a\nnamespace: b\n")
+		c.reload()
+		fx.AssertEmpty(t, 10*time.Millisecond)
+		assert.Equal(t, len(c.Services()), 1)
+		assert.NoError(t, os.Remove(filepath.Join(dir, "broken.yaml")))
+	})
+	t.Run("service removed", func(t *testing.T) {
+		assert.NoError(t, os.Remove(filepath.Join(dir, "db.yaml")))
+		c.reload()
+		fx.WaitOrFail(t, "service")
+		assert.Equal(t, c.GetService("db.example.com") == nil, true)
+		assert.Equal(t, len(c.GetProxyServiceTargets(&model.Proxy{IPAddresses: []string{"10.0.0.1"}, Metadata: &model.NodeMetadata{}})), 0)
+	})
+}
@@ -0,0 +1,306 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kubeyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/network"
+)
+
+const (
+	// ServiceKind is the kind of a document declaring a service, optionally with its endpoints.
+	ServiceKind = "Service"
+	// EndpointsKind is the kind of a document declaring additional endpoints of a service.
+	EndpointsKind = "Endpoints"
+)
+
+var supportedExtensions = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// Document is a single service or endpoints definition. Files may contain multiple documents,
+// separated by "---" in YAML.
+type Document struct {
+	Kind string `json:"kind"`
+
+	// Hostname of the service. For Endpoints documents, this is the service the endpoints belong to.
+	Hostname  string `json:"hostname"`
+	Namespace string `json:"namespace"`
+
+	// Addresses are the virtual IPs of the service.
+	Addresses []string `json:"addresses,omitempty"`
+	Ports     []Port   `json:"ports,omitempty"`
+	// Resolution is one of STATIC (the default), DNS, DNS_ROUND_ROBIN or NONE.
+	Resolution      string            `json:"resolution,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	ServiceAccounts []string          `json:"serviceAccounts,omitempty"`
+	MeshExternal    bool              `json:"meshExternal,omitempty"`
+
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+}
+
+// Port is a port exposed by a service.
+type Port struct {
+	Name     string `json:"name"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol,omitempty"`
+	// TargetPort is the port of the endpoints. Defaults to Port.
+	TargetPort int `json:"targetPort,omitempty"`
+}
+
+// Endpoint is a single instance of a service.
+type Endpoint struct {
+	Address string `json:"address"`
+	// Ports overrides the target port of the endpoint, keyed by service port name.
+	Ports          map[string]int    `json:"ports,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Network        string            `json:"network,omitempty"`
+	Locality       string            `json:"locality,omitempty"`
+	ServiceAccount string            `json:"serviceAccount,omitempty"`
+	Weight         uint32            `json:"weight,omitempty"`
+	// TLSMode is either "istio" (the default) or "disabled".
+	TLSMode string `json:"tlsMode,omitempty"`
+}
+
+// readDir parses all supported files under root.
+func readDir(root string) ([]Document, error) {
+	var docs []Document
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !supportedExtensions[filepath.Ext(path)] || (info.Mode()&os.ModeType) != 0 {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		parsed, err := parseDocuments(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		docs = append(docs, parsed...)
+		return nil
+	})
+	return docs, err
+}
+
+func parseDocuments(data []byte) ([]Document, error) {
+	var docs []Document
+	decoder := kubeyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		doc := Document{}
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+			return nil, err
+		}
+		if doc.Kind == "" && doc.Hostname == "" {
+			// Empty document
+			continue
+		}
+		if doc.Kind != ServiceKind && doc.Kind != EndpointsKind {
+			return nil, fmt.Errorf("unknown kind %q for %s", doc.Kind, doc.Hostname)
+		}
+		if doc.Hostname == "" || doc.Namespace == "" {
+			return nil, fmt.Errorf("%s is missing hostname or namespace", doc.Kind)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// registryState is the converted content of the watched directory.
+type registryState struct {
+	services  map[host.Name]*model.Service
+	endpoints map[host.Name][]*model.IstioEndpoint
+	// targets indexes service targets by endpoint address.
+	targets map[string][]model.ServiceTarget
+	// labels indexes workload labels by endpoint address.
+	labels map[string]map[string]string
+}
+
+// convert builds the registry state from the parsed documents. Invalid documents are skipped with a warning.
+func convert(docs []Document, clusterID cluster.ID) *registryState {
+	state := &registryState{
+		services:  map[host.Name]*model.Service{},
+		endpoints: map[host.Name][]*model.IstioEndpoint{},
+		targets:   map[string][]model.ServiceTarget{},
+		labels:    map[string]map[string]string{},
+	}
+	var endpointDocs []Document
+	// targetPorts holds the target port of each service port, keyed by service
+	targetPorts := map[host.Name]map[string]int{}
+	for _, doc := range docs {
+		if doc.Kind == EndpointsKind {
+			endpointDocs = append(endpointDocs, doc)
+			continue
+		}
+		svc, err := convertService(doc, clusterID)
+		if err != nil {
+			log.Warnf("skipping service %s/%s: %v", doc.Namespace, doc.Hostname, err)
+			continue
+		}
+		if _, f := state.services[svc.Hostname]; f {
+			log.Warnf("skipping duplicate service %s/%s", doc.Namespace, doc.Hostname)
+			continue
+		}
+		state.services[svc.Hostname] = svc
+		targetPorts[svc.Hostname] = map[string]int{}
+		for _, p := range doc.Ports {
+			if p.TargetPort != 0 {
+				targetPorts[svc.Hostname][p.Name] = p.TargetPort
+			}
+		}
+		endpointDocs = append(endpointDocs, doc)
+	}
+	for _, doc := range endpointDocs {
+		svc, f := state.services[host.Name(doc.Hostname)]
+		if !f || svc.Attributes.Namespace != doc.Namespace {
+			log.Warnf("skipping endpoints for unknown service %s/%s", doc.Namespace, doc.Hostname)
+			continue
+		}
+		for _, ep := range doc.Endpoints {
+			state.addEndpoint(svc, ep, targetPorts[svc.Hostname], clusterID)
+		}
+	}
+	// Keep the endpoint order stable so unchanged files do not trigger pushes
+	for _, eps := range state.endpoints {
+		sort.SliceStable(eps, func(i, j int) bool {
+			if eps[i].Address != eps[j].Address {
+				return eps[i].Address < eps[j].Address
+			}
+			return eps[i].ServicePortName < eps[j].ServicePortName
+		})
+	}
+	return state
+}
+
+func (s *registryState) addEndpoint(svc *model.Service, ep Endpoint, targetPorts map[string]int, clusterID cluster.ID) {
+	tlsMode := model.IstioMutualTLSModeLabel
+	if ep.TLSMode != "" {
+		tlsMode = ep.TLSMode
+	}
+	for _, port := range svc.Ports {
+		endpointPort := port.Port
+		if p, f := targetPorts[port.Name]; f {
+			endpointPort = p
+		}
+		if p, f := ep.Ports[port.Name]; f {
+			endpointPort = p
+		}
+		istioEndpoint := &model.IstioEndpoint{
+			Labels:          ep.Labels,
+			Address:         ep.Address,
+			ServicePortName: port.Name,
+			ServiceAccount:  ep.ServiceAccount,
+			Network:         network.ID(ep.Network),
+			Locality: model.Locality{
+				Label:     ep.Locality,
+				ClusterID: clusterID,
+			},
+			EndpointPort: uint32(endpointPort),
+			LbWeight:     ep.Weight,
+			TLSMode:      tlsMode,
+			Namespace:    svc.Attributes.Namespace,
+			HealthStatus: model.Healthy,
+		}
+		s.endpoints[svc.Hostname] = append(s.endpoints[svc.Hostname], istioEndpoint)
+		s.targets[ep.Address] = append(s.targets[ep.Address], model.ServiceTarget{
+			Service: svc,
+			Port: model.ServiceInstancePort{
+				ServicePort: port,
+				TargetPort:  uint32(endpointPort),
+			},
+		})
+		if _, f := s.labels[ep.Address]; !f {
+			s.labels[ep.Address] = ep.Labels
+		}
+	}
+}
+
+func convertService(doc Document, clusterID cluster.ID) (*model.Service, error) {
+	resolution, err := convertResolution(doc.Resolution)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Ports) == 0 {
+		return nil, fmt.Errorf("no ports defined")
+	}
+	ports := make(model.PortList, 0, len(doc.Ports))
+	for _, p := range doc.Ports {
+		if p.Name == "" || p.Port <= 0 {
+			return nil, fmt.Errorf("invalid port %+v", p)
+		}
+		ports = append(ports, &model.Port{
+			Name:     p.Name,
+			Port:     p.Port,
+			Protocol: protocol.Parse(p.Protocol),
+		})
+	}
+	svc := &model.Service{
+		Hostname:        host.Name(doc.Hostname),
+		Ports:           ports,
+		ServiceAccounts: doc.ServiceAccounts,
+		Resolution:      resolution,
+		MeshExternal:    doc.MeshExternal,
+		DefaultAddress:  constants.UnspecifiedIP,
+		Attributes: model.ServiceAttributes{
+			ServiceRegistry: provider.File,
+			Name:            doc.Hostname,
+			Namespace:       doc.Namespace,
+			Labels:          doc.Labels,
+		},
+	}
+	if len(doc.Addresses) > 0 {
+		svc.DefaultAddress = doc.Addresses[0]
+		svc.ClusterVIPs.SetAddressesFor(clusterID, doc.Addresses)
+	}
+	return svc, nil
+}
+
+func convertResolution(r string) (model.Resolution, error) {
+	switch strings.ToUpper(r) {
+	case "", "STATIC":
+		return model.ClientSideLB, nil
+	case "DNS":
+		return model.DNSLB, nil
+	case "DNS_ROUND_ROBIN":
+		return model.DNSRoundRobinLB, nil
+	case "NONE":
+		return model.Passthrough, nil
+	default:
+		return model.ClientSideLB, fmt.Errorf("unknown resolution %q", r)
+	}
+}
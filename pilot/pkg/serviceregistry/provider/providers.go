@@ -24,6 +24,8 @@ const (
 	Kubernetes ID = "Kubernetes"
 	// External is a service registry for externally provided ServiceEntries
 	External ID = "External"
+	// File is a service registry backed by service definitions in a local directory
+	File ID = "File"
 )
 
 func (id ID) String() string {
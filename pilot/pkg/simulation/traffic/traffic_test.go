@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package traffic
+
+import (
+	"net/http"
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func headerRoute(name string, headers ...*route.HeaderMatcher) *route.Route {
+	return &route.Route{
+		Name: name,
+		Match: &route.RouteMatch{
+			PathSpecifier: &route.RouteMatch_Prefix{Prefix: "/"},
+			Headers:       headers,
+		},
+	}
+}
+
+func stringMatch(name string, m *matcher.StringMatcher) *route.HeaderMatcher {
+	return &route.HeaderMatcher{Name: name, HeaderMatchSpecifier: &route.HeaderMatcher_StringMatch{StringMatch: m}}
+}
+
+func TestMatchRouteHeaders(t *testing.T) {
+	vh := &route.VirtualHost{Routes: []*route.Route{
+		headerRoute("exact", stringMatch("end-user", &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_Exact{Exact: "jason"},
+		})),
+		headerRoute("ignore-case", stringMatch("end-user", &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_Exact{Exact: "Bob"},
+			IgnoreCase:   true,
+		})),
+		headerRoute("prefix", stringMatch("x-version", &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_Prefix{Prefix: "v1"},
+		})),
+		headerRoute("regex", stringMatch("x-version", &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_SafeRegex{SafeRegex: &matcher.RegexMatcher{Regex: "v[0-9]"}},
+		})),
+		headerRoute("authority", stringMatch(":authority", &matcher.StringMatcher{
+			MatchPattern: &matcher.StringMatcher_Suffix{Suffix: ".example.com"},
+		})),
+		headerRoute("present", &route.HeaderMatcher{
+			Name:                 "x-canary",
+			HeaderMatchSpecifier: &route.HeaderMatcher_PresentMatch{PresentMatch: true},
+		}),
+		headerRoute("inverted", &route.HeaderMatcher{
+			Name: "x-debug",
+			HeaderMatchSpecifier: &route.HeaderMatcher_StringMatch{StringMatch: &matcher.StringMatcher{
+				MatchPattern: &matcher.StringMatcher_Exact{Exact: "true"},
+			}},
+			InvertMatch: true,
+		}),
+		headerRoute("default"),
+	}}
+	cases := []struct {
+		name    string
+		headers http.Header
+		want    string
+	}{
+		{"exact", http.Header{"End-User": {"jason"}}, "exact"},
+		{"exact mismatch", http.Header{"End-User": {"jasonx"}, "X-Debug": {"true"}}, "default"},
+		{"ignore case", http.Header{"End-User": {"bOB"}}, "ignore-case"},
+		{"prefix", http.Header{"X-Version": {"v10"}}, "prefix"},
+		{"regex matches the full value", http.Header{"X-Version": {"v2"}}, "regex"},
+		{"regex partial match", http.Header{"X-Version": {"v22"}, "X-Debug": {"true"}}, "default"},
+		{"authority", http.Header{"Host": {"foo.example.com"}}, "authority"},
+		{"present", http.Header{"X-Canary": {""}}, "present"},
+		{"inverted", http.Header{}, "inverted"},
+		{"no match", http.Header{"X-Debug": {"true"}}, "default"},
+	}
+	sim := New(t, nil, nil, nil)
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := sim.matchRoute(vh, Call{Headers: tt.headers}.FillDefaults())
+			assert.Equal(t, r.GetName(), tt.want)
+		})
+	}
+}
+
+func TestMatchRouteWithoutHeaders(t *testing.T) {
+	// Routes without header matchers match any headers, as before header matching was supported.
+	vh := &route.VirtualHost{Routes: []*route.Route{headerRoute("default")}}
+	sim := New(t, nil, nil, nil)
+	for _, headers := range []http.Header{nil, {"End-User": {"jason"}}, {"Host": {"foo.example.com"}}} {
+		assert.Equal(t, sim.matchRoute(vh, Call{Headers: headers}.FillDefaults()).GetName(), "default")
+	}
+}
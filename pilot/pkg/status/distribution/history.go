@@ -0,0 +1,129 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distribution
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/ledger"
+)
+
+// configHistory retains the contents of the configs tracked in the ledger. The ledger only records the
+// generation of each config, which is enough to tell which revision was current at a previous ledger
+// version, but not what that revision looked like.
+type configHistory struct {
+	mu        sync.RWMutex
+	retention time.Duration
+	clock     clock.Clock
+	// map from config key to its retained revisions, oldest first.
+	revisions map[string][]configRevision
+}
+
+type configRevision struct {
+	generation string
+	config     config.Config
+	// time at which the revision was replaced or deleted, zero while it is current.
+	superseded time.Time
+}
+
+func newConfigHistory(retention time.Duration, clk clock.Clock) *configHistory {
+	return &configHistory{
+		retention: retention,
+		clock:     clk,
+		revisions: make(map[string][]configRevision),
+	}
+}
+
+func (h *configHistory) update(cfg config.Config) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := cfg.Key()
+	h.supersede(key)
+	h.revisions[key] = append(h.revisions[key], configRevision{
+		generation: ledgerValue(strconv.FormatInt(cfg.Generation, 10)),
+		config:     cfg,
+	})
+	h.prune(key, h.clock.Now())
+}
+
+func (h *configHistory) delete(cfg config.Config) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.supersede(cfg.Key())
+	h.prune(cfg.Key(), h.clock.Now())
+}
+
+func (h *configHistory) supersede(key string) {
+	if revs := h.revisions[key]; len(revs) > 0 && revs[len(revs)-1].superseded.IsZero() {
+		revs[len(revs)-1].superseded = h.clock.Now()
+	}
+}
+
+// prune drops the revisions of key replaced for longer than the retention, as the ledger versions
+// referencing them have expired as well.
+func (h *configHistory) prune(key string, now time.Time) {
+	revs := h.revisions[key]
+	expired := 0
+	for expired < len(revs) && !revs[expired].superseded.IsZero() && now.Sub(revs[expired].superseded) > h.retention {
+		expired++
+	}
+	if expired == len(revs) {
+		delete(h.revisions, key)
+	} else if expired > 0 {
+		h.revisions[key] = append([]configRevision(nil), revs[expired:]...)
+	}
+}
+
+// at returns the configs that were current when the ledger was at the given version, sorted by key.
+func (h *configHistory) at(l ledger.Ledger, version string) ([]config.Config, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.clock.Now()
+	for key := range h.revisions {
+		h.prune(key, now)
+	}
+	var res []config.Config
+	for key, revs := range h.revisions {
+		generation, err := l.GetPreviousValue(version, key)
+		if err != nil {
+			return nil, err
+		}
+		// Generations are not always bumped on update, so pick the newest revision with that generation.
+		for i := len(revs) - 1; i >= 0; i-- {
+			if revs[i].generation == generation {
+				res = append(res, revs[i].config)
+				break
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key() < res[j].Key()
+	})
+	return res, nil
+}
+
+// ledgerValue returns value as read back from the ledger, which stores at most 8 bytes per value.
+func ledgerValue(value string) string {
+	if len(value) > 8 {
+		return value[:8]
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distribution
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	clock "k8s.io/utils/clock/testing"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/ledger"
+)
+
+func TestConfigHistory(t *testing.T) {
+	RegisterTestingT(t)
+	fakeClock := clock.NewFakeClock(time.Now())
+	r := initReporterWithoutStarting()
+	r.clock = fakeClock
+	r.ledger = ledger.Make(time.Minute)
+	r.history = newConfigHistory(time.Minute, fakeClock)
+
+	vs := func(name string, generation int64, hosts string) config.Config {
+		return config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.VirtualService,
+				Namespace:        "default",
+				Name:             name,
+				Generation:       generation,
+			},
+			Spec: hosts,
+		}
+	}
+	names := func(cfgs []config.Config) []string {
+		var res []string
+		for _, c := range cfgs {
+			res = append(res, c.Name+"/"+c.Spec.(string))
+		}
+		return res
+	}
+
+	r.AddInProgressResource(vs("foo", 1, "a"))
+	r.AddInProgressResource(vs("bar", 1, "b"))
+	v1 := r.ledger.RootHash()
+	r.AddInProgressResource(vs("foo", 2, "c"))
+	v2 := r.ledger.RootHash()
+	r.DeleteInProgressResource(vs("bar", 1, "b"))
+	v3 := r.ledger.RootHash()
+
+	versions := r.ConfigVersions()
+	Expect(versions).To(HaveLen(4))
+	Expect(versions[3].Hash).To(Equal(v3))
+
+	cfgs, err := r.ConfigAt(v1)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(names(cfgs)).To(Equal([]string{"bar/b", "foo/a"}))
+	cfgs, err = r.ConfigAt(v2)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(names(cfgs)).To(Equal([]string{"bar/b", "foo/c"}))
+	cfgs, err = r.ConfigAt(v3)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(names(cfgs)).To(Equal([]string{"foo/c"}))
+
+	_, err = r.ConfigAt("unknown")
+	Expect(err).To(HaveOccurred())
+
+	// Revisions replaced for longer than the retention are dropped.
+	fakeClock.Step(2 * time.Minute)
+	r.AddInProgressResource(vs("foo", 3, "d"))
+	cfgs, err = r.ConfigAt(r.ledger.RootHash())
+	Expect(err).NotTo(HaveOccurred())
+	Expect(names(cfgs)).To(Equal([]string{"foo/d"}))
+	Expect(r.history.revisions).To(HaveLen(1))
+	Expect(r.history.revisions["networking.istio.io/v1alpha3/VirtualService/default/foo"]).To(HaveLen(2))
+}
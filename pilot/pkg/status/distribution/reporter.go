@@ -63,6 +63,10 @@ type Reporter struct {
 	ledger                 ledger.Ledger
 	distributionEventQueue chan distributionEvent
 	controller             *Controller
+	// HistoryRetention is how long the contents of replaced configs are retained for ConfigAt.
+	// It should match the retention of the ledger. Config history is disabled if zero.
+	HistoryRetention time.Duration
+	history          *configHistory
 }
 
 var _ xds.DistributionStatusCache = &Reporter{}
@@ -83,9 +87,35 @@ func (r *Reporter) Init(ledger ledger.Ledger, stop <-chan struct{}) {
 	r.status = make(map[string]string)
 	r.reverseStatus = make(map[string]sets.String)
 	r.inProgressResources = make(map[string]*inProgressEntry)
+	if r.HistoryRetention > 0 {
+		r.history = newConfigHistory(r.HistoryRetention, r.clock)
+	}
 	go r.readFromEventQueue(stop)
 }
 
+// ConfigVersions returns the config versions retained in the ledger, oldest first.
+func (r *Reporter) ConfigVersions() []ledger.RootHash {
+	return r.ledger.History()
+}
+
+// ConfigAt returns the configs that were current at the given config version.
+func (r *Reporter) ConfigAt(version string) ([]config.Config, error) {
+	if r.history == nil {
+		return nil, fmt.Errorf("config history is disabled")
+	}
+	retained := false
+	for _, root := range r.ledger.History() {
+		if root.Hash == version {
+			retained = true
+			break
+		}
+	}
+	if !retained {
+		return nil, fmt.Errorf("config version %q is unknown or no longer retained", version)
+	}
+	return r.history.at(r.ledger, version)
+}
+
 // Start starts the reporter, which watches dataplane ack's and resource changes so that it can update status leader
 // with distribution information.
 func (r *Reporter) Start(clientSet kubernetes.Interface, namespace string, podname string, stop <-chan struct{}) {
@@ -202,6 +232,9 @@ func (r *Reporter) removeCompletedResource(completedResources []status.Resource)
 // AddInProgressResource must be called every time a resource change is detected by pilot.  This allows us to lookup
 // only the resources we expect to be in flight, not the ones that have already distributed
 func (r *Reporter) AddInProgressResource(res config.Config) {
+	if r.history != nil {
+		r.history.update(res)
+	}
 	tryLedgerPut(r.ledger, res)
 	myRes := status.ResourceFromModelConfig(res)
 	if myRes == (status.Resource{}) {
@@ -217,6 +250,9 @@ func (r *Reporter) AddInProgressResource(res config.Config) {
 }
 
 func (r *Reporter) DeleteInProgressResource(res config.Config) {
+	if r.history != nil {
+		r.history.delete(res)
+	}
 	tryLedgerDelete(r.ledger, res)
 	if r.controller != nil {
 		r.controller.configDeleted(res)
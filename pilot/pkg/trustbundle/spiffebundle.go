@@ -0,0 +1,109 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustbundle
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/slices"
+	"istio.io/istio/pkg/spiffe"
+)
+
+// SpiffeBundleEndpoint serves the trust anchors of the local trust domain as a SPIFFE bundle, so that other meshes
+// or SPIRE deployments can federate with this mesh. Only the roots of the Istio CA or RA are published: trust
+// anchors configured in MeshConfig or fetched from remote bundle endpoints may belong to other trust domains.
+type SpiffeBundleEndpoint struct {
+	tb          *TrustBundle
+	refreshHint time.Duration
+	now         func() time.Time
+
+	mutex    sync.Mutex
+	certs    []string
+	sequence uint64
+}
+
+// NewSpiffeBundleEndpoint returns a SPIFFE bundle endpoint serving the local trust anchors of tb. Consumers are told
+// to poll the endpoint every refreshHint.
+func NewSpiffeBundleEndpoint(tb *TrustBundle, refreshHint time.Duration) *SpiffeBundleEndpoint {
+	return &SpiffeBundleEndpoint{
+		tb:          tb,
+		refreshHint: refreshHint,
+		now:         time.Now,
+	}
+}
+
+// localTrustAnchors returns the trust anchors of the local trust domain, in a stable order.
+func (tb *TrustBundle) localTrustAnchors() []string {
+	tb.mutex.RLock()
+	defer tb.mutex.RUnlock()
+	var certs []string
+	for _, source := range []Source{SourceIstioCA, SourceIstioRA} {
+		for _, cert := range tb.sourceConfig[source].Certs {
+			if !slices.Contains(certs, cert) {
+				certs = append(certs, cert)
+			}
+		}
+	}
+	return certs
+}
+
+// bundle returns the current trust anchors along with their sequence number. The sequence number is seeded from the
+// clock, so it keeps increasing across istiod restarts, and is bumped whenever the trust anchors change.
+func (e *SpiffeBundleEndpoint) bundle() ([]string, uint64) {
+	certs := e.tb.localTrustAnchors()
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.sequence == 0 || !slices.Equal(certs, e.certs) {
+		e.sequence = max(e.sequence+1, uint64(e.now().Unix()))
+		e.certs = certs
+	}
+	return e.certs, e.sequence
+}
+
+func (e *SpiffeBundleEndpoint) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	anchors, sequence := e.bundle()
+	certs := make([]*x509.Certificate, 0, len(anchors))
+	for _, anchor := range anchors {
+		rest := []byte(anchor)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				trustBundleLog.Errorf("failed to parse trust anchor for the SPIFFE bundle: %v", err)
+				continue
+			}
+			certs = append(certs, cert)
+		}
+	}
+	if len(certs) == 0 {
+		http.Error(w, "no trust anchors available", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := spiffe.MarshalBundle(certs, sequence, e.refreshHint)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
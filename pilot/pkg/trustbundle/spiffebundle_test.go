@@ -0,0 +1,87 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustbundle
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+type spiffeBundle struct {
+	Keys []struct {
+		Use string   `json:"use"`
+		X5c []string `json:"x5c"`
+	} `json:"keys"`
+	Sequence    uint64 `json:"spiffe_sequence"`
+	RefreshHint int    `json:"spiffe_refresh_hint"`
+}
+
+func fetchSpiffeBundle(t *testing.T, e *SpiffeBundleEndpoint) (int, spiffeBundle) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var bundle spiffeBundle
+	if rec.Code == http.StatusOK {
+		assert.Equal(t, rec.Header().Get("Content-Type"), "application/json")
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bundle))
+	}
+	return rec.Code, bundle
+}
+
+func TestSpiffeBundleEndpoint(t *testing.T) {
+	tb := NewTrustBundle(nil)
+	e := NewSpiffeBundleEndpoint(tb, 5*time.Minute)
+	now := time.Unix(1000, 0)
+	e.now = func() time.Time { return now }
+
+	code, _ := fetchSpiffeBundle(t, e)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+
+	assert.NoError(t, tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: []string{rootCACert}},
+		Source:            SourceIstioCA,
+	}))
+	// Trust anchors of other trust domains are not published.
+	assert.NoError(t, tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: []string{intermediateCACert}},
+		Source:            SourceMeshConfig,
+	}))
+	code, bundle := fetchSpiffeBundle(t, e)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, len(bundle.Keys), 1)
+	assert.Equal(t, bundle.Keys[0].Use, "x509-svid")
+	assert.Equal(t, len(bundle.Keys[0].X5c), 1)
+	assert.Equal(t, bundle.RefreshHint, 300)
+	first := bundle.Sequence
+	assert.Equal(t, first >= uint64(now.Unix()), true)
+
+	// The sequence only changes along with the trust anchors.
+	now = now.Add(time.Hour)
+	_, bundle = fetchSpiffeBundle(t, e)
+	assert.Equal(t, bundle.Sequence, first)
+
+	assert.NoError(t, tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: []string{intermediateCACert}},
+		Source:            SourceIstioRA,
+	}))
+	_, bundle = fetchSpiffeBundle(t, e)
+	assert.Equal(t, len(bundle.Keys), 2)
+	assert.Equal(t, bundle.Sequence, uint64(now.Unix()))
+}
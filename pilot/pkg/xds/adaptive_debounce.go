@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sync"
+	"time"
+)
+
+// adaptiveDebounce tunes the debounce quiet window based on the observed push load. The window grows
+// when pushes are slow or proxies from the previous push are still queued when the next push starts,
+// and shrinks back towards the minimum once the mesh is idle.
+type adaptiveDebounce struct {
+	min time.Duration
+	max time.Duration
+	// pending returns the number of proxies waiting in the push queue.
+	pending func() int
+
+	mu           sync.RWMutex
+	current      time.Duration
+	lastDuration time.Duration
+	lastPending  int
+}
+
+// AdaptiveDebounceStatus describes the state of the adaptive debounce, for debugging.
+type AdaptiveDebounceStatus struct {
+	// DebounceAfter is the current effective quiet window.
+	DebounceAfter string `json:"debounceAfter"`
+	// MinDebounceAfter and MaxDebounceAfter bound the quiet window.
+	MinDebounceAfter string `json:"minDebounceAfter"`
+	MaxDebounceAfter string `json:"maxDebounceAfter"`
+	// LastPushDuration is the duration of the last debounced push.
+	LastPushDuration string `json:"lastPushDuration"`
+	// LastQueueDepth is the number of proxies still queued when the last debounced push started.
+	LastQueueDepth int `json:"lastQueueDepth"`
+}
+
+func newAdaptiveDebounce(minAfter, maxAfter time.Duration, pending func() int) *adaptiveDebounce {
+	return &adaptiveDebounce{
+		min:     minAfter,
+		max:     max(minAfter, maxAfter),
+		pending: pending,
+		current: minAfter,
+	}
+}
+
+// window returns the current quiet window.
+func (a *adaptiveDebounce) window() time.Duration {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.current
+}
+
+// queueDepth returns the current depth of the push queue, to be passed to observe once the push completes.
+func (a *adaptiveDebounce) queueDepth() int {
+	if a.pending == nil {
+		return 0
+	}
+	return a.pending()
+}
+
+// observe records a debounced push that took pushDuration, started while queueDepth proxies from earlier
+// pushes were still queued, and adjusts the window accordingly.
+func (a *adaptiveDebounce) observe(pushDuration time.Duration, queueDepth int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastDuration = pushDuration
+	a.lastPending = queueDepth
+	switch {
+	case queueDepth > 0 || pushDuration > a.current:
+		// We are pushing faster than the proxies can absorb, batch more events together.
+		a.current = min(a.current*2, a.max)
+	case pushDuration < a.current/2:
+		a.current = max(a.current*3/4, a.min)
+	}
+	debounceAfterWindow.Record(a.current.Seconds())
+}
+
+func (a *adaptiveDebounce) status() AdaptiveDebounceStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return AdaptiveDebounceStatus{
+		DebounceAfter:    a.current.String(),
+		MinDebounceAfter: a.min.String(),
+		MaxDebounceAfter: a.max.String(),
+		LastPushDuration: a.lastDuration.String(),
+		LastQueueDepth:   a.lastPending,
+	}
+}
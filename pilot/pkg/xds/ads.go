@@ -84,6 +84,9 @@ type Connection struct {
 
 	s   *DiscoveryServer
 	ids []string
+
+	// priority is the PushPriority of the connection in the push queue.
+	priority PushPriority
 }
 
 func (conn *Connection) XdsConnection() *xds.Connection {
@@ -261,6 +264,7 @@ func (s *DiscoveryServer) initConnection(node *core.Node, con *Connection, ident
 	con.SetID(connectionID(proxy.ID))
 	con.node = node
 	con.proxy = proxy
+	con.priority = s.PushPriority(proxy)
 	if proxy.IsZTunnel() && !features.EnableAmbient {
 		return fmt.Errorf("ztunnel requires PILOT_ENABLE_AMBIENT=true")
 	}
@@ -184,6 +184,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 
 	s.addDebugHandler(mux, internalMux, "/debug/syncz", "Synchronization status of all Envoys connected to this Pilot instance", s.Syncz)
 	s.addDebugHandler(mux, internalMux, "/debug/config_distribution", "Version status of all Envoys connected to this Pilot instance", s.distributedVersions)
+	s.addDebugHandler(mux, internalMux, "/debug/config_history", "Config versions known to this Pilot instance, "+
+		"or the configs at a previous version with ?version=<version or nonce>", s.configHistory)
 
 	s.addDebugHandler(mux, internalMux, "/debug/registryz", "Debug support for registry", s.registryz)
 	s.addDebugHandler(mux, internalMux, "/debug/endpointz", "Obsolete, use endpointShardz", s.endpointShardz)
@@ -208,6 +210,7 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
 	s.addDebugHandler(mux, internalMux, "/debug/mcsz", "List information about Kubernetes MCS services", s.mcsz)
+	s.addDebugHandler(mux, internalMux, "/debug/ca_rotation", "Progress of the rotation of the plugged-in CA", s.caRotationz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/list", "List all supported debug commands in json", s.list)
 }
@@ -394,6 +397,48 @@ func (s *DiscoveryServer) distributedVersions(w http.ResponseWriter, req *http.R
 	}
 }
 
+// caRotationz reports the progress of the rotation of the plugged-in CA.
+func (s *DiscoveryServer) caRotationz(w http.ResponseWriter, req *http.Request) {
+	if s.CARotationStatus == nil {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = fmt.Fprint(w, "plugged-in CA rotation is not enabled, set PILOT_ENABLE_PLUGGED_CA_ROTATION\n")
+		return
+	}
+	writeJSON(w, s.CARotationStatus(), req)
+}
+
+// configHistory lists the retained config versions, or the configs which were current at the given version.
+// As the version prefixes the nonces sent to proxies, a nonce is accepted as well.
+func (s *DiscoveryServer) configHistory(w http.ResponseWriter, req *http.Request) {
+	if !features.EnableDistributionTracking || s.ConfigHistory == nil {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = fmt.Fprint(w, DistributionTrackingDisabledMessage)
+		return
+	}
+	version := req.URL.Query().Get("version")
+	if version == "" {
+		writeJSON(w, s.ConfigHistory.ConfigVersions(), req)
+		return
+	}
+	if len(version) > VersionLen {
+		version = version[:VersionLen]
+	}
+	cfgs, err := s.ConfigHistory.ConfigAt(version)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+	resourceID := req.URL.Query().Get("resource")
+	configs := make([]kubernetesConfig, 0, len(cfgs))
+	for _, c := range cfgs {
+		if resourceID == "" || c.Key() == resourceID {
+			configs = append(configs, kubernetesConfig{c})
+		}
+	}
+	writeJSON(w, configs, req)
+}
+
 // VersionLen is the Config Version and is only used as the nonce prefix, but we can reconstruct
 // it because is is a b64 encoding of a 64 bit array, which will always be 12 chars in length.
 // len = ceil(bitlength/(2^6))+1
@@ -892,6 +937,22 @@ func (s *DiscoveryServer) pushStatusHandler(w http.ResponseWriter, req *http.Req
 		handleHTTPError(w, err)
 		return
 	}
+	if s.DebounceOptions.adaptive != nil {
+		// Expose the effective debounce window alongside the push status
+		var status map[string]any
+		if err := json.Unmarshal(out, &status); err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+		if status == nil {
+			status = map[string]any{}
+		}
+		status["debounce"] = s.DebounceOptions.adaptive.status()
+		if out, err = json.MarshalIndent(status, "", "    "); err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+	}
 	w.Header().Add("Content-Type", "application/json")
 
 	_, _ = w.Write(out)
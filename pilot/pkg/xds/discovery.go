@@ -56,6 +56,17 @@ type DebounceOptions struct {
 
 	// enableEDSDebounce indicates whether EDS pushes should be debounced.
 	enableEDSDebounce bool
+
+	// adaptive, if set, replaces DebounceAfter with a quiet window tuned from the observed push load.
+	adaptive *adaptiveDebounce
+}
+
+// debounceAfter returns the current quiet window.
+func (o DebounceOptions) debounceAfter() time.Duration {
+	if o.adaptive != nil {
+		return o.adaptive.window()
+	}
+	return o.DebounceAfter
 }
 
 // DiscoveryServer is Pilot's gRPC implementation for Envoy's xds APIs
@@ -74,6 +85,9 @@ type DiscoveryServer struct {
 	// may also choose to not send any updates.
 	ProxyNeedsPush func(proxy *model.Proxy, req *model.PushRequest) bool
 
+	// PushPriority determines the priority of a proxy in the push queue. It is evaluated once, when the proxy connects.
+	PushPriority func(proxy *model.Proxy) PushPriority
+
 	// concurrentPushLimit is a semaphore that limits the amount of concurrent XDS pushes.
 	concurrentPushLimit chan struct{}
 	// RequestRateLimit limits the number of new XDS requests allowed. This helps prevent thundering hurd of incoming requests.
@@ -104,6 +118,9 @@ type DiscoveryServer struct {
 
 	StatusReporter DistributionStatusCache
 
+	// ConfigHistory is used by the config history debug endpoint, nil when distribution tracking is disabled.
+	ConfigHistory ConfigHistory
+
 	// Authenticators for XDS requests. Should be same/subset of the CA authenticators.
 	Authenticators []security.Authenticator
 
@@ -123,6 +140,9 @@ type DiscoveryServer struct {
 	// ListRemoteClusters collects debug information about other clusters this istiod reads from.
 	ListRemoteClusters func() []cluster.DebugInfo
 
+	// CARotationStatus reports the progress of the plugged-in CA rotation, nil if the rotation is disabled.
+	CARotationStatus func() any
+
 	// ClusterAliases are alias names for cluster. When a proxy connects with a cluster ID
 	// and if it has a different alias we should use that a cluster ID for proxy.
 	ClusterAliases map[cluster.ID]cluster.ID
@@ -140,6 +160,7 @@ func NewDiscoveryServer(env *model.Environment, clusterAliases map[string]string
 		Env:                 env,
 		Generators:          map[string]model.XdsResourceGenerator{},
 		ProxyNeedsPush:      DefaultProxyNeedsPush,
+		PushPriority:        DefaultPushPriority,
 		concurrentPushLimit: make(chan struct{}, features.PushThrottle),
 		RequestRateLimit:    rate.NewLimiter(rate.Limit(features.RequestLimit), 1),
 		InboundUpdates:      atomic.NewInt64(0),
@@ -157,6 +178,10 @@ func NewDiscoveryServer(env *model.Environment, clusterAliases map[string]string
 		DiscoveryStartTime: processStartTime,
 	}
 
+	if features.EnableAdaptiveDebounce {
+		out.DebounceOptions.adaptive = newAdaptiveDebounce(features.DebounceAfter, features.DebounceAfterMax, out.pushQueue.Pending)
+	}
+
 	out.ClusterAliases = make(map[cluster.ID]cluster.ID)
 	for alias := range clusterAliases {
 		out.ClusterAliases[cluster.ID(alias)] = cluster.ID(clusterAliases[alias])
@@ -200,6 +225,9 @@ var processStartTime = time.Now()
 // CachesSynced is called when caches have been synced so that server can accept connections.
 func (s *DiscoveryServer) CachesSynced() {
 	log.Infof("All caches have been synced up in %v, marking server ready", time.Since(s.DiscoveryStartTime))
+	if features.XDSCacheSnapshotPath != "" {
+		s.restoreXdsCache(features.XDSCacheSnapshotPath)
+	}
 	s.serverReady.Store(true)
 }
 
@@ -213,6 +241,9 @@ func (s *DiscoveryServer) Start(stopCh <-chan struct{}) {
 	go s.periodicRefreshMetrics(stopCh)
 	go s.sendPushes(stopCh)
 	go s.Cache.Run(stopCh)
+	if features.XDSCacheSnapshotPath != "" {
+		go s.persistXdsCache(features.XDSCacheSnapshotPath, features.XDSCacheSnapshotInterval, stopCh)
+	}
 }
 
 // Push metrics are updated periodically (10s default)
@@ -338,7 +369,14 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts DebounceO
 	freeCh := make(chan struct{}, 1)
 
 	push := func(req *model.PushRequest, debouncedEvents int, startDebounce time.Time) {
-		pushFn(req)
+		if opts.adaptive != nil {
+			queueDepth := opts.adaptive.queueDepth()
+			t0 := time.Now()
+			pushFn(req)
+			opts.adaptive.observe(time.Since(t0), queueDepth)
+		} else {
+			pushFn(req)
+		}
 		updateSent.Add(int64(debouncedEvents))
 		debounceTime.Record(time.Since(startDebounce).Seconds())
 		freeCh <- struct{}{}
@@ -348,7 +386,8 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts DebounceO
 		eventDelay := time.Since(startDebounce)
 		quietTime := time.Since(lastConfigUpdateTime)
 		// it has been too long or quiet enough
-		if eventDelay >= opts.debounceMax || quietTime >= opts.DebounceAfter {
+		debounceAfter := opts.debounceAfter()
+		if eventDelay >= opts.debounceMax || quietTime >= debounceAfter {
 			if req != nil {
 				pushCounter++
 				if req.ConfigsUpdated == nil {
@@ -366,7 +405,7 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts DebounceO
 				debouncedEvents = 0
 			}
 		} else {
-			timeChan = time.After(opts.DebounceAfter - quietTime)
+			timeChan = time.After(debounceAfter - quietTime)
 		}
 	}
 
@@ -391,7 +430,7 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts DebounceO
 
 			lastConfigUpdateTime = time.Now()
 			if debouncedEvents == 0 {
-				timeChan = time.After(opts.DebounceAfter)
+				timeChan = time.After(opts.debounceAfter())
 				startDebounce = lastConfigUpdateTime
 			}
 			debouncedEvents++
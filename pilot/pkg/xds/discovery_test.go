@@ -368,3 +368,38 @@ func BenchmarkPushRequest(b *testing.B) {
 		}
 	}
 }
+
+func TestAdaptiveDebounce(t *testing.T) {
+	pending := 0
+	a := newAdaptiveDebounce(100*time.Millisecond, time.Second, func() int { return pending })
+
+	// Slow pushes grow the window
+	a.observe(200*time.Millisecond, a.queueDepth())
+	if got := a.window(); got != 200*time.Millisecond {
+		t.Fatalf("expected window to grow to 200ms, got %v", got)
+	}
+	// Proxies still queued from the previous push grow the window, up to the max
+	pending = 10
+	for i := 0; i < 5; i++ {
+		a.observe(time.Millisecond, a.queueDepth())
+	}
+	if got := a.window(); got != time.Second {
+		t.Fatalf("expected window to be capped at 1s, got %v", got)
+	}
+	if got := a.status().LastQueueDepth; got != 10 {
+		t.Fatalf("expected last queue depth 10, got %v", got)
+	}
+	// Once idle, the window shrinks back down to the min
+	pending = 0
+	for i := 0; i < 20; i++ {
+		a.observe(time.Millisecond, a.queueDepth())
+	}
+	if got := a.window(); got != 100*time.Millisecond {
+		t.Fatalf("expected window to shrink to 100ms, got %v", got)
+	}
+	// A push that is neither slow nor fast leaves the window unchanged
+	a.observe(75*time.Millisecond, a.queueDepth())
+	if got := a.window(); got != 100*time.Millisecond {
+		t.Fatalf("expected window to stay at 100ms, got %v", got)
+	}
+}
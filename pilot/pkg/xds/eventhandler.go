@@ -16,6 +16,8 @@ package xds
 
 import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/ledger"
 	"istio.io/istio/pkg/util/sets"
 )
 
@@ -37,3 +39,12 @@ type DistributionStatusCache interface {
 	RegisterDisconnect(s string, types sets.Set[EventType])
 	QueryLastNonce(conID string, eventType EventType) (noncePrefix string)
 }
+
+// ConfigHistory provides the configs which were current at previous config versions, allowing to tell
+// what config a proxy was computed from given the version prefixing its nonce.
+type ConfigHistory interface {
+	// ConfigVersions returns the retained config versions, oldest first.
+	ConfigVersions() []ledger.RootHash
+	// ConfigAt returns the configs which were current at the given config version.
+	ConfigAt(version string) ([]config.Config, error)
+}
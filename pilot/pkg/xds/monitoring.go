@@ -26,8 +26,9 @@ import (
 )
 
 var (
-	typeTag    = monitoring.CreateLabel("type")
-	versionTag = monitoring.CreateLabel("version")
+	typeTag     = monitoring.CreateLabel("type")
+	versionTag  = monitoring.CreateLabel("version")
+	priorityTag = monitoring.CreateLabel("priority")
 
 	monServices = monitoring.NewGauge(
 		"pilot_services",
@@ -60,6 +61,11 @@ var (
 		[]float64{.01, .1, 1, 3, 5, 10, 20, 30},
 	)
 
+	debounceAfterWindow = monitoring.NewGauge(
+		"pilot_debounce_after_seconds",
+		"Current debounce quiet window in seconds, when adaptive debounce is enabled.",
+	)
+
 	pushContextInitTime = monitoring.NewDistribution(
 		"pilot_pushcontext_init_seconds",
 		"Total time in seconds Pilot takes to init pushContext.",
@@ -72,6 +78,11 @@ var (
 		[]float64{.01, .1, 1, 3, 5, 10, 20, 30},
 	)
 
+	pushQueueDepth = monitoring.NewGauge(
+		"pilot_push_queue_depth",
+		"Number of proxies waiting in the push queue, labeled by push priority.",
+	)
+
 	proxiesQueueTime = monitoring.NewDistribution(
 		"pilot_proxy_queue_time",
 		"Time in seconds, a proxy is in the push queue before being dequeued.",
@@ -127,6 +138,17 @@ func recordXDSClients(version string, delta float64) {
 	xdsClients.With(versionTag.Value(version)).Record(xdsClientTracker[version])
 }
 
+// pushQueueDepthMetric is a precomputed monitoring.Metric for each push priority.
+var pushQueueDepthMetric = map[PushPriority]monitoring.Metric{
+	PushPriorityHigh:   pushQueueDepth.With(priorityTag.Value(string(PushPriorityHigh))),
+	PushPriorityNormal: pushQueueDepth.With(priorityTag.Value(string(PushPriorityNormal))),
+	PushPriorityLow:    pushQueueDepth.With(priorityTag.Value(string(PushPriorityLow))),
+}
+
+func recordPushQueueDepth(priority PushPriority, depth int) {
+	pushQueueDepthMetric[priority].Record(float64(depth))
+}
+
 // triggerMetric is a precomputed monitoring.Metric for each trigger type. This saves on a lot of allocations
 var triggerMetric = map[model.TriggerReason]monitoring.Metric{
 	model.EndpointUpdate:  pushTriggers.With(typeTag.Value(string(model.EndpointUpdate))),
@@ -48,14 +48,17 @@ func TestNDS(t *testing.T) {
 					"random-1.host.example": {
 						Ips:      []string{"240.240.116.21"},
 						Registry: "External",
+						Ports:    []*dnsProto.NameTable_Port{{Name: "http", Port: 80, Protocol: "HTTP"}},
 					},
 					"random-2.host.example": {
 						Ips:      []string{"9.9.9.9"},
 						Registry: "External",
+						Ports:    []*dnsProto.NameTable_Port{{Name: "http", Port: 80, Protocol: "HTTP"}},
 					},
 					"random-3.host.example": {
 						Ips:      []string{"240.240.81.100"},
 						Registry: "External",
+						Ports:    []*dnsProto.NameTable_Port{{Name: "http", Port: 80, Protocol: "HTTP"}},
 					},
 				},
 			},
@@ -70,6 +73,7 @@ func TestNDS(t *testing.T) {
 					"random-2.host.example": {
 						Ips:      []string{"9.9.9.9"},
 						Registry: "External",
+						Ports:    []*dnsProto.NameTable_Port{{Name: "http", Port: 80, Protocol: "HTTP"}},
 					},
 				},
 			},
@@ -0,0 +1,59 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// PushPriorityKey is the pod annotation, or namespace label, used to select the PushPriority of a proxy.
+// Valid values are "high", "normal" and "low".
+const PushPriorityKey = "istio.io/push-priority"
+
+func parsePushPriority(v string) (PushPriority, bool) {
+	switch p := PushPriority(v); p {
+	case PushPriorityHigh, PushPriorityNormal, PushPriorityLow:
+		return p, true
+	default:
+		return "", false
+	}
+}
+
+// DefaultPushPriority returns the priority set by the PushPriorityKey annotation on the proxy's workload.
+// Without the annotation, gateways and waypoints are pushed with high priority and other proxies with
+// normal priority.
+func DefaultPushPriority(proxy *model.Proxy) PushPriority {
+	if p, ok := parsePushPriority(proxy.Metadata.Annotations[PushPriorityKey]); ok {
+		return p
+	}
+	if proxy.Type == model.Router || proxy.Type == model.Waypoint {
+		return PushPriorityHigh
+	}
+	return PushPriorityNormal
+}
+
+// NamespacePushPriority returns a function computing the push priority of a proxy, which, in addition to
+// DefaultPushPriority, honors the PushPriorityKey label on the proxy's namespace. The workload
+// annotation takes precedence over the namespace label.
+func NamespacePushPriority(namespaceLabels func(namespace string) map[string]string) func(proxy *model.Proxy) PushPriority {
+	return func(proxy *model.Proxy) PushPriority {
+		if _, ok := parsePushPriority(proxy.Metadata.Annotations[PushPriorityKey]); !ok {
+			if p, ok := parsePushPriority(namespaceLabels(proxy.ConfigNamespace)[PushPriorityKey]); ok {
+				return p
+			}
+		}
+		return DefaultPushPriority(proxy)
+	}
+}
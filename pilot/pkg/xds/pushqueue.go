@@ -20,6 +20,34 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 )
 
+// PushPriority controls the order in which queued connections are pushed.
+type PushPriority string
+
+const (
+	PushPriorityHigh   PushPriority = "high"
+	PushPriorityNormal PushPriority = "normal"
+	PushPriorityLow    PushPriority = "low"
+)
+
+// pushPriorities lists all priorities, from highest to lowest.
+var pushPriorities = []PushPriority{PushPriorityHigh, PushPriorityNormal, PushPriorityLow}
+
+// index returns the position of the priority in pushPriorities. Unknown priorities are treated as normal.
+func (p PushPriority) index() int {
+	switch p {
+	case PushPriorityHigh:
+		return 0
+	case PushPriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// maxPriorityPasses is the number of times a non-empty priority level may be passed over in favor of
+// a higher priority before it is served anyway. This prevents starvation of lower priorities.
+const maxPriorityPasses = 10
+
 type PushQueue struct {
 	cond *sync.Cond
 
@@ -27,8 +55,11 @@ type PushQueue struct {
 	// the PushRequest will be merged.
 	pending map[*Connection]*model.PushRequest
 
-	// queue maintains ordering of the queue
-	queue []*Connection
+	// queues maintains ordering of the queue for each priority, indexed by PushPriority.index().
+	queues [][]*Connection
+
+	// passes tracks how many times each non-empty priority has been passed over since it was last served.
+	passes []int
 
 	// processing stores all connections that have been Dequeue(), but not MarkDone().
 	// The value stored will be initially be nil, but may be populated if the connection is Enqueue().
@@ -42,6 +73,8 @@ func NewPushQueue() *PushQueue {
 	return &PushQueue{
 		pending:    make(map[*Connection]*model.PushRequest),
 		processing: make(map[*Connection]*model.PushRequest),
+		queues:     make([][]*Connection, len(pushPriorities)),
+		passes:     make([]int, len(pushPriorities)),
 		cond:       sync.NewCond(&sync.Mutex{}),
 	}
 }
@@ -68,32 +101,79 @@ func (p *PushQueue) Enqueue(con *Connection, pushRequest *model.PushRequest) {
 	}
 
 	p.pending[con] = pushRequest
-	p.queue = append(p.queue, con)
+	p.push(con)
 	// Signal waiters on Dequeue that a new item is available
 	p.cond.Signal()
 }
 
-// Remove a proxy from the queue. If there are no proxies ready to be removed, this will block
+func (p *PushQueue) push(con *Connection) {
+	i := con.priority.index()
+	p.queues[i] = append(p.queues[i], con)
+	recordPushQueueDepth(pushPriorities[i], len(p.queues[i]))
+}
+
+// pop removes the next connection to push. The highest priority connection is returned, unless a lower
+// priority has been passed over too many times, in which case the most starved one is served first.
+func (p *PushQueue) pop() *Connection {
+	next := -1
+	for i := len(p.queues) - 1; i >= 0; i-- {
+		if len(p.queues[i]) > 0 && p.passes[i] >= maxPriorityPasses {
+			next = i
+			break
+		}
+	}
+	if next == -1 {
+		for i := range p.queues {
+			if len(p.queues[i]) > 0 {
+				next = i
+				break
+			}
+		}
+	}
+	if next == -1 {
+		return nil
+	}
+	p.passes[next] = 0
+	for i := next + 1; i < len(p.queues); i++ {
+		if len(p.queues[i]) > 0 {
+			p.passes[i]++
+		}
+	}
+
+	con := p.queues[next][0]
+	// The underlying array will still exist, despite the slice changing, so the object may not GC without this
+	// See https://github.com/grpc/grpc-go/issues/4758
+	p.queues[next][0] = nil
+	p.queues[next] = p.queues[next][1:]
+	recordPushQueueDepth(pushPriorities[next], len(p.queues[next]))
+	return con
+}
+
+func (p *PushQueue) len() int {
+	n := 0
+	for _, q := range p.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Remove a proxy from the queue. If there are no proxies ready to be removed, this will block.
+// Proxies with a higher PushPriority are removed first.
 func (p *PushQueue) Dequeue() (con *Connection, request *model.PushRequest, shutdown bool) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 
 	// Block until there is one to remove. Enqueue will signal when one is added.
-	for len(p.queue) == 0 && !p.shuttingDown {
+	for p.len() == 0 && !p.shuttingDown {
 		p.cond.Wait()
 	}
 
-	if len(p.queue) == 0 {
+	con = p.pop()
+	if con == nil {
 		// We must be shutting down.
 		return nil, nil, true
 	}
 
-	con = p.queue[0]
-	// The underlying array will still exist, despite the slice changing, so the object may not GC without this
-	// See https://github.com/grpc/grpc-go/issues/4758
-	p.queue[0] = nil
-	p.queue = p.queue[1:]
-
 	request = p.pending[con]
 	delete(p.pending, con)
 
@@ -113,7 +193,7 @@ func (p *PushQueue) MarkDone(con *Connection) {
 	// This means we need to add it back to the queue.
 	if request != nil {
 		p.pending[con] = request
-		p.queue = append(p.queue, con)
+		p.push(con)
 		p.cond.Signal()
 	}
 }
@@ -122,7 +202,7 @@ func (p *PushQueue) MarkDone(con *Connection) {
 func (p *PushQueue) Pending() int {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
-	return len(p.queue)
+	return p.len()
 }
 
 // ShutDown will cause queue to ignore all new items added to it. As soon as the
@@ -373,3 +373,125 @@ func TestProxyQueue(t *testing.T) {
 		}
 	})
 }
+
+func TestProxyQueuePriority(t *testing.T) {
+	newCon := func(id string, priority PushPriority) *Connection {
+		con := newConnection("", nil)
+		con.SetID(id)
+		con.priority = priority
+		return con
+	}
+
+	t.Run("higher priority first", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		low := newCon("low", PushPriorityLow)
+		normal := newCon("normal", PushPriorityNormal)
+		unset := newCon("unset", "")
+		high := newCon("high", PushPriorityHigh)
+		p.Enqueue(low, &model.PushRequest{})
+		p.Enqueue(normal, &model.PushRequest{})
+		p.Enqueue(unset, &model.PushRequest{})
+		p.Enqueue(high, &model.PushRequest{})
+
+		ExpectDequeue(t, p, high)
+		ExpectDequeue(t, p, normal)
+		ExpectDequeue(t, p, unset)
+		ExpectDequeue(t, p, low)
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("requeue after markdone keeps priority", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		normal := newCon("normal", PushPriorityNormal)
+		high := newCon("high", PushPriorityHigh)
+		p.Enqueue(high, &model.PushRequest{})
+		ExpectDequeue(t, p, high)
+		p.Enqueue(normal, &model.PushRequest{})
+		p.Enqueue(high, &model.PushRequest{})
+		p.MarkDone(high)
+
+		ExpectDequeue(t, p, high)
+		ExpectDequeue(t, p, normal)
+	})
+
+	t.Run("lower priority is not starved", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		low := newCon("low", PushPriorityLow)
+		p.Enqueue(low, &model.PushRequest{})
+		highs := make([]*Connection, 0, maxPriorityPasses+1)
+		for i := 0; i <= maxPriorityPasses; i++ {
+			high := newCon(fmt.Sprintf("high-%d", i), PushPriorityHigh)
+			highs = append(highs, high)
+			p.Enqueue(high, &model.PushRequest{})
+		}
+
+		for i := 0; i < maxPriorityPasses; i++ {
+			ExpectDequeue(t, p, highs[i])
+		}
+		ExpectDequeue(t, p, low)
+		ExpectDequeue(t, p, highs[maxPriorityPasses])
+	})
+}
+
+func TestDefaultPushPriority(t *testing.T) {
+	cases := []struct {
+		name        string
+		proxy       *model.Proxy
+		nsLabels    map[string]string
+		expected    PushPriority
+		expectedDef PushPriority
+	}{
+		{
+			name:        "sidecar",
+			proxy:       &model.Proxy{Type: model.SidecarProxy, Metadata: &model.NodeMetadata{}},
+			expected:    PushPriorityNormal,
+			expectedDef: PushPriorityNormal,
+		},
+		{
+			name:        "gateway",
+			proxy:       &model.Proxy{Type: model.Router, Metadata: &model.NodeMetadata{}},
+			expected:    PushPriorityHigh,
+			expectedDef: PushPriorityHigh,
+		},
+		{
+			name:        "namespace label",
+			proxy:       &model.Proxy{Type: model.Router, Metadata: &model.NodeMetadata{}},
+			nsLabels:    map[string]string{PushPriorityKey: "low"},
+			expected:    PushPriorityLow,
+			expectedDef: PushPriorityHigh,
+		},
+		{
+			name: "annotation overrides namespace label",
+			proxy: &model.Proxy{
+				Type:     model.SidecarProxy,
+				Metadata: &model.NodeMetadata{Annotations: map[string]string{PushPriorityKey: "high"}},
+			},
+			nsLabels:    map[string]string{PushPriorityKey: "low"},
+			expected:    PushPriorityHigh,
+			expectedDef: PushPriorityHigh,
+		},
+		{
+			name: "invalid annotation",
+			proxy: &model.Proxy{
+				Type:     model.SidecarProxy,
+				Metadata: &model.NodeMetadata{Annotations: map[string]string{PushPriorityKey: "urgent"}},
+			},
+			expected:    PushPriorityNormal,
+			expectedDef: PushPriorityNormal,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultPushPriority(tt.proxy); got != tt.expectedDef {
+				t.Errorf("DefaultPushPriority: expected %v, got %v", tt.expectedDef, got)
+			}
+			fn := NamespacePushPriority(func(string) map[string]string { return tt.nsLabels })
+			if got := fn(tt.proxy); got != tt.expected {
+				t.Errorf("NamespacePushPriority: expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
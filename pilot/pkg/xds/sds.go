@@ -18,6 +18,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -47,6 +48,8 @@ import (
 type SecretResource struct {
 	credentials.SecretResource
 	pkpConfHash string
+	// stapled is set for gateways, which are served OCSP staples when stapling is enabled.
+	stapled bool
 }
 
 var _ model.XdsCacheEntry = SecretResource{}
@@ -56,6 +59,9 @@ func (sr SecretResource) Type() string {
 }
 
 func (sr SecretResource) Key() any {
+	if sr.stapled {
+		return sr.SecretResource.Key() + "/" + sr.pkpConfHash + "/ocsp"
+	}
 	return sr.SecretResource.Key() + "/" + sr.pkpConfHash
 }
 
@@ -95,14 +101,20 @@ func (s *SecretGen) parseResources(names []string, proxy *model.Proxy) []SecretR
 	if pkpConf != nil {
 		pkpConfHashStr = strconv.FormatUint(xxhashv2.Sum64String(pkpConf.String()), 10)
 	}
+	// Staples are only used by servers, so they are only served to gateways.
+	stapled := s.ocspStapler != nil && proxy.Type == model.Router
 	for _, resource := range names {
+		if resource == credentials.IstioCARevocationResourceName {
+			// Served by generateCARevocation
+			continue
+		}
 		sr, err := credentials.ParseResourceName(resource, proxy.VerifiedIdentity.Namespace, proxy.Metadata.ClusterID, s.configCluster)
 		if err != nil {
 			pilotSDSCertificateErrors.Increment()
 			log.Warnf("error parsing resource name: %v", err)
 			continue
 		}
-		res = append(res, SecretResource{sr, pkpConfHashStr})
+		res = append(res, SecretResource{sr, pkpConfHashStr, stapled})
 	}
 	return res
 }
@@ -139,6 +151,9 @@ func (s *SecretGen) Generate(proxy *model.Proxy, w *model.WatchedResource, req *
 	resources := filterAuthorizedResources(s.parseResources(w.ResourceNames, proxy), proxy, proxyClusterSecrets)
 
 	var results model.Resources
+	if res := s.generateCARevocation(w.ResourceNames, updatedSecrets); res != nil {
+		results = append(results, res)
+	}
 	cached, regenerated := 0, 0
 	for _, sr := range resources {
 		if updatedSecrets != nil {
@@ -154,6 +169,9 @@ func (s *SecretGen) Generate(proxy *model.Proxy, w *model.WatchedResource, req *
 			// We skip cache if assertions are enabled, so that the cache will assert our eviction logic is correct
 			results = append(results, cachedItem)
 			cached++
+			if sr.stapled {
+				s.ocspStapler.Used(sr.Name, sr.Namespace)
+			}
 			continue
 		}
 		regenerated++
@@ -202,10 +220,81 @@ func (s *SecretGen) generate(sr SecretResource, configClusterSecrets, proxyClust
 	if err := ValidateCertificate(certInfo.Cert); err != nil {
 		recordInvalidCertificate(sr.ResourceName, err)
 	}
+	if sr.stapled && certInfo.Staple == nil {
+		// A staple provided in the secret takes precedence.
+		if staple := s.ocspStapler.Staple(sr.Name, sr.Namespace, certInfo.Cert); staple != nil {
+			withStaple := *certInfo
+			withStaple.Staple = staple
+			certInfo = &withStaple
+		}
+	}
 	res := toEnvoyTLSSecret(sr.ResourceName, certInfo, proxy, s.meshConfig)
 	return res
 }
 
+// CARevocationSource provides the secret served as credentials.IstioCARevocationResourceName.
+type CARevocationSource interface {
+	// CARevocation returns the PEM encoded trust anchors of the mesh and certificate revocation list of the Istiod CA.
+	// The roots are nil if the revocation list has not been generated yet. The revocation list is nil if it must
+	// not be served, as the mesh trusts issuers other than the Istiod CA.
+	CARevocation() (roots []byte, crl []byte)
+}
+
+// OCSPStapler provides the OCSP responses stapled to the certificates served to gateways.
+type OCSPStapler interface {
+	// Staple returns the OCSP response of the PEM encoded certificate chain of a secret, or nil if none is available.
+	Staple(name, namespace string, certChain []byte) []byte
+	// Used records that the staple of a secret is still served from the cache, so that it keeps being refreshed.
+	Used(name, namespace string)
+}
+
+// CARevocationConfigKey is the config key of pushes updating the credentials.IstioCARevocationResourceName secret.
+var CARevocationConfigKey = model.ConfigKey{Kind: kind.Secret, Name: credentials.IstioCARevocationResourceName}
+
+// generateCARevocation generates the credentials.IstioCARevocationResourceName secret, if it is requested.
+func (s *SecretGen) generateCARevocation(names []string, updatedSecrets sets.Set[model.ConfigKey]) *discovery.Resource {
+	if s.caRevocation == nil || !slices.Contains(names, credentials.IstioCARevocationResourceName) {
+		return nil
+	}
+	if updatedSecrets != nil && !updatedSecrets.Contains(CARevocationConfigKey) {
+		return nil
+	}
+	roots, crl := s.caRevocation.CARevocation()
+	if roots == nil {
+		pilotSDSCertificateErrors.Increment()
+		log.Warnf("certificate revocation list of the Istiod CA is not available")
+		return nil
+	}
+	validationContext := &envoytls.CertificateValidationContext{
+		TrustedCa: &core.DataSource{
+			Specifier: &core.DataSource_InlineBytes{
+				InlineBytes: roots,
+			},
+		},
+	}
+	// Without a revocation list, only the trust anchors are served, as Envoy rejects the certificates of issuers
+	// without one.
+	if crl != nil {
+		validationContext.Crl = &core.DataSource{
+			Specifier: &core.DataSource_InlineBytes{
+				InlineBytes: crl,
+			},
+		}
+		// The revocation list only covers certificates issued by the Istiod CA, not the intermediates.
+		validationContext.OnlyVerifyLeafCertCrl = true
+	}
+	res := protoconv.MessageToAny(&envoytls.Secret{
+		Name: credentials.IstioCARevocationResourceName,
+		Type: &envoytls.Secret_ValidationContext{
+			ValidationContext: validationContext,
+		},
+	})
+	return &discovery.Resource{
+		Name:     credentials.IstioCARevocationResourceName,
+		Resource: res,
+	}
+}
+
 func ValidateCertificate(data []byte) error {
 	block, _ := pem.Decode(data)
 	if block == nil {
@@ -454,6 +543,9 @@ type SecretGen struct {
 	cache         model.XdsCache
 	configCluster cluster.ID
 	meshConfig    *mesh.MeshConfig
+
+	caRevocation CARevocationSource
+	ocspStapler  OCSPStapler
 }
 
 var _ model.XdsResourceGenerator = &SecretGen{}
@@ -470,3 +562,13 @@ func NewSecretGen(sc credscontroller.MulticlusterController, cache model.XdsCach
 		meshConfig:    meshConfig,
 	}
 }
+
+// SetCARevocationSource sets the source of the credentials.IstioCARevocationResourceName secret.
+func (s *SecretGen) SetCARevocationSource(source CARevocationSource) {
+	s.caRevocation = source
+}
+
+// SetOCSPStapler sets the source of the OCSP responses stapled to gateway certificates.
+func (s *SecretGen) SetOCSPStapler(stapler OCSPStapler) {
+	s.ocspStapler = stapler
+}
@@ -32,6 +32,8 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	credentials "istio.io/istio/pilot/pkg/credentials/kube"
 	"istio.io/istio/pilot/pkg/model"
+	credentialsmodel "istio.io/istio/pilot/pkg/model/credentials"
+	pilotxds "istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pilot/test/xds"
 	"istio.io/istio/pilot/test/xdstest"
@@ -40,6 +42,7 @@ import (
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/util/assert"
 	"istio.io/istio/pkg/util/sets"
 )
 
@@ -452,3 +455,132 @@ func TestPrivateKeyProviderProxyConfig(t *testing.T) {
 		}
 	}
 }
+
+type fakeCARevocationSource struct {
+	roots, crl []byte
+}
+
+func (f fakeCARevocationSource) CARevocation() ([]byte, []byte) {
+	return f.roots, f.crl
+}
+
+func TestCARevocation(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+		KubernetesObjects: []runtime.Object{genericCert},
+		KubeClientModifier: func(c kube.Client) {
+			cc := c.Kube().(*fake.Clientset)
+			xds.DisableAuthorizationForSecret(cc)
+		},
+	})
+	type validationContext struct {
+		CaCert string
+		CaCrl  string
+	}
+	gen := s.Discovery.Generators[v3.SecretType].(*pilotxds.SecretGen)
+	proxy := s.SetupProxy(&model.Proxy{
+		Metadata:         &model.NodeMetadata{ClusterID: constants.DefaultClusterName},
+		VerifiedIdentity: &spiffe.Identity{Namespace: "istio-system"},
+		Type:             model.Router,
+	})
+	resources := &model.WatchedResource{ResourceNames: []string{credentialsmodel.IstioCARevocationResourceName, "kubernetes://generic"}}
+	generate := func(req *model.PushRequest) map[string]validationContext {
+		req.Start = time.Now()
+		secrets, _, _ := gen.Generate(proxy, resources, req)
+		got := map[string]validationContext{}
+		for _, scrt := range xdstest.ExtractTLSSecrets(t, model.ResourcesToAny(secrets)) {
+			got[scrt.Name] = validationContext{
+				CaCert: string(scrt.GetValidationContext().GetTrustedCa().GetInlineBytes()),
+				CaCrl:  string(scrt.GetValidationContext().GetCrl().GetInlineBytes()),
+			}
+		}
+		return got
+	}
+
+	// Without a source, the resource is ignored.
+	got := generate(&model.PushRequest{Full: true})
+	assert.Equal(t, len(got), 1)
+
+	gen.SetCARevocationSource(fakeCARevocationSource{})
+	got = generate(&model.PushRequest{Full: true})
+	assert.Equal(t, len(got), 1)
+
+	// Without a revocation list, only the trust anchors are served.
+	gen.SetCARevocationSource(fakeCARevocationSource{roots: []byte("roots")})
+	got = generate(&model.PushRequest{Full: true})
+	assert.Equal(t, got[credentialsmodel.IstioCARevocationResourceName], validationContext{CaCert: "roots"})
+
+	gen.SetCARevocationSource(fakeCARevocationSource{roots: []byte("roots"), crl: []byte("crl")})
+	got = generate(&model.PushRequest{Full: true})
+	assert.Equal(t, got[credentialsmodel.IstioCARevocationResourceName], validationContext{CaCert: "roots", CaCrl: "crl"})
+	assert.Equal(t, len(got), 2)
+
+	// Incremental pushes only include the updated secrets.
+	got = generate(&model.PushRequest{ConfigsUpdated: sets.New(pilotxds.CARevocationConfigKey)})
+	assert.Equal(t, got, map[string]validationContext{credentialsmodel.IstioCARevocationResourceName: {CaCert: "roots", CaCrl: "crl"}})
+	got = generate(&model.PushRequest{ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.Secret, Name: "generic", Namespace: "istio-system"})})
+	assert.Equal(t, len(got), 1)
+	_, ok := got[credentialsmodel.IstioCARevocationResourceName]
+	assert.Equal(t, ok, false)
+}
+
+type fakeOCSPStapler struct {
+	used sets.String
+}
+
+func (fakeOCSPStapler) Staple(name, namespace string, _ []byte) []byte {
+	return []byte("staple/" + namespace + "/" + name)
+}
+
+func (f fakeOCSPStapler) Used(name, namespace string) {
+	f.used.Insert(namespace + "/" + name)
+}
+
+func TestOCSPStapler(t *testing.T) {
+	stapledCert := makeSecret("stapled", map[string]string{
+		credentials.TLSSecretCert:       readFile(filepath.Join(certDir, "default/cert-chain.pem")),
+		credentials.TLSSecretKey:        readFile(filepath.Join(certDir, "default/key.pem")),
+		credentials.TLSSecretOcspStaple: "from-secret",
+	})
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+		KubernetesObjects: []runtime.Object{genericCert, stapledCert},
+		KubeClientModifier: func(c kube.Client) {
+			cc := c.Kube().(*fake.Clientset)
+			xds.DisableAuthorizationForSecret(cc)
+		},
+	})
+	gen := s.Discovery.Generators[v3.SecretType].(*pilotxds.SecretGen)
+	stapler := fakeOCSPStapler{used: sets.New[string]()}
+	gen.SetOCSPStapler(stapler)
+	resources := &model.WatchedResource{ResourceNames: []string{"kubernetes://generic", "kubernetes://stapled"}}
+	generate := func(proxyType model.NodeType) map[string]string {
+		proxy := s.SetupProxy(&model.Proxy{
+			Metadata:         &model.NodeMetadata{ClusterID: constants.DefaultClusterName},
+			VerifiedIdentity: &spiffe.Identity{Namespace: "istio-system"},
+			Type:             proxyType,
+		})
+		secrets, _, _ := gen.Generate(proxy, resources, &model.PushRequest{Full: true, Start: time.Now()})
+		got := map[string]string{}
+		for _, scrt := range xdstest.ExtractTLSSecrets(t, model.ResourcesToAny(secrets)) {
+			got[scrt.Name] = string(scrt.GetTlsCertificate().GetOcspStaple().GetInlineBytes())
+		}
+		return got
+	}
+
+	// Gateways are served the fetched staple, unless the secret provides one.
+	assert.Equal(t, generate(model.Router), map[string]string{
+		"kubernetes://generic": "staple/istio-system/generic",
+		"kubernetes://stapled": "from-secret",
+	})
+	assert.Equal(t, stapler.used.Len(), 0)
+	// Staples served from the cache keep being refreshed.
+	assert.Equal(t, generate(model.Router), map[string]string{
+		"kubernetes://generic": "staple/istio-system/generic",
+		"kubernetes://stapled": "from-secret",
+	})
+	assert.Equal(t, stapler.used.Contains("istio-system/generic"), true)
+	// Sidecars only use certificates as clients, and are not served staples, even when cached for gateways.
+	assert.Equal(t, generate(model.SidecarProxy), map[string]string{
+		"kubernetes://generic": "",
+		"kubernetes://stapled": "from-secret",
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// restoreXdsCache loads the cache snapshot written by a previous istiod instance. It must be called once
+// the config and service registries are synced, so entries can be validated against the current state.
+func (s *DiscoveryServer) restoreXdsCache(path string) {
+	cache, ok := s.Cache.(model.PersistentXdsCache)
+	if !ok {
+		return
+	}
+	t0 := time.Now()
+	snapshot, err := model.ReadXdsCacheSnapshot(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warnf("failed to read xds cache snapshot: %v", err)
+		}
+		return
+	}
+	valid := snapshot.ValidEntries(model.EnvironmentFingerprint(s.Env), model.ConfigFingerprints(s.Env))
+	restored := cache.Restore(valid)
+	log.Infof("restored %d/%d xds cache entries from %s in %v", restored, len(snapshot.Entries), path, time.Since(t0))
+}
+
+// persistXdsCache periodically writes the cache to path until stopCh is closed.
+func (s *DiscoveryServer) persistXdsCache(path string, interval time.Duration, stopCh <-chan struct{}) {
+	if _, ok := s.Cache.(model.PersistentXdsCache); !ok {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.writeXdsCacheSnapshot(path); err != nil {
+				log.Warnf("failed to write xds cache snapshot: %v", err)
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (s *DiscoveryServer) writeXdsCacheSnapshot(path string) error {
+	// The fingerprints are read from the current config, so they only describe the cached entries
+	// if every received update has been applied to the cache. Otherwise, wait for the next tick.
+	inbound := s.InboundUpdates.Load()
+	if !s.IsServerReady() || inbound != s.CommittedUpdates.Load() {
+		return nil
+	}
+	fingerprints := model.ConfigFingerprints(s.Env)
+	snapshot := model.NewXdsCacheSnapshot(s.Cache.(model.PersistentXdsCache), model.EnvironmentFingerprint(s.Env), fingerprints)
+	if s.InboundUpdates.Load() != inbound || s.CommittedUpdates.Load() != inbound {
+		return nil
+	}
+	return model.WriteXdsCacheSnapshot(path, snapshot)
+}
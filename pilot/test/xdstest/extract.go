@@ -185,12 +185,7 @@ func UnmarshalAny[T any](t test.Failer, a *anypb.Any) *T {
 }
 
 func ExtractListener(name string, ll []*listener.Listener) *listener.Listener {
-	for _, l := range ll {
-		if l.Name == name {
-			return l
-		}
-	}
-	return nil
+	return util.ExtractListener(name, ll)
 }
 
 func ExtractVirtualHosts(rc *route.RouteConfiguration) map[string][]string {
@@ -211,19 +206,11 @@ func ExtractVirtualHosts(rc *route.RouteConfiguration) map[string][]string {
 }
 
 func ExtractRouteConfigurations(rc []*route.RouteConfiguration) map[string]*route.RouteConfiguration {
-	res := map[string]*route.RouteConfiguration{}
-	for _, l := range rc {
-		res[l.Name] = l
-	}
-	return res
+	return util.ExtractRouteConfigurations(rc)
 }
 
 func ExtractListenerFilters(l *listener.Listener) map[string]*listener.ListenerFilter {
-	res := map[string]*listener.ListenerFilter{}
-	for _, lf := range l.ListenerFilters {
-		res[lf.Name] = lf
-	}
-	return res
+	return util.ExtractListenerFilters(l)
 }
 
 func ExtractFilterChain(name string, l *listener.Listener) *listener.FilterChain {
@@ -264,33 +251,19 @@ func ExtractFilterNames(t test.Failer, fcs *listener.FilterChain) ([]string, []s
 }
 
 func ExtractTCPProxy(t test.Failer, fcs *listener.FilterChain) *tcpproxy.TcpProxy {
-	for _, fc := range fcs.Filters {
-		if fc.Name == wellknown.TCPProxy {
-			tcpProxy := &tcpproxy.TcpProxy{}
-			if fc.GetTypedConfig() != nil {
-				if err := fc.GetTypedConfig().UnmarshalTo(tcpProxy); err != nil {
-					t.Fatalf("failed to unmarshal tcp proxy: %v", err)
-				}
-			}
-			return tcpProxy
-		}
+	tcpProxy, err := util.ExtractTCPProxy(fcs)
+	if err != nil {
+		t.Fatal(err)
 	}
-	return nil
+	return tcpProxy
 }
 
 func ExtractHTTPConnectionManager(t test.Failer, fcs *listener.FilterChain) *hcm.HttpConnectionManager {
-	for _, fc := range fcs.Filters {
-		if fc.Name == wellknown.HTTPConnectionManager {
-			h := &hcm.HttpConnectionManager{}
-			if fc.GetTypedConfig() != nil {
-				if err := fc.GetTypedConfig().UnmarshalTo(h); err != nil {
-					t.Fatalf("failed to unmarshal hcm: %v", err)
-				}
-			}
-			return h
-		}
+	h, err := util.ExtractHTTPConnectionManager(fcs)
+	if err != nil {
+		t.Fatal(err)
 	}
-	return nil
+	return h
 }
 
 func ExtractLocalityLbEndpoints(cla []*endpoint.ClusterLoadAssignment) map[string][]*endpoint.LocalityLbEndpoints {
@@ -26,6 +26,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/resource"
@@ -116,6 +117,7 @@ func (a *Analyzer) Analyze(c analysis.Context) {
 			if line, ok := util.ErrorLine(r, fmt.Sprintf(util.MetadataName)); ok {
 				m.Line = line
 			}
+			m.Fix = injectionFix(r)
 
 			c.Report(gvk.Namespace, m)
 			return true
@@ -167,6 +169,23 @@ func (a *Analyzer) Analyze(c analysis.Context) {
 	})
 }
 
+// injectionFix labels a namespace to enable sidecar injection.
+func injectionFix(r *resource.Instance) *diag.Fix {
+	description := fmt.Sprintf("label namespace %s with %s=%s", r.Metadata.FullName.Name, util.InjectionLabelName, util.InjectionLabelEnableValue)
+	if len(r.Metadata.Labels) == 0 {
+		return diag.NewFix(description, diag.PatchOperation{
+			Op:    diag.PatchAdd,
+			Path:  diag.JSONPointer("metadata", "labels"),
+			Value: map[string]any{util.InjectionLabelName: util.InjectionLabelEnableValue},
+		})
+	}
+	return diag.NewFix(description, diag.PatchOperation{
+		Op:    diag.PatchAdd,
+		Path:  diag.JSONPointer("metadata", "labels", util.InjectionLabelName),
+		Value: util.InjectionLabelEnableValue,
+	})
+}
+
 // GetInjectedConfigMapValuesStruct retrieves value of sidecarInjectorWebhook.enableNamespacesByDefault
 // defined in the sidecar injector configuration.
 func GetEnableNamespacesByDefaultFromInjectedConfigMap(cm *v1.ConfigMap) bool {
@@ -23,6 +23,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/resource"
@@ -52,14 +53,22 @@ func (c *ConflictingMeshGatewayHostsAnalyzer) Metadata() analysis.Metadata {
 func (c *ConflictingMeshGatewayHostsAnalyzer) Analyze(ctx analysis.Context) {
 	hs := initMeshGatewayHosts(ctx)
 	reported := make(map[resource.FullName]bool)
-	for scopedFqdn, vsList := range hs {
-		scope, _ := scopedFqdn.GetScopeAndFqdn()
+	scopedFqdns := make([]util.ScopedFqdn, 0, len(hs))
+	for scopedFqdn := range hs {
+		scopedFqdns = append(scopedFqdns, scopedFqdn)
+	}
+	// Iterate in a stable order, so that the same virtual services get the fixes.
+	sort.Slice(scopedFqdns, func(i, j int) bool { return scopedFqdns[i] < scopedFqdns[j] })
+	for _, scopedFqdn := range scopedFqdns {
+		vsList := hs[scopedFqdn]
+		scope, fqdn := scopedFqdn.GetScopeAndFqdn()
 		if scope != util.ExportToAllNamespaces {
 			noScopedVSList := getExportToAllNamespacesVSListForScopedHost(scopedFqdn, hs)
 			vsList = append(vsList, noScopedVSList...)
 		}
 		if len(vsList) > 1 {
 			vsNames := combineResourceEntryNames(vsList)
+			kept := firstResourceEntry(vsList)
 			for i := range vsList {
 				if reported[vsList[i].Metadata.FullName] {
 					continue
@@ -70,6 +79,9 @@ func (c *ConflictingMeshGatewayHostsAnalyzer) Analyze(ctx analysis.Context) {
 				if line, ok := util.ErrorLine(vsList[i], fmt.Sprintf(util.MetadataName)); ok {
 					m.Line = line
 				}
+				if vsList[i] != kept {
+					m.Fix = conflictingHostFix(vsList[i], fqdn, kept)
+				}
 
 				ctx.Report(gvk.VirtualService, m)
 			}
@@ -93,6 +105,37 @@ func getExportToAllNamespacesVSListForScopedHost(sh util.ScopedFqdn, meshGateway
 	return vss
 }
 
+func firstResourceEntry(rList []*resource.Instance) *resource.Instance {
+	first := rList[0]
+	for _, r := range rList[1:] {
+		if r.Metadata.FullName.String() < first.Metadata.FullName.String() {
+			first = r
+		}
+	}
+	return first
+}
+
+// conflictingHostFix removes the conflicting host from a virtual service, leaving it to the virtual service kept.
+func conflictingHostFix(r *resource.Instance, fqdn string, kept *resource.Instance) *diag.Fix {
+	vs := r.Message.(*v1alpha3.VirtualService)
+	if len(vs.Hosts) < 2 {
+		// Removing the only host would leave the virtual service invalid.
+		return nil
+	}
+	for i, h := range vs.Hosts {
+		if util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, h) != fqdn {
+			continue
+		}
+		path := diag.JSONPointer("spec", "hosts", i)
+		return diag.NewFix(
+			fmt.Sprintf("remove host %s, which is also routed by VirtualService %s", h, kept.Metadata.FullName),
+			diag.PatchOperation{Op: diag.PatchTest, Path: path, Value: h},
+			diag.PatchOperation{Op: diag.PatchRemove, Path: path},
+		)
+	}
+	return nil
+}
+
 func combineResourceEntryNames(rList []*resource.Instance) string {
 	names := make([]string, 0, len(rList))
 	for _, r := range rList {
@@ -21,6 +21,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -70,6 +71,8 @@ func (d *DestinationRuleAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 			if line, ok := util.ErrorLine(r, key); ok {
 				m.Line = line
 			}
+			m.Fix = unknownSubsetFix(ns, ad, destHostsAndSubsets,
+				diag.JSONPointer("spec", ad.RouteRule, ad.ServiceIndex, "route", ad.DestinationIndex, "destination", "subset"))
 
 			ctx.Report(gvk.VirtualService, m)
 		}
@@ -81,21 +84,48 @@ func (d *DestinationRuleAnalyzer) analyzeVirtualService(r *resource.Instance, ct
 			m := msg.NewReferencedResourceNotFound(r, "mirror+subset in destinationrule",
 				fmt.Sprintf("%s+%s", ad.Destination.GetHost(), ad.Destination.GetSubset()))
 
-			var key string
+			var key, subsetPath string
 			if ad.RouteRule == "http.mirror" {
 				key = fmt.Sprintf(util.MirrorHost, ad.ServiceIndex)
+				subsetPath = diag.JSONPointer("spec", "http", ad.ServiceIndex, "mirror", "subset")
 			} else {
 				key = fmt.Sprintf(util.MirrorsHost, ad.ServiceIndex, ad.DestinationIndex)
+				subsetPath = diag.JSONPointer("spec", "http", ad.ServiceIndex, "mirrors", ad.DestinationIndex, "destination", "subset")
 			}
 			if line, ok := util.ErrorLine(r, key); ok {
 				m.Line = line
 			}
+			m.Fix = unknownSubsetFix(ns, ad, destHostsAndSubsets, subsetPath)
 
 			ctx.Report(gvk.VirtualService, m)
 		}
 	}
 }
 
+// unknownSubsetFix replaces the unknown subset of a destination with the subset of its host, if the destination rules
+// of the host define exactly one. Otherwise, there is no fix: removing the subset would route the traffic to all the
+// endpoints of the host, and any other choice is a guess.
+func unknownSubsetFix(vsNamespace resource.Namespace, ad *AnnotatedDestination, destHostsAndSubsets map[hostAndSubset]bool,
+	subsetPath string,
+) *diag.Fix {
+	name := util.GetResourceNameFromHost(vsNamespace, ad.Destination.GetHost())
+	var subsets []string
+	for hs := range destHostsAndSubsets {
+		if hs.host == name {
+			subsets = append(subsets, hs.subset)
+		}
+	}
+	if len(subsets) != 1 {
+		return nil
+	}
+	return diag.NewFix(
+		fmt.Sprintf("replace the unknown subset %q of the destination %s with %q, the only subset of the host",
+			ad.Destination.GetSubset(), ad.Destination.GetHost(), subsets[0]),
+		diag.PatchOperation{Op: diag.PatchTest, Path: subsetPath, Value: ad.Destination.GetSubset()},
+		diag.PatchOperation{Op: diag.PatchReplace, Path: subsetPath, Value: subsets[0]},
+	)
+}
+
 func (d *DestinationRuleAnalyzer) checkDestinationSubset(vsNamespace resource.Namespace, destination *v1alpha3.Destination,
 	destHostsAndSubsets map[hostAndSubset]bool,
 ) bool {
@@ -0,0 +1,58 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JSON patch operations used by fixes.
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchTest    = "test"
+)
+
+// Fix is a machine-applicable fix of the issue reported by a message.
+type Fix struct {
+	// Description explains what the fix does.
+	Description string `json:"description"`
+	// Patch is a JSON patch (RFC 6902) of the resource of the message.
+	Patch []PatchOperation `json:"patch"`
+}
+
+// PatchOperation is an operation of a JSON patch.
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// NewFix returns a fix applying the patch operations.
+func NewFix(description string, ops ...PatchOperation) *Fix {
+	return &Fix{Description: description, Patch: ops}
+}
+
+// JSONPointer returns the JSON pointer (RFC 6901) of the path segments, which are object keys or array indexes.
+func JSONPointer(segments ...any) string {
+	var sb strings.Builder
+	for _, s := range segments {
+		sb.WriteString("/")
+		sb.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(fmt.Sprint(s)))
+	}
+	return sb.String()
+}
@@ -63,6 +63,9 @@ type Message struct {
 
 	// Line is the line number of the error place in the message
 	Line int
+
+	// Fix is an optional machine-applicable fix of the issue
+	Fix *Fix
 }
 
 // Unstructured returns this message as a JSON-style unstructured map
@@ -88,6 +91,9 @@ func (m *Message) Unstructured(includeOrigin bool) map[string]any {
 		docQueryString = fmt.Sprintf("?ref=%s", m.DocRef)
 	}
 	result["documentationUrl"] = fmt.Sprintf("%s/%s/%s", url.ConfigAnalysis, strings.ToLower(m.Type.Code()), docQueryString)
+	if m.Fix != nil {
+		result["fix"] = m.Fix
+	}
 
 	return result
 }
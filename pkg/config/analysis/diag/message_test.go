@@ -73,6 +73,28 @@ func TestMessage_JSON(t *testing.T) {
 		`,"level":"Error","message":"Cheese type not found: \"Feta\"","origin":"toppings/cheese","reference":"path/to/file"}`))
 }
 
+func TestMessageWithFix_JSON(t *testing.T) {
+	g := NewWithT(t)
+	mt := NewMessageType(Error, "IST0042", "Cheese type not found: %q")
+	m := NewMessage(mt, &resource.Instance{Origin: testOrigin{name: "toppings/cheese", ref: testReference{"path/to/file"}}}, "Feta")
+	m.Fix = NewFix("remove the cheese",
+		PatchOperation{Op: PatchTest, Path: JSONPointer("spec", "toppings", 0), Value: "Feta"},
+		PatchOperation{Op: PatchRemove, Path: JSONPointer("spec", "toppings", 0)})
+
+	j, _ := json.Marshal(&m)
+	g.Expect(string(j)).To(Equal(`{"code":"IST0042","documentationUrl":"` + url.ConfigAnalysis + `/ist0042/"` +
+		`,"fix":{"description":"remove the cheese","patch":[{"op":"test","path":"/spec/toppings/0","value":"Feta"},` +
+		`{"op":"remove","path":"/spec/toppings/0"}]}` +
+		`,"level":"Error","message":"Cheese type not found: \"Feta\"","origin":"toppings/cheese","reference":"path/to/file"}`))
+}
+
+func TestJSONPointer(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(JSONPointer("metadata", "labels", "istio.io/rev")).To(Equal("/metadata/labels/istio.io~1rev"))
+	g.Expect(JSONPointer("spec", "hosts", 1, "a~b")).To(Equal("/spec/hosts/1/a~0b"))
+}
+
 func TestMessage_ReplaceLine(t *testing.T) {
 	testCases := []string{"test.yaml", "test.yaml:1", "test.yaml:10", "test.yaml: 10", "test", "test:10", "123:10", "123"}
 	result := make([]string, 0)
@@ -35,6 +35,7 @@ import (
 	"istio.io/istio/pkg/log"
 	netutil "istio.io/istio/pkg/util/net"
 	"istio.io/istio/pkg/util/sets"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
 )
 
 // Constants for duration fields
@@ -428,9 +429,21 @@ func ValidateMeshConfigProxyConfig(config *meshconfig.ProxyConfig) (errs error)
 		}
 	}
 
+	if err := validateKeyAlgorithms(config.ProxyMetadata); err != nil {
+		errs = multierror.Append(errs, multierror.Prefix(err, "invalid proxy metadata:"))
+	}
+
 	return
 }
 
+// validateKeyAlgorithms validates the algorithms used by the agent to generate workload private keys,
+// which are configured by the proxy metadata.
+func validateKeyAlgorithms(metadata map[string]string) error {
+	return pkiutil.ValidateKeyAlgorithms(
+		pkiutil.SupportedECSignatureAlgorithms(metadata["ECC_SIGNATURE_ALGORITHM"]),
+		pkiutil.SupportedRSASignatureAlgorithms(metadata["RSA_SIGNATURE_ALGORITHM"]))
+}
+
 func ValidateControlPlaneAuthPolicy(policy meshconfig.AuthenticationPolicy) error {
 	if policy == meshconfig.AuthenticationPolicy_NONE || policy == meshconfig.AuthenticationPolicy_MUTUAL_TLS {
 		return nil
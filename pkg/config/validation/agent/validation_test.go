@@ -652,6 +652,51 @@ func TestValidateMeshConfigProxyConfig(t *testing.T) {
 			),
 			isValid: true,
 		},
+		{
+			name: "ed25519 keys",
+			in: modify(valid,
+				func(c *meshconfig.ProxyConfig) {
+					c.ProxyMetadata = map[string]string{"ECC_SIGNATURE_ALGORITHM": "ED25519"}
+				},
+			),
+			isValid: true,
+		},
+		{
+			name: "rsa-pss signatures",
+			in: modify(valid,
+				func(c *meshconfig.ProxyConfig) {
+					c.ProxyMetadata = map[string]string{"RSA_SIGNATURE_ALGORITHM": "PSS"}
+				},
+			),
+			isValid: true,
+		},
+		{
+			name: "unsupported ecc signature algorithm",
+			in: modify(valid,
+				func(c *meshconfig.ProxyConfig) {
+					c.ProxyMetadata = map[string]string{"ECC_SIGNATURE_ALGORITHM": "ED448"}
+				},
+			),
+			isValid: false,
+		},
+		{
+			name: "unsupported ecc curve falls back to P256",
+			in: modify(valid,
+				func(c *meshconfig.ProxyConfig) {
+					c.ProxyMetadata = map[string]string{"ECC_SIGNATURE_ALGORITHM": "ECDSA", "ECC_CURVE": "P521"}
+				},
+			),
+			isValid: true,
+		},
+		{
+			name: "unsupported rsa signature algorithm",
+			in: modify(valid,
+				func(c *meshconfig.ProxyConfig) {
+					c.ProxyMetadata = map[string]string{"RSA_SIGNATURE_ALGORITHM": "PKCS1"}
+				},
+			),
+			isValid: false,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
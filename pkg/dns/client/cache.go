@@ -0,0 +1,172 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+	"github.com/miekg/dns"
+)
+
+// CacheOptions configures the cache of upstream DNS responses.
+type CacheOptions struct {
+	// MaxEntries is the maximum number of cached responses. The cache is disabled if this is 0.
+	MaxEntries int
+	// MaxTTL caps the time a successful response is cached, regardless of the TTL of its records.
+	MaxTTL time.Duration
+	// MaxNegativeTTL caps the time NXDOMAIN and empty responses are cached.
+	MaxNegativeTTL time.Duration
+}
+
+type cacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+type cacheEntry struct {
+	response *dns.Msg
+	stored   time.Time
+	expires  time.Time
+}
+
+// responseCache is a LRU cache of upstream responses, honoring the TTL of the response records.
+// NXDOMAIN and NODATA responses are cached following RFC 2308, using the TTL of the SOA record
+// in the authority section.
+type responseCache struct {
+	opts CacheOptions
+
+	mu  sync.Mutex
+	lru simplelru.LRUCache[cacheKey, cacheEntry]
+
+	// now is overridden in tests
+	now func() time.Time
+}
+
+func newResponseCache(opts CacheOptions) *responseCache {
+	if opts.MaxEntries <= 0 {
+		return nil
+	}
+	lru, err := simplelru.NewLRU[cacheKey, cacheEntry](opts.MaxEntries, nil)
+	if err != nil {
+		// Only possible with a non-positive size
+		panic(err)
+	}
+	return &responseCache{
+		opts: opts,
+		lru:  lru,
+		now:  time.Now,
+	}
+}
+
+func keyFor(req *dns.Msg) (cacheKey, bool) {
+	if len(req.Question) != 1 {
+		return cacheKey{}, false
+	}
+	q := req.Question[0]
+	return cacheKey{name: strings.ToLower(q.Name), qtype: q.Qtype, qclass: q.Qclass}, true
+}
+
+// get returns the cached response for the request, with the TTLs of the records reduced by the time spent
+// in the cache, or nil if there is no valid entry.
+func (c *responseCache) get(req *dns.Msg) *dns.Msg {
+	key, ok := keyFor(req)
+	if !ok {
+		return nil
+	}
+	now := c.now()
+	c.mu.Lock()
+	entry, f := c.lru.Get(key)
+	if f && !now.Before(entry.expires) {
+		c.lru.Remove(key)
+		f = false
+	}
+	c.mu.Unlock()
+	if !f {
+		cacheMisses.Increment()
+		return nil
+	}
+	cacheHits.Increment()
+
+	response := entry.response.Copy()
+	response.Id = req.Id
+	elapsed := uint32(now.Sub(entry.stored) / time.Second)
+	for _, section := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			if rr.Header().Ttl > elapsed {
+				rr.Header().Ttl -= elapsed
+			} else {
+				rr.Header().Ttl = 0
+			}
+		}
+	}
+	return response
+}
+
+// add stores the upstream response for the request, if it can be cached.
+func (c *responseCache) add(req *dns.Msg, response *dns.Msg) {
+	key, ok := keyFor(req)
+	if !ok {
+		return
+	}
+	ttl := c.ttl(response)
+	if ttl <= 0 {
+		return
+	}
+	now := c.now()
+	entry := cacheEntry{
+		response: response.Copy(),
+		stored:   now,
+		expires:  now.Add(ttl),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Add(key, entry)
+}
+
+// ttl returns how long the response can be cached, or 0 if it must not be cached.
+func (c *responseCache) ttl(response *dns.Msg) time.Duration {
+	if response.Truncated {
+		return 0
+	}
+	switch {
+	case response.Rcode == dns.RcodeSuccess && len(response.Answer) > 0:
+		minTTL := response.Answer[0].Header().Ttl
+		for _, rr := range response.Answer[1:] {
+			minTTL = min(minTTL, rr.Header().Ttl)
+		}
+		return min(time.Duration(minTTL)*time.Second, c.opts.MaxTTL)
+	case response.Rcode == dns.RcodeNameError || response.Rcode == dns.RcodeSuccess:
+		// Negative responses are only cached if the SOA record tells us for how long
+		for _, rr := range response.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return min(time.Duration(min(soa.Hdr.Ttl, soa.Minttl))*time.Second, c.opts.MaxNegativeTTL)
+			}
+		}
+	}
+	return 0
+}
+
+func (c *responseCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func query(host string) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(host, dns.TypeA)
+	return m
+}
+
+func answer(req *dns.Msg, ttl uint32) *dns.Msg {
+	res := new(dns.Msg)
+	res.SetReply(req)
+	res.Answer = a(req.Question[0].Name, []netip.Addr{netip.MustParseAddr("1.1.1.1")})
+	res.Answer[0].Header().Ttl = ttl
+	return res
+}
+
+func nxdomain(req *dns.Msg, soaTTL, minTTL uint32) *dns.Msg {
+	res := new(dns.Msg)
+	res.SetRcode(req, dns.RcodeNameError)
+	res.Ns = []dns.RR{&dns.SOA{
+		Hdr:    dns.RR_Header{Name: "com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: soaTTL},
+		Ns:     "ns.com.",
+		Mbox:   "admin.com.",
+		Minttl: minTTL,
+	}}
+	return res
+}
+
+func TestResponseCache(t *testing.T) {
+	now := time.Now()
+	newCache := func(size int) *responseCache {
+		c := newResponseCache(CacheOptions{MaxEntries: size, MaxTTL: time.Minute, MaxNegativeTTL: 10 * time.Second})
+		c.now = func() time.Time { return now }
+		return c
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Equal(t, newResponseCache(CacheOptions{}) == nil, true)
+	})
+	t.Run("positive", func(t *testing.T) {
+		c := newCache(10)
+		req := query("www.example.com.")
+		assert.Equal(t, c.get(req) == nil, true)
+		c.add(req, answer(req, 30))
+
+		now = now.Add(10 * time.Second)
+		again := query("WWW.example.com.")
+		res := c.get(again)
+		assert.Equal(t, res.Id, again.Id)
+		assert.Equal(t, res.Answer[0].Header().Ttl, uint32(20))
+
+		now = now.Add(20 * time.Second)
+		assert.Equal(t, c.get(req) == nil, true)
+		assert.Equal(t, c.len(), 0)
+	})
+	t.Run("positive ttl capped", func(t *testing.T) {
+		c := newCache(10)
+		req := query("www.example.com.")
+		c.add(req, answer(req, 3600))
+		now = now.Add(59 * time.Second)
+		assert.Equal(t, c.get(req) != nil, true)
+		now = now.Add(time.Second)
+		assert.Equal(t, c.get(req) == nil, true)
+	})
+	t.Run("negative", func(t *testing.T) {
+		c := newCache(10)
+		req := query("missing.example.com.")
+		c.add(req, nxdomain(req, 300, 5))
+		now = now.Add(4 * time.Second)
+		res := c.get(req)
+		assert.Equal(t, res.Rcode, dns.RcodeNameError)
+		assert.Equal(t, res.Ns[0].Header().Ttl, uint32(296))
+		now = now.Add(time.Second)
+		assert.Equal(t, c.get(req) == nil, true)
+	})
+	t.Run("negative ttl capped", func(t *testing.T) {
+		c := newCache(10)
+		req := query("missing.example.com.")
+		c.add(req, nxdomain(req, 300, 300))
+		now = now.Add(10 * time.Second)
+		assert.Equal(t, c.get(req) == nil, true)
+	})
+	t.Run("not cached", func(t *testing.T) {
+		c := newCache(10)
+		req := query("www.example.com.")
+		c.add(req, serverFailure(req))
+		noSOA := new(dns.Msg)
+		noSOA.SetRcode(req, dns.RcodeNameError)
+		c.add(req, noSOA)
+		truncated := answer(req, 30)
+		truncated.Truncated = true
+		c.add(req, truncated)
+		assert.Equal(t, c.len(), 0)
+	})
+	t.Run("bounded", func(t *testing.T) {
+		c := newCache(2)
+		first, second, third := query("a.example.com."), query("b.example.com."), query("c.example.com.")
+		c.add(first, answer(first, 30))
+		c.add(second, answer(second, 30))
+		c.add(third, answer(third, 30))
+		assert.Equal(t, c.len(), 2)
+		assert.Equal(t, c.get(first) == nil, true)
+		assert.Equal(t, c.get(third) != nil, true)
+	})
+	t.Run("cached response is not modified", func(t *testing.T) {
+		c := newCache(10)
+		req := query("www.example.com.")
+		c.add(req, answer(req, 30))
+		c.get(req).Answer = nil
+		assert.Equal(t, len(c.get(req).Answer), 1)
+	})
+}
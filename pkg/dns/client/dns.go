@@ -58,6 +58,9 @@ type LocalDNSServer struct {
 
 	respondBeforeSync         bool
 	forwardToUpstreamParallel bool
+
+	// cache holds upstream responses. It is nil if caching is disabled.
+	cache *responseCache
 }
 
 // LookupTable is borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hostsfile.go
@@ -77,6 +80,12 @@ type LookupTable struct {
 	// The cname records here (comprised of different variants of the hosts above,
 	// expanded by the search namespaces) pointing to the actual host.
 	cname map[string][]dns.RR
+	// The key is a SRV query name (like _http._tcp.productpage.ns1.), the value is the SRV records
+	// for the matching port of the host.
+	srv map[string][]dns.RR
+	// The key is a reverse lookup name (like 1.0.0.10.in-addr.arpa.), the value is the PTR records
+	// pointing to every host resolving to that IP.
+	ptr map[string][]dns.RR
 }
 
 const (
@@ -86,10 +95,13 @@ const (
 	defaultTTLInSeconds = 30
 )
 
-func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardToUpstreamParallel bool) (*LocalDNSServer, error) {
+func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardToUpstreamParallel bool,
+	cacheOpts CacheOptions,
+) (*LocalDNSServer, error) {
 	h := &LocalDNSServer{
 		proxyNamespace:            proxyNamespace,
 		forwardToUpstreamParallel: forwardToUpstreamParallel,
+		cache:                     newResponseCache(cacheOpts),
 	}
 
 	// proxyDomain could contain the namespace making it redundant.
@@ -190,6 +202,8 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 		name4:    map[string][]dns.RR{},
 		name6:    map[string][]dns.RR{},
 		cname:    map[string][]dns.RR{},
+		srv:      map[string][]dns.RR{},
+		ptr:      map[string][]dns.RR{},
 	}
 	h.BuildAlternateHosts(nt, lookupTable.buildDNSAnswers)
 	h.lookupTable.Store(lookupTable)
@@ -198,9 +212,9 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 }
 
 // BuildAlternateHosts builds alternate hosts for Kubernetes services in the name table and
-// calls the passed in function with the built alternate hosts.
+// calls the passed in function with the fully qualified host, the built alternate hosts and the ports of the host.
 func (h *LocalDNSServer) BuildAlternateHosts(nt *dnsProto.NameTable,
-	apply func(map[string]struct{}, []netip.Addr, []netip.Addr, []string),
+	apply func(string, map[string]struct{}, []netip.Addr, []netip.Addr, []*dnsProto.NameTable_Port, []string),
 ) {
 	for hostname, ni := range nt.Table {
 		// Given a host
@@ -208,25 +222,32 @@ func (h *LocalDNSServer) BuildAlternateHosts(nt *dnsProto.NameTable,
 		// if its a k8s host, store all variants (i.e. shortname+., shortname+namespace+., fqdn+., etc.)
 		// shortname+. is only for hosts in current namespace
 		var altHosts sets.String
+		fqdn := hostname
+		if !strings.HasSuffix(fqdn, ".") {
+			fqdn += "."
+		}
 		if ni.Registry == string(provider.Kubernetes) {
 			altHosts = generateAltHosts(hostname, ni, h.proxyNamespace, h.proxyDomain, h.proxyDomainParts)
 		} else {
-			if !strings.HasSuffix(hostname, ".") {
-				hostname += "."
-			}
-			altHosts = sets.New(hostname)
+			altHosts = sets.New(fqdn)
 		}
 		ipv4, ipv6 := netutil.ParseIPsSplitToV4V6(ni.Ips)
 		if len(ipv6) == 0 && len(ipv4) == 0 {
 			// malformed ips
 			continue
 		}
-		apply(altHosts, ipv4, ipv6, h.searchNamespaces)
+		apply(fqdn, altHosts, ipv4, ipv6, ni.Ports, h.searchNamespaces)
 	}
 }
 
 // upstream sends the request to the upstream server, with associated logs and metrics
 func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string) *dns.Msg {
+	if h.cache != nil {
+		if response := h.cache.get(req); response != nil {
+			log.Debugf("cached upstream response for hostname %q : %v", hostname, response)
+			return response
+		}
+	}
 	upstreamRequests.Increment()
 	start := time.Now()
 	// We did not find the host in our internal cache. Query upstream and return the response as is.
@@ -234,6 +255,9 @@ func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string
 	response := h.queryUpstream(proxy.upstreamClient, req, log)
 	requestDuration.Record(time.Since(start).Seconds())
 	log.Debugf("upstream response for hostname %q : %v", hostname, response)
+	if h.cache != nil {
+		h.cache.add(req, response)
+	}
 	return response
 }
 
@@ -510,6 +534,18 @@ func generateAltHosts(hostname string, nameinfo *dnsProto.NameTable_NameInfo, pr
 // If it is not part of the registry, return nil so that caller queries upstream. If it is part
 // of registry, we will look it up in one of our tables, failing which we will return NXDOMAIN.
 func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, bool) {
+	// SRV and PTR records are only synthesized for exact names, there is nothing to expand.
+	switch qtype {
+	case dns.TypeSRV:
+		if answers, f := table.srv[hostname]; f {
+			return answers, true
+		}
+	case dns.TypePTR:
+		if answers, f := table.ptr[hostname]; f {
+			return answers, true
+		}
+	}
+
 	question := string(host.Name(hostname))
 	wildcard := false
 	// First check if host exists in all hosts.
@@ -555,7 +591,6 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 	case dns.TypeAAAA:
 		ipAnswers = table.name6[hostname]
 	default:
-		// TODO: handle PTR records for reverse dns lookups
 		return nil, false
 	}
 
@@ -605,7 +640,21 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 // in the lookup table with a CNAME record as the DNS response. This technique eliminates the need
 // to do string parsing, memory allocations, etc. at query time at the cost of Nx number of entries (i.e. memory) to store
 // the lookup table, where N is number of search namespaces.
-func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []netip.Addr, ipv6 []netip.Addr, searchNamespaces []string) {
+func (table *LookupTable) buildDNSAnswers(hostname string, altHosts map[string]struct{}, ipv4 []netip.Addr, ipv6 []netip.Addr,
+	ports []*dnsProto.NameTable_Port, searchNamespaces []string,
+) {
+	hostname = strings.ToLower(hostname)
+	// Wildcard hosts cannot be the target of SRV or PTR records
+	wildcard := strings.HasPrefix(hostname, "*")
+	if !wildcard {
+		for _, ip := range append(ipv4, ipv6...) {
+			reverse, err := dns.ReverseAddr(ip.String())
+			if err != nil {
+				continue
+			}
+			table.ptr[reverse] = append(table.ptr[reverse], ptr(reverse, hostname))
+		}
+	}
 	for h := range altHosts {
 		h = strings.ToLower(h)
 		table.allHosts.Insert(h)
@@ -615,6 +664,17 @@ func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []n
 		if len(ipv6) > 0 {
 			table.name6[h] = aaaa(h, ipv6)
 		}
+		if !wildcard {
+			for _, p := range ports {
+				if p.Name == "" {
+					continue
+				}
+				name := srvName(h, p)
+				table.srv[name] = srv(name, hostname, p.Port)
+				// Other record types for the SRV name are answered with NODATA rather than forwarded
+				table.allHosts.Insert(name)
+			}
+		}
 		if len(searchNamespaces) > 0 {
 			// NOTE: Right now, rather than storing one expanded host for each one of the search namespace
 			// entries, we are going to store just the first one (assuming that most clients will
@@ -675,6 +735,43 @@ func cname(host string, targetHost string) []dns.RR {
 	return []dns.RR{answer}
 }
 
+// srvName returns the SRV query name of the port of host, following the Kubernetes DNS
+// specification (_<port name>._<protocol>.<host>).
+func srvName(host string, port *dnsProto.NameTable_Port) string {
+	proto := "tcp"
+	if strings.EqualFold(port.Protocol, "UDP") {
+		proto = "udp"
+	}
+	return "_" + strings.ToLower(port.Name) + "._" + proto + "." + host
+}
+
+func srv(name string, target string, port uint32) []dns.RR {
+	answer := new(dns.SRV)
+	answer.Hdr = dns.RR_Header{
+		Name:   name,
+		Rrtype: dns.TypeSRV,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLInSeconds,
+	}
+	answer.Priority = 0
+	answer.Weight = 100
+	answer.Port = uint16(port)
+	answer.Target = target
+	return []dns.RR{answer}
+}
+
+func ptr(reverse string, targetHost string) dns.RR {
+	answer := new(dns.PTR)
+	answer.Hdr = dns.RR_Header{
+		Name:   reverse,
+		Rrtype: dns.TypePTR,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLInSeconds,
+	}
+	answer.Ptr = targetHost
+	return answer
+}
+
 // Size returns if buffer size *advertised* in the requests OPT record.
 // Or when the request was over TCP, we return the maximum allowed size of 64K.
 func size(proto string, r *dns.Msg) int {
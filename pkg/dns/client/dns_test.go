@@ -43,7 +43,7 @@ func TestDNS(t *testing.T) {
 
 func TestBuildAlternateHosts(t *testing.T) {
 	// Create the server instance without starting it, as it's unnecessary for this test
-	d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", false)
+	d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", false, CacheOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -95,7 +95,9 @@ func TestBuildAlternateHosts(t *testing.T) {
 
 	nt := d.NameTable()
 	nt = proto.Clone(nt).(*dnsProto.NameTable)
-	d.BuildAlternateHosts(nt, func(althosts map[string]struct{}, ipv4 []netip.Addr, ipv6 []netip.Addr, _ []string) {
+	d.BuildAlternateHosts(nt, func(_ string, althosts map[string]struct{}, ipv4 []netip.Addr, ipv6 []netip.Addr,
+		_ []*dnsProto.NameTable_Port, _ []string,
+	) {
 		for host := range althosts {
 			if _, exists := nt.Table[host]; !exists {
 				addresses := make([]string, 0, len(ipv4)+len(ipv6))
@@ -131,6 +133,7 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 		host                     string
 		id                       int
 		queryAAAA                bool
+		qtype                    uint16
 		expected                 []dns.RR
 		expectResolutionFailure  int
 		expectExternalResolution bool
@@ -337,6 +340,41 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 			host:     "example.localhost.",
 			expected: a("example.localhost.", []netip.Addr{netip.MustParseAddr("3.3.3.3")}),
 		},
+		{
+			name:     "success: SRV query for k8s host - fqdn",
+			host:     "_http._tcp.productpage.ns1.svc.cluster.local.",
+			qtype:    dns.TypeSRV,
+			expected: srv("_http._tcp.productpage.ns1.svc.cluster.local.", "productpage.ns1.svc.cluster.local.", 9080),
+		},
+		{
+			name:     "success: SRV query for k8s host - shortname",
+			host:     "_http._tcp.productpage.",
+			qtype:    dns.TypeSRV,
+			expected: srv("_http._tcp.productpage.", "productpage.ns1.svc.cluster.local.", 9080),
+		},
+		{
+			name:     "success: SRV query for udp port",
+			host:     "_dns._udp.ipv4.localhost.",
+			qtype:    dns.TypeSRV,
+			expected: srv("_dns._udp.ipv4.localhost.", "ipv4.localhost.", 53),
+		},
+		{
+			name:                    "success: A query for SRV name",
+			host:                    "_http._tcp.productpage.",
+			expectResolutionFailure: dns.RcodeSuccess,
+		},
+		{
+			name:     "success: PTR query for k8s service",
+			host:     "9.9.9.9.in-addr.arpa.",
+			qtype:    dns.TypePTR,
+			expected: []dns.RR{ptr("9.9.9.9.in-addr.arpa.", "productpage.ns1.svc.cluster.local.")},
+		},
+		{
+			name:     "success: PTR query for host with a period",
+			host:     "3.3.3.3.in-addr.arpa.",
+			qtype:    dns.TypePTR,
+			expected: []dns.RR{ptr("3.3.3.3.in-addr.arpa.", "example.localhost.")},
+		},
 	}
 
 	clients := []dns.Client{
@@ -373,6 +411,9 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 				if tt.queryAAAA {
 					q = dns.TypeAAAA
 				}
+				if tt.qtype != 0 {
+					q = tt.qtype
+				}
 				m.SetQuestion(tt.host, q)
 				if tt.modifyReq != nil {
 					tt.modifyReq(m)
@@ -589,7 +630,7 @@ func makeUpstream(t test.Failer, responses map[string]string) string {
 
 func initDNS(t test.Failer, forwardToUpstreamParallel bool) *LocalDNSServer {
 	srv := makeUpstream(t, map[string]string{"www.bing.com.": "1.1.1.1"})
-	testAgentDNS, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", forwardToUpstreamParallel)
+	testAgentDNS, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", forwardToUpstreamParallel, CacheOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -613,6 +654,7 @@ func fillTable(server *LocalDNSServer) {
 				Registry:  "Kubernetes",
 				Namespace: "ns1",
 				Shortname: "productpage",
+				Ports:     []*dnsProto.NameTable_Port{{Name: "http", Port: 9080, Protocol: "HTTP"}},
 			},
 			"example.ns2.svc.cluster.local": {
 				Ips:       []string{"10.10.10.10"},
@@ -646,6 +688,7 @@ func fillTable(server *LocalDNSServer) {
 			"ipv4.localhost": {
 				Ips:      []string{"2.2.2.2"},
 				Registry: "External",
+				Ports:    []*dnsProto.NameTable_Port{{Name: "dns", Port: 53, Protocol: "UDP"}},
 			},
 			"*.b.wildcard": {
 				Ips:      []string{"11.11.11.11"},
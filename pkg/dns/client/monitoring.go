@@ -34,6 +34,16 @@ var (
 		"Total number of DNS failures.",
 	)
 
+	cacheHits = monitoring.NewSum(
+		"dns_upstream_cache_hits_total",
+		"Total number of DNS requests that would be forwarded to upstream, served from the response cache.",
+	)
+
+	cacheMisses = monitoring.NewSum(
+		"dns_upstream_cache_misses_total",
+		"Total number of DNS requests not found in the response cache.",
+	)
+
 	requestDuration = monitoring.NewDistribution(
 		"dns_upstream_request_duration_seconds",
 		"Total time in seconds Istio takes to get DNS response from upstream.",
@@ -117,6 +117,7 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 			nameInfo := &dnsProto.NameTable_NameInfo{
 				Ips:      addressList,
 				Registry: string(svc.Attributes.ServiceRegistry),
+				Ports:    namePorts(svc.Ports),
 			}
 			if svc.Attributes.ServiceRegistry == provider.Kubernetes &&
 				!strings.HasSuffix(hostName.String(), "."+constants.DefaultClusterSetLocalDomain) {
@@ -136,14 +137,49 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 			if svc.Attributes.ServiceRegistry == provider.Kubernetes {
 				ni.Ips = addressList
 				ni.Registry = string(provider.Kubernetes)
+				ni.Ports = namePorts(svc.Ports)
 				if !strings.HasSuffix(hostName.String(), "."+constants.DefaultClusterSetLocalDomain) {
 					ni.Namespace = svc.Attributes.Namespace
 					ni.Shortname = svc.Attributes.Name
 				}
 			} else {
 				ni.Ips = append(ni.Ips, addressList...)
+				ni.Ports = mergePorts(ni.Ports, namePorts(svc.Ports))
 			}
 		}
 	}
 	return out
 }
+
+// namePorts converts the service ports to the name table representation, used by the agent for SRV records.
+func namePorts(ports model.PortList) []*dnsProto.NameTable_Port {
+	if len(ports) == 0 {
+		return nil
+	}
+	out := make([]*dnsProto.NameTable_Port, 0, len(ports))
+	for _, p := range ports {
+		out = append(out, &dnsProto.NameTable_Port{
+			Name:     p.Name,
+			Port:     uint32(p.Port),
+			Protocol: string(p.Protocol),
+		})
+	}
+	return out
+}
+
+// mergePorts appends the ports of b that are not already present in a.
+func mergePorts(a, b []*dnsProto.NameTable_Port) []*dnsProto.NameTable_Port {
+	for _, p := range b {
+		found := false
+		for _, existing := range a {
+			if existing.Name == p.Name && existing.Port == p.Port {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, p)
+		}
+	}
+	return a
+}
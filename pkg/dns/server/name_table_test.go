@@ -192,6 +192,13 @@ func TestNameTable(t *testing.T) {
 	decoratedService.DefaultAddress = "10.0.0.7"
 	decoratedService.Attributes.ServiceRegistry = provider.Kubernetes
 
+	headlessPorts := []*dnsProto.NameTable_Port{{Name: "tcp-port", Port: 9000, Protocol: "TCP"}}
+	wildcardPorts := []*dnsProto.NameTable_Port{
+		{Name: "tcp-port", Port: 9000, Protocol: "TCP"},
+		{Name: "http-port", Port: 8000, Protocol: "HTTP"},
+	}
+	mysqlPorts := []*dnsProto.NameTable_Port{{Name: "tcp", Port: 3306, Protocol: "TCP"}}
+
 	push := model.NewPushContext()
 	push.Mesh = mesh
 	push.AddPublicServices([]*model.Service{headlessService})
@@ -266,6 +273,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -299,6 +307,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -338,6 +347,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -378,6 +388,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -393,6 +404,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "wildcard-svc",
 						Namespace: "testns",
+						Ports:     wildcardPorts,
 					},
 				},
 			},
@@ -414,6 +426,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "9.6.7.8", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
@@ -427,6 +440,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
@@ -440,6 +454,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
@@ -458,6 +473,7 @@ func TestNameTable(t *testing.T) {
 					serviceWithVIP1.Hostname.String(): {
 						Ips:      []string{serviceWithVIP1.DefaultAddress},
 						Registry: provider.External.String(),
+						Ports:    mysqlPorts,
 					},
 				},
 			},
@@ -478,6 +494,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  provider.Kubernetes.String(),
 						Shortname: decoratedService.Attributes.Name,
 						Namespace: decoratedService.Attributes.Namespace,
+						Ports:     mysqlPorts,
 					},
 				},
 			},
@@ -498,6 +515,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  provider.Kubernetes.String(),
 						Shortname: decoratedService.Attributes.Name,
 						Namespace: decoratedService.Attributes.Namespace,
+						Ports:     mysqlPorts,
 					},
 				},
 			},
@@ -176,6 +176,8 @@ type AgentOptions struct {
 	DNSAddr string
 	// DNSForwardParallel indicates whether the agent should send parallel DNS queries to all upstream nameservers.
 	DNSForwardParallel bool
+	// DNSCache configures the cache of responses from the upstream nameservers.
+	DNSCache dnsClient.CacheOptions
 	// ProxyType is the type of proxy we are configured to handle
 	ProxyType model.NodeType
 	// ProxyNamespace to use for local dns resolution
@@ -525,7 +527,7 @@ func (a *Agent) initLocalDNSServer() (err error) {
 	// we don't need dns server on gateways
 	if a.cfg.DNSCapture && a.cfg.ProxyType == model.SidecarProxy {
 		if a.localDNSServer, err = dnsClient.NewLocalDNSServer(a.cfg.ProxyNamespace, a.cfg.ProxyDomain, a.cfg.DNSAddr,
-			a.cfg.DNSForwardParallel); err != nil {
+			a.cfg.DNSForwardParallel, a.cfg.DNSCache); err != nil {
 			return err
 		}
 		a.localDNSServer.StartDNS()
@@ -576,7 +578,9 @@ func (a *Agent) GetDNSTable() *dnsProto.NameTable {
 	if a.localDNSServer != nil && a.localDNSServer.NameTable() != nil {
 		nt := a.localDNSServer.NameTable()
 		nt = proto.Clone(nt).(*dnsProto.NameTable)
-		a.localDNSServer.BuildAlternateHosts(nt, func(althosts map[string]struct{}, ipv4 []netip.Addr, ipv6 []netip.Addr, _ []string) {
+		a.localDNSServer.BuildAlternateHosts(nt, func(_ string, althosts map[string]struct{}, ipv4 []netip.Addr, ipv6 []netip.Addr,
+			ports []*dnsProto.NameTable_Port, _ []string,
+		) {
 			for host := range althosts {
 				if _, exists := nt.Table[host]; !exists {
 					addresses := make([]string, 0, len(ipv4)+len(ipv6))
@@ -589,6 +593,7 @@ func (a *Agent) GetDNSTable() *dnsProto.NameTable {
 					nt.Table[host] = &dnsProto.NameTable_NameInfo{
 						Ips:      addresses,
 						Registry: "Kubernetes",
+						Ports:    ports,
 					}
 				}
 			}
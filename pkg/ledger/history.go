@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"sync"
+	"time"
+)
+
+// RootHash is a state of the Ledger, identified by its root hash.
+type RootHash struct {
+	Hash string `json:"hash"`
+	// Time at which the Ledger entered this state.
+	Time time.Time `json:"time"`
+}
+
+// rootHistory records the root hashes of the Ledger over time. Root hashes are dropped after the retention,
+// as the values of a previous state can no longer be retrieved once its nodes have expired.
+type rootHistory struct {
+	mu        sync.Mutex
+	retention time.Duration
+	roots     []RootHash
+	now       func() time.Time
+}
+
+func newRootHistory(retention time.Duration) *rootHistory {
+	return &rootHistory{retention: retention, now: time.Now}
+}
+
+func (h *rootHistory) record(hash string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n := len(h.roots); n > 0 && h.roots[n-1].Hash == hash {
+		return
+	}
+	now := h.now()
+	h.roots = append(h.roots, RootHash{Hash: hash, Time: now})
+	// A state stays retrievable until the retention has elapsed after it was replaced, so the current
+	// state is always kept.
+	expired := 0
+	for expired < len(h.roots)-1 && now.Sub(h.roots[expired+1].Time) > h.retention {
+		expired++
+	}
+	h.roots = h.roots[expired:]
+}
+
+func (h *rootHistory) list() []RootHash {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]RootHash(nil), h.roots...)
+}
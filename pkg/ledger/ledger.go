@@ -40,15 +40,18 @@ type Ledger interface {
 	RootHash() string
 	// GetPreviousValue executes a get against a previous version of the ledger, using that version's root hash.
 	GetPreviousValue(previousRootHash, key string) (result string, err error)
+	// History returns the previous states of the Ledger which are still retained, oldest first.
+	History() []RootHash
 }
 
 type smtLedger struct {
-	tree *smt
+	tree    *smt
+	history *rootHistory
 }
 
 // Make returns a Ledger which will retain previous nodes after they are deleted.
 func Make(retention time.Duration) Ledger {
-	return smtLedger{tree: newSMT(hasher, nil, retention)}
+	return smtLedger{tree: newSMT(hasher, nil, retention), history: newRootHistory(retention)}
 }
 
 // Put adds a key value pair to the ledger, overwriting previous values and marking them for
@@ -56,12 +59,14 @@ func Make(retention time.Duration) Ledger {
 func (s smtLedger) Put(key, value string) (result string, err error) {
 	b, err := s.tree.Update([][]byte{coerceKeyToHashLen(key)}, [][]byte{coerceToHashLen(value)})
 	result = string(b)
+	s.recordRoot()
 	return
 }
 
 // Delete removes a key value pair from the ledger, marking it for removal after the retention specified in Make()
 func (s smtLedger) Delete(key string) (err error) {
-	_, err = s.tree.Update([][]byte{[]byte(key)}, [][]byte{defaultLeaf})
+	_, err = s.tree.Update([][]byte{coerceKeyToHashLen(key)}, [][]byte{defaultLeaf})
+	s.recordRoot()
 	return
 }
 
@@ -88,6 +93,20 @@ func (s smtLedger) Get(key string) (result string, err error) {
 	return s.GetPreviousValue(s.RootHash(), key)
 }
 
+// History returns the root hashes of the ledger still retained, oldest first.
+func (s smtLedger) History() []RootHash {
+	if s.history == nil {
+		return nil
+	}
+	return s.history.list()
+}
+
+func (s smtLedger) recordRoot() {
+	if s.history != nil {
+		s.history.record(s.RootHash())
+	}
+}
+
 // RootHash represents the hash of the current state of the ledger.
 func (s smtLedger) RootHash() string {
 	return base64.StdEncoding.EncodeToString(s.tree.Root())
@@ -162,3 +162,72 @@ func addConfig(ledger Ledger, b *testing.B) string {
 	assert.NoError(b, err)
 	return objectID
 }
+
+func TestPutDeleteGet(t *testing.T) {
+	for _, key := range []string{"foo", "virtual-service/frontend/default"} {
+		t.Run(key, func(t *testing.T) {
+			l := Make(time.Minute)
+			_, err := l.Put("bar", "1")
+			assert.NoError(t, err)
+			empty := l.RootHash()
+
+			_, err = l.Put(key, "2")
+			assert.NoError(t, err)
+			written := l.RootHash()
+			res, err := l.Get(key)
+			assert.NoError(t, err)
+			assert.Equal(t, res, "2")
+
+			// Deleting a key restores the state of the ledger without it.
+			assert.NoError(t, l.Delete(key))
+			assert.Equal(t, l.RootHash(), empty)
+			res, err = l.Get(key)
+			assert.NoError(t, err)
+			assert.Equal(t, res, "")
+			res, err = l.GetPreviousValue(written, key)
+			assert.NoError(t, err)
+			assert.Equal(t, res, "2")
+			res, err = l.Get("bar")
+			assert.NoError(t, err)
+			assert.Equal(t, res, "1")
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	l := Make(time.Minute).(smtLedger)
+	now := time.Now()
+	l.history.now = func() time.Time { return now }
+
+	assert.Equal(t, len(l.History()), 0)
+	_, err := l.Put("foo", "1")
+	assert.NoError(t, err)
+	first := l.RootHash()
+	now = now.Add(time.Second)
+	_, err = l.Put("foo", "2")
+	assert.NoError(t, err)
+	// Writing the same value does not change the state of the ledger.
+	_, err = l.Put("foo", "2")
+	assert.NoError(t, err)
+	now = now.Add(time.Second)
+	assert.NoError(t, l.Delete("foo"))
+
+	history := l.History()
+	assert.Equal(t, len(history), 3)
+	assert.Equal(t, history[0].Hash, first)
+	assert.Equal(t, history[2].Hash, l.RootHash())
+	res, err := l.GetPreviousValue(history[1].Hash, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, res, "2")
+	res, err = l.Get("foo")
+	assert.NoError(t, err)
+	assert.Equal(t, res != "2", true)
+
+	// States replaced for longer than the retention are dropped, but the current state is kept.
+	now = now.Add(2 * time.Minute)
+	_, err = l.Put("bar", "1")
+	assert.NoError(t, err)
+	history = l.History()
+	assert.Equal(t, len(history), 2)
+	assert.Equal(t, history[1].Hash, l.RootHash())
+}
@@ -291,6 +291,18 @@ type NodeMetadata struct {
 	// This depends on DNSCapture.
 	DNSAutoAllocate StringBool `json:"DNS_AUTO_ALLOCATE,omitempty"`
 
+	// DNSCacheSize is the maximum number of upstream DNS responses cached by the DNS proxy of the agent.
+	// Caching is disabled if unset or 0. This depends on DNSCapture.
+	DNSCacheSize string `json:"DNS_CACHE_SIZE,omitempty"`
+
+	// DNSCacheMaxTTL caps the time an upstream DNS response is cached, as a duration such as "5m".
+	// Defaults to 5 minutes.
+	DNSCacheMaxTTL string `json:"DNS_CACHE_MAX_TTL,omitempty"`
+
+	// DNSCacheMaxNegativeTTL caps the time an upstream NXDOMAIN or empty DNS response is cached,
+	// as a duration such as "30s". Defaults to 30 seconds.
+	DNSCacheMaxNegativeTTL string `json:"DNS_CACHE_MAX_NEGATIVE_TTL,omitempty"`
+
 	// EnableHBONE, if set, will enable generation of HBONE listener config.
 	// Note: this only impacts sidecars; ztunnel and waypoint proxy unconditionally use HBONE.
 	EnableHBONE StringBool `json:"ENABLE_HBONE,omitempty"`
@@ -187,12 +187,16 @@ type Options struct {
 	ClusterID string
 
 	// The type of Elliptical Signature algorithm to use
-	// when generating private keys. Currently only ECDSA is supported.
+	// when generating private keys. ECDSA and ED25519 are supported.
 	ECCSigAlg string
 
-	// The type of curve to use when generating private keys with ECC. Currently only ECDSA is supported.
+	// The type of curve to use when generating private keys with ECDSA.
 	ECCCurve string
 
+	// The signature scheme to use when generating RSA private keys. If set to PSS, the CSR is signed with
+	// RSASSA-PSS, and the CA is requested to sign the certificate with RSASSA-PSS.
+	RSASigAlg string
+
 	// FileMountedCerts indicates whether the proxy is using file
 	// mounted certs created by a foreign CA. Refresh is managed by the external
 	// CA, by updating the Secret or VM file. We will watch the file for changes
@@ -402,6 +406,8 @@ func (ac *AuthContext) Header(header string) []string {
 type Caller struct {
 	AuthSource AuthSource
 	Identities []string
+	// AuthenticatorType is the type of the Authenticator which authenticated the caller.
+	AuthenticatorType string
 
 	KubernetesInfo KubernetesInfo
 }
@@ -439,6 +445,7 @@ func (am *authenticationManager) authenticate(ctx context.Context) *Caller {
 		u, err := authn.Authenticate(req)
 		if u != nil && len(u.Identities) > 0 && err == nil {
 			securityLog.Debugf("Authentication successful through auth source %v", u.AuthSource)
+			u.AuthenticatorType = authn.AuthenticatorType()
 			return u
 		}
 		am.authFailMsgs = append(am.authFailMsgs, fmt.Sprintf("Authenticator %s: %v", authn.AuthenticatorType(), err))
@@ -90,6 +90,25 @@ type bundleDoc struct {
 	RefreshHint int    `json:"spiffe_refresh_hint,omitempty"`
 }
 
+// MarshalBundle encodes the X.509 authorities of a trust domain as a SPIFFE bundle, the JWKS document served by
+// SPIFFE bundle endpoints. The sequence must increase whenever the authorities change, and refreshHint tells
+// consumers how often to poll the bundle endpoint.
+func MarshalBundle(certs []*x509.Certificate, sequence uint64, refreshHint time.Duration) ([]byte, error) {
+	doc := bundleDoc{
+		JSONWebKeySet: jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(certs))},
+		Sequence:      sequence,
+		RefreshHint:   int(refreshHint.Seconds()),
+	}
+	for _, cert := range certs {
+		doc.Keys = append(doc.Keys, jose.JSONWebKey{
+			Key:          cert.PublicKey,
+			Certificates: []*x509.Certificate{cert},
+			Use:          "x509-svid",
+		})
+	}
+	return json.Marshal(doc)
+}
+
 func SetTrustDomain(value string) {
 	// Replace special characters in spiffe
 	v := strings.Replace(value, "@", ".", -1)
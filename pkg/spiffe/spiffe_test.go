@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
@@ -332,6 +333,45 @@ func TestRetrieveSpiffeBundleRootCerts(t *testing.T) {
 	}
 }
 
+func TestMarshalBundle(t *testing.T) {
+	h := &handler{statusCode: http.StatusOK}
+	s := httptest.NewTLSServer(h)
+	defer s.Close()
+	caCertPool := x509.NewCertPool()
+	caCertPool.AddCert(s.Certificate())
+
+	body, err := MarshalBundle([]*x509.Certificate{s.Certificate()}, 42, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to marshal bundle: %v", err)
+	}
+	doc := new(bundleDoc)
+	if err := json.Unmarshal(body, doc); err != nil {
+		t.Fatalf("failed to unmarshal bundle: %v", err)
+	}
+	if doc.Sequence != 42 || doc.RefreshHint != 300 {
+		t.Errorf("got sequence %d and refresh hint %d; wanted 42 and 300", doc.Sequence, doc.RefreshHint)
+	}
+
+	// The bundle can be consumed by a SPIFFE bundle endpoint client.
+	h.body = body
+	rootCertMap, err := RetrieveSpiffeBundleRootCerts(map[string]string{"foo": s.Listener.Addr().String()}, caCertPool, time.Millisecond*50)
+	if err != nil {
+		t.Fatalf("failed to retrieve bundle: %v", err)
+	}
+	if certs := rootCertMap["foo"]; len(certs) != 1 || !certs[0].Equal(s.Certificate()) {
+		t.Errorf("got certs %v; wanted the server certificate", certs)
+	}
+
+	// An empty bundle still has a keys member.
+	body, err = MarshalBundle(nil, 1, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to marshal bundle: %v", err)
+	}
+	if !strings.Contains(string(body), `"keys":[]`) {
+		t.Errorf("got %s; wanted an empty keys member", body)
+	}
+}
+
 // TestVerifyPeerCert tests VerifyPeerCert is effective at the client side, using a TLS server.
 func TestGetGeneralCertPoolAndVerifyPeerCert(t *testing.T) {
 	validRootCert := string(util.ReadFile(t, validRootCertFile1))
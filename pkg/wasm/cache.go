@@ -19,6 +19,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -62,6 +63,11 @@ type LocalFileCache struct {
 	// directory path used to store Wasm module.
 	dir string
 
+	// total size in bytes of the cached Wasm modules.
+	size int64
+	// indexDirty is set when the cache index changed since it was last persisted.
+	indexDirty bool
+
 	// mux is needed because stale Wasm module files will be purged periodically.
 	mux sync.Mutex
 
@@ -105,6 +111,12 @@ type cacheEntry struct {
 	last time.Time
 	// set of URLs referencing this entry
 	referencingURLs sets.String
+	// Hex-encoded sha256 checksum of the module file.
+	checksum string
+	// Size of the module file in bytes.
+	size int64
+	// Fingerprint of the public keys the module signature was verified with, empty if it was not verified.
+	verifiedBy string
 }
 
 type cacheOptions struct {
@@ -133,6 +145,8 @@ func (o cacheOptions) sanitize() cacheOptions {
 	if o.HTTPRequestMaxRetries != 0 {
 		ret.HTTPRequestMaxRetries = o.HTTPRequestMaxRetries
 	}
+	ret.PublicKeysFile = o.PublicKeysFile
+	ret.MaxCacheSize = o.MaxCacheSize
 
 	return ret
 }
@@ -154,6 +168,7 @@ func NewLocalFileCache(dir string, options Options) *LocalFileCache {
 		cacheOptions: cacheOptions.sanitize(),
 		stopChan:     make(chan struct{}),
 	}
+	cache.loadIndex()
 
 	go func() {
 		cache.purge()
@@ -185,14 +200,18 @@ func shouldIgnoreResourceVersion(pullPolicy PullPolicy, u *url.URL) bool {
 	}
 }
 
-func getModulePath(baseDir string, mkey moduleKey) (string, error) {
+func moduleFilePath(baseDir string, mkey moduleKey) string {
 	sha := sha256.Sum256([]byte(mkey.name))
 	hashedName := hex.EncodeToString(sha[:])
-	moduleDir := filepath.Join(baseDir, hashedName)
-	if err := os.Mkdir(moduleDir, 0o755); err != nil && !os.IsExist(err) {
+	return filepath.Join(baseDir, hashedName, fmt.Sprintf("%s.wasm", mkey.checksum))
+}
+
+func getModulePath(baseDir string, mkey moduleKey) (string, error) {
+	modulePath := moduleFilePath(baseDir, mkey)
+	if err := os.Mkdir(filepath.Dir(modulePath), 0o755); err != nil && !os.IsExist(err) {
 		return "", err
 	}
-	return filepath.Join(moduleDir, fmt.Sprintf("%s.wasm", mkey.checksum)), nil
+	return modulePath, nil
 }
 
 // Get returns path the local Wasm module file.
@@ -225,19 +244,36 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 	// First check if the cache entry is already downloaded and policy does not require to pull always.
 	ce, checksum := c.getEntry(key, shouldIgnoreResourceVersion(opts.PullPolicy, u))
 	if ce != nil {
-		return ce, nil
+		verified, err := c.verifiedWithTrustedKeys(ce)
+		if err != nil {
+			wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+			return nil, err
+		}
+		if verified {
+			return ce, nil
+		}
+		// The module was cached before signature verification was enabled, or verified with keys
+		// which are no longer trusted. Fetch and verify it again, resolving its tag anew.
+		wasmLog.Debugf("cached Wasm module %s was not verified with the trusted keys, fetching it again", key.downloadURL)
+	} else {
+		key.checksum = checksum
 	}
-	key.checksum = checksum
 	// Fetch the image now as it is not available in cache.
 	var b []byte         // Byte array of Wasm binary.
 	var dChecksum string // Hex-Encoded sha256 checksum of binary.
 	var binaryFetcher func() ([]byte, error)
+	var verifiedBy string // Fingerprint of the keys the signature was verified with.
 	insecure := c.allowInsecure(u.Host)
 
 	ctx, cancel := context.WithTimeout(context.Background(), opts.RequestTimeout)
 	defer cancel()
 	switch u.Scheme {
 	case "http", "https":
+		if c.PublicKeysFile != "" {
+			wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+			return nil, fmt.Errorf("%w: Wasm module %s cannot be verified, only OCI images can be signed",
+				errSignatureVerification, key.downloadURL)
+		}
 		// Download the Wasm module with http fetcher.
 		b, err = c.httpFetcher.Fetch(ctx, key.downloadURL, insecure)
 		if err != nil {
@@ -255,12 +291,24 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 		if opts.PullSecret != nil {
 			imgFetcherOps.PullSecret = opts.PullSecret
 		}
+		if c.PublicKeysFile != "" {
+			imgFetcherOps.PublicKeys, err = loadPublicKeys(c.PublicKeysFile)
+			if err != nil {
+				wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+				return nil, err
+			}
+			verifiedBy = publicKeysFingerprint(imgFetcherOps.PublicKeys)
+		}
 		wasmLog.Debugf("fetching oci image from %s with options: %v", key.downloadURL, imgFetcherOps)
 		fetcher := NewImageFetcher(ctx, imgFetcherOps)
 		binaryFetcher, dChecksum, err = fetcher.PrepareFetch(u.Host + u.Path)
 		if err != nil {
-			wasmRemoteFetchCount.With(resultTag.Value(manifestFailure)).Increment()
-			return nil, fmt.Errorf("could not fetch Wasm OCI image: %v", err)
+			if errors.Is(err, errSignatureVerification) {
+				wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+			} else {
+				wasmRemoteFetchCount.With(resultTag.Value(manifestFailure)).Increment()
+			}
+			return nil, fmt.Errorf("could not fetch Wasm OCI image: %w", err)
 		}
 	default:
 		return nil, fmt.Errorf("unsupported Wasm module downloading URL scheme: %v", u.Scheme)
@@ -270,6 +318,7 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 		key.checksum = dChecksum
 		// check again if the cache is having the checksum.
 		if ce, _ := c.getEntry(key, true); ce != nil {
+			c.markVerified(ce, verifiedBy)
 			return ce, nil
 		}
 	} else if dChecksum != key.checksum {
@@ -293,11 +342,44 @@ func (c *LocalFileCache) getOrFetch(key cacheKey, opts GetOptions) (*cacheEntry,
 	wasmRemoteFetchCount.With(resultTag.Value(fetchSuccess)).Increment()
 
 	key.checksum = dChecksum
-	return c.addEntry(key, b)
+	return c.addEntry(key, b, verifiedBy)
 }
 
-// Cleanup closes background Wasm module purge routine.
+// markVerified records that the signature of the cached module was verified with the keys of the fingerprint.
+// verifiedWithTrustedKeys returns whether the signature of the cached module was verified with the
+// keys currently in PublicKeysFile. Modules are always trusted when no keys are configured.
+func (c *LocalFileCache) verifiedWithTrustedKeys(ce *cacheEntry) (bool, error) {
+	if c.PublicKeysFile == "" {
+		return true, nil
+	}
+	keys, err := loadPublicKeys(c.PublicKeysFile)
+	if err != nil {
+		return false, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return ce.verifiedBy == publicKeysFingerprint(keys), nil
+}
+
+func (c *LocalFileCache) markVerified(ce *cacheEntry, verifiedBy string) {
+	if verifiedBy == "" {
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if ce.verifiedBy != verifiedBy {
+		ce.verifiedBy = verifiedBy
+		c.indexDirty = true
+	}
+}
+
+// Cleanup persists the cache index and closes background Wasm module purge routine.
 func (c *LocalFileCache) Cleanup() {
+	c.mux.Lock()
+	if c.indexDirty {
+		c.saveIndex()
+	}
+	c.mux.Unlock()
 	close(c.stopChan)
 }
 
@@ -313,13 +395,15 @@ func (c *LocalFileCache) updateChecksum(key cacheKey) bool {
 		}
 		ce.checksum = key.checksum
 		ce.resourceVersionByResource[key.resourceName] = key.resourceVersion
+		c.indexDirty = true
 	}
 	return needChecksumUpdate
 }
 
 // addEntry adds a wasmModule to cache with cacheKey, writes the module to the local file system,
-// and returns the created entry.
-func (c *LocalFileCache) addEntry(key cacheKey, wasmModule []byte) (*cacheEntry, error) {
+// and returns the created entry. verifiedBy is the fingerprint of the keys the module signature was
+// verified with, if any.
+func (c *LocalFileCache) addEntry(key cacheKey, wasmModule []byte, verifiedBy string) (*cacheEntry, error) {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 	needChecksumUpdate := c.updateChecksum(key)
@@ -331,9 +415,23 @@ func (c *LocalFileCache) addEntry(key cacheKey, wasmModule []byte) (*cacheEntry,
 		if needChecksumUpdate {
 			ce.referencingURLs.Insert(key.downloadURL)
 		}
+		if verifiedBy != "" {
+			ce.verifiedBy = verifiedBy
+		}
+		c.saveIndex()
 		return ce, nil
 	}
 
+	size := int64(len(wasmModule))
+	if c.MaxCacheSize > 0 {
+		if size > c.MaxCacheSize {
+			return nil, fmt.Errorf("module %s of %d bytes exceeds the cache size limit of %d bytes",
+				key.downloadURL, size, c.MaxCacheSize)
+		}
+		// Make room for the new module.
+		c.evict(c.MaxCacheSize - size)
+	}
+
 	modulePath, err := getModulePath(c.dir, key.moduleKey)
 	if err != nil {
 		return nil, err
@@ -343,19 +441,64 @@ func (c *LocalFileCache) addEntry(key cacheKey, wasmModule []byte) (*cacheEntry,
 		return nil, err
 	}
 
+	sha := sha256.Sum256(wasmModule)
 	ce := cacheEntry{
 		modulePath:      modulePath,
 		last:            time.Now(),
 		referencingURLs: sets.New[string](),
+		checksum:        hex.EncodeToString(sha[:]),
+		size:            size,
+		verifiedBy:      verifiedBy,
 	}
 	if needChecksumUpdate {
 		ce.referencingURLs.Insert(key.downloadURL)
 	}
 	c.modules[key.moduleKey] = &ce
-	wasmCacheEntries.Record(float64(len(c.modules)))
+	c.size += size
+	c.recordCacheMetrics()
+	c.saveIndex()
 	return &ce, nil
 }
 
+// evict removes the least recently used modules until the total size of the cache is at most limit.
+// It must be called with the mutex held.
+func (c *LocalFileCache) evict(limit int64) {
+	for c.size > limit && len(c.modules) > 0 {
+		var lruKey moduleKey
+		var lru *cacheEntry
+		for k, m := range c.modules {
+			if lru == nil || m.last.Before(lru.last) {
+				lruKey, lru = k, m
+			}
+		}
+		if err := c.removeEntry(lruKey, lru); err != nil {
+			wasmLog.Errorf("failed to evict Wasm module %v: %v", lru.modulePath, err)
+			return
+		}
+		wasmCacheEvictions.Increment()
+		wasmLog.Debugf("evicted least recently used Wasm module %v", lru.modulePath)
+	}
+}
+
+// removeEntry deletes the module from the map as well as the local dir. It must be called with the mutex held.
+func (c *LocalFileCache) removeEntry(k moduleKey, m *cacheEntry) error {
+	if err := os.Remove(m.modulePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for downloadURL := range m.referencingURLs {
+		delete(c.checksums, downloadURL)
+	}
+	delete(c.modules, k)
+	c.size -= m.size
+	c.indexDirty = true
+	return nil
+}
+
+func (c *LocalFileCache) recordCacheMetrics() {
+	wasmCacheEntries.Record(float64(len(c.modules)))
+	wasmCacheSize.Record(float64(c.size))
+}
+
 // getEntry finds a cached module, and returns the found cache entry and its checksum.
 func (c *LocalFileCache) getEntry(key cacheKey, ignoreResourceVersion bool) (*cacheEntry, string) {
 	cacheHit := false
@@ -393,6 +536,7 @@ func (c *LocalFileCache) getEntry(key cacheKey, ignoreResourceVersion bool) (*ca
 	if ce, ok := c.modules[key.moduleKey]; ok {
 		// Update last touched time.
 		ce.last = time.Now()
+		c.indexDirty = true
 		cacheHit = true
 		c.updateChecksum(key)
 		return ce, key.checksum
@@ -413,17 +557,17 @@ func (c *LocalFileCache) purge() {
 					continue
 				}
 				// The module has not be touched for expiry duration, delete it from the map as well as the local dir.
-				if err := os.Remove(m.modulePath); err != nil {
+				if err := c.removeEntry(k, m); err != nil {
 					wasmLog.Errorf("failed to purge Wasm module %v: %v", m.modulePath, err)
 				} else {
-					for downloadURL := range m.referencingURLs {
-						delete(c.checksums, downloadURL)
-					}
-					delete(c.modules, k)
 					wasmLog.Debugf("successfully removed stale Wasm module %v", m.modulePath)
 				}
 			}
-			wasmCacheEntries.Record(float64(len(c.modules)))
+			c.recordCacheMetrics()
+			// Persist the last use times of the modules along with purged modules.
+			if c.indexDirty {
+				c.saveIndex()
+			}
 			c.mux.Unlock()
 		case <-c.stopChan:
 			// Currently this will only happen in test.
@@ -39,6 +39,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 
+	"istio.io/istio/pkg/test/util/assert"
 	"istio.io/istio/pkg/util/sets"
 )
 
@@ -747,7 +748,7 @@ func TestWasmCache(t *testing.T) {
 			}
 
 			if diff := cmp.Diff(c.wantCachedModules, cache.modules,
-				cmpopts.IgnoreFields(cacheEntry{}, "last", "referencingURLs"),
+				cmpopts.IgnoreFields(cacheEntry{}, "last", "referencingURLs", "checksum", "size"),
 				cmp.AllowUnexported(cacheEntry{}),
 			); diff != "" {
 				t.Errorf("unexpected module cache: (-want, +got)\n%v", diff)
@@ -957,3 +958,78 @@ func generateModulePath(t *testing.T, baseDir, resourceName, filename string) st
 	}
 	return filepath.Join(moduleDir, filename)
 }
+
+func TestWasmCacheRestoreIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	gotNumRequest := 0
+	binary := append(wasmHeader, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotNumRequest++
+		w.Write(binary)
+	}))
+	defer ts.Close()
+	opts := GetOptions{RequestTimeout: time.Second * 10}
+
+	cache := NewLocalFileCache(tmpDir, defaultOptions())
+	wantPath, err := cache.Get(ts.URL, opts)
+	assert.NoError(t, err)
+	cache.Cleanup()
+
+	// A new cache, as created on agent restart, reuses the module already on disk.
+	cache = NewLocalFileCache(tmpDir, defaultOptions())
+	gotPath, err := cache.Get(ts.URL, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, gotPath, wantPath)
+	assert.Equal(t, gotNumRequest, 1)
+	cache.Cleanup()
+
+	// A module modified on disk is dropped and downloaded again.
+	assert.NoError(t, os.WriteFile(wantPath, append(wasmHeader, 2), 0o644))
+	cache = NewLocalFileCache(tmpDir, defaultOptions())
+	assert.Equal(t, len(cache.modules), 0)
+	_, err = cache.Get(ts.URL, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, gotNumRequest, 2)
+	cache.Cleanup()
+}
+
+func TestWasmCacheSizeLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Each module is 10 bytes and unique per path.
+		w.Write(append(wasmHeader, r.URL.Path[1], 0))
+	}))
+	defer ts.Close()
+	opts := GetOptions{RequestTimeout: time.Second * 10}
+	options := defaultOptions()
+	options.MaxCacheSize = 25
+	cache := NewLocalFileCache(tmpDir, options)
+	defer cache.Cleanup()
+
+	pathA, err := cache.Get(ts.URL+"/a", opts)
+	assert.NoError(t, err)
+	pathB, err := cache.Get(ts.URL+"/b", opts)
+	assert.NoError(t, err)
+	// Touch a, so that b is the least recently used module.
+	_, err = cache.Get(ts.URL+"/a", opts)
+	assert.NoError(t, err)
+	pathC, err := cache.Get(ts.URL+"/c", opts)
+	assert.NoError(t, err)
+
+	for path, exists := range map[string]bool{pathA: true, pathB: false, pathC: true} {
+		_, err := os.Stat(path)
+		assert.Equal(t, err == nil, exists)
+	}
+	cache.mux.Lock()
+	assert.Equal(t, cache.size, int64(20))
+	assert.Equal(t, len(cache.modules), 2)
+	_, found := cache.checksums[ts.URL+"/b"]
+	assert.Equal(t, found, false)
+	cache.mux.Unlock()
+
+	options.MaxCacheSize = 5
+	small := NewLocalFileCache(t.TempDir(), options)
+	defer small.Cleanup()
+	_, err = small.Get(ts.URL+"/a", opts)
+	assert.Error(t, err)
+}
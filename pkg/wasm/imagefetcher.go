@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -42,9 +43,11 @@ import (
 // Basically, this supports fetching and unpackaging three types of container images containing a Wasm binary.
 
 type ImageFetcherOption struct {
-	// TODO(mathetake) Add signature verification stuff.
 	PullSecret []byte
 	Insecure   bool
+	// PublicKeys, if set, are used to verify the cosign signature of the image. Images without a signature
+	// made by one of the keys are rejected.
+	PublicKeys []crypto.PublicKey
 }
 
 func (o *ImageFetcherOption) useDefaultKeyChain() bool {
@@ -59,7 +62,8 @@ func (o ImageFetcherOption) String() string {
 }
 
 type ImageFetcher struct {
-	fetchOpts []remote.Option
+	fetchOpts  []remote.Option
+	publicKeys []crypto.PublicKey
 }
 
 func NewImageFetcher(ctx context.Context, opt ImageFetcherOption) *ImageFetcher {
@@ -84,7 +88,8 @@ func NewImageFetcher(ctx context.Context, opt ImageFetcherOption) *ImageFetcher
 	}
 
 	return &ImageFetcher{
-		fetchOpts: append(fetchOpts, remote.WithContext(ctx)),
+		fetchOpts:  append(fetchOpts, remote.WithContext(ctx)),
+		publicKeys: opt.PublicKeys,
 	}
 }
 
@@ -125,6 +130,11 @@ func (o *ImageFetcher) PrepareFetch(url string) (binaryFetcher func() ([]byte, e
 
 	// Check Manifest's digest if expManifestDigest is not empty.
 	d, _ := img.Digest()
+	if len(o.publicKeys) > 0 {
+		if err = verifySignature(ref.Context(), d, o.publicKeys, o.fetchOpts...); err != nil {
+			return
+		}
+	}
 	actualDigest = d.Hex
 	binaryFetcher = func() ([]byte, error) {
 		manifest, err := img.Manifest()
@@ -0,0 +1,170 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+// indexFileName is the name of the file, in the cache directory, persisting the cache index across agent restarts.
+const indexFileName = "wasm-cache-index.json"
+
+// cacheIndex is the persisted form of the LocalFileCache index.
+type cacheIndex struct {
+	Modules []indexEntry `json:"modules"`
+}
+
+type indexEntry struct {
+	// Name of the module, see moduleKey.
+	Name string `json:"name"`
+	// Digest of the module, the key of the module in the cache. For OCI images this is the image digest.
+	Digest string `json:"digest"`
+	// Checksum is the sha256 checksum of the module file, used to detect files modified or truncated on disk.
+	Checksum string `json:"checksum"`
+	Size     int64  `json:"size"`
+	// LastUse is the last time the module was referenced.
+	LastUse time.Time `json:"lastUse"`
+	// URLs are the tagged URLs resolved to this module.
+	URLs []string `json:"urls,omitempty"`
+	// VerifiedBy is the fingerprint of the public keys the module signature was verified with,
+	// empty if the signature was not verified.
+	VerifiedBy string `json:"verifiedBy,omitempty"`
+}
+
+func (c *LocalFileCache) indexPath() string {
+	return filepath.Join(c.dir, indexFileName)
+}
+
+// loadIndex restores the cache entries persisted by a previous agent. Modules whose files are missing or
+// do not match their recorded checksum are dropped. When signature verification is enabled, so are the
+// modules that were not verified with the currently trusted keys, so that they are fetched and verified again.
+func (c *LocalFileCache) loadIndex() {
+	b, err := os.ReadFile(c.indexPath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			wasmLog.Warnf("failed to read Wasm cache index: %v", err)
+		}
+		return
+	}
+	index := cacheIndex{}
+	if err := json.Unmarshal(b, &index); err != nil {
+		wasmLog.Warnf("failed to parse Wasm cache index, ignoring it: %v", err)
+		return
+	}
+
+	trustedKeys := ""
+	if c.PublicKeysFile != "" {
+		keys, err := loadPublicKeys(c.PublicKeysFile)
+		if err != nil {
+			wasmLog.Warnf("failed to load Wasm public keys, dropping the cache index: %v", err)
+		} else {
+			trustedKeys = publicKeysFingerprint(keys)
+		}
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, e := range index.Modules {
+		key := moduleKey{name: e.Name, checksum: e.Digest}
+		modulePath := moduleFilePath(c.dir, key)
+		if c.PublicKeysFile != "" && (trustedKeys == "" || e.VerifiedBy != trustedKeys) {
+			wasmLog.Infof("dropping Wasm module %s from cache index: signature not verified with the trusted keys", e.Name)
+			if err := os.Remove(modulePath); err != nil && !os.IsNotExist(err) {
+				wasmLog.Errorf("failed to remove Wasm module %v: %v", modulePath, err)
+			}
+			continue
+		}
+		module, err := os.ReadFile(modulePath)
+		if err != nil {
+			wasmLog.Debugf("dropping Wasm module %s from cache index: %v", e.Name, err)
+			continue
+		}
+		if sha := sha256.Sum256(module); int64(len(module)) != e.Size || hex.EncodeToString(sha[:]) != e.Checksum {
+			wasmLog.Warnf("dropping Wasm module %s from cache index: checksum mismatch", e.Name)
+			if err := os.Remove(modulePath); err != nil {
+				wasmLog.Errorf("failed to remove Wasm module %v: %v", modulePath, err)
+			}
+			continue
+		}
+		c.modules[key] = &cacheEntry{
+			modulePath:      modulePath,
+			last:            e.LastUse,
+			referencingURLs: sets.New(e.URLs...),
+			checksum:        e.Checksum,
+			size:            e.Size,
+			verifiedBy:      e.VerifiedBy,
+		}
+		c.size += e.Size
+		for _, u := range e.URLs {
+			c.checksums[u] = &checksumEntry{
+				checksum:                  e.Digest,
+				resourceVersionByResource: make(map[string]string),
+			}
+		}
+	}
+	wasmLog.Infof("restored %d Wasm modules (%d bytes) from cache index", len(c.modules), c.size)
+	c.recordCacheMetrics()
+}
+
+// saveIndex persists the cache index. It must be called with the mutex held.
+func (c *LocalFileCache) saveIndex() {
+	c.indexDirty = false
+	if len(c.modules) == 0 {
+		if err := os.Remove(c.indexPath()); err != nil && !os.IsNotExist(err) {
+			wasmLog.Errorf("failed to remove Wasm cache index: %v", err)
+		}
+		return
+	}
+	index := cacheIndex{Modules: make([]indexEntry, 0, len(c.modules))}
+	for k, m := range c.modules {
+		index.Modules = append(index.Modules, indexEntry{
+			Name:       k.name,
+			Digest:     k.checksum,
+			Checksum:   m.checksum,
+			Size:       m.size,
+			LastUse:    m.last,
+			URLs:       sets.SortedList(m.referencingURLs),
+			VerifiedBy: m.verifiedBy,
+		})
+	}
+	sort.Slice(index.Modules, func(i, j int) bool {
+		if index.Modules[i].Name != index.Modules[j].Name {
+			return index.Modules[i].Name < index.Modules[j].Name
+		}
+		return index.Modules[i].Digest < index.Modules[j].Digest
+	})
+	b, err := json.Marshal(index)
+	if err != nil {
+		wasmLog.Errorf("failed to marshal Wasm cache index: %v", err)
+		return
+	}
+	// Write to a temporary file first, so that a crash never leaves a truncated index behind.
+	tmp := c.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		wasmLog.Errorf("failed to write Wasm cache index: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, c.indexPath()); err != nil {
+		wasmLog.Errorf("failed to write Wasm cache index: %v", err)
+	}
+}
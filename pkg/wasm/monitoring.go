@@ -23,6 +23,7 @@ const (
 	downloadFailure  = "download_failure"
 	manifestFailure  = "manifest_failure"
 	checksumMismatch = "checksum_mismatched"
+	signatureFailure = "signature_failure"
 
 	// For Wasm conversion metric.
 	conversionSuccess   = "success"
@@ -42,6 +43,16 @@ var (
 		"number of Wasm remote fetch cache entries.",
 	)
 
+	wasmCacheSize = monitoring.NewGauge(
+		"wasm_cache_size_bytes",
+		"total size in bytes of the Wasm modules in the cache.",
+	)
+
+	wasmCacheEvictions = monitoring.NewSum(
+		"wasm_cache_evictions_total",
+		"number of Wasm modules evicted from the cache to stay under its size limit.",
+	)
+
 	wasmCacheLookupCount = monitoring.NewSum(
 		"wasm_cache_lookup_count",
 		"number of Wasm remote fetch cache lookups.",
@@ -49,7 +60,7 @@ var (
 
 	wasmRemoteFetchCount = monitoring.NewSum(
 		"wasm_remote_fetch_count",
-		"number of Wasm remote fetches and results, including success, download failure, and checksum mismatch.",
+		"number of Wasm remote fetches and results, including success, download failure, checksum mismatch and signature failure.",
 	)
 
 	wasmConfigConversionCount = monitoring.NewSum(
@@ -34,6 +34,12 @@ type Options struct {
 	InsecureRegistries    sets.String
 	HTTPRequestTimeout    time.Duration
 	HTTPRequestMaxRetries int
+	// PublicKeysFile is a file with PEM encoded public keys. If set, only OCI images with a cosign
+	// signature made by one of the keys are loaded, and modules fetched over HTTP are rejected.
+	PublicKeysFile string
+	// MaxCacheSize is the maximum total size in bytes of the cached modules. When adding a module would exceed
+	// it, the least recently used modules are evicted. Zero means no limit.
+	MaxCacheSize int64
 }
 
 func defaultOptions() Options {
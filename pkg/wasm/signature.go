@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// This file implements offline verification of cosign (https://github.com/sigstore/cosign) signatures
+// against a set of trusted public keys. Cosign stores the signatures of an image in the same repository,
+// as an image tagged "sha256-<image digest>.sig". Each layer of that image is a "simple signing" payload
+// referencing the digest of the signed image, with the signature of the payload in an annotation.
+
+const (
+	cosignSignatureMediaType  = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	cosignSignatureType       = "cosign container image signature"
+
+	// Signature payloads are small JSON documents.
+	maxSignaturePayloadSize = 1024 * 1024
+)
+
+// errSignatureVerification is returned when the signature of an image cannot be verified.
+var errSignatureVerification = errors.New("signature verification failed")
+
+// simpleSigning is the payload signed by cosign.
+// https://github.com/containers/image/blob/main/docs/containers-signature.5.md
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// ParsePublicKeys parses the PEM encoded ECDSA, RSA or Ed25519 public keys in data.
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %v", err)
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no public keys found")
+	}
+	return keys, nil
+}
+
+// loadPublicKeys reads the public keys from file. The file is read on every call, so that keys mounted
+// from a Secret can be rotated without restarting the agent.
+func loadPublicKeys(file string) ([]crypto.PublicKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read public keys: %v", errSignatureVerification, err)
+	}
+	keys, err := ParsePublicKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", errSignatureVerification, file, err)
+	}
+	return keys, nil
+}
+
+// publicKeysFingerprint identifies the set of trusted keys, independently of their order and PEM encoding.
+func publicKeysFingerprint(keys []crypto.PublicKey) string {
+	ders := make([]string, 0, len(keys))
+	for _, k := range keys {
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			continue
+		}
+		ders = append(ders, string(der))
+	}
+	sort.Strings(ders)
+	h := sha256.New()
+	for _, der := range ders {
+		h.Write([]byte(der))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func signatureTag(repo name.Repository, digest v1.Hash) name.Tag {
+	return repo.Tag(fmt.Sprintf("%s-%s.sig", digest.Algorithm, digest.Hex))
+}
+
+// verifySignature checks that the image with the given digest in repo has a cosign signature made by one of keys.
+func verifySignature(repo name.Repository, digest v1.Hash, keys []crypto.PublicKey, opts ...remote.Option) error {
+	tag := signatureTag(repo, digest)
+	sigImg, err := remote.Image(tag, opts...)
+	if err != nil {
+		return fmt.Errorf("%w: could not fetch signature %s: %v", errSignatureVerification, tag, err)
+	}
+	manifest, err := sigImg.Manifest()
+	if err != nil {
+		return fmt.Errorf("%w: could not fetch signature manifest: %v", errSignatureVerification, err)
+	}
+	for _, desc := range manifest.Layers {
+		if desc.MediaType != cosignSignatureMediaType {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(desc.Annotations[cosignSignatureAnnotation])
+		if err != nil || len(sig) == 0 {
+			continue
+		}
+		layer, err := sigImg.LayerByDigest(desc.Digest)
+		if err != nil {
+			return fmt.Errorf("%w: could not fetch signature layer: %v", errSignatureVerification, err)
+		}
+		payload, err := readPayload(layer)
+		if err != nil {
+			return fmt.Errorf("%w: could not read signature payload: %v", errSignatureVerification, err)
+		}
+		if !verifyPayload(payload, sig, keys) {
+			continue
+		}
+		ss := simpleSigning{}
+		if err := json.Unmarshal(payload, &ss); err != nil {
+			continue
+		}
+		// The signature is only valid for this image if the signed payload references it.
+		if ss.Critical.Type == cosignSignatureType && ss.Critical.Image.DockerManifestDigest == digest.String() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no signature of %s@%s matches the trusted keys", errSignatureVerification, repo, digest)
+}
+
+func readPayload(layer v1.Layer) ([]byte, error) {
+	r, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, maxSignaturePayloadSize))
+}
+
+// verifyPayload returns true if sig is a signature of payload by any of keys. Cosign signs the
+// SHA-256 digest of the payload, except for Ed25519 keys which sign the payload itself.
+func verifyPayload(payload, sig []byte, keys []crypto.PublicKey) bool {
+	digest := sha256.Sum256(payload)
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest[:], sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil ||
+				rsa.VerifyPSS(k, crypto.SHA256, digest[:], sig, nil) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, payload, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// pushWasmImage pushes a compat variant Wasm image to ref and returns its digest.
+func pushWasmImage(t *testing.T, ref string) v1.Hash {
+	t.Helper()
+	l, err := newMockLayer(types.DockerLayer, map[string][]byte{"plugin.wasm": append(wasmHeader, []byte("signed plugin")...)})
+	assert.NoError(t, err)
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: l})
+	assert.NoError(t, err)
+	assert.NoError(t, crane.Push(img, ref))
+	d, err := img.Digest()
+	assert.NoError(t, err)
+	return d
+}
+
+// pushSignature pushes a cosign signature of signedDigest to the signature tag of imageDigest.
+func pushSignature(t *testing.T, repo string, imageDigest, signedDigest v1.Hash, sign func([]byte) []byte) {
+	t.Helper()
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":%q},"optional":null}`,
+		repo, signedDigest.String(), cosignSignatureType))
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(payload, cosignSignatureMediaType),
+		Annotations: map[string]string{
+			cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sign(payload)),
+		},
+	})
+	assert.NoError(t, err)
+	r, err := name.NewRepository(repo)
+	assert.NoError(t, err)
+	assert.NoError(t, crane.Push(img, signatureTag(r, imageDigest).String()))
+}
+
+func ecdsaSigner(t *testing.T) (*ecdsa.PrivateKey, func([]byte) []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	return key, func(payload []byte) []byte {
+		digest := sha256.Sum256(payload)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		assert.NoError(t, err)
+		return sig
+	}
+}
+
+func encodePublicKey(t *testing.T, key crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	ecKey, _ := ecdsaSigner(t)
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	keys, err := ParsePublicKeys(append(encodePublicKey(t, ecKey.Public()), encodePublicKey(t, edKey)...))
+	assert.NoError(t, err)
+	assert.Equal(t, len(keys), 2)
+
+	_, err = ParsePublicKeys([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestVerifySignature(t *testing.T) {
+	s := httptest.NewServer(registry.New())
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+
+	key, sign := ecdsaSigner(t)
+	_, otherSign := ecdsaSigner(t)
+	keys := []crypto.PublicKey{key.Public()}
+
+	cases := []struct {
+		name     string
+		sign     func(repo string, digest v1.Hash)
+		expected bool
+	}{
+		{
+			name: "signed",
+			sign: func(repo string, digest v1.Hash) {
+				pushSignature(t, repo, digest, digest, sign)
+			},
+			expected: true,
+		},
+		{
+			name: "unsigned",
+			sign: func(string, v1.Hash) {},
+		},
+		{
+			name: "signed by another key",
+			sign: func(repo string, digest v1.Hash) {
+				pushSignature(t, repo, digest, digest, otherSign)
+			},
+		},
+		{
+			name: "signature of another image",
+			sign: func(repo string, digest v1.Hash) {
+				other := v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%064d", 0)}
+				pushSignature(t, repo, digest, other, sign)
+			},
+		},
+	}
+	for i, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := fmt.Sprintf("%s/test/signature%d", u.Host, i)
+			digest := pushWasmImage(t, repo+":v1")
+			tt.sign(repo, digest)
+
+			fetcher := &ImageFetcher{publicKeys: keys}
+			_, actualDigest, err := fetcher.PrepareFetch(repo + ":v1")
+			if tt.expected {
+				assert.NoError(t, err)
+				assert.Equal(t, actualDigest, digest.Hex)
+			} else {
+				assert.Equal(t, errors.Is(err, errSignatureVerification), true)
+			}
+		})
+	}
+}
+
+func TestWasmCacheSignatureVerification(t *testing.T) {
+	s := httptest.NewServer(registry.New())
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+
+	key, sign := ecdsaSigner(t)
+	keysFile := filepath.Join(t.TempDir(), "keys.pem")
+	assert.NoError(t, os.WriteFile(keysFile, encodePublicKey(t, key.Public()), 0o644))
+
+	signed := fmt.Sprintf("%s/test/signed", u.Host)
+	pushSignature(t, signed, pushWasmImage(t, signed+":v1"), pushWasmImage(t, signed+":v1"), sign)
+	unsigned := fmt.Sprintf("%s/test/unsigned", u.Host)
+	pushWasmImage(t, unsigned+":v1")
+
+	cache := NewLocalFileCache(t.TempDir(), Options{PublicKeysFile: keysFile})
+	defer close(cache.stopChan)
+	opts := GetOptions{RequestTimeout: 10 * time.Second}
+
+	_, err = cache.Get("oci://"+signed+":v1", opts)
+	assert.NoError(t, err)
+	_, err = cache.Get("oci://"+unsigned+":v1", opts)
+	assert.Equal(t, errors.Is(err, errSignatureVerification), true)
+	_, err = cache.Get(s.URL+"/plugin.wasm", opts)
+	assert.Equal(t, errors.Is(err, errSignatureVerification), true)
+
+	// Keys are reloaded from the file on every fetch.
+	assert.NoError(t, os.WriteFile(keysFile, []byte("invalid"), 0o644))
+	_, err = cache.Get("oci://"+signed+":v2", opts)
+	assert.Equal(t, errors.Is(err, errSignatureVerification), true)
+}
+
+func TestWasmCacheKeyRotation(t *testing.T) {
+	s := httptest.NewServer(registry.New())
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+
+	key, sign := ecdsaSigner(t)
+	keysFile := filepath.Join(t.TempDir(), "keys.pem")
+	assert.NoError(t, os.WriteFile(keysFile, encodePublicKey(t, key.Public()), 0o644))
+	signed := fmt.Sprintf("%s/test/signed", u.Host)
+	digest := pushWasmImage(t, signed+":v1")
+	pushSignature(t, signed, digest, digest, sign)
+
+	cache := NewLocalFileCache(t.TempDir(), Options{PublicKeysFile: keysFile})
+	defer close(cache.stopChan)
+	opts := GetOptions{RequestTimeout: 10 * time.Second}
+	_, err = cache.Get("oci://"+signed+":v1", opts)
+	assert.NoError(t, err)
+
+	// The cached module was verified with a key which is no longer trusted, so it is verified again.
+	newKey, newSign := ecdsaSigner(t)
+	assert.NoError(t, os.WriteFile(keysFile, encodePublicKey(t, newKey.Public()), 0o644))
+	_, err = cache.Get("oci://"+signed+":v1", opts)
+	assert.Equal(t, errors.Is(err, errSignatureVerification), true)
+
+	// Once signed with the new key, it is served again.
+	pushSignature(t, signed, digest, digest, newSign)
+	_, err = cache.Get("oci://"+signed+":v1", opts)
+	assert.NoError(t, err)
+	_, err = cache.Get("oci://"+signed+"@sha256:"+digest.Hex, opts)
+	assert.NoError(t, err)
+}
+
+func TestWasmCacheRestoreIndexVerification(t *testing.T) {
+	s := httptest.NewServer(registry.New())
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	assert.NoError(t, err)
+
+	key, sign := ecdsaSigner(t)
+	keysFile := filepath.Join(t.TempDir(), "keys.pem")
+	assert.NoError(t, os.WriteFile(keysFile, encodePublicKey(t, key.Public()), 0o644))
+	signed := fmt.Sprintf("%s/test/signed", u.Host)
+	digest := pushWasmImage(t, signed+":v1")
+	pushSignature(t, signed, digest, digest, sign)
+	opts := GetOptions{RequestTimeout: 10 * time.Second}
+	tmpDir := t.TempDir()
+
+	// Modules cached before signature verification was enabled are not restored.
+	cache := NewLocalFileCache(tmpDir, Options{})
+	_, err = cache.Get("oci://"+signed+":v1", opts)
+	assert.NoError(t, err)
+	cache.Cleanup()
+	cache = NewLocalFileCache(tmpDir, Options{PublicKeysFile: keysFile})
+	assert.Equal(t, len(cache.modules), 0)
+	_, err = cache.Get("oci://"+signed+":v1", opts)
+	assert.NoError(t, err)
+	cache.Cleanup()
+
+	// Modules verified with the trusted keys are restored.
+	cache = NewLocalFileCache(tmpDir, Options{PublicKeysFile: keysFile})
+	assert.Equal(t, len(cache.modules), 1)
+	assert.Equal(t, len(cache.checksums), 1)
+	cache.Cleanup()
+
+	// Modules verified with other keys are not.
+	otherKey, _ := ecdsaSigner(t)
+	assert.NoError(t, os.WriteFile(keysFile, encodePublicKey(t, otherKey.Public()), 0o644))
+	cache = NewLocalFileCache(tmpDir, Options{PublicKeysFile: keysFile})
+	assert.Equal(t, len(cache.modules), 0)
+	assert.Equal(t, len(cache.checksums), 0)
+	_, err = cache.Get("oci://"+signed+":v1", opts)
+	assert.Equal(t, errors.Is(err, errSignatureVerification), true)
+	cache.Cleanup()
+}
@@ -591,6 +591,7 @@ func (sc *SecretManagerClient) generateNewSecret(resourceName string) (*security
 		PKCS8Key:   sc.configOptions.Pkcs8Keys,
 		ECSigAlg:   pkiutil.SupportedECSignatureAlgorithms(sc.configOptions.ECCSigAlg),
 		ECCCurve:   pkiutil.SupportedEllipticCurves(sc.configOptions.ECCCurve),
+		RSASigAlg:  pkiutil.SupportedRSASignatureAlgorithms(sc.configOptions.RSASigAlg),
 	}
 
 	// Generate the cert/key, send CSR to CA.
@@ -16,6 +16,8 @@ package ca
 
 import (
 	"context"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/x509"
 	"encoding/pem"
@@ -116,6 +118,9 @@ type IstioCAOptions struct {
 
 	// OnRootCertUpdate is the cb which can only be called by self-signed root cert rotator
 	OnRootCertUpdate func() error
+
+	// RevocationStore persists the certificates revoked by the CA. If nil, certificates cannot be revoked.
+	RevocationStore RevocationStore
 }
 
 type RootCertUpdateFunc func() error
@@ -267,6 +272,20 @@ func NewSelfSignedDebugIstioCAOptions(rootCertFile string, caCertTTL, defaultCer
 // NewPluggedCertIstioCAOptions returns a new IstioCAOptions instance using given certificate.
 func NewPluggedCertIstioCAOptions(fileBundle SigningCAFileBundle,
 	defaultCertTTL, maxCertTTL time.Duration, caRSAKeySize int,
+) (caOpts *IstioCAOptions, err error) {
+	return newPluggedCertIstioCAOptions(fileBundle, nil, defaultCertTTL, maxCertTTL, caRSAKeySize)
+}
+
+// NewPluggedCertIstioCAOptionsWithSigner returns a new IstioCAOptions instance using given certificates, whose signing
+// private key is held by signer, e.g. in an HSM. The SigningKeyFile of the bundle is not used.
+func NewPluggedCertIstioCAOptionsWithSigner(fileBundle SigningCAFileBundle, signer crypto.Signer,
+	defaultCertTTL, maxCertTTL time.Duration, caRSAKeySize int,
+) (caOpts *IstioCAOptions, err error) {
+	return newPluggedCertIstioCAOptions(fileBundle, signer, defaultCertTTL, maxCertTTL, caRSAKeySize)
+}
+
+func newPluggedCertIstioCAOptions(fileBundle SigningCAFileBundle, signer crypto.Signer,
+	defaultCertTTL, maxCertTTL time.Duration, caRSAKeySize int,
 ) (caOpts *IstioCAOptions, err error) {
 	caOpts = &IstioCAOptions{
 		CAType:         pluggedCertCA,
@@ -275,8 +294,14 @@ func NewPluggedCertIstioCAOptions(fileBundle SigningCAFileBundle,
 		CARSAKeySize:   caRSAKeySize,
 	}
 
-	if caOpts.KeyCertBundle, err = util.NewVerifiedKeyCertBundleFromFile(
-		fileBundle.SigningCertFile, fileBundle.SigningKeyFile, fileBundle.CertChainFiles, fileBundle.RootCertFile); err != nil {
+	if signer != nil {
+		caOpts.KeyCertBundle, err = util.NewVerifiedKeyCertBundleFromSigner(
+			fileBundle.SigningCertFile, signer, fileBundle.CertChainFiles, fileBundle.RootCertFile)
+	} else {
+		caOpts.KeyCertBundle, err = util.NewVerifiedKeyCertBundleFromFile(
+			fileBundle.SigningCertFile, fileBundle.SigningKeyFile, fileBundle.CertChainFiles, fileBundle.RootCertFile)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to create CA KeyCertBundle (%v)", err)
 	}
 
@@ -338,6 +363,8 @@ type IstioCA struct {
 	// rootCertRotator periodically rotates self-signed root cert for CA. It is nil
 	// if CA is not self-signed CA.
 	rootCertRotator *SelfSignedCARootCertRotator
+
+	revocationStore RevocationStore
 }
 
 // NewIstioCA returns a new IstioCA instance.
@@ -346,6 +373,8 @@ func NewIstioCA(opts *IstioCAOptions) (*IstioCA, error) {
 		maxCertTTL:    opts.MaxCertTTL,
 		keyCertBundle: opts.KeyCertBundle,
 		caRSAKeySize:  opts.CARSAKeySize,
+
+		revocationStore: opts.RevocationStore,
 	}
 
 	if opts.CAType == selfSignedCA && opts.RotatorConfig != nil && opts.RotatorConfig.CheckInterval > time.Duration(0) {
@@ -413,6 +442,8 @@ func (ca *IstioCA) GenKeyCert(hostnames []string, certTTL time.Duration, checkLi
 		default:
 			opts.ECCCurve = util.P256Curve
 		}
+	} else if _, ok := (*signingKey).(ed25519.PrivateKey); ok {
+		opts.ECSigAlg = util.Ed25519SigAlg
 	}
 
 	csrPEM, privPEM, err := util.GenCSR(opts)
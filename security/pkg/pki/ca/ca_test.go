@@ -17,6 +17,10 @@ package ca
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"os"
@@ -482,6 +486,43 @@ func TestSignCSR(t *testing.T) {
 			},
 			expectedError: "",
 		},
+		"Workload uses Ed25519": {
+			forCA: false,
+			certOpts: util.CertOptions{
+				// This value is not used, instead, subjectID should be used in certificate.
+				Host:     "spiffe://different.com/test",
+				ECSigAlg: util.Ed25519SigAlg,
+				IsCA:     false,
+			},
+			maxTTL:       time.Hour,
+			requestedTTL: 30 * time.Minute,
+			verifyFields: util.VerifyFields{
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+				KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+				IsCA:        false,
+				Host:        subjectID,
+			},
+			expectedError: "",
+		},
+		"Workload uses RSA-PSS": {
+			forCA: false,
+			certOpts: util.CertOptions{
+				// This value is not used, instead, subjectID should be used in certificate.
+				Host:       "spiffe://different.com/test",
+				RSAKeySize: 2048,
+				RSASigAlg:  util.RsaPssSigAlg,
+				IsCA:       false,
+			},
+			maxTTL:       time.Hour,
+			requestedTTL: 30 * time.Minute,
+			verifyFields: util.VerifyFields{
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+				KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+				IsCA:        false,
+				Host:        subjectID,
+			},
+			expectedError: "",
+		},
 		"Workload uses EC": {
 			forCA: false,
 			certOpts: util.CertOptions{
@@ -509,7 +550,7 @@ func TestSignCSR(t *testing.T) {
 			maxTTL:       365 * 24 * time.Hour,
 			requestedTTL: 30 * 24 * time.Hour,
 			verifyFields: util.VerifyFields{
-				KeyUsage: x509.KeyUsageCertSign,
+				KeyUsage: x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 				IsCA:     true,
 				Host:     subjectID,
 			},
@@ -524,7 +565,7 @@ func TestSignCSR(t *testing.T) {
 			maxTTL:       365 * 24 * time.Hour,
 			requestedTTL: 30 * 24 * time.Hour,
 			verifyFields: util.VerifyFields{
-				KeyUsage: x509.KeyUsageCertSign,
+				KeyUsage: x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 				IsCA:     true,
 				Host:     subjectID,
 			},
@@ -607,6 +648,9 @@ func TestSignCSR(t *testing.T) {
 		if !reflect.DeepEqual(expected, san) {
 			t.Errorf("%s: Unexpected extensions: wanted %v but got %v", id, expected, san)
 		}
+		if util.IsRSAPSS(cert.SignatureAlgorithm) != (tc.certOpts.RSASigAlg == util.RsaPssSigAlg) {
+			t.Errorf("%s: Unexpected signature algorithm %v", id, cert.SignatureAlgorithm)
+		}
 	}
 }
 
@@ -694,6 +738,83 @@ func TestSignWithCertChain(t *testing.T) {
 	}
 }
 
+// opaqueSigner hides the type of the private key, as for a key held by an HSM.
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+func TestPluggedCertCAWithSigner(t *testing.T) {
+	rootCertFile := "../testdata/multilevelpki/root-cert.pem"
+	certChainFile := []string{"../testdata/multilevelpki/int-cert-chain.pem"}
+	signingCertFile := "../testdata/multilevelpki/int-cert.pem"
+	signingKeyFile := "../testdata/multilevelpki/int-key.pem"
+	fileBundle := SigningCAFileBundle{rootCertFile, certChainFile, signingCertFile, ""}
+
+	keyPEM, err := os.ReadFile(signingKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := util.ParsePemEncodedKey(keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := opaqueSigner{key.(crypto.Signer)}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPluggedCertIstioCAOptionsWithSigner(fileBundle, opaqueSigner{otherKey}, time.Hour, time.Hour, 2048); err == nil {
+		t.Fatalf("expected error for a signer not matching the signing cert")
+	}
+
+	caopts, err := NewPluggedCertIstioCAOptionsWithSigner(fileBundle, signer, 30*time.Minute, time.Hour, 2048)
+	if err != nil {
+		t.Fatalf("Failed to create a plugged-cert CA Options: %v", err)
+	}
+	ca, err := NewIstioCA(caopts)
+	if err != nil {
+		t.Fatalf("Got error while creating plugged-cert CA: %v", err)
+	}
+	if _, privKeyPEM, _, _ := ca.GetCAKeyCertBundle().GetAllPem(); len(privKeyPEM) != 0 {
+		t.Errorf("expected no private key PEM, got %s", privKeyPEM)
+	}
+
+	for _, rsaSigAlg := range []util.SupportedRSASignatureAlgorithms{"", util.RsaPssSigAlg} {
+		csrPEM, privPEM, err := util.GenCSR(util.CertOptions{
+			Host:       "spiffe://different.com/test",
+			RSAKeySize: 2048,
+			RSASigAlg:  rsaSigAlg,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		certPEM, err := ca.signWithCertChain(csrPEM, []string{"localhost"}, time.Hour, true, false)
+		if err != nil {
+			t.Fatalf("%q: failed to sign CSR: %v", rsaSigAlg, err)
+		}
+		tlsCert, err := tls.X509KeyPair(certPEM, privPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(tlsCert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signingCert, _, _, _ := ca.GetCAKeyCertBundle().GetAll()
+		if err := cert.CheckSignatureFrom(signingCert); err != nil {
+			t.Errorf("%q: certificate not signed by the CA: %v", rsaSigAlg, err)
+		}
+		if got := util.IsRSAPSS(cert.SignatureAlgorithm); got != (rsaSigAlg == util.RsaPssSigAlg) {
+			t.Errorf("%q: unexpected signature algorithm %v", rsaSigAlg, cert.SignatureAlgorithm)
+		}
+	}
+
+	if _, _, err := ca.GenKeyCert([]string{"istiod.istio-system.svc"}, time.Hour, false); err != nil {
+		t.Errorf("failed to generate key cert: %v", err)
+	}
+}
+
 func TestGenKeyCert(t *testing.T) {
 	cases := map[string]struct {
 		rootCertFile      string
@@ -0,0 +1,242 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	// RevocationConfigMap is the name of the ConfigMap storing the certificates revoked by the CA.
+	RevocationConfigMap = "istio-ca-revocations"
+	// RevocationConfigMapKey is the key of the ConfigMap data holding the JSON encoded list of revoked certificates.
+	RevocationConfigMapKey = "revocations.json"
+)
+
+// RevokedCertificate is a certificate revoked by the CA.
+type RevokedCertificate struct {
+	// SerialNumber is the hex encoded serial number of the certificate.
+	SerialNumber   string    `json:"serialNumber"`
+	RevocationTime time.Time `json:"revocationTime"`
+	// ReasonCode is the reason of the revocation, as defined in RFC 5280 section 5.3.1.
+	ReasonCode int `json:"reasonCode,omitempty"`
+	// NotAfter is the expiration time of the certificate, if known. Expired certificates are omitted from the CRL.
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+}
+
+func (r RevokedCertificate) serialNumber() (*big.Int, error) {
+	serial, ok := new(big.Int).SetString(r.SerialNumber, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid serial number %q", r.SerialNumber)
+	}
+	return serial, nil
+}
+
+// RevocationStore stores the certificates revoked by the CA.
+type RevocationStore interface {
+	// List returns the revoked certificates.
+	List() ([]RevokedCertificate, error)
+	// Revoke adds the certificates to the revoked certificates. Certificates which are already revoked keep their
+	// original revocation.
+	Revoke(certs ...RevokedCertificate) error
+}
+
+type configMapRevocationStore struct {
+	client    corev1.CoreV1Interface
+	namespace string
+}
+
+// NewConfigMapRevocationStore returns a RevocationStore backed by the RevocationConfigMap in the namespace.
+func NewConfigMapRevocationStore(client corev1.CoreV1Interface, namespace string) RevocationStore {
+	return &configMapRevocationStore{
+		client:    client,
+		namespace: namespace,
+	}
+}
+
+func (s *configMapRevocationStore) List() ([]RevokedCertificate, error) {
+	cm, err := s.client.ConfigMaps(s.namespace).Get(context.TODO(), RevocationConfigMap, metav1.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseRevocations(cm)
+}
+
+func (s *configMapRevocationStore) Revoke(certs ...RevokedCertificate) error {
+	for _, c := range certs {
+		if _, err := c.serialNumber(); err != nil {
+			return err
+		}
+	}
+	configMaps := s.client.ConfigMaps(s.namespace)
+	// A concurrent update of the ConfigMap, or its creation, fails with a conflict; the update is then retried.
+	retriable := func(err error) bool {
+		return apierror.IsConflict(err) || apierror.IsAlreadyExists(err)
+	}
+	return retry.OnError(retry.DefaultRetry, retriable, func() error {
+		cm, err := configMaps.Get(context.TODO(), RevocationConfigMap, metav1.GetOptions{})
+		create := apierror.IsNotFound(err)
+		if create {
+			cm = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: RevocationConfigMap, Namespace: s.namespace}}
+		} else if err != nil {
+			return err
+		}
+		revoked, err := ParseRevocations(cm)
+		if err != nil {
+			return err
+		}
+		revoked, err = appendRevocations(revoked, certs)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(revoked, "", "  ")
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[RevocationConfigMapKey] = string(data)
+		if create {
+			_, err = configMaps.Create(context.TODO(), cm, metav1.CreateOptions{})
+		} else {
+			_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		}
+		return err
+	})
+}
+
+// appendRevocations appends the certificates which are not revoked yet to the revoked certificates.
+func appendRevocations(revoked, certs []RevokedCertificate) ([]RevokedCertificate, error) {
+	serials := sets.New[string]()
+	for _, r := range revoked {
+		serial, err := r.serialNumber()
+		if err != nil {
+			return nil, err
+		}
+		serials.Insert(serial.String())
+	}
+	for _, c := range certs {
+		serial, err := c.serialNumber()
+		if err != nil {
+			return nil, err
+		}
+		if serials.InsertContains(serial.String()) {
+			continue
+		}
+		revoked = append(revoked, c)
+	}
+	return revoked, nil
+}
+
+// ParseRevocations returns the revoked certificates stored in the revocation ConfigMap. A nil ConfigMap has no
+// revoked certificates.
+func ParseRevocations(cm *v1.ConfigMap) ([]RevokedCertificate, error) {
+	if cm == nil || cm.Data[RevocationConfigMapKey] == "" {
+		return nil, nil
+	}
+	var revoked []RevokedCertificate
+	if err := json.Unmarshal([]byte(cm.Data[RevocationConfigMapKey]), &revoked); err != nil {
+		return nil, fmt.Errorf("failed to parse %s/%s: %v", cm.Namespace, cm.Name, err)
+	}
+	return revoked, nil
+}
+
+// Revoke revokes the certificates issued by the CA. The CA includes them in the revocation lists it issues once its
+// revocation store is updated.
+func (ca *IstioCA) Revoke(certs ...RevokedCertificate) error {
+	if ca.revocationStore == nil {
+		return fmt.Errorf("the CA does not support revoking certificates")
+	}
+	return ca.revocationStore.Revoke(certs...)
+}
+
+// RevokedCertificates returns the certificates revoked by the CA.
+func (ca *IstioCA) RevokedCertificates() ([]RevokedCertificate, error) {
+	if ca.revocationStore == nil {
+		return nil, nil
+	}
+	return ca.revocationStore.List()
+}
+
+// GenerateCRL returns a PEM encoded certificate revocation list of the revoked certificates, signed by the CA
+// and valid for the given duration. Certificates which have already expired are omitted.
+func (ca *IstioCA) GenerateCRL(revoked []RevokedCertificate, validity time.Duration) ([]byte, error) {
+	signingCert, signingKey, _, _ := ca.keyCertBundle.GetAll()
+	if signingCert == nil || signingKey == nil {
+		return nil, fmt.Errorf("Istio CA is not ready") // nolint
+	}
+	if signingCert.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return nil, fmt.Errorf("the CA certificate %q does not have the CRLSign key usage required to sign revocation lists",
+			signingCert.Subject.String())
+	}
+	signer, ok := (*signingKey).(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("CA private key does not implement crypto.Signer")
+	}
+
+	now := time.Now()
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, r := range revoked {
+		if r.NotAfter != nil && r.NotAfter.Before(now) {
+			continue
+		}
+		serial, err := r.serialNumber()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: r.RevocationTime,
+			ReasonCode:     r.ReasonCode,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SerialNumber.Cmp(entries[j].SerialNumber) < 0
+	})
+
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificateEntries: entries,
+		// The CRL number must increase with each CRL issued; the issuance time in nanoseconds does.
+		Number:     big.NewInt(now.UnixNano()),
+		ThisUpdate: now.Add(-util.ClockSkewGracePeriod),
+		NextUpdate: now.Add(validity),
+	}, signingCert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CRL: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl}), nil
+}
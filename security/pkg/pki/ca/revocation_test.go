@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func TestRevokedCertificates(t *testing.T) {
+	ca, err := createCA(time.Hour, "")
+	assert.NoError(t, err)
+	revoked, err := ca.RevokedCertificates()
+	assert.NoError(t, err)
+	assert.Equal(t, len(revoked), 0)
+
+	client := fake.NewSimpleClientset()
+	ca.revocationStore = NewConfigMapRevocationStore(client.CoreV1(), "istio-system")
+	revoked, err = ca.RevokedCertificates()
+	assert.NoError(t, err)
+	assert.Equal(t, len(revoked), 0)
+
+	_, err = client.CoreV1().ConfigMaps("istio-system").Create(context.TODO(), &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: RevocationConfigMap, Namespace: "istio-system"},
+		Data: map[string]string{
+			RevocationConfigMapKey: `[{"serialNumber": "abc", "revocationTime": "2024-01-01T00:00:00Z", "reasonCode": 1}]`,
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	revoked, err = ca.RevokedCertificates()
+	assert.NoError(t, err)
+	assert.Equal(t, revoked, []RevokedCertificate{{
+		SerialNumber:   "abc",
+		RevocationTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ReasonCode:     1,
+	}})
+}
+
+func TestRevoke(t *testing.T) {
+	ca, err := createCA(time.Hour, "")
+	assert.NoError(t, err)
+	assert.Error(t, ca.Revoke(RevokedCertificate{SerialNumber: "1a"}))
+
+	ca.revocationStore = NewConfigMapRevocationStore(fake.NewSimpleClientset().CoreV1(), "istio-system")
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	// The ConfigMap is created by the first revocation.
+	assert.NoError(t, ca.Revoke(RevokedCertificate{SerialNumber: "1a", RevocationTime: first, ReasonCode: 1}))
+	// Certificates which are already revoked keep their original revocation.
+	assert.NoError(t, ca.Revoke(
+		RevokedCertificate{SerialNumber: "001A", RevocationTime: second},
+		RevokedCertificate{SerialNumber: "ff", RevocationTime: second},
+	))
+	revoked, err := ca.RevokedCertificates()
+	assert.NoError(t, err)
+	assert.Equal(t, revoked, []RevokedCertificate{
+		{SerialNumber: "1a", RevocationTime: first, ReasonCode: 1},
+		{SerialNumber: "ff", RevocationTime: second},
+	})
+
+	assert.Error(t, ca.Revoke(RevokedCertificate{SerialNumber: "invalid"}))
+}
+
+func TestParseRevocations(t *testing.T) {
+	revoked, err := ParseRevocations(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, len(revoked), 0)
+
+	_, err = ParseRevocations(&v1.ConfigMap{Data: map[string]string{RevocationConfigMapKey: "not json"}})
+	assert.Error(t, err)
+
+	revoked, err = ParseRevocations(&v1.ConfigMap{Data: map[string]string{
+		RevocationConfigMapKey: `[{"serialNumber": "1a", "revocationTime": "2024-01-01T00:00:00Z", "reasonCode": 1}]`,
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, revoked, []RevokedCertificate{{
+		SerialNumber:   "1a",
+		RevocationTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ReasonCode:     1,
+	}})
+}
+
+func TestGenerateCRL(t *testing.T) {
+	for _, sigAlg := range []util.SupportedECSignatureAlgorithms{"", util.EcdsaSigAlg} {
+		t.Run(string(sigAlg), func(t *testing.T) {
+			ca, err := createCA(time.Hour, sigAlg)
+			assert.NoError(t, err)
+
+			expired := time.Now().Add(-time.Minute)
+			valid := time.Now().Add(time.Hour)
+			revocationTime := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+			crlPEM, err := ca.GenerateCRL([]RevokedCertificate{
+				{SerialNumber: "ff", RevocationTime: revocationTime, ReasonCode: 1, NotAfter: &valid},
+				{SerialNumber: "1", RevocationTime: revocationTime},
+				{SerialNumber: "2", RevocationTime: revocationTime, NotAfter: &expired},
+			}, time.Hour)
+			assert.NoError(t, err)
+
+			block, _ := pem.Decode(crlPEM)
+			assert.Equal(t, block.Type, "X509 CRL")
+			crl, err := x509.ParseRevocationList(block.Bytes)
+			assert.NoError(t, err)
+			signingCert, _, _, _ := ca.GetCAKeyCertBundle().GetAll()
+			assert.NoError(t, crl.CheckSignatureFrom(signingCert))
+			assert.Equal(t, crl.NextUpdate.After(time.Now().Add(59*time.Minute)), true)
+
+			// The expired certificate is omitted, and entries are sorted by serial number.
+			assert.Equal(t, len(crl.RevokedCertificateEntries), 2)
+			assert.Equal(t, crl.RevokedCertificateEntries[0].SerialNumber.Int64(), int64(1))
+			assert.Equal(t, crl.RevokedCertificateEntries[1].SerialNumber.Int64(), int64(0xff))
+			assert.Equal(t, crl.RevokedCertificateEntries[1].ReasonCode, 1)
+			assert.Equal(t, crl.RevokedCertificateEntries[1].RevocationTime.Equal(revocationTime), true)
+
+			_, err = ca.GenerateCRL([]RevokedCertificate{{SerialNumber: "invalid"}}, time.Hour)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGenerateCRLWithoutCRLSign(t *testing.T) {
+	ca, err := createCA(time.Hour, "")
+	assert.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"cluster.local"}},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	ca.keyCertBundle, err = util.NewVerifiedKeyCertBundleFromPem(certPEM, keyPEM, nil, certPEM)
+	assert.NoError(t, err)
+
+	_, err = ca.GenerateCRL(nil, time.Hour)
+	assert.Error(t, err)
+}
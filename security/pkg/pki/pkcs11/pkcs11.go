@@ -0,0 +1,58 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pkcs11 provides a crypto.Signer for private keys held by a PKCS#11 token, such as an HSM.
+package pkcs11
+
+import (
+	"crypto"
+	"fmt"
+	"io"
+)
+
+// Config configures the PKCS#11 token and key used by a Signer.
+type Config struct {
+	// ModulePath is the path of the PKCS#11 module of the token, e.g. /usr/lib/softhsm/libsofthsm2.so.
+	ModulePath string
+	// TokenLabel is the label of the token holding the key.
+	TokenLabel string
+	// Pin is the user PIN of the token.
+	Pin string
+	// KeyLabel is the label of the private key.
+	KeyLabel string
+}
+
+// Validate returns an error if a required field is not set.
+func (c Config) Validate() error {
+	switch {
+	case c.ModulePath == "":
+		return fmt.Errorf("PKCS#11 module path is required")
+	case c.TokenLabel == "":
+		return fmt.Errorf("PKCS#11 token label is required")
+	case c.KeyLabel == "":
+		return fmt.Errorf("PKCS#11 key label is required")
+	}
+	return nil
+}
+
+// Signer is a crypto.Signer whose private key never leaves the PKCS#11 token.
+type Signer struct {
+	crypto.Signer
+	closer io.Closer
+}
+
+// Close releases the sessions to the token. The Signer must not be used afterwards.
+func (s *Signer) Close() error {
+	return s.closer.Close()
+}
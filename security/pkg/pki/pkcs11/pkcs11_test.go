@@ -0,0 +1,40 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{ModulePath: "/usr/lib/softhsm/libsofthsm2.so", TokenLabel: "istio", KeyLabel: "ca"}
+	assert.NoError(t, valid.Validate())
+
+	for name, modify := range map[string]func(c *Config){
+		"no module": func(c *Config) { c.ModulePath = "" },
+		"no token":  func(c *Config) { c.TokenLabel = "" },
+		"no key":    func(c *Config) { c.KeyLabel = "" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := valid
+			modify(&c)
+			assert.Error(t, c.Validate())
+			_, err := NewSigner(c)
+			assert.Error(t, err)
+		})
+	}
+}
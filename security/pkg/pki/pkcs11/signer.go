@@ -0,0 +1,48 @@
+//go:build cgo
+// +build cgo
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"fmt"
+
+	"github.com/ThalesIgnite/crypto11"
+)
+
+// NewSigner opens the token and returns a Signer for the private key with the configured label.
+func NewSigner(config Config) (*Signer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, err := crypto11.Configure(&crypto11.Config{
+		Path:       config.ModulePath,
+		TokenLabel: config.TokenLabel,
+		Pin:        config.Pin,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PKCS#11 token %q: %v", config.TokenLabel, err)
+	}
+	key, err := ctx.FindKeyPair(nil, []byte(config.KeyLabel))
+	if err == nil && key == nil {
+		err = fmt.Errorf("not found")
+	}
+	if err != nil {
+		_ = ctx.Close()
+		return nil, fmt.Errorf("failed to find PKCS#11 key %q: %v", config.KeyLabel, err)
+	}
+	return &Signer{Signer: key, closer: ctx}, nil
+}
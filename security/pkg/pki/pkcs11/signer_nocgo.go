@@ -0,0 +1,31 @@
+//go:build !cgo
+// +build !cgo
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"fmt"
+)
+
+// NewSigner returns an error, as PKCS#11 modules can only be loaded by binaries built with cgo. The released pilot
+// images are built without cgo; the pilot-pkcs11 image is built with it.
+func NewSigner(config Config) (*Signer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("PKCS#11 is not supported: the binary was built without cgo, use the pilot-pkcs11 image instead")
+}
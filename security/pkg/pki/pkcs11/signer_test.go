@@ -0,0 +1,69 @@
+//go:build cgo
+// +build cgo
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"os"
+	"testing"
+
+	"github.com/ThalesIgnite/crypto11"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// TestSoftHSMSigner runs against a SoftHSM token, e.g. initialized with:
+//
+//	softhsm2-util --init-token --free --label istio --pin 1234 --so-pin 1234
+//	PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so PKCS11_TOKEN_LABEL=istio PKCS11_PIN=1234 go test .
+func TestSoftHSMSigner(t *testing.T) {
+	config := Config{
+		ModulePath: os.Getenv("PKCS11_MODULE"),
+		TokenLabel: os.Getenv("PKCS11_TOKEN_LABEL"),
+		Pin:        os.Getenv("PKCS11_PIN"),
+		KeyLabel:   "istio-test-ca",
+	}
+	if config.ModulePath == "" {
+		t.Skip("PKCS11_MODULE is not set")
+	}
+
+	ctx, err := crypto11.Configure(&crypto11.Config{Path: config.ModulePath, TokenLabel: config.TokenLabel, Pin: config.Pin})
+	assert.NoError(t, err)
+	defer ctx.Close()
+	key, err := ctx.GenerateECDSAKeyPairWithLabel([]byte("istio-test-ca-id"), []byte(config.KeyLabel), elliptic.P256())
+	assert.NoError(t, err)
+	defer key.Delete()
+
+	signer, err := NewSigner(config)
+	assert.NoError(t, err)
+	defer signer.Close()
+	assert.Equal(t, signer.Public().(*ecdsa.PublicKey).Equal(key.Public()), true)
+
+	digest := sha256.Sum256([]byte("istio"))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.NoError(t, err)
+	assert.Equal(t, ecdsa.VerifyASN1(signer.Public().(*ecdsa.PublicKey), digest[:], sig), true)
+
+	config.KeyLabel = "missing"
+	_, err = NewSigner(config)
+	assert.Error(t, err)
+}
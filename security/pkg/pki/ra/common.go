@@ -58,12 +58,17 @@ type IstioRAOptions struct {
 	TrustDomain string
 	// CertSignerDomain info
 	CertSignerDomain string
+	// Vault : Vault PKI configuration, used when ExternalCAType is ExtCAVault
+	Vault VaultOptions
 }
 
 const (
 	// ExtCAK8s : Integrate with external CA using k8s CSR API
 	ExtCAK8s CaExternalType = "ISTIOD_RA_KUBERNETES_API"
 
+	// ExtCAVault : Integrate with external CA using the HashiCorp Vault PKI secrets engine
+	ExtCAVault CaExternalType = "ISTIOD_RA_VAULT_PKI"
+
 	// DefaultExtCACertDir : Location of external CA certificate
 	DefaultExtCACertDir string = "./etc/external-ca-cert"
 )
@@ -99,6 +104,13 @@ func NewIstioRA(opts *IstioRAOptions) (RegistrationAuthority, error) {
 		}
 		return istioRA, err
 	}
+	if opts.ExternalCAType == ExtCAVault {
+		istioRA, err := NewVaultRA(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a Vault RA: %v", err)
+		}
+		return istioRA, err
+	}
 	return nil, fmt.Errorf("invalid CA Name %s", opts.ExternalCAType)
 }
 
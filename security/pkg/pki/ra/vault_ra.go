@@ -0,0 +1,394 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ra
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/security/pkg/pki/ca"
+	raerror "istio.io/istio/security/pkg/pki/error"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	// VaultAuthKubernetes logs in to Vault with the Kubernetes auth method, using the service account token of istiod.
+	VaultAuthKubernetes = "kubernetes"
+	// VaultAuthAppRole logs in to Vault with the AppRole auth method.
+	VaultAuthAppRole = "approle"
+
+	defaultVaultPKIMount       = "pki"
+	defaultVaultTokenPath      = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	vaultRequestTimeout        = 10 * time.Second
+	vaultTokenRenewalThreshold = 0.8
+)
+
+// VaultOptions configures the Vault PKI backend of the Istio RA.
+type VaultOptions struct {
+	// Addr : Address of the Vault server, e.g. https://vault.vault.svc:8200
+	Addr string
+	// Namespace : Vault Enterprise namespace, if any
+	Namespace string
+	// CACertFile : File containing the PEM encoded CA certificate used to verify the Vault server
+	CACertFile string
+	// PKIMount : Path the PKI secrets engine is mounted at. Defaults to pki.
+	PKIMount string
+	// Role : PKI role used to sign workload certificates. The role constrains the identities that can be issued: it
+	// should allow the SPIFFE URI SANs of the trust domain and not require a common name.
+	Role string
+	// AuthMethod : Vault auth method, either kubernetes or approle
+	AuthMethod string
+	// AuthMount : Path the auth method is mounted at. Defaults to the name of the auth method.
+	AuthMount string
+	// AuthRole : Role of the Kubernetes auth method
+	AuthRole string
+	// TokenPath : File containing the service account token for the Kubernetes auth method
+	TokenPath string
+	// AppRoleID : Role ID of the AppRole auth method
+	AppRoleID string
+	// AppRoleSecretIDFile : File containing the secret ID of the AppRole auth method
+	AppRoleSecretIDFile string
+}
+
+// Validate checks that the options are complete.
+func (o *VaultOptions) Validate() error {
+	if o.Addr == "" {
+		return fmt.Errorf("vault address is required")
+	}
+	if o.Role == "" {
+		return fmt.Errorf("vault PKI role is required")
+	}
+	switch o.AuthMethod {
+	case VaultAuthKubernetes:
+		if o.AuthRole == "" {
+			return fmt.Errorf("vault role of the kubernetes auth method is required")
+		}
+	case VaultAuthAppRole:
+		if o.AppRoleID == "" || o.AppRoleSecretIDFile == "" {
+			return fmt.Errorf("role ID and secret ID file of the approle auth method are required")
+		}
+	default:
+		return fmt.Errorf("unsupported vault auth method %q", o.AuthMethod)
+	}
+	return nil
+}
+
+// VaultRA is a RA that signs certificates with the PKI secrets engine of HashiCorp Vault.
+type VaultRA struct {
+	opts          VaultOptions
+	raOpts        *IstioRAOptions
+	client        *http.Client
+	keyCertBundle *util.KeyCertBundle
+	now           func() time.Time
+
+	// mutex protects token and tokenExpiry.
+	mutex       sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// vaultError is returned for non-successful Vault responses.
+type vaultError struct {
+	statusCode int
+	errors     []string
+}
+
+func (e *vaultError) Error() string {
+	return fmt.Sprintf("vault returned status %d: %s", e.statusCode, strings.Join(e.errors, "; "))
+}
+
+// NewVaultRA : Create a RA that signs certificates with a Vault PKI secrets engine. The CA chain of the PKI
+// mount is read at startup: a change of the issuer in Vault requires a restart of istiod.
+func NewVaultRA(raOpts *IstioRAOptions) (*VaultRA, error) {
+	opts := raOpts.Vault
+	if opts.PKIMount == "" {
+		opts.PKIMount = defaultVaultPKIMount
+	}
+	if opts.AuthMount == "" {
+		opts.AuthMount = opts.AuthMethod
+	}
+	if opts.TokenPath == "" {
+		opts.TokenPath = defaultVaultTokenPath
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, raerror.NewError(raerror.CAIllegalConfig, err)
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.CACertFile != "" {
+		caCert, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("failed to read vault CA certificate: %v", err))
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("invalid vault CA certificate in %s", opts.CACertFile))
+		}
+	}
+	r := &VaultRA{
+		opts:   opts,
+		raOpts: raOpts,
+		client: &http.Client{
+			Timeout:   vaultRequestTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+		now: time.Now,
+	}
+	bundle, err := r.fetchCAChain()
+	if err != nil {
+		return nil, raerror.NewError(raerror.CAInitFail, fmt.Errorf("failed to read the CA chain from vault: %v", err))
+	}
+	r.keyCertBundle = bundle
+	return r, nil
+}
+
+// fetchCAChain reads the issuing CA chain of the PKI mount. A self-signed certificate in the chain is used as the
+// root, otherwise the root is read from the CA cert file of the RA options.
+func (r *VaultRA) fetchCAChain() (*util.KeyCertBundle, error) {
+	body, err := r.do(http.MethodGet, "/v1/"+r.opts.PKIMount+"/ca_chain", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	chain, root, err := splitCAChain([]string{string(body)})
+	if err != nil {
+		return nil, err
+	}
+	if len(root) == 0 && r.raOpts.CaCertFile != "" {
+		if root, err = os.ReadFile(r.raOpts.CaCertFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(root) == 0 {
+		return nil, fmt.Errorf("no root certificate in the CA chain of %s and no CA cert file configured", r.opts.PKIMount)
+	}
+	return util.NewKeyCertBundleFromPem(nil, nil, chain, root), nil
+}
+
+// splitCAChain splits PEM encoded CA certificates into the intermediate certificates and the self-signed roots.
+func splitCAChain(pems []string) (chain, root []byte, err error) {
+	for _, p := range pems {
+		rest := []byte(p)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse X.509 certificate: %v", err)
+			}
+			if cert.CheckSignatureFrom(cert) == nil {
+				root = append(root, pem.EncodeToMemory(block)...)
+			} else {
+				chain = append(chain, pem.EncodeToMemory(block)...)
+			}
+		}
+	}
+	return chain, root, nil
+}
+
+// login returns a Vault token, logging in again when the current token is close to expiry.
+func (r *VaultRA) login(force bool) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !force && r.token != "" && r.now().Before(r.tokenExpiry) {
+		return r.token, nil
+	}
+	req := map[string]string{}
+	switch r.opts.AuthMethod {
+	case VaultAuthKubernetes:
+		jwt, err := os.ReadFile(r.opts.TokenPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the service account token: %v", err)
+		}
+		req["role"] = r.opts.AuthRole
+		req["jwt"] = strings.TrimSpace(string(jwt))
+	case VaultAuthAppRole:
+		secretID, err := os.ReadFile(r.opts.AppRoleSecretIDFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the approle secret ID: %v", err)
+		}
+		req["role_id"] = r.opts.AppRoleID
+		req["secret_id"] = strings.TrimSpace(string(secretID))
+	}
+	body, err := r.do(http.MethodPost, "/v1/auth/"+r.opts.AuthMount+"/login", "", req)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to vault: %v", err)
+	}
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("invalid vault login response: %v", err)
+	}
+	r.token = resp.Auth.ClientToken
+	lease := time.Duration(float64(resp.Auth.LeaseDuration)*vaultTokenRenewalThreshold) * time.Second
+	r.tokenExpiry = r.now().Add(lease)
+	pkiRaLog.Debugf("logged in to vault with the %s auth method, token valid for %v", r.opts.AuthMethod, lease)
+	return r.token, nil
+}
+
+// do sends a request to Vault and returns the body of a successful response.
+func (r *VaultRA) do(method, path, token string, reqBody any) ([]byte, error) {
+	var body io.Reader
+	if reqBody != nil {
+		b, err := json.Marshal(reqBody)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(r.opts.Addr, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if r.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.opts.Namespace)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		verr := &vaultError{statusCode: resp.StatusCode}
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &errResp) == nil {
+			verr.errors = errResp.Errors
+		}
+		return nil, verr
+	}
+	return respBody, nil
+}
+
+// vaultSign signs the CSR with the PKI role, returning the leaf certificate and the issuing CA chain.
+func (r *VaultRA) vaultSign(csrPEM []byte, subjectIDs []string, lifetime time.Duration) ([]byte, []string, error) {
+	req := map[string]any{
+		"csr":                  string(csrPEM),
+		"uri_sans":             strings.Join(subjectIDs, ","),
+		"ttl":                  fmt.Sprintf("%ds", int64(lifetime.Seconds())),
+		"exclude_cn_from_sans": true,
+		"format":               "pem",
+	}
+	path := "/v1/" + r.opts.PKIMount + "/sign/" + r.opts.Role
+	var body []byte
+	for attempt := 0; ; attempt++ {
+		token, err := r.login(attempt > 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err = r.do(http.MethodPost, path, token, req)
+		if verr, ok := err.(*vaultError); ok && verr.statusCode == http.StatusForbidden && attempt == 0 {
+			// The token may have been revoked, log in again.
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		break
+	}
+	var resp struct {
+		Data struct {
+			Certificate string   `json:"certificate"`
+			IssuingCA   string   `json:"issuing_ca"`
+			CAChain     []string `json:"ca_chain"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, nil, fmt.Errorf("invalid vault sign response: %v", err)
+	}
+	if resp.Data.Certificate == "" {
+		return nil, nil, fmt.Errorf("vault sign response has no certificate")
+	}
+	caChain := resp.Data.CAChain
+	if len(caChain) == 0 && resp.Data.IssuingCA != "" {
+		caChain = []string{resp.Data.IssuingCA}
+	}
+	return []byte(util.AppendCertByte([]byte(resp.Data.Certificate), nil)), caChain, nil
+}
+
+// Sign takes a PEM-encoded CSR and cert opts, and returns a certificate signed by the Vault PKI role.
+func (r *VaultRA) Sign(csrPEM []byte, certOpts ca.CertOpts) ([]byte, error) {
+	lifetime, err := preSign(r.raOpts, csrPEM, certOpts.SubjectIDs, certOpts.TTL, certOpts.ForCA)
+	if err != nil {
+		return nil, err
+	}
+	cert, _, err := r.vaultSign(csrPEM, certOpts.SubjectIDs, lifetime)
+	if err != nil {
+		return nil, raerror.NewError(raerror.CertGenError, err)
+	}
+	return cert, nil
+}
+
+// SignWithCertChain is similar to Sign but returns the leaf cert and the intermediate certificates of the issuing CA
+// chain returned by Vault. The root cert is appended by the CA server from the KeyCertBundle.
+func (r *VaultRA) SignWithCertChain(csrPEM []byte, certOpts ca.CertOpts) ([]string, error) {
+	lifetime, err := preSign(r.raOpts, csrPEM, certOpts.SubjectIDs, certOpts.TTL, certOpts.ForCA)
+	if err != nil {
+		return nil, err
+	}
+	cert, caChain, err := r.vaultSign(csrPEM, certOpts.SubjectIDs, lifetime)
+	if err != nil {
+		return nil, raerror.NewError(raerror.CertGenError, err)
+	}
+	chain, _, err := splitCAChain(caChain)
+	if err != nil {
+		return nil, raerror.NewError(raerror.CertGenError, fmt.Errorf("invalid CA chain from vault: %v", err))
+	}
+	if len(chain) == 0 {
+		chain = r.keyCertBundle.GetCertChainPem()
+	}
+	root := r.keyCertBundle.GetRootCertPem()
+	if err := util.VerifyCertificate(nil, append(cert, chain...), root, nil); err != nil {
+		return nil, raerror.NewError(raerror.CertGenError, fmt.Errorf("certificate signed by vault does not chain to the root: %v", err))
+	}
+	return []string{string(append(cert, chain...))}, nil
+}
+
+// GetCAKeyCertBundle returns the KeyCertBundle for the CA.
+func (r *VaultRA) GetCAKeyCertBundle() *util.KeyCertBundle {
+	return r.keyCertBundle
+}
+
+// SetCACertificatesFromMeshConfig is a no-op: the root certificate of the Vault RA is read from Vault.
+func (r *VaultRA) SetCACertificatesFromMeshConfig([]*meshconfig.MeshConfig_CertificateData) {}
+
+// GetRootCertFromMeshConfig returns the root cert of the Vault PKI mount, which signs for every signer.
+func (r *VaultRA) GetRootCertFromMeshConfig(string) ([]byte, error) {
+	return r.keyCertBundle.GetRootCertPem(), nil
+}
@@ -0,0 +1,336 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ra
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/security/pkg/pki/ca"
+	pkiutil "istio.io/istio/security/pkg/pki/util"
+)
+
+// fakeVault is a minimal Vault server with an AppRole and a Kubernetes auth method, and a PKI mount whose role
+// signs with the sample intermediate CA.
+type fakeVault struct {
+	t      *testing.T
+	caCert []byte
+	caKey  []byte
+	root   []byte
+
+	mu      sync.Mutex
+	tokens  map[string]bool
+	logins  int
+	signed  int
+	lastTTL string
+}
+
+func newFakeVault(t *testing.T) *fakeVault {
+	read := func(name string) []byte {
+		b, err := os.ReadFile(path.Join(env.IstioSrc, "samples/certs", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	return &fakeVault{t: t, caCert: read("ca-cert.pem"), caKey: read("ca-key.pem"), root: read("root-cert.pem"), tokens: map[string]bool{}}
+}
+
+func (v *fakeVault) revokeTokens() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.tokens = map[string]bool{}
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var req map[string]any
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&req)
+	}
+	fail := func(code int, msg string) {
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(map[string]any{"errors": []string{msg}})
+	}
+	switch r.URL.Path {
+	case "/v1/pki/ca_chain":
+		_, _ = w.Write(append(append([]byte{}, v.caCert...), v.root...))
+	case "/v1/auth/approle/login", "/v1/auth/kubernetes/login":
+		if req["secret_id"] != "secret" && req["jwt"] != "jwt" {
+			fail(http.StatusBadRequest, "invalid credentials")
+			return
+		}
+		v.logins++
+		token := fmt.Sprintf("token-%d", v.logins)
+		v.tokens[token] = true
+		_ = json.NewEncoder(w).Encode(map[string]any{"auth": map[string]any{"client_token": token, "lease_duration": 3600}})
+	case "/v1/pki/sign/istio":
+		if !v.tokens[r.Header.Get("X-Vault-Token")] {
+			fail(http.StatusForbidden, "permission denied")
+			return
+		}
+		csr, err := pkiutil.ParsePemEncodedCSR([]byte(req["csr"].(string)))
+		if err != nil {
+			fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		sans := strings.Split(req["uri_sans"].(string), ",")
+		if !strings.HasPrefix(sans[0], "spiffe://cluster.local/") {
+			fail(http.StatusBadRequest, "URI SANs not allowed by this role")
+			return
+		}
+		caCert, err := pkiutil.ParsePemEncodedCertificate(v.caCert)
+		assert.NoError(v.t, err)
+		caKey, err := pkiutil.ParsePemEncodedKey(v.caKey)
+		assert.NoError(v.t, err)
+		der, err := pkiutil.GenCertFromCSR(csr, caCert, csr.PublicKey, caKey, sans, time.Hour, false)
+		assert.NoError(v.t, err)
+		v.signed++
+		v.lastTTL = req["ttl"].(string)
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"certificate": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			"issuing_ca":  string(v.caCert),
+			"ca_chain":    []string{string(v.caCert), string(v.root)},
+		}})
+	default:
+		fail(http.StatusNotFound, "unsupported path "+r.URL.Path)
+	}
+}
+
+func writeTestFile(t *testing.T, content string) string {
+	p := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	return p
+}
+
+func createFakeVaultRA(addr string, opts VaultOptions) (*VaultRA, error) {
+	opts.Addr = addr
+	opts.Role = "istio"
+	return NewVaultRA(&IstioRAOptions{
+		ExternalCAType: ExtCAVault,
+		DefaultCertTTL: 30 * time.Minute,
+		MaxCertTTL:     time.Hour,
+		Vault:          opts,
+	})
+}
+
+func TestVaultOptionsValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    VaultOptions
+		wantErr string
+	}{
+		{
+			name: "kubernetes",
+			opts: VaultOptions{Addr: "https://vault:8200", Role: "istio", AuthMethod: VaultAuthKubernetes, AuthRole: "istiod"},
+		},
+		{
+			name: "approle",
+			opts: VaultOptions{Addr: "https://vault:8200", Role: "istio", AuthMethod: VaultAuthAppRole, AppRoleID: "id", AppRoleSecretIDFile: "/secret"},
+		},
+		{
+			name:    "no address",
+			opts:    VaultOptions{Role: "istio", AuthMethod: VaultAuthKubernetes, AuthRole: "istiod"},
+			wantErr: "vault address is required",
+		},
+		{
+			name:    "no role",
+			opts:    VaultOptions{Addr: "https://vault:8200", AuthMethod: VaultAuthKubernetes, AuthRole: "istiod"},
+			wantErr: "vault PKI role is required",
+		},
+		{
+			name:    "kubernetes without role",
+			opts:    VaultOptions{Addr: "https://vault:8200", Role: "istio", AuthMethod: VaultAuthKubernetes},
+			wantErr: "vault role of the kubernetes auth method is required",
+		},
+		{
+			name:    "approle without secret",
+			opts:    VaultOptions{Addr: "https://vault:8200", Role: "istio", AuthMethod: VaultAuthAppRole, AppRoleID: "id"},
+			wantErr: "role ID and secret ID file of the approle auth method are required",
+		},
+		{
+			name:    "unknown auth method",
+			opts:    VaultOptions{Addr: "https://vault:8200", Role: "istio", AuthMethod: "token"},
+			wantErr: `unsupported vault auth method "token"`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Equal(t, err.Error(), tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultRASign(t *testing.T) {
+	v := newFakeVault(t)
+	server := httptest.NewServer(v)
+	defer server.Close()
+
+	r, err := createFakeVaultRA(server.URL, VaultOptions{
+		AuthMethod:          VaultAuthAppRole,
+		AppRoleID:           "id",
+		AppRoleSecretIDFile: writeTestFile(t, "secret\n"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, r.GetCAKeyCertBundle().GetRootCertPem(), v.root)
+	assert.Equal(t, r.GetCAKeyCertBundle().GetCertChainPem(), v.caCert)
+
+	csrPEM := createFakeCsr(t)
+	certOpts := ca.CertOpts{SubjectIDs: []string{testCsrHostName}, TTL: 10 * time.Minute}
+	cert, err := r.Sign(csrPEM, certOpts)
+	assert.NoError(t, err)
+	assert.NoError(t, pkiutil.VerifyCertificate(nil, append(cert, v.caCert...), v.root, nil))
+	assert.Equal(t, v.lastTTL, "600s")
+
+	// The chain has the intermediate CA, the root is appended by the CA server.
+	chain, err := r.SignWithCertChain(csrPEM, certOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, len(chain), 1)
+	certs, _, err := pkiutil.ParsePemEncodedCertificateChain([]byte(chain[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, len(certs), 2)
+	assert.Equal(t, bytes.Contains([]byte(chain[0]), v.root), false)
+
+	// The token is reused until it is revoked.
+	assert.Equal(t, v.logins, 1)
+	v.revokeTokens()
+	_, err = r.Sign(csrPEM, certOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, v.logins, 2)
+
+	// Identities not in the CSR are rejected before reaching Vault.
+	signed := v.signed
+	_, err = r.Sign(csrPEM, ca.CertOpts{SubjectIDs: []string{"spiffe://cluster.local/ns/other/sa/other"}})
+	assert.Error(t, err)
+	assert.Equal(t, v.signed, signed)
+}
+
+func TestVaultRAKubernetesAuth(t *testing.T) {
+	v := newFakeVault(t)
+	server := httptest.NewServer(v)
+	defer server.Close()
+
+	r, err := createFakeVaultRA(server.URL, VaultOptions{
+		AuthMethod: VaultAuthKubernetes,
+		AuthRole:   "istiod",
+		TokenPath:  writeTestFile(t, "jwt"),
+	})
+	assert.NoError(t, err)
+	_, err = r.Sign(createFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}})
+	assert.NoError(t, err)
+	assert.Equal(t, v.lastTTL, "1800s")
+
+	r, err = createFakeVaultRA(server.URL, VaultOptions{
+		AuthMethod: VaultAuthKubernetes,
+		AuthRole:   "istiod",
+		TokenPath:  writeTestFile(t, "invalid"),
+	})
+	assert.NoError(t, err)
+	_, err = r.Sign(createFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}})
+	assert.Error(t, err)
+}
+
+// TestVaultRADevServer signs against a Vault server started with `vault server -dev`. It is skipped unless
+// VAULT_DEV_ADDR and VAULT_DEV_ROOT_TOKEN are set.
+func TestVaultRADevServer(t *testing.T) {
+	addr, rootToken := os.Getenv("VAULT_DEV_ADDR"), os.Getenv("VAULT_DEV_ROOT_TOKEN")
+	if addr == "" || rootToken == "" {
+		t.Skip("VAULT_DEV_ADDR and VAULT_DEV_ROOT_TOKEN are not set")
+	}
+	call := func(method, p string, body map[string]any) map[string]any {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		req, err := http.NewRequest(method, addr+"/v1/"+p, bytes.NewReader(b))
+		assert.NoError(t, err)
+		req.Header.Set("X-Vault-Token", rootToken)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			t.Fatalf("%s %s: status %d", method, p, resp.StatusCode)
+		}
+		out := map[string]any{}
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return out
+	}
+	mount := fmt.Sprintf("istio-pki-%d", time.Now().UnixNano())
+	call(http.MethodPost, "sys/mounts/"+mount, map[string]any{"type": "pki", "config": map[string]any{"max_lease_ttl": "87600h"}})
+	t.Cleanup(func() { call(http.MethodDelete, "sys/mounts/"+mount, nil) })
+	call(http.MethodPost, mount+"/root/generate/internal", map[string]any{"common_name": "Istio Vault Root", "ttl": "8760h"})
+	call(http.MethodPost, mount+"/roles/istio", map[string]any{
+		"allowed_uri_sans": "spiffe://cluster.local/*",
+		"require_cn":       false,
+		"max_ttl":          "24h",
+	})
+	call(http.MethodPost, "sys/policies/acl/"+mount, map[string]any{
+		"policy": fmt.Sprintf(`path "%s/sign/istio" { capabilities = ["create", "update"] }`, mount),
+	})
+	approle := "approle-" + mount
+	call(http.MethodPost, "sys/auth/"+approle, map[string]any{"type": "approle"})
+	t.Cleanup(func() { call(http.MethodDelete, "sys/auth/"+approle, nil) })
+	call(http.MethodPost, "auth/"+approle+"/role/istiod", map[string]any{"token_policies": mount})
+	roleID := call(http.MethodGet, "auth/"+approle+"/role/istiod/role-id", nil)["data"].(map[string]any)["role_id"].(string)
+	secretID := call(http.MethodPost, "auth/"+approle+"/role/istiod/secret-id", nil)["data"].(map[string]any)["secret_id"].(string)
+
+	r, err := NewVaultRA(&IstioRAOptions{
+		ExternalCAType: ExtCAVault,
+		DefaultCertTTL: 30 * time.Minute,
+		MaxCertTTL:     time.Hour,
+		Vault: VaultOptions{
+			Addr:                addr,
+			PKIMount:            mount,
+			Role:                "istio",
+			AuthMethod:          VaultAuthAppRole,
+			AuthMount:           approle,
+			AppRoleID:           roleID,
+			AppRoleSecretIDFile: writeTestFile(t, secretID),
+		},
+	})
+	assert.NoError(t, err)
+	chain, err := r.SignWithCertChain(createFakeCsr(t), ca.CertOpts{SubjectIDs: []string{testCsrHostName}})
+	assert.NoError(t, err)
+	assert.NoError(t, pkiutil.VerifyCertificate(nil, []byte(chain[0]), r.GetCAKeyCertBundle().GetRootCertPem(), nil))
+
+	// The role only allows identities of the trust domain.
+	_, err = r.Sign(createFakeCsrForHost(t, "spiffe://other.domain/ns/default/sa/default"),
+		ca.CertOpts{SubjectIDs: []string{"spiffe://other.domain/ns/default/sa/default"}})
+	assert.Error(t, err)
+}
+
+func createFakeCsrForHost(t *testing.T, host string) []byte {
+	csrPEM, _, err := pkiutil.GenCSR(pkiutil.CertOptions{Host: host, RSAKeySize: 2048})
+	assert.NoError(t, err)
+	return csrPEM
+}
@@ -142,6 +142,15 @@ func GetEllipticCurve(privKey *crypto.PrivateKey) (elliptic.Curve, error) {
 			return key.Curve, nil
 		}
 		return elliptic.P256(), nil
+	case crypto.Signer:
+		// The private key is held by a signer, e.g. in an HSM.
+		if pub, ok := key.Public().(*ecdsa.PublicKey); ok {
+			if pub.Curve == elliptic.P384() {
+				return pub.Curve, nil
+			}
+			return elliptic.P256(), nil
+		}
+		return nil, fmt.Errorf("private key is not ECDSA based")
 	default:
 		return nil, fmt.Errorf("private key is not ECDSA based")
 	}
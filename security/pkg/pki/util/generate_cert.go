@@ -46,9 +46,16 @@ type SupportedECSignatureAlgorithms string
 // to be used in key generation (e.g. P256, P384)
 type SupportedEllipticCurves string
 
+// SupportedRSASignatureAlgorithms are the signature schemes
+// to be used with RSA keys (e.g. PSS)
+type SupportedRSASignatureAlgorithms string
+
 const (
-	// only ECDSA is currently supported
-	EcdsaSigAlg SupportedECSignatureAlgorithms = "ECDSA"
+	EcdsaSigAlg   SupportedECSignatureAlgorithms = "ECDSA"
+	Ed25519SigAlg SupportedECSignatureAlgorithms = "ED25519"
+
+	// RsaPssSigAlg signs with RSASSA-PSS instead of RSASSA-PKCS1-v1_5.
+	RsaPssSigAlg SupportedRSASignatureAlgorithms = "PSS"
 
 	// supported curves when using ECC
 	P256Curve SupportedEllipticCurves = "P256"
@@ -102,15 +109,16 @@ type CertOptions struct {
 	PKCS8Key bool
 
 	// The type of Elliptical Signature algorithm to use
-	// when generating private keys. Currently only ECDSA is supported.
+	// when generating private keys. ECDSA and Ed25519 are supported.
 	// If empty, RSA is used, otherwise ECC is used.
 	ECSigAlg SupportedECSignatureAlgorithms
 
-	// The type of Elliptical Signature algorithm to use
-	// when generating private keys. Currently only ECDSA is supported.
-	// If empty, RSA is used, otherwise ECC is used.
+	// The elliptic curve to use when ECSigAlg is ECDSA.
 	ECCCurve SupportedEllipticCurves
 
+	// The signature scheme to use with RSA keys. If empty, RSASSA-PKCS1-v1_5 is used.
+	RSASigAlg SupportedRSASignatureAlgorithms
+
 	// Subjective Alternative Name values.
 	DNSNames string
 }
@@ -139,7 +147,12 @@ func GenCertKeyFromOptions(options CertOptions) (pemCert []byte, pemKey []byte,
 			if err != nil {
 				return nil, nil, fmt.Errorf("cert generation fails at EC key generation (%v)", err)
 			}
-
+		case Ed25519SigAlg:
+			edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cert generation fails at Ed25519 key generation (%v)", err)
+			}
+			return genCert(options, edPriv, edPub)
 		default:
 			return nil, nil, errors.New("cert generation fails due to unsupported EC signature algorithm")
 		}
@@ -165,6 +178,9 @@ func genCert(options CertOptions, priv any, key any) ([]byte, []byte, error) {
 	if !options.IsSelfSigned {
 		signerCert, signerKey = options.SignerCert, options.SignerPriv
 	}
+	if template.SignatureAlgorithm, err = rsaSignatureAlgorithm(options.RSASigAlg, signerKey); err != nil {
+		return nil, nil, err
+	}
 	certBytes, err := x509.CreateCertificate(rand.Reader, template, signerCert, key, signerKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("cert generation fails at X509 cert creation (%v)", err)
@@ -174,6 +190,58 @@ func genCert(options CertOptions, priv any, key any) ([]byte, []byte, error) {
 	return pemCert, pemKey, err
 }
 
+// ValidateKeyAlgorithms returns an error if the signature algorithms used to generate private keys
+// are not supported. Empty values select the defaults. Unsupported elliptic curves are not rejected,
+// as P256 is used instead; see IsSupportedEllipticCurve.
+func ValidateKeyAlgorithms(ecSigAlg SupportedECSignatureAlgorithms, rsaSigAlg SupportedRSASignatureAlgorithms) error {
+	switch ecSigAlg {
+	case "", EcdsaSigAlg, Ed25519SigAlg:
+	default:
+		return fmt.Errorf("unsupported EC signature algorithm %q, must be one of %v or %v", ecSigAlg, EcdsaSigAlg, Ed25519SigAlg)
+	}
+	switch rsaSigAlg {
+	case "", RsaPssSigAlg:
+	default:
+		return fmt.Errorf("unsupported RSA signature algorithm %q, must be %v", rsaSigAlg, RsaPssSigAlg)
+	}
+	return nil
+}
+
+// IsSupportedEllipticCurve returns whether the curve is supported. Keys are generated with P256
+// for the curves which are not.
+func IsSupportedEllipticCurve(curve SupportedEllipticCurves) bool {
+	return curve == "" || curve == P256Curve || curve == P384Curve
+}
+
+// rsaSignatureAlgorithm returns the signature algorithm to sign with signerKey using the RSA signature scheme
+// sigAlg. The default algorithm of the key is used if sigAlg is empty or the key is not an RSA key.
+func rsaSignatureAlgorithm(sigAlg SupportedRSASignatureAlgorithms, signerKey crypto.PrivateKey) (x509.SignatureAlgorithm, error) {
+	switch sigAlg {
+	case "":
+		return x509.UnknownSignatureAlgorithm, nil
+	case RsaPssSigAlg:
+		// The signer may hold the private key, e.g. in an HSM, so its public key determines the key type.
+		if signer, ok := signerKey.(crypto.Signer); ok {
+			if _, ok := signer.Public().(*rsa.PublicKey); ok {
+				return x509.SHA256WithRSAPSS, nil
+			}
+		}
+		return x509.UnknownSignatureAlgorithm, nil
+	default:
+		return x509.UnknownSignatureAlgorithm, fmt.Errorf("unsupported RSA signature algorithm %q", sigAlg)
+	}
+}
+
+// IsRSAPSS returns whether the signature algorithm is RSASSA-PSS.
+func IsRSAPSS(sigAlg x509.SignatureAlgorithm) bool {
+	switch sigAlg {
+	case x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS:
+		return true
+	default:
+		return false
+	}
+}
+
 func publicKey(priv any) any {
 	switch k := priv.(type) {
 	case *rsa.PrivateKey:
@@ -249,6 +317,12 @@ func GenCertFromCSR(csr *x509.CertificateRequest, signingCert *x509.Certificate,
 	if err != nil {
 		return nil, err
 	}
+	// Sign with RSASSA-PSS if it was used to sign the CSR, so that peers only supporting RSASSA-PSS can verify the chain.
+	if IsRSAPSS(csr.SignatureAlgorithm) {
+		if tmpl.SignatureAlgorithm, err = rsaSignatureAlgorithm(RsaPssSigAlg, signingKey); err != nil {
+			return nil, err
+		}
+	}
 	return x509.CreateCertificate(rand.Reader, tmpl, signingCert, publicKey, signingKey)
 }
 
@@ -293,8 +367,8 @@ func genCertTemplateFromCSR(csr *x509.CertificateRequest, subjectIDs []string, t
 	var keyUsage x509.KeyUsage
 	extKeyUsages := []x509.ExtKeyUsage{}
 	if isCA {
-		// If the cert is a CA cert, the private key is allowed to sign other certificates.
-		keyUsage = x509.KeyUsageCertSign
+		// If the cert is a CA cert, the private key is allowed to sign other certificates and revocation lists.
+		keyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
 	} else {
 		// Otherwise the private key is allowed for digital signature and key encipherment.
 		keyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
@@ -346,8 +420,8 @@ func genCertTemplateFromCSR(csr *x509.CertificateRequest, subjectIDs []string, t
 func genCertTemplateFromOptions(options CertOptions) (*x509.Certificate, error) {
 	var keyUsage x509.KeyUsage
 	if options.IsCA {
-		// If the cert is a CA cert, the private key is allowed to sign other certificates.
-		keyUsage = x509.KeyUsageCertSign
+		// If the cert is a CA cert, the private key is allowed to sign other certificates and revocation lists.
+		keyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
 	} else {
 		// Otherwise the private key is allowed for digital signature and key encipherment.
 		keyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
@@ -447,6 +521,12 @@ func encodePem(isCSR bool, csrOrCert []byte, priv any, pkcs8 bool) (
 				return nil, nil, err
 			}
 			privPem = pem.EncodeToMemory(&pem.Block{Type: blockTypeECPrivateKey, Bytes: encodedKey})
+		case ed25519.PrivateKey:
+			// Ed25519 keys can only be encoded with PKCS#8.
+			if encodedKey, err = x509.MarshalPKCS8PrivateKey(k); err != nil {
+				return nil, nil, err
+			}
+			privPem = pem.EncodeToMemory(&pem.Block{Type: blockTypePKCS8PrivateKey, Bytes: encodedKey})
 		}
 	}
 	err = nil
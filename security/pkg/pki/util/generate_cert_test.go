@@ -24,6 +24,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -102,7 +103,7 @@ func TestGenCertKeyFromOptions(t *testing.T) {
 		NotBefore:   caCertNotBefore,
 		TTL:         caCertTTL,
 		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-		KeyUsage:    x509.KeyUsageCertSign,
+		KeyUsage:    x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 		IsCA:        true,
 		Org:         "MyOrg",
 		Host:        host,
@@ -654,6 +655,16 @@ func TestGenCertFromCSR(t *testing.T) {
 				Version:            3,
 			},
 		},
+		{
+			name:       "Use RSA-PSS signature",
+			subjectIDs: []string{"test.com"},
+			signeeKey:  rsaSigneeKey,
+			csrTemplate: &x509.CertificateRequest{
+				SignatureAlgorithm: x509.SHA256WithRSAPSS,
+				DNSNames:           []string{"name_in_csr"},
+				Version:            3,
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -709,6 +720,60 @@ func TestGenCertFromCSR(t *testing.T) {
 		if _, err := out.Verify(vo); err != nil {
 			t.Errorf("verification of the signed certificate failed %v", err)
 		}
+		// The certificate is signed with RSASSA-PSS if the CSR was.
+		if IsRSAPSS(out.SignatureAlgorithm) != IsRSAPSS(c.csrTemplate.SignatureAlgorithm) {
+			t.Errorf("unexpected signature algorithm %v", out.SignatureAlgorithm)
+		}
+	}
+}
+
+func TestGenCertKeyFromOptionsKeyAlgorithms(t *testing.T) {
+	cases := []struct {
+		name           string
+		options        CertOptions
+		expectedKey    any
+		expectedSigAlg x509.SignatureAlgorithm
+	}{
+		{
+			name:           "Ed25519",
+			options:        CertOptions{ECSigAlg: Ed25519SigAlg},
+			expectedKey:    ed25519.PublicKey{},
+			expectedSigAlg: x509.PureEd25519,
+		},
+		{
+			name:           "RSA-PSS",
+			options:        CertOptions{RSAKeySize: 2048, RSASigAlg: RsaPssSigAlg},
+			expectedKey:    &rsa.PublicKey{},
+			expectedSigAlg: x509.SHA256WithRSAPSS,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.options.Host = "spiffe://cluster.local/ns/default/sa/default"
+			c.options.TTL = time.Hour
+			c.options.IsSelfSigned = true
+			c.options.IsCA = true
+			certPem, keyPem, err := GenCertKeyFromOptions(c.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cert, err := ParsePemEncodedCertificate(certPem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.TypeOf(cert.PublicKey) != reflect.TypeOf(c.expectedKey) {
+				t.Errorf("unexpected key type %T", cert.PublicKey)
+			}
+			if cert.SignatureAlgorithm != c.expectedSigAlg {
+				t.Errorf("unexpected signature algorithm %v", cert.SignatureAlgorithm)
+			}
+			if err := cert.CheckSignatureFrom(cert); err != nil {
+				t.Errorf("invalid signature: %v", err)
+			}
+			if _, err := ParsePemEncodedKey(keyPem); err != nil {
+				t.Errorf("failed to parse key: %v", err)
+			}
+		})
 	}
 }
 
@@ -21,6 +21,7 @@ package util
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -56,6 +57,11 @@ func GenCSR(options CertOptions) ([]byte, []byte, error) {
 			if err != nil {
 				return nil, nil, fmt.Errorf("EC key generation failed (%v)", err)
 			}
+		case Ed25519SigAlg:
+			_, priv, err = ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Ed25519 key generation failed (%v)", err)
+			}
 		default:
 			return nil, nil, errors.New("csr cert generation fails due to unsupported EC signature algorithm")
 		}
@@ -73,6 +79,9 @@ func GenCSR(options CertOptions) ([]byte, []byte, error) {
 	if err != nil {
 		return nil, nil, fmt.Errorf("CSR template creation failed (%v)", err)
 	}
+	if template.SignatureAlgorithm, err = rsaSignatureAlgorithm(options.RSASigAlg, priv); err != nil {
+		return nil, nil, err
+	}
 
 	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, template, crypto.PrivateKey(priv))
 	if err != nil {
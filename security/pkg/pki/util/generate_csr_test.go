@@ -16,6 +16,7 @@ package util
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -45,14 +46,38 @@ func TestGenCSR(t *testing.T) {
 				ECSigAlg: EcdsaSigAlg,
 			},
 		},
+		"GenCSR with Ed25519": {
+			csrOptions: CertOptions{
+				Host:     "test_ca.com",
+				Org:      "MyOrg",
+				ECSigAlg: Ed25519SigAlg,
+			},
+		},
+		"GenCSR with RSA-PSS": {
+			csrOptions: CertOptions{
+				Host:       "test_ca.com",
+				Org:        "MyOrg",
+				RSAKeySize: 2048,
+				RSASigAlg:  RsaPssSigAlg,
+			},
+		},
 		"GenCSR with EC errors due to invalid signature algorithm": {
 			csrOptions: CertOptions{
 				Host:     "test_ca.com",
 				Org:      "MyOrg",
-				ECSigAlg: "ED25519",
+				ECSigAlg: "ED448",
 			},
 			err: errors.New("csr cert generation fails due to unsupported EC signature algorithm"),
 		},
+		"GenCSR with RSA errors due to invalid signature algorithm": {
+			csrOptions: CertOptions{
+				Host:       "test_ca.com",
+				Org:        "MyOrg",
+				RSAKeySize: 2048,
+				RSASigAlg:  "PKCS1",
+			},
+			err: errors.New(`unsupported RSA signature algorithm "PKCS1"`),
+		},
 	}
 
 	for id, tc := range cases {
@@ -85,15 +110,22 @@ func TestGenCSR(t *testing.T) {
 		if !strings.HasSuffix(string(csr.Extensions[0].Value), "test_ca.com") {
 			t.Errorf("%s: csr host does not match", id)
 		}
-		if tc.csrOptions.ECSigAlg != "" {
-			if tc.csrOptions.ECSigAlg != EcdsaSigAlg {
-				t.Errorf("%s: Only ECDSA signature algorithms are currently supported", id)
-			}
+		switch tc.csrOptions.ECSigAlg {
+		case EcdsaSigAlg:
 			if reflect.TypeOf(csr.PublicKey) != reflect.TypeOf(&ecdsa.PublicKey{}) {
 				t.Errorf("%s: decoded PKCS#8 returned unexpected key type: %T", id, csr.PublicKey)
 			}
-		} else if reflect.TypeOf(csr.PublicKey) != reflect.TypeOf(&rsa.PublicKey{}) {
-			t.Errorf("%s: decoded PKCS#8 returned unexpected key type: %T", id, csr.PublicKey)
+		case Ed25519SigAlg:
+			if reflect.TypeOf(csr.PublicKey) != reflect.TypeOf(ed25519.PublicKey{}) {
+				t.Errorf("%s: decoded PKCS#8 returned unexpected key type: %T", id, csr.PublicKey)
+			}
+		default:
+			if reflect.TypeOf(csr.PublicKey) != reflect.TypeOf(&rsa.PublicKey{}) {
+				t.Errorf("%s: decoded PKCS#8 returned unexpected key type: %T", id, csr.PublicKey)
+			}
+		}
+		if IsRSAPSS(csr.SignatureAlgorithm) != (tc.csrOptions.RSASigAlg == RsaPssSigAlg) {
+			t.Errorf("%s: unexpected csr signature algorithm: %v", id, csr.SignatureAlgorithm)
 		}
 	}
 }
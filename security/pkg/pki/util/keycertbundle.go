@@ -21,6 +21,7 @@ package util
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
@@ -70,31 +71,62 @@ func NewVerifiedKeyCertBundleFromPem(certBytes, privKeyBytes, certChainBytes, ro
 func NewVerifiedKeyCertBundleFromFile(certFile string, privKeyFile string, certChainFiles []string, rootCertFile string) (
 	*KeyCertBundle, error,
 ) {
-	certBytes, err := os.ReadFile(certFile)
+	privKeyBytes, err := os.ReadFile(privKeyFile)
 	if err != nil {
 		return nil, err
 	}
-	privKeyBytes, err := os.ReadFile(privKeyFile)
+	certBytes, certChainBytes, rootCertBytes, err := readCertFiles(certFile, certChainFiles, rootCertFile)
 	if err != nil {
 		return nil, err
 	}
-	var certChainBytes []byte
-	if len(certChainFiles) > 0 {
-		for _, f := range certChainFiles {
-			var b []byte
-
-			if b, err = os.ReadFile(f); err != nil {
-				return nil, err
-			}
+	return NewVerifiedKeyCertBundleFromPem(certBytes, privKeyBytes, certChainBytes, rootCertBytes)
+}
 
-			certChainBytes = append(certChainBytes, b...)
-		}
+// NewVerifiedKeyCertBundleFromSigner returns a new KeyCertBundle whose private key is held by signer, or error if
+// the provided certs failed the verification. This allows the key to be kept in an HSM: the bundle has no PEM
+// encoded private key, and certificates are signed through signer.
+func NewVerifiedKeyCertBundleFromSigner(certFile string, signer crypto.Signer, certChainFiles []string, rootCertFile string) (
+	*KeyCertBundle, error,
+) {
+	certBytes, certChainBytes, rootCertBytes, err := readCertFiles(certFile, certChainFiles, rootCertFile)
+	if err != nil {
+		return nil, err
 	}
-	rootCertBytes, err := os.ReadFile(rootCertFile)
+	if err := VerifyWithSigner(certBytes, signer, certChainBytes, rootCertBytes); err != nil {
+		return nil, err
+	}
+	cert, err := ParsePemEncodedCertificate(certBytes)
 	if err != nil {
 		return nil, err
 	}
-	return NewVerifiedKeyCertBundleFromPem(certBytes, privKeyBytes, certChainBytes, rootCertBytes)
+	privKey := crypto.PrivateKey(signer)
+	return &KeyCertBundle{
+		certBytes:      copyBytes(certBytes),
+		cert:           cert,
+		privKeyBytes:   []byte{},
+		privKey:        &privKey,
+		certChainBytes: copyBytes(certChainBytes),
+		rootCertBytes:  copyBytes(rootCertBytes),
+	}, nil
+}
+
+func readCertFiles(certFile string, certChainFiles []string, rootCertFile string) (
+	certBytes, certChainBytes, rootCertBytes []byte, err error,
+) {
+	if certBytes, err = os.ReadFile(certFile); err != nil {
+		return nil, nil, nil, err
+	}
+	for _, f := range certChainFiles {
+		var b []byte
+		if b, err = os.ReadFile(f); err != nil {
+			return nil, nil, nil, err
+		}
+		certChainBytes = append(certChainBytes, b...)
+	}
+	if rootCertBytes, err = os.ReadFile(rootCertFile); err != nil {
+		return nil, nil, nil, err
+	}
+	return certBytes, certChainBytes, rootCertBytes, nil
 }
 
 // NewKeyCertBundleWithRootCertFromFile returns a new KeyCertBundle with the root cert without verification.
@@ -168,6 +200,19 @@ func (b *KeyCertBundle) VerifyAndSetAll(certBytes, privKeyBytes, certChainBytes,
 	return nil
 }
 
+// VerifyAndSetRootCert verifies that the cert can be verified from the new root certs through the cert chain,
+// and replaces the root certs. This allows to trust additional roots, or to drop roots, without changing the
+// signing key and cert.
+func (b *KeyCertBundle) VerifyAndSetRootCert(rootCertBytes []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if _, err := verifyCertChain(b.certBytes, b.certChainBytes, rootCertBytes); err != nil {
+		return err
+	}
+	b.rootCertBytes = copyBytes(rootCertBytes)
+	return nil
+}
+
 // Setting all values together avoids inconsistency.
 func (b *KeyCertBundle) setAllFromPem(certBytes, privKeyBytes, certChainBytes, rootCertBytes []byte) {
 	b.mutex.Lock()
@@ -203,7 +248,7 @@ func (b *KeyCertBundle) CertOptions() (*CertOptions, error) {
 		IsDualUse: ids[0] == b.cert.Subject.CommonName,
 	}
 
-	switch (*b.privKey).(type) {
+	switch key := (*b.privKey).(type) {
 	case *rsa.PrivateKey:
 		size, err := GetRSAKeySize(*b.privKey)
 		if err != nil {
@@ -212,6 +257,20 @@ func (b *KeyCertBundle) CertOptions() (*CertOptions, error) {
 		opts.RSAKeySize = size
 	case *ecdsa.PrivateKey:
 		opts.ECSigAlg = EcdsaSigAlg
+	case ed25519.PrivateKey:
+		opts.ECSigAlg = Ed25519SigAlg
+	case crypto.Signer:
+		// The private key is held by a signer, e.g. in an HSM.
+		switch pub := key.Public().(type) {
+		case *rsa.PublicKey:
+			opts.RSAKeySize = pub.N.BitLen()
+		case *ecdsa.PublicKey:
+			opts.ECSigAlg = EcdsaSigAlg
+		case ed25519.PublicKey:
+			opts.ECSigAlg = Ed25519SigAlg
+		default:
+			return nil, errors.New("unknown public key type of signer")
+		}
 	default:
 		return nil, errors.New("unknown private key type")
 	}
@@ -282,7 +341,38 @@ func TimeBeforeCertExpires(certBytes []byte, now time.Time) (time.Duration, erro
 
 // Verify that the cert chain, root cert and key/cert match.
 func Verify(certBytes, privKeyBytes, certChainBytes, rootCertBytes []byte) error {
-	// Verify the cert can be verified from the root cert through the cert chain.
+	if _, err := verifyCertChain(certBytes, certChainBytes, rootCertBytes); err != nil {
+		return err
+	}
+
+	// Verify that the key can be correctly parsed.
+	if _, err := ParsePemEncodedKey(privKeyBytes); err != nil {
+		return fmt.Errorf("failed to parse private key PEM: %v", err)
+	}
+
+	// Verify the cert and key match.
+	if _, err := tls.X509KeyPair(certBytes, privKeyBytes); err != nil {
+		return fmt.Errorf("the cert does not match the key: %v", err)
+	}
+
+	return nil
+}
+
+// VerifyWithSigner verifies that the cert chain and root cert match, and that the cert matches the public key of signer.
+func VerifyWithSigner(certBytes []byte, signer crypto.Signer, certChainBytes, rootCertBytes []byte) error {
+	cert, err := verifyCertChain(certBytes, certChainBytes, rootCertBytes)
+	if err != nil {
+		return err
+	}
+	pub, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(cert.PublicKey) {
+		return fmt.Errorf("the cert does not match the public key of the signer")
+	}
+	return nil
+}
+
+// verifyCertChain verifies the cert can be verified from the root cert through the cert chain.
+func verifyCertChain(certBytes, certChainBytes, rootCertBytes []byte) (*x509.Certificate, error) {
 	rcp := x509.NewCertPool()
 	rcp.AppendCertsFromPEM(rootCertBytes)
 
@@ -295,27 +385,16 @@ func Verify(certBytes, privKeyBytes, certChainBytes, rootCertBytes []byte) error
 	}
 	cert, err := ParsePemEncodedCertificate(certBytes)
 	if err != nil {
-		return fmt.Errorf("failed to parse cert PEM: %v", err)
+		return nil, fmt.Errorf("failed to parse cert PEM: %v", err)
 	}
 	chains, err := cert.Verify(opts)
 
 	if len(chains) == 0 || err != nil {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"cannot verify the cert with the provided root chain and cert "+
 				"pool with error: %v", err)
 	}
-
-	// Verify that the key can be correctly parsed.
-	if _, err = ParsePemEncodedKey(privKeyBytes); err != nil {
-		return fmt.Errorf("failed to parse private key PEM: %v", err)
-	}
-
-	// Verify the cert and key match.
-	if _, err := tls.X509KeyPair(certBytes, privKeyBytes); err != nil {
-		return fmt.Errorf("the cert does not match the key: %v", err)
-	}
-
-	return nil
+	return cert, nil
 }
 
 func extractCertExpiryTimestamp(certType string, certPem []byte) (float64, error) {
@@ -15,7 +15,10 @@
 package util
 
 import (
+	"bytes"
+	"crypto"
 	"fmt"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -204,6 +207,122 @@ func TestCertOptionsAndRetrieveID(t *testing.T) {
 	}
 }
 
+func TestVerifyAndSetRootCert(t *testing.T) {
+	bundle, err := NewVerifiedKeyCertBundleFromFile(intCertFile, intKeyFile, []string{intCertChainFile}, rootCertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCert, err := os.ReadFile(rootCertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anotherRootCert, err := os.ReadFile(anotherRootCertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bundle.VerifyAndSetRootCert(anotherRootCert); err == nil {
+		t.Errorf("expected error for roots not trusting the cert")
+	}
+	if got := bundle.GetRootCertPem(); !bytes.Equal(got, rootCert) {
+		t.Errorf("root cert changed after failed verification")
+	}
+	combined := append(append([]byte{}, rootCert...), anotherRootCert...)
+	if err := bundle.VerifyAndSetRootCert(combined); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := bundle.GetRootCertPem(); !bytes.Equal(got, combined) {
+		t.Errorf("expected root cert %s, got %s", combined, got)
+	}
+}
+
+// opaqueSigner hides the type of the private key, as for a key held by an HSM.
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+func TestNewVerifiedKeyCertBundleFromSigner(t *testing.T) {
+	testCases := map[string]struct {
+		certFile     string
+		keyFile      string
+		rootCertFile string
+		certOptions  *CertOptions
+		expectedErr  string
+	}{
+		"RSA Success": {
+			certFile:     certChainFile1,
+			keyFile:      keyFile1,
+			rootCertFile: rootCertFile1,
+			certOptions: &CertOptions{
+				Host:       "watt",
+				TTL:        100 * 365 * 24 * time.Hour,
+				Org:        "Juju org",
+				RSAKeySize: 2048,
+			},
+		},
+		"EC Success": {
+			certFile:     ecClientCertFile,
+			keyFile:      ecClientKeyFile,
+			rootCertFile: ecRootCertFile,
+			certOptions: &CertOptions{
+				Host:     "watt",
+				TTL:      10 * 365 * 24 * time.Hour,
+				Org:      "Juju org",
+				ECSigAlg: EcdsaSigAlg,
+			},
+		},
+		"Key mismatch": {
+			certFile:     certChainFile1,
+			keyFile:      ecClientKeyFile,
+			rootCertFile: rootCertFile1,
+			expectedErr:  "the cert does not match the public key of the signer",
+		},
+		"Root mismatch": {
+			certFile:     certChainFile1,
+			keyFile:      keyFile1,
+			rootCertFile: ecRootCertFile,
+			expectedErr:  "cannot verify the cert with the provided root chain and cert pool",
+		},
+	}
+	for id, tc := range testCases {
+		t.Run(id, func(t *testing.T) {
+			keyPEM, err := os.ReadFile(tc.keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := ParsePemEncodedKey(keyPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			k, err := NewVerifiedKeyCertBundleFromSigner(tc.certFile, opaqueSigner{key.(crypto.Signer)}, nil, tc.rootCertFile)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, privKeyPEM, _, _ := k.GetAllPem(); len(privKeyPEM) != 0 {
+				t.Errorf("expected no private key PEM, got %s", privKeyPEM)
+			}
+			opts, err := k.CertOptions()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			compareCertOptions(opts, tc.certOptions, t)
+			if opts.ECSigAlg != tc.certOptions.ECSigAlg {
+				t.Errorf("ECSigAlg does not match, %s vs %s", opts.ECSigAlg, tc.certOptions.ECSigAlg)
+			}
+			_, privKey, _, _ := k.GetAll()
+			if _, err := GetEllipticCurve(privKey); (err == nil) != (tc.certOptions.ECSigAlg == EcdsaSigAlg) {
+				t.Errorf("unexpected elliptic curve result: %v", err)
+			}
+		})
+	}
+}
+
 func compareCertOptions(actual, expected *CertOptions, t *testing.T) {
 	if actual.Host != expected.Host {
 		t.Errorf("host does not match, %s vs %s", actual.Host, expected.Host)
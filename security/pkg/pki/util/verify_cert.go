@@ -16,6 +16,7 @@ package util
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"fmt"
@@ -91,8 +92,12 @@ func VerifyCertificate(privPem []byte, certChainPem []byte, rootCertPem []byte,
 		privECKey, privECOk := priv.(*ecdsa.PrivateKey)
 		pubECKey, pubECOk := cert.PublicKey.(*ecdsa.PublicKey)
 
+		privEdKey, privEdOk := priv.(ed25519.PrivateKey)
+		pubEdKey, pubEdOk := cert.PublicKey.(ed25519.PublicKey)
+
 		rsaMatch := privRSAOk && pubRSAOk
 		ecMatch := privECOk && pubECOk
+		edMatch := privEdOk && pubEdOk
 
 		if rsaMatch {
 			if !reflect.DeepEqual(privRSAKey.PublicKey, *pubRSAKey) {
@@ -102,6 +107,10 @@ func VerifyCertificate(privPem []byte, certChainPem []byte, rootCertPem []byte,
 			if !reflect.DeepEqual(privECKey.PublicKey, *pubECKey) {
 				return fmt.Errorf("the generated private EC key and cert doesn't match")
 			}
+		} else if edMatch {
+			if !pubEdKey.Equal(privEdKey.Public()) {
+				return fmt.Errorf("the generated private Ed25519 key and cert doesn't match")
+			}
 		} else {
 			return fmt.Errorf("algorithms for private key and cert do not match")
 		}
@@ -0,0 +1,223 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// AuditEvent is the audit record of a certificate signing request handled by the CA.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// CallerIdentities are the identities of the authenticated caller.
+	CallerIdentities []string `json:"callerIdentities,omitempty"`
+	// AuthenticatorType is the type of the authenticator which authenticated the caller.
+	AuthenticatorType string `json:"authenticatorType,omitempty"`
+	RemoteAddress     string `json:"remoteAddress,omitempty"`
+	ClusterID         string `json:"clusterID,omitempty"`
+	// ImpersonatedIdentity is set if a trusted node requested a certificate on behalf of another identity.
+	ImpersonatedIdentity string `json:"impersonatedIdentity,omitempty"`
+	// SANs are the identities requested for the certificate.
+	SANs         []string `json:"sans,omitempty"`
+	CertSigner   string   `json:"certSigner,omitempty"`
+	RequestedTTL Duration `json:"requestedTTL"`
+	// GrantedTTL is the time from issuance until the certificate expires. It may be shorter than
+	// RequestedTTL, if the request exceeded the maximum TTL allowed by the CA.
+	GrantedTTL   Duration   `json:"grantedTTL,omitempty"`
+	NotAfter     *time.Time `json:"notAfter,omitempty"`
+	SerialNumber string     `json:"serialNumber,omitempty"`
+	Success      bool       `json:"success"`
+	// Reason is the reason of a failure.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Duration is a time.Duration which is encoded in JSON as a string, such as "24h0m0s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// AuditSink records audit events.
+type AuditSink interface {
+	// Write records the event.
+	Write(event *AuditEvent) error
+	// Close flushes pending events and releases the resources of the sink.
+	Close() error
+}
+
+// audit records the event to all sinks. Failures are logged and counted, but do not fail the request.
+func (s *Server) audit(event *AuditEvent) {
+	if len(s.AuditSinks) == 0 {
+		return
+	}
+	event.Time = time.Now()
+	for _, sink := range s.AuditSinks {
+		if err := sink.Write(event); err != nil {
+			serverCaLog.Errorf("failed to write audit event for %v: %v", event.SANs, err)
+			s.monitoring.AuditError.Increment()
+		}
+	}
+}
+
+// fileAuditSink appends audit events, one JSON object per line, to a file rotated by size.
+type fileAuditSink struct {
+	mu     sync.Mutex
+	logger *lumberjack.Logger
+}
+
+// NewFileAuditSink creates an AuditSink appending to the file at path. The file is rotated when it
+// reaches maxSizeMB megabytes, and at most maxBackups rotated files are retained (0 retains all).
+func NewFileAuditSink(path string, maxSizeMB, maxBackups int) AuditSink {
+	return &fileAuditSink{
+		logger: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    maxSizeMB,
+			MaxBackups: maxBackups,
+		},
+	}
+}
+
+func (f *fileAuditSink) Write(event *AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err = f.logger.Write(append(b, '\n'))
+	return err
+}
+
+func (f *fileAuditSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logger.Close()
+}
+
+const (
+	webhookQueueSize     = 1000
+	webhookMaxRetries    = 3
+	webhookRetryInterval = time.Second
+)
+
+// webhookAuditSink posts audit events as JSON to an HTTP endpoint. Events are sent asynchronously,
+// so that a slow endpoint does not delay certificate signing; events are dropped if the queue is full.
+type webhookAuditSink struct {
+	url    string
+	client *http.Client
+	done   chan struct{}
+
+	mu     sync.RWMutex
+	queue  chan *AuditEvent
+	closed bool
+}
+
+// NewWebhookAuditSink creates an AuditSink posting each event to url.
+func NewWebhookAuditSink(url string, timeout time.Duration) AuditSink {
+	w := &webhookAuditSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+		queue:  make(chan *AuditEvent, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *webhookAuditSink) Write(event *AuditEvent) error {
+	// Copy the event, as it is sent after the caller returns.
+	e := *event
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return fmt.Errorf("webhook audit sink is closed")
+	}
+	select {
+	case w.queue <- &e:
+		return nil
+	default:
+		auditDroppedCounts.Increment()
+		return fmt.Errorf("webhook audit queue is full")
+	}
+}
+
+func (w *webhookAuditSink) run() {
+	defer close(w.done)
+	for event := range w.queue {
+		b, err := json.Marshal(event)
+		if err != nil {
+			serverCaLog.Errorf("failed to marshal audit event: %v", err)
+			continue
+		}
+		for attempt := 0; ; attempt++ {
+			err = w.post(b)
+			if err == nil || attempt == webhookMaxRetries {
+				break
+			}
+			time.Sleep(webhookRetryInterval * time.Duration(attempt+1))
+		}
+		if err != nil {
+			serverCaLog.Errorf("failed to send audit event for %v to webhook: %v", event.SANs, err)
+			auditDroppedCounts.Increment()
+		}
+	}
+}
+
+func (w *webhookAuditSink) post(body []byte) error {
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close sends the queued events and stops the sink.
+func (w *webhookAuditSink) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	pb "istio.io/api/security/v1alpha1"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+	mockca "istio.io/istio/security/pkg/pki/ca/mock"
+	caerror "istio.io/istio/security/pkg/pki/error"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+type fakeAuditSink struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+func (f *fakeAuditSink) Write(event *AuditEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func (f *fakeAuditSink) Close() error {
+	return nil
+}
+
+func TestCreateCertificateAudit(t *testing.T) {
+	certPEM, _, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:         "spiffe://cluster.local/ns/default/sa/test",
+		TTL:          time.Hour,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	assert.NoError(t, err)
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	assert.NoError(t, err)
+
+	authn := &mockAuthenticator{identities: []string{"spiffe://cluster.local/ns/default/sa/test"}}
+	cases := []struct {
+		name           string
+		authenticators []security.Authenticator
+		ca             CertificateAuthority
+		expected       AuditEvent
+	}{
+		{
+			name:           "unauthenticated",
+			authenticators: []security.Authenticator{&mockAuthenticator{errMsg: "Not authorized"}},
+			ca:             &mockca.FakeCA{},
+			expected: AuditEvent{
+				Reason: "request authenticate failure",
+			},
+		},
+		{
+			name:           "sign error",
+			authenticators: []security.Authenticator{authn},
+			ca:             &mockca.FakeCA{SignErr: caerror.NewError(caerror.TTLError, fmt.Errorf("ttl too long"))},
+			expected: AuditEvent{
+				CallerIdentities:  authn.identities,
+				AuthenticatorType: "mockAuthenticator",
+				SANs:              authn.identities,
+				Reason:            "CSR signing error: ttl too long",
+			},
+		},
+		{
+			name:           "success",
+			authenticators: []security.Authenticator{authn},
+			ca: &mockca.FakeCA{
+				SignedCert:    certPEM,
+				KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, nil, []byte("root_cert")),
+			},
+			expected: AuditEvent{
+				CallerIdentities:  authn.identities,
+				AuthenticatorType: "mockAuthenticator",
+				SANs:              authn.identities,
+				SerialNumber:      cert.SerialNumber.Text(16),
+				NotAfter:          &cert.NotAfter,
+				Success:           true,
+			},
+		},
+	}
+
+	p := &peer.Peer{Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)}, AuthInfo: credentials.TLSInfo{}}
+	ctx := peer.NewContext(context.Background(), p)
+	ctx = metadata.NewIncomingContext(ctx, metadata.MD{"clusterid": []string{"Kubernetes"}})
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &fakeAuditSink{}
+			server := &Server{
+				ca:             tt.ca,
+				Authenticators: tt.authenticators,
+				monitoring:     newMonitoringMetrics(),
+				AuditSinks:     []AuditSink{sink},
+			}
+			_, _ = server.CreateCertificate(ctx, &pb.IstioCertificateRequest{Csr: "dumb CSR", ValidityDuration: 3600})
+
+			assert.Equal(t, len(sink.events), 1)
+			got := sink.events[0]
+			assert.Equal(t, got.Time.IsZero(), false)
+			if tt.expected.Success {
+				assert.Equal(t, got.GrantedTTL > 0 && got.GrantedTTL <= Duration(time.Hour), true)
+			}
+			tt.expected.Time = got.Time
+			tt.expected.GrantedTTL = got.GrantedTTL
+			tt.expected.RemoteAddress = "192.168.1.1"
+			tt.expected.ClusterID = "Kubernetes"
+			tt.expected.RequestedTTL = Duration(time.Hour)
+			assert.Equal(t, got, tt.expected)
+		})
+	}
+}
+
+func TestFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink := NewFileAuditSink(path, 1, 1)
+	assert.NoError(t, sink.Write(&AuditEvent{SANs: []string{"a"}, Success: true}))
+	assert.NoError(t, sink.Write(&AuditEvent{SANs: []string{"b"}, Reason: "failed"}))
+	assert.NoError(t, sink.Close())
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	var got []AuditEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		e := AuditEvent{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		got = append(got, e)
+	}
+	assert.Equal(t, got, []AuditEvent{
+		{SANs: []string{"a"}, Success: true},
+		{SANs: []string{"b"}, Reason: "failed"},
+	})
+}
+
+func TestWebhookAuditSink(t *testing.T) {
+	var mu sync.Mutex
+	var got []AuditEvent
+	failures := 1
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Fail the first request, which should be retried.
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		e := AuditEvent{}
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		got = append(got, e)
+	}))
+	defer s.Close()
+
+	sink := NewWebhookAuditSink(s.URL, time.Second)
+	event := &AuditEvent{SANs: []string{"a"}, RequestedTTL: Duration(time.Hour), Success: true}
+	assert.NoError(t, sink.Write(event))
+	// The event is copied, so changes after Write are not sent.
+	event.SANs = []string{"changed"}
+	assert.NoError(t, sink.Write(&AuditEvent{SANs: []string{"b"}}))
+	retry.UntilOrFail(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 2
+	}, retry.Timeout(10*time.Second))
+	assert.NoError(t, sink.Close())
+	assert.Equal(t, got, []AuditEvent{
+		{SANs: []string{"a"}, RequestedTTL: Duration(time.Hour), Success: true},
+		{SANs: []string{"b"}},
+	})
+	assert.Error(t, sink.Write(event))
+}
@@ -19,11 +19,15 @@ import (
 )
 
 const (
-	errorlabel = "error"
+	errorlabel     = "error"
+	namespaceLabel = "namespace"
+	limitLabel     = "limit"
 )
 
 var (
-	errorTag = monitoring.CreateLabel(errorlabel)
+	errorTag     = monitoring.CreateLabel(errorlabel)
+	namespaceTag = monitoring.CreateLabel(namespaceLabel)
+	limitTag     = monitoring.CreateLabel(limitLabel)
 
 	csrCounts = monitoring.NewSum(
 		"citadel_server_csr_count",
@@ -55,6 +59,21 @@ var (
 		"The number of certificates issuances that have succeeded.",
 	)
 
+	auditErrorCounts = monitoring.NewSum(
+		"citadel_server_audit_error_count",
+		"The number of errors occurred when recording audit events of CSRs.",
+	)
+
+	auditDroppedCounts = monitoring.NewSum(
+		"citadel_server_audit_dropped_count",
+		"The number of audit events which could not be delivered to the audit webhook.",
+	)
+
+	throttledCounts = monitoring.NewSum(
+		"citadel_server_csr_throttled_count",
+		"The number of CSRs rejected by the rate limits of the CA, by namespace and exceeded limit.",
+	)
+
 	rootCertExpiryTimestamp = monitoring.NewGauge(
 		"citadel_server_root_cert_expiry_timestamp",
 		"The unix timestamp, in seconds, when Citadel root cert will expire. "+
@@ -75,6 +94,8 @@ type monitoringMetrics struct {
 	CSRError          monitoring.Metric
 	IDExtractionError monitoring.Metric
 	certSignErrors    monitoring.Metric
+	AuditError        monitoring.Metric
+	throttled         monitoring.Metric
 }
 
 // newMonitoringMetrics creates a new monitoringMetrics.
@@ -86,9 +107,15 @@ func newMonitoringMetrics() monitoringMetrics {
 		CSRError:          csrParsingErrorCounts,
 		IDExtractionError: idExtractionErrorCounts,
 		certSignErrors:    certSignErrorCounts,
+		AuditError:        auditErrorCounts,
+		throttled:         throttledCounts,
 	}
 }
 
 func (m *monitoringMetrics) GetCertSignError(err string) monitoring.Metric {
 	return m.certSignErrors.With(errorTag.Value(err))
 }
+
+func (m *monitoringMetrics) GetThrottled(namespace, limit string) monitoring.Metric {
+	return m.throttled.With(namespaceTag.Value(namespace), limitTag.Value(limit))
+}
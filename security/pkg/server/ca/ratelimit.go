@@ -0,0 +1,145 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pkg/spiffe"
+)
+
+// rateLimiterSweepInterval is how often the token buckets of identities and namespaces which are no longer limited
+// are dropped.
+const rateLimiterSweepInterval = 10 * time.Minute
+
+const (
+	limitIdentity  = "identity"
+	limitNamespace = "namespace"
+)
+
+// csrRateLimiter limits the rate of CSRs with token buckets keyed by identity and by namespace, so that a crash-looping
+// workload cannot flood the CA. A zero rate disables the corresponding limit.
+type csrRateLimiter struct {
+	identityLimit  rate.Limit
+	identityBurst  int
+	namespaceLimit rate.Limit
+	namespaceBurst int
+	now            func() time.Time
+
+	mutex      sync.Mutex
+	identities map[string]*rate.Limiter
+	namespaces map[string]*rate.Limiter
+	lastSweep  time.Time
+}
+
+func newCSRRateLimiter(identityQPS float64, identityBurst int, namespaceQPS float64, namespaceBurst int) *csrRateLimiter {
+	if identityQPS <= 0 && namespaceQPS <= 0 {
+		return nil
+	}
+	return &csrRateLimiter{
+		identityLimit:  rate.Limit(identityQPS),
+		identityBurst:  max(identityBurst, 1),
+		namespaceLimit: rate.Limit(namespaceQPS),
+		namespaceBurst: max(namespaceBurst, 1),
+		now:            time.Now,
+		identities:     map[string]*rate.Limiter{},
+		namespaces:     map[string]*rate.Limiter{},
+	}
+}
+
+// namespaceOf returns the namespace of the first SPIFFE identity, or an empty string if there is none.
+func namespaceOf(identities []string) string {
+	for _, identity := range identities {
+		if id, err := spiffe.ParseIdentity(identity); err == nil {
+			return id.Namespace
+		}
+	}
+	return ""
+}
+
+// allow takes a token for the identities of a CSR and for their namespace. If either bucket is empty, no token is
+// taken, and the exceeded limit is returned with the delay after which the request may be retried.
+func (l *csrRateLimiter) allow(identities []string) (limit string, retryAfter time.Duration) {
+	identity := strings.Join(identities, ",")
+	namespace := namespaceOf(identities)
+	now := l.now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.sweep(now)
+
+	var reservations []*rate.Reservation
+	check := func(name string, limiter *rate.Limiter) {
+		r := limiter.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if delay := r.DelayFrom(now); delay > retryAfter {
+			limit, retryAfter = name, delay
+		}
+	}
+	if l.identityLimit > 0 {
+		check(limitIdentity, getLimiter(l.identities, identity, l.identityLimit, l.identityBurst))
+	}
+	if l.namespaceLimit > 0 && namespace != "" {
+		check(limitNamespace, getLimiter(l.namespaces, namespace, l.namespaceLimit, l.namespaceBurst))
+	}
+	if retryAfter > 0 {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	return limit, retryAfter
+}
+
+func getLimiter(limiters map[string]*rate.Limiter, key string, limit rate.Limit, burst int) *rate.Limiter {
+	limiter, ok := limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(limit, burst)
+		limiters[key] = limiter
+	}
+	return limiter
+}
+
+// sweep drops the token buckets which have been refilled, as they are equivalent to new ones.
+func (l *csrRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for _, limiters := range []map[string]*rate.Limiter{l.identities, l.namespaces} {
+		for key, limiter := range limiters {
+			if limiter.TokensAt(now) >= float64(limiter.Burst()) {
+				delete(limiters, key)
+			}
+		}
+	}
+}
+
+// rateLimitError returns a RESOURCE_EXHAUSTED status carrying the delay after which the request may be retried.
+func rateLimitError(limit string, retryAfter time.Duration) error {
+	retryAfter = retryAfter.Round(time.Millisecond)
+	st := status.Newf(codes.ResourceExhausted, "CSR exceeds the %s rate limit, retry after %v", limit, retryAfter)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "istio.io/api/security/v1alpha1"
+	"istio.io/istio/pkg/monitoring/monitortest"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test/util/assert"
+	mockca "istio.io/istio/security/pkg/pki/ca/mock"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func TestCSRRateLimiter(t *testing.T) {
+	assert.Equal(t, newCSRRateLimiter(0, 10, 0, 100) == nil, true)
+
+	l := newCSRRateLimiter(1, 2, 2, 3)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+	foo := []string{"spiffe://cluster.local/ns/default/sa/foo"}
+	bar := []string{"spiffe://cluster.local/ns/default/sa/bar"}
+	other := []string{"spiffe://cluster.local/ns/other/sa/foo"}
+
+	// The identity burst is exhausted first.
+	for i := 0; i < 2; i++ {
+		limit, retryAfter := l.allow(foo)
+		assert.Equal(t, limit, "")
+		assert.Equal(t, retryAfter, time.Duration(0))
+	}
+	limit, retryAfter := l.allow(foo)
+	assert.Equal(t, limit, limitIdentity)
+	assert.Equal(t, retryAfter, time.Second)
+
+	// Then the namespace burst, shared by all identities of the namespace.
+	limit, _ = l.allow(bar)
+	assert.Equal(t, limit, "")
+	limit, retryAfter = l.allow(bar)
+	assert.Equal(t, limit, limitNamespace)
+	assert.Equal(t, retryAfter, 500*time.Millisecond)
+	limit, _ = l.allow(other)
+	assert.Equal(t, limit, "")
+
+	// Rejected requests do not take tokens, so bar is allowed once the namespace bucket refills.
+	now = now.Add(500 * time.Millisecond)
+	limit, _ = l.allow(bar)
+	assert.Equal(t, limit, "")
+
+	// Refilled buckets are dropped.
+	now = now.Add(rateLimiterSweepInterval)
+	l.allow(other)
+	assert.Equal(t, len(l.identities), 1)
+	assert.Equal(t, len(l.namespaces), 1)
+}
+
+func TestCreateCertificateRateLimited(t *testing.T) {
+	mt := monitortest.New(t)
+	l := newCSRRateLimiter(1, 1, 0, 0)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+	server := &Server{
+		ca: &mockca.FakeCA{
+			SignedCert:    []byte("cert"),
+			KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte("cert_chain"), []byte("root_cert")),
+		},
+		Authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{"spiffe://cluster.local/ns/default/sa/foo"}}},
+		monitoring:     newMonitoringMetrics(),
+		rateLimiter:    l,
+	}
+	p := &peer.Peer{Addr: &net.IPAddr{IP: net.IPv4(192, 168, 1, 1)}, AuthInfo: credentials.TLSInfo{}}
+	ctx := peer.NewContext(context.Background(), p)
+	request := &pb.IstioCertificateRequest{Csr: "dumb CSR"}
+
+	_, err := server.CreateCertificate(ctx, request)
+	assert.NoError(t, err)
+	_, err = server.CreateCertificate(ctx, request)
+	s, _ := status.FromError(err)
+	assert.Equal(t, s.Code(), codes.ResourceExhausted)
+	assert.Equal(t, len(s.Details()), 1)
+	retryInfo, ok := s.Details()[0].(*errdetails.RetryInfo)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, retryInfo.RetryDelay.AsDuration(), time.Second)
+	mt.Assert(throttledCounts.Name(), map[string]string{"namespace": "default", "limit": limitIdentity}, monitortest.Exactly(1))
+
+	now = now.Add(time.Second)
+	_, err = server.CreateCertificate(ctx, request)
+	assert.NoError(t, err)
+}
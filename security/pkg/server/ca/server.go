@@ -16,6 +16,8 @@ package ca
 
 import (
 	"context"
+	"fmt"
+	"net/url"
 	"time"
 
 	"google.golang.org/grpc"
@@ -31,6 +33,7 @@ import (
 	"istio.io/istio/security/pkg/pki/ca"
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
+	"istio.io/istio/security/pkg/server/ca/authenticate/kubeauth"
 )
 
 var serverCaLog = log.RegisterScope("serverca", "Citadel server log")
@@ -55,6 +58,10 @@ type Server struct {
 	serverCertTTL  time.Duration
 
 	nodeAuthorizer *MulticlusterNodeAuthorizor
+	rateLimiter    *csrRateLimiter
+
+	// AuditSinks record an AuditEvent for every CSR handled by the server.
+	AuditSinks []AuditSink
 }
 
 type SaNode struct {
@@ -76,11 +83,20 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	*pb.IstioCertificateResponse, error,
 ) {
 	s.monitoring.CSR.Increment()
+	event := &AuditEvent{
+		RemoteAddress: security.GetConnectionAddress(ctx),
+		ClusterID:     string(kubeauth.ExtractClusterID(ctx)),
+		RequestedTTL:  Duration(time.Duration(request.ValidityDuration) * time.Second),
+	}
+	defer s.audit(event)
 	caller, err := security.Authenticate(ctx, s.Authenticators)
 	if caller == nil || err != nil {
 		s.monitoring.AuthnError.Increment()
+		event.Reason = "request authenticate failure"
 		return nil, status.Error(codes.Unauthenticated, "request authenticate failure")
 	}
+	event.CallerIdentities = caller.Identities
+	event.AuthenticatorType = caller.AuthenticatorType
 
 	serverCaLog := serverCaLog.WithLabels("client", security.GetConnectionAddress(ctx))
 	// By default, we will use the callers identity for the certificate
@@ -89,12 +105,14 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	impersonatedIdentity := crMetadata[security.ImpersonatedIdentity].GetStringValue()
 	if impersonatedIdentity != "" {
 		serverCaLog.Debugf("impersonated identity: %s", impersonatedIdentity)
+		event.ImpersonatedIdentity = impersonatedIdentity
 		// If there is an impersonated identity, we will override to use that identity (only single value
 		// supported), if the real caller is authorized.
 		if s.nodeAuthorizer == nil {
 			s.monitoring.AuthnError.Increment()
 			// Return an opaque error (for security purposes) but log the full reason
 			serverCaLog.Warnf("impersonation not allowed, as node authorizer is not configured")
+			event.Reason = "impersonation not allowed, as node authorizer is not configured"
 			return nil, status.Error(codes.Unauthenticated, "request impersonation authentication failure")
 
 		}
@@ -102,6 +120,7 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 			s.monitoring.AuthnError.Increment()
 			// Return an opaque error (for security purposes) but log the full reason
 			serverCaLog.Warnf("impersonation failed for identity %s, error: %v", impersonatedIdentity, err)
+			event.Reason = fmt.Sprintf("impersonation failure: %v", err)
 			return nil, status.Error(codes.Unauthenticated, "request impersonation authentication failure")
 		}
 		// Node is authorized to impersonate; overwrite the SAN to the impersonated identity.
@@ -109,6 +128,17 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	}
 	serverCaLog.Debugf("generating a certificate, sans: %v, requested ttl: %s", sans, time.Duration(request.ValidityDuration*int64(time.Second)))
 	certSigner := crMetadata[security.CertSigner].GetStringValue()
+	event.SANs = sans
+	event.CertSigner = certSigner
+	if s.rateLimiter != nil {
+		if limit, retryAfter := s.rateLimiter.allow(sans); retryAfter > 0 {
+			namespace := namespaceOf(sans)
+			s.monitoring.GetThrottled(namespace, limit).Increment()
+			serverCaLog.Debugf("CSR of %v exceeds the %s rate limit, retry after %v", sans, limit, retryAfter)
+			event.Reason = fmt.Sprintf("CSR exceeds the %s rate limit", limit)
+			return nil, rateLimitError(limit, retryAfter)
+		}
+	}
 	_, _, certChainBytes, rootCertBytes := s.ca.GetCAKeyCertBundle().GetAll()
 	certOpts := ca.CertOpts{
 		SubjectIDs: sans,
@@ -127,6 +157,7 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	}
 	if signErr != nil {
 		serverCaLog.Errorf("CSR signing error: %v", signErr.Error())
+		event.Reason = fmt.Sprintf("CSR signing error: %v", signErr)
 		s.monitoring.GetCertSignError(signErr.(*caerror.Error).ErrorType()).Increment()
 		return nil, status.Errorf(signErr.(*caerror.Error).HTTPErrorCode(), "CSR signing error (%v)", signErr.(*caerror.Error))
 	}
@@ -145,9 +176,25 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	}
 	s.monitoring.Success.Increment()
 	serverCaLog.Debugf("CSR successfully signed, sans %v.", caller.Identities)
+	event.Success = true
+	if len(s.AuditSinks) > 0 {
+		recordIssuedCert(event, respCertChain[0])
+	}
 	return response, nil
 }
 
+// recordIssuedCert records the details of the issued leaf certificate in the audit event.
+func recordIssuedCert(event *AuditEvent, leafPEM string) {
+	cert, err := util.ParsePemEncodedCertificate([]byte(leafPEM))
+	if err != nil {
+		serverCaLog.Warnf("failed to parse issued certificate for audit: %v", err)
+		return
+	}
+	event.SerialNumber = cert.SerialNumber.Text(16)
+	event.NotAfter = &cert.NotAfter
+	event.GrantedTTL = Duration(time.Until(cert.NotAfter).Round(time.Second))
+}
+
 func recordCertsExpiry(keyCertBundle *util.KeyCertBundle) {
 	rootCertExpiry, err := keyCertBundle.ExtractRootCertExpiryTimestamp()
 	if err != nil {
@@ -188,6 +235,8 @@ func New(
 		serverCertTTL:  ttl,
 		ca:             ca,
 		monitoring:     newMonitoringMetrics(),
+		rateLimiter: newCSRRateLimiter(features.CACSRRateLimitPerIdentity, features.CACSRBurstPerIdentity,
+			features.CACSRRateLimitPerNamespace, features.CACSRBurstPerNamespace),
 	}
 
 	if len(features.CATrustedNodeAccounts) > 0 {
@@ -195,5 +244,25 @@ func New(
 		// Worst case is we deny some requests though which are retried
 		server.nodeAuthorizer = NewMulticlusterNodeAuthenticator(features.CATrustedNodeAccounts, controller)
 	}
+
+	if features.CAAuditLogPath != "" {
+		server.AuditSinks = append(server.AuditSinks,
+			NewFileAuditSink(features.CAAuditLogPath, features.CAAuditLogMaxSize, features.CAAuditLogMaxBackups))
+	}
+	if features.CAAuditWebhookURL != "" {
+		if _, err := url.ParseRequestURI(features.CAAuditWebhookURL); err != nil {
+			return nil, fmt.Errorf("invalid CA_AUDIT_WEBHOOK_URL: %v", err)
+		}
+		server.AuditSinks = append(server.AuditSinks, NewWebhookAuditSink(features.CAAuditWebhookURL, features.CAAuditWebhookTimeout))
+	}
 	return server, nil
 }
+
+// Close flushes the audit events pending in the audit sinks.
+func (s *Server) Close() {
+	for _, sink := range s.AuditSinks {
+		if err := sink.Close(); err != nil {
+			serverCaLog.Errorf("failed to close audit sink: %v", err)
+		}
+	}
+}
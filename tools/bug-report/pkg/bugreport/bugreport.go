@@ -48,6 +48,7 @@ import (
 	"istio.io/istio/tools/bug-report/pkg/kubeclient"
 	"istio.io/istio/tools/bug-report/pkg/kubectlcmd"
 	"istio.io/istio/tools/bug-report/pkg/processlog"
+	"istio.io/istio/tools/bug-report/pkg/redact"
 )
 
 const (
@@ -78,7 +79,11 @@ All names except label and annotation keys support '*' glob matching pattern.
 
 e.g.
 --include ns1,ns2 (only namespaces ns1 and ns2)
---include n*//p*/l=v* (pods with name beginning with 'p' in namespaces beginning with 'n' and having label 'l' with value beginning with 'v'.)`,
+--include n*//p*/l=v* (pods with name beginning with 'p' in namespaces beginning with 'n' and having label 'l' with value beginning with 'v'.)
+
+Secret data, bearer tokens, JWTs and PEM private keys are redacted from all collected content unless
+--disable-redaction is set. Additional content, such as hostnames, can be redacted with --redact-pattern.
+The archive includes a redaction-report file listing the redactions made in each file.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runBugReportCommand(ctx, cmd, logOpts)
 		},
@@ -110,6 +115,10 @@ func runBugReportCommand(ctx cli.Context, _ *cobra.Command, logOpts *log.Options
 	if err != nil {
 		return err
 	}
+	redactor, err := redact.New(config.RedactPatterns)
+	if err != nil {
+		return err
+	}
 	clusterCtxStr := ""
 	if config.Context == "" {
 		var err error
@@ -188,6 +197,11 @@ func runBugReportCommand(ctx cli.Context, _ *cobra.Command, logOpts *log.Options
 	outPath := filepath.Join(outDir, "bug-report.tar.gz")
 
 	if !config.DryRun {
+		if !config.DisableRedaction {
+			if err := redactOutput(redactor); err != nil {
+				return err
+			}
+		}
 		common.LogAndPrintf("Creating an archive at %s.\n", outPath)
 		archiveDir := archive.DirToArchive(tempDir)
 		if tempDir != "" {
@@ -210,6 +224,21 @@ func runBugReportCommand(ctx cli.Context, _ *cobra.Command, logOpts *log.Options
 	return nil
 }
 
+// redactOutput scrubs sensitive content from all collected files and adds a report of the redactions.
+// No archive is created if redaction fails, as it could contain sensitive content.
+func redactOutput(redactor *redact.Redactor) error {
+	defer logRuntime(time.Now(), "Done redacting sensitive content")
+
+	outDir := archive.OutputRootDir(tempDir)
+	report, err := redactor.Dir(outDir)
+	if err != nil {
+		return fmt.Errorf("failed to redact %s: %v", outDir, err)
+	}
+	common.LogAndPrintf("Redacted %d values from %d files, see %s in the archive.\n", report.Total(), len(report.Files), redact.ReportFileName)
+	writeFile(filepath.Join(outDir, redact.ReportFileName), report.String(), false)
+	return nil
+}
+
 func dumpRevisionsAndVersions(ctx cli.Context, resources *cluster2.Resources, istioNamespace string, dryRun bool) {
 	defer logRuntime(time.Now(), "Done getting control plane revisions/versions")
 
@@ -54,7 +54,14 @@ func addFlags(cmd *cobra.Command, args *config2.BugReportConfig) {
 
 	// full secrets
 	cmd.PersistentFlags().BoolVarP(&args.FullSecrets, "full-secrets", "", false,
-		"If set, secret contents are included in output.")
+		"If set, secret contents are included in output. The contents are redacted unless --disable-redaction is set.")
+
+	// redaction
+	cmd.PersistentFlags().BoolVar(&args.DisableRedaction, "disable-redaction", false,
+		"If set, Secret data, bearer tokens, JWTs and private keys are not redacted from the archive.")
+	cmd.PersistentFlags().StringArrayVar(&args.RedactPatterns, "redact-pattern", nil,
+		"Regular expression for additional content to redact from the archive, such as hostnames. "+
+			"Matches are replaced with a hash so that they can still be correlated. Can be repeated.")
 
 	// istio namespaces
 	cmd.PersistentFlags().StringVar(&args.IstioNamespace, "istio-namespace", bugReportDefaultIstioNamespace,
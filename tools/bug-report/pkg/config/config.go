@@ -141,6 +141,13 @@ type BugReportConfig struct {
 	// FullSecrets controls whether secret contents are included.
 	FullSecrets bool `json:"fullSecrets,omitempty"`
 
+	// DisableRedaction controls whether sensitive content such as Secret data, bearer tokens
+	// and private keys is kept in the archive instead of being redacted.
+	DisableRedaction bool `json:"disableRedaction,omitempty"`
+	// RedactPatterns is a list of regular expressions matching additional content to redact,
+	// such as customer hostnames.
+	RedactPatterns []string `json:"redactPatterns,omitempty"`
+
 	// CommandTimeout is the maximum amount of time running the command
 	// before giving up, even if not all logs are captured. Upon timeout,
 	// the command creates an archive with only the logs captured so far.
@@ -188,6 +195,10 @@ func (b *BugReportConfig) String() string {
 	}
 	out += fmt.Sprintf("istio-namespace: %s\n", b.IstioNamespace)
 	out += fmt.Sprintf("full-secrets: %v\n", b.FullSecrets)
+	out += fmt.Sprintf("disable-redaction: %v\n", b.DisableRedaction)
+	if len(b.RedactPatterns) > 0 {
+		out += fmt.Sprintf("redact-patterns: %s\n", strings.Join(b.RedactPatterns, ", "))
+	}
 	out += fmt.Sprintf("timeout (mins): %v\n", math.Round(float64(int(b.CommandTimeout))/float64(time.Minute)))
 	out += fmt.Sprintf("include: %s\n", b.Include)
 	out += fmt.Sprintf("exclude: %s\n", b.Exclude)
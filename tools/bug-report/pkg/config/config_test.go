@@ -32,6 +32,9 @@ context: d
 istioNamespace: e1
 dryRun: true
 fullSecrets: true
+disableRedaction: true
+redactPatterns:
+  - foo\.example\.com
 commandTimeout: 5m
 include:
   - ns1,ns2/d1,d2/p1,p2/l1=lv1,l2=lv2/a1=av1,a2=av2/c1,c2
@@ -53,12 +56,14 @@ ignoredErrors:
 		t.Fatal(err)
 	}
 	want := &BugReportConfig{
-		KubeConfigPath: "a/b/c",
-		Context:        "d",
-		IstioNamespace: "e1",
-		DryRun:         true,
-		FullSecrets:    true,
-		CommandTimeout: Duration(5 * time.Minute),
+		KubeConfigPath:   "a/b/c",
+		Context:          "d",
+		IstioNamespace:   "e1",
+		DryRun:           true,
+		FullSecrets:      true,
+		DisableRedaction: true,
+		RedactPatterns:   []string{`foo\.example\.com`},
+		CommandTimeout:   Duration(5 * time.Minute),
 		Include: []*SelectionSpec{
 			{
 				Namespaces:  []string{"ns1", "ns2"},